@@ -0,0 +1,161 @@
+//go:build ignore
+
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// ----------------------
+// Version & Constants
+// ----------------------
+const version = "v0.0.1"
+const defaultInputFile = "example-sentences.tsv"
+const defaultOutputFile = "frequencies.txt"
+
+// ----------------------
+// Custom Usage Function
+// ----------------------
+
+func printCustomUsage() {
+	fmt.Fprintf(os.Stderr, "makefreq (%s) - Counts word forms in a Finnish corpus for tsk's frequency ranking.\n\n", version)
+	fmt.Fprintf(os.Stderr, "USAGE:\n")
+	fmt.Fprintf(os.Stderr, "  makefreq [flags]\n")
+	fmt.Fprintf(os.Stderr, "  cut -f1 example-sentences.tsv | makefreq\n\n")
+	fmt.Fprintf(os.Stderr, "By default, it reads the Finnish (first) column of '%s' and writes to '%s'.\n", defaultInputFile, defaultOutputFile)
+	fmt.Fprintf(os.Stderr, "If '%s' is not found, it will attempt to read from standard input.\n\n", defaultInputFile)
+	fmt.Fprintf(os.Stderr, "The output has one 'word<TAB>count' line per word form, most frequent first.\n\n")
+	fmt.Fprintf(os.Stderr, "FLAGS:\n")
+	flag.PrintDefaults()
+}
+
+// ----------------------
+// Main Application
+// ----------------------
+
+func main() {
+	fmt.Printf("makefreq (%s) - Word Frequency Counter\n\n", version)
+
+	// --- Flag setup ---
+	inputFile := flag.String("in", "", "Input TSV file, Finnish text in the first column. (default: example-sentences.tsv or stdin)")
+	outputFile := flag.String("out", defaultOutputFile, "Output frequency list.")
+	minCount := flag.Int("min", 2, "Leave out word forms seen fewer than this many times.")
+	flag.Usage = printCustomUsage
+	flag.Parse()
+
+	// --- Determine Input Source ---
+	var reader io.Reader
+	var inputSourceName string
+
+	// Priority: 1. -in flag, 2. Stdin pipe, 3. Default file
+	if *inputFile != "" {
+		file, err := os.Open(*inputFile)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error opening specified input file '%s': %v\n", *inputFile, err)
+			os.Exit(1)
+		}
+		defer file.Close()
+		reader = file
+		inputSourceName = *inputFile
+	} else {
+		stat, _ := os.Stdin.Stat()
+		if (stat.Mode() & os.ModeCharDevice) == 0 {
+			reader = os.Stdin
+			inputSourceName = "standard input"
+		} else {
+			file, err := os.Open(defaultInputFile)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error opening default input file '%s': %v\n", defaultInputFile, err)
+				fmt.Fprintln(os.Stderr, "You can specify a file with -in or pipe data to the program.")
+				os.Exit(1)
+			}
+			defer file.Close()
+			reader = file
+			inputSourceName = defaultInputFile
+		}
+	}
+
+	// --- Processing ---
+	fmt.Printf("Counting words in %s...\n", inputSourceName)
+	start := time.Now()
+
+	counts, err := countWords(reader)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Error reading input:", err)
+		os.Exit(1)
+	}
+	fmt.Printf(" -> Counted %d distinct word forms in %v.\n", len(counts), time.Since(start))
+
+	fmt.Printf("Writing frequency list to %s...\n", *outputFile)
+	written, err := saveFrequencies(counts, *minCount, *outputFile)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Error writing frequency list:", err)
+		os.Exit(1)
+	}
+	fmt.Printf(" -> Wrote %d word forms seen at least %d times.\n\n", written, *minCount)
+	fmt.Println("Counting complete.")
+}
+
+// countWords tallies the lowercase word forms in the first tab-separated
+// column of each line. Words are split the same way `tsk read` splits them:
+// on anything that isn't a letter or a hyphen.
+func countWords(r io.Reader) (map[string]int, error) {
+	scanner := bufio.NewScanner(r)
+	counts := make(map[string]int)
+
+	for scanner.Scan() {
+		finnish, _, _ := strings.Cut(scanner.Text(), "\t")
+		fields := strings.FieldsFunc(finnish, func(r rune) bool {
+			return !unicode.IsLetter(r) && r != '-'
+		})
+		for _, field := range fields {
+			if word := strings.Trim(field, "-"); word != "" {
+				counts[strings.ToLower(word)]++
+			}
+		}
+	}
+
+	return counts, scanner.Err()
+}
+
+// saveFrequencies writes the word forms seen at least minCount times, most
+// frequent first and alphabetically among equals, so that line numbers are
+// stable frequency ranks.
+func saveFrequencies(counts map[string]int, minCount int, path string) (int, error) {
+	var words []string
+	for word, count := range counts {
+		if count >= minCount {
+			words = append(words, word)
+		}
+	}
+	sort.Slice(words, func(i, j int) bool {
+		if counts[words[i]] != counts[words[j]] {
+			return counts[words[i]] > counts[words[j]]
+		}
+		return words[i] < words[j]
+	})
+
+	file, err := os.Create(path)
+	if err != nil {
+		return 0, fmt.Errorf("could not create file: %w", err)
+	}
+	defer file.Close()
+
+	writer := bufio.NewWriter(file)
+	for _, word := range words {
+		fmt.Fprintf(writer, "%s\t%d\n", word, counts[word])
+	}
+	if err := writer.Flush(); err != nil {
+		return 0, err
+	}
+
+	return len(words), nil
+}
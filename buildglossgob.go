@@ -1,3 +1,5 @@
+//go:build ignore
+
 package main
 
 import (
@@ -28,6 +30,13 @@ type Gloss struct {
 	Word     string   `json:"word"`
 	Pos      string   `json:"pos"`
 	Meanings []string `json:"meanings"`
+
+	// Only in data from `tsk update-data`, and only where Wiktionary has
+	// them.
+	Etymology string   `json:"etymology,omitempty"`
+	Synonyms  []string `json:"synonyms,omitempty"`
+	Antonyms  []string `json:"antonyms,omitempty"`
+	Derived   []string `json:"derived,omitempty"`
 }
 
 // ----------------------
@@ -0,0 +1,1951 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"database/sql"
+	"encoding/csv"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"maps"
+	"math"
+	"net"
+	"net/http"
+	"net/http/pprof"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/hiAndrewQuinn/tsk/pkg/tsk"
+	"github.com/rivo/tview"
+)
+
+// ----------------------
+// CLI Subcommands
+// ----------------------
+
+// subcommands maps the first positional argument to its handler. Anything
+// that isn't a known subcommand falls through to the plain word lookup.
+var subcommands = map[string]func(args []string) error{
+	"suffix":  runSuffixCommand,
+	"pattern": runPatternCommand,
+	"rhymes":  runRhymesCommand,
+	"read":    runReadCommand,
+	"ocr":     runOCRCommand,
+	"diff":    runDiffCommand,
+	"merge":   runMergeCommand,
+
+	"import-sentences": runImportSentencesCommand,
+	"import-words":     runImportWordsCommand,
+	"tags":             runTagsCommand,
+	"quiz":             runQuizCommand,
+	"stats":            runStatsCommand,
+	"report":           runReportCommand,
+	"update-data":      runUpdateDataCommand,
+	"update-audio":     runUpdateAudioCommand,
+	"ssh-serve":        runSSHServeCommand,
+	"lsp":              runLSPCommand,
+	"build-stardict":   runBuildStardictCommand,
+	"export":           runExportCommand,
+	"export-pdf":       runExportPDFCommand,
+	"serve":            runSSHServeCommand,
+	"overrides":        runOverridesCommand,
+	"random":           runRandomCommand,
+	"verify-data":      runVerifyDataCommand,
+}
+
+// commandDoc describes a subcommand for `tsk --help` and `tsk help NAME`.
+type commandDoc struct {
+	name    string
+	args    string
+	summary string
+	example string
+}
+
+// commandDocs lists the subcommands in the order `tsk --help` shows them.
+var commandDocs = []commandDoc{
+	{"lookup", "WORD...", "Print the glosses of words, like plain `tsk WORD...`, taking the same flags.", "lookup --format csv hei maailma"},
+	{"suffix", "ENDING...", "List words ending in ENDING, e.g. for rhymes.", "suffix llinen"},
+	{"pattern", "PATTERN...", "List words matching PATTERN, where . or _ is exactly one letter.", "pattern k___a"},
+	{"rhymes", "WORD...", "List words sharing WORD's last syllable, or --syllables N or --letters N.", "rhymes --syllables 2 herttuainen"},
+	{"read", "FILE", "Print a glossary and the unknown words of a text.", "read --watch draft.txt"},
+	{"ocr", "IMAGE", "Like read, but for text in an image. Needs Tesseract installed.", "ocr screenshot.png"},
+	{"diff", "LIST_A LIST_B", "Compare two word lists or marked-word exports.", "diff laptop.txt desktop.txt"},
+	{"merge", "LIST...", "Combine word lists into one, e.g. from different machines.", "merge --out all.txt laptop.txt desktop.jsonl"},
+	{"import-sentences", "FILE", "Add your own Finnish/English sentence pairs (CSV or TSV) to Ctrl-T.", "import-sentences --source \"Suomen mestari 1\" chapter1.tsv"},
+	{"import-words", "FILE", "Add a vocabulary list (CSV or TSV of word, tag and meaning) to search, quiz and export by tag.", "import-words --tag kappale1 chapter1.txt"},
+	{"tags", "", "List the tags of your imported word lists, or forget one with --remove.", "tags --remove kappale1"},
+	{"quiz", "[LIST...]", "Review your marked words, and any word lists given, with spaced repetition.", "quiz --stats"},
+	{"stats", "", "Count the dictionary's words, glosses and sentences, and your lookups and marks.", "stats --json"},
+	{"report", "", "Sum up your study log: words looked up each day, streaks, and the words you keep looking up.", "report --weeks 8"},
+	{"export", "", "Save your marked words and sentences as quitting the TUI does.", "export --as anki"},
+	{"export-pdf", "", "Typeset your marked words, or a --input list, as a printable two-column vocabulary sheet.", "export-pdf --input chapter3.txt --title \"Kappale 3\""},
+	{"overrides", "", "Print the glosses you edited with Ctrl-J, and the dictionary's, as JSON lines to share.", "overrides --out my-fixes.jsonl"},
+	{"verify-data", "[PACK]", "Check a dictionary pack against its checksums and signature, or --write and sign them.", "verify-data estonian.zip"},
+	{"update-data", "", "Download the latest Wiktionary data, which tsk then prefers to its own.", "update-data --from kaikki.org-dictionary-Finnish.jsonl.gz"},
+	{"update-audio", "", "Download Wiktionary's recordings of native speakers, for Ctrl-K to play.", "update-audio --from kaikki.org-dictionary-Finnish.jsonl.gz"},
+	{"serve", "", "Serve the TUI to anyone who connects with ssh, each in their own session. Also called ssh-serve.", "serve --addr :2222 --password-file ~/tsk-password"},
+	{"lsp", "", "Serve glosses on hover and word completion to editors, over stdio.", ""},
+	{"build-stardict", "", "Write the glosses as a StarDict dictionary, for KOReader and other e-readers.", "build-stardict --out ~/koreader/data/dict/tsk"},
+	{"random", "", "Print a random word with its meanings and an example sentence, of a --pos and --band if given.", "random --pos verb --band top5k"},
+	{"wotd", "", "Print the word of the day with its meanings and an example sentence.", "wotd --date 2025-12-06"},
+	{"completion", "SHELL", "Print a bash, zsh or fish script that completes subcommands and headwords.", "completion bash"},
+	{"help", "[COMMAND]", "Show this help, or a subcommand's flags.", "help quiz"},
+}
+
+// findCommandDoc returns the doc of a subcommand, going by its aliases too.
+func findCommandDoc(name string) (commandDoc, bool) {
+	if name == "ssh-serve" {
+		name = "serve"
+	}
+	for _, doc := range commandDocs {
+		if doc.name == name {
+			return doc, true
+		}
+	}
+	return commandDoc{}, false
+}
+
+// printCommandDoc prints a subcommand's usage line, summary and example.
+func printCommandDoc(doc commandDoc) {
+	fmt.Fprintf(os.Stderr, "USAGE:\n  tsk %s [flags] %s\n\n", doc.name, doc.args)
+	fmt.Fprintf(os.Stderr, "%s\n", doc.summary)
+	if doc.example != "" {
+		fmt.Fprintf(os.Stderr, "  $ tsk %s\n", doc.example)
+	}
+}
+
+// newCommandFlags makes the flag set of a subcommand, whose -h prints the
+// subcommand's doc before its flags.
+func newCommandFlags(name string) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ExitOnError)
+	fs.Usage = func() {
+		if doc, ok := findCommandDoc(name); ok {
+			printCommandDoc(doc)
+		}
+		fmt.Fprintf(os.Stderr, "\nFLAGS:\n")
+		fs.PrintDefaults()
+	}
+	return fs
+}
+
+// runHelpCommand prints the usage of tsk, or of one subcommand, e.g.
+// `tsk help quiz`. It isn't in subcommands, since it looks them up.
+func runHelpCommand(args []string) error {
+	if len(args) == 0 {
+		flag.Usage()
+		return nil
+	}
+	if len(args) > 1 {
+		return fmt.Errorf("usage: tsk help [COMMAND]")
+	}
+	name := args[0]
+	if name == "lookup" {
+		doc, _ := findCommandDoc(name)
+		printCommandDoc(doc)
+		fmt.Fprintf(os.Stderr, "\nFLAGS:\n")
+		flag.PrintDefaults()
+		return nil
+	}
+	if doc, ok := findCommandDoc(name); ok && name == "help" {
+		printCommandDoc(doc)
+		return nil
+	}
+	cmd, ok := subcommands[name]
+	if !ok {
+		return fmt.Errorf("unknown command '%s' (choose from %s)", name, strings.Join(subcommandNames(), ", "))
+	}
+	return cmd([]string{"-h"})
+}
+
+// runSuffixCommand prints every word ending in each of the given endings,
+// e.g. `tsk suffix llinen sto`.
+func runSuffixCommand(args []string) error {
+	fs := newCommandFlags("suffix")
+	minFrequency := fs.Int("min-frequency", 0, "only list words seen at least this many times in the example sentences")
+	fs.Parse(args)
+
+	if fs.NArg() == 0 {
+		return fmt.Errorf("usage: tsk suffix [--min-frequency N] ENDING [ENDING...]")
+	}
+
+	words, err := loadWords()
+	if err != nil {
+		return fmt.Errorf("loading words: %w", err)
+	}
+	index := tsk.NewSuffixIndex(words)
+	frequencies, err := loadFrequencies()
+	if err != nil {
+		return fmt.Errorf("loading frequencies: %w", err)
+	}
+
+	fmt.Println("===")
+	for i, ending := range fs.Args() {
+		ending = strings.TrimPrefix(ending, "-")
+		// Every word is listed, and filtered before any are left out.
+		matches := filterByFrequency(index.FindWordsN(ending, 0), frequencies, *minFrequency)
+		tsk.SortFinnish(matches)
+		if len(matches) == 0 {
+			fmt.Printf("No words ending in '%s' found.\n", ending)
+		}
+		for _, match := range matches {
+			fmt.Println(match)
+		}
+
+		// Print a separator between results, but not after the last one.
+		if i < fs.NArg()-1 {
+			fmt.Println("---")
+		}
+	}
+	fmt.Println("===")
+	return nil
+}
+
+// runRhymesCommand prints the words sharing each given word's ending: its
+// last syllable, or the last --syllables or --letters of it, e.g.
+// `tsk rhymes --syllables 2 herttuainen`.
+func runRhymesCommand(args []string) error {
+	fs := newCommandFlags("rhymes")
+	syllables := fs.Int("syllables", 1, "share this many syllables at the end of the word")
+	letters := fs.Int("letters", 0, "share this many letters at the end of the word instead")
+	minFrequency := fs.Int("min-frequency", 0, "only list words seen at least this many times in the example sentences")
+	fs.Parse(args)
+
+	if fs.NArg() == 0 {
+		return fmt.Errorf("usage: tsk rhymes [--syllables N | --letters N] [--min-frequency N] WORD [WORD...]")
+	}
+
+	words, err := loadWords()
+	if err != nil {
+		return fmt.Errorf("loading words: %w", err)
+	}
+	index := tsk.NewSuffixIndex(words)
+	frequencies, err := loadFrequencies()
+	if err != nil {
+		return fmt.Errorf("loading frequencies: %w", err)
+	}
+
+	fmt.Println("===")
+	for i, word := range fs.Args() {
+		ending := tsk.RhymeEnding(word, *syllables)
+		if runes := []rune(word); *letters > 0 {
+			ending = string(runes[max(0, len(runes)-*letters):])
+		}
+		var matches []string
+		for _, match := range filterByFrequency(index.FindWordsN(ending, 0), frequencies, *minFrequency) {
+			if match != word {
+				matches = append(matches, match)
+			}
+		}
+		tsk.SortFinnish(matches)
+		fmt.Printf("-%s:\n", ending)
+		if len(matches) == 0 {
+			fmt.Printf("No other words ending in '%s' found.\n", ending)
+		}
+		for _, match := range matches {
+			fmt.Println(match)
+		}
+
+		// Print a separator between results, but not after the last one.
+		if i < fs.NArg()-1 {
+			fmt.Println("---")
+		}
+	}
+	fmt.Println("===")
+	return nil
+}
+
+// runPatternCommand prints every word matching each fixed-length pattern,
+// where '.' or '_' stands for exactly one letter, e.g. `tsk pattern k___a`.
+// A glob, with '?' for one letter and '*' for anything, like "k*ssa", is
+// matched against the trie instead, up to --limit words.
+func runPatternCommand(args []string) error {
+	fs := newCommandFlags("pattern")
+	minFrequency := fs.Int("min-frequency", 0, "only list words seen at least this many times in the example sentences")
+	limit := fs.Int("limit", GLOB_LIMIT, "list at most this many words for a glob with ? or *; 0 for no limit")
+	fs.Parse(args)
+
+	if fs.NArg() == 0 {
+		return fmt.Errorf("usage: tsk pattern [--min-frequency N] [--limit N] PATTERN [PATTERN...]")
+	}
+
+	words, err := loadWords()
+	if err != nil {
+		return fmt.Errorf("loading words: %w", err)
+	}
+	index := tsk.NewPatternIndex(words)
+	var trie *tsk.Trie
+	frequencies, err := loadFrequencies()
+	if err != nil {
+		return fmt.Errorf("loading frequencies: %w", err)
+	}
+
+	fmt.Println("===")
+	for i, pattern := range fs.Args() {
+		var matches []string
+		if strings.ContainsAny(pattern, "?*") {
+			if trie == nil {
+				trie = tsk.NewTrie(words)
+			}
+			matches = filterByFrequency(trie.Glob(pattern, 0), frequencies, *minFrequency)
+			if *limit > 0 && len(matches) > *limit {
+				matches = matches[:*limit]
+			}
+		} else {
+			matches = filterByFrequency(index.Match(pattern, 0), frequencies, *minFrequency)
+		}
+		tsk.SortFinnish(matches)
+		if len(matches) == 0 {
+			fmt.Printf("No words matching '%s' found.\n", pattern)
+		}
+		for _, match := range matches {
+			fmt.Println(match)
+		}
+
+		// Print a separator between results, but not after the last one.
+		if i < fs.NArg()-1 {
+			fmt.Println("---")
+		}
+	}
+	fmt.Println("===")
+	return nil
+}
+
+// ----------------------
+// Word List Diff & Merge (`tsk diff`, `tsk merge`)
+// ----------------------
+
+// readWordList reads the words from a marked-words export: either the .jsonl
+// gloss dump or the one-column .txt/.csv list. Plain one-word-per-line files,
+// like a course syllabus, work too, as do Anki's tab-separated exports: the
+// first field of each note is taken, and "#" header lines are skipped.
+// Words are returned without duplicates.
+func readWordList(path string) ([]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var words []string
+	seen := make(map[string]struct{})
+	add := func(word string) {
+		word = strings.TrimSpace(word)
+		if word == "" {
+			return
+		}
+		if _, ok := seen[word]; !ok {
+			seen[word] = struct{}{}
+			words = append(words, word)
+		}
+	}
+
+	if strings.EqualFold(filepath.Ext(path), ".jsonl") {
+		scanner := bufio.NewScanner(f)
+		scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+		for lineNum := 1; scanner.Scan(); lineNum++ {
+			if strings.TrimSpace(scanner.Text()) == "" {
+				continue
+			}
+			var g tsk.Gloss
+			if err := json.Unmarshal(scanner.Bytes(), &g); err != nil {
+				return nil, fmt.Errorf("%s line %d: %w", path, lineNum, err)
+			}
+			add(g.Word)
+		}
+		return words, scanner.Err()
+	}
+
+	reader := csv.NewReader(f)
+	reader.FieldsPerRecord = -1
+	reader.Comment = '#'
+	reader.LazyQuotes = true
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		if len(record) == 0 || record[0] == "Base Form" {
+			continue
+		}
+		word, _, _ := strings.Cut(record[0], "\t")
+		add(word)
+	}
+	return words, nil
+}
+
+// writeWordList writes words in the same one-column format as the marked-word
+// .txt export.
+func writeWordList(w io.Writer, words []string) error {
+	cw := csv.NewWriter(w)
+	cw.Write([]string{"Base Form"})
+	for _, word := range words {
+		cw.Write([]string{word})
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+func printWordSection(title string, words []string) {
+	fmt.Printf("%s (%d):\n", title, len(words))
+	for _, word := range words {
+		fmt.Println(word)
+	}
+}
+
+// runDiffCommand reports which words are only in the first list, only in the
+// second, and in both.
+func runDiffCommand(args []string) error {
+	fs := newCommandFlags("diff")
+	fs.Parse(args)
+	args = fs.Args()
+	if len(args) != 2 {
+		return fmt.Errorf("usage: tsk diff LIST_A LIST_B")
+	}
+	a, err := readWordList(args[0])
+	if err != nil {
+		return err
+	}
+	b, err := readWordList(args[1])
+	if err != nil {
+		return err
+	}
+
+	onlyA := diffWords(b, a)
+	onlyB := diffWords(a, b)
+	both := diffWords(onlyA, a)
+	tsk.SortFinnish(onlyA)
+	tsk.SortFinnish(onlyB)
+	tsk.SortFinnish(both)
+
+	fmt.Println("===")
+	printWordSection("Only in "+args[0], onlyA)
+	fmt.Println("---")
+	printWordSection("Only in "+args[1], onlyB)
+	fmt.Println("---")
+	printWordSection("In both", both)
+	fmt.Println("===")
+	return nil
+}
+
+// runMergeCommand combines any number of word lists into one sorted list.
+func runMergeCommand(args []string) error {
+	fs := newCommandFlags("merge")
+	out := fs.String("out", "", "write the merged list to this `file` instead of standard output")
+	fs.Parse(args)
+
+	if fs.NArg() < 2 {
+		return fmt.Errorf("usage: tsk merge [--out FILE] LIST LIST [LIST...]")
+	}
+
+	var merged []string
+	for _, path := range fs.Args() {
+		words, err := readWordList(path)
+		if err != nil {
+			return err
+		}
+		merged = append(merged, diffWords(merged, words)...)
+	}
+	tsk.SortFinnish(merged)
+
+	if *out == "" {
+		return writeWordList(os.Stdout, merged)
+	}
+	f, err := os.Create(*out)
+	if err != nil {
+		return err
+	}
+	err = writeWordList(f, merged)
+	// A full disk may only show when the file is closed.
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fmt.Errorf("writing to %s: %w", *out, err)
+	}
+	fmt.Printf("Saved %d merged words to %s\n", len(merged), *out)
+	return nil
+}
+
+// ----------------------
+// Batch Lookup (`tsk --file`)
+// ----------------------
+
+// Lookup statuses, for batch output and its summary.
+const (
+	lookupFound     = "found"
+	lookupInflected = "inflected"
+	lookupMissing   = "missing"
+)
+
+// lookupResult is what looking up one word on the command line found.
+type lookupResult struct {
+	Word        string              `json:"word"`
+	Status      string              `json:"status"`
+	BaseForms   []string            `json:"base_forms,omitempty"`
+	Forms       map[string][]string `json:"forms,omitempty"` // base form -> what the word is of it
+	Glosses     []tsk.Gloss         `json:"glosses,omitempty"`
+	Suggestions []string            `json:"suggestions,omitempty"`
+}
+
+// lookupWord looks term up as a base form, then as an inflected form, and
+// failing both suggests similar words from fuzzy, which is only called
+// when it's needed. Case doesn't matter: "Äiti" is found as äiti, and
+// the result is then for the headword as the dictionary writes it.
+func lookupWord(term string, glosses map[string][]tsk.Gloss, fuzzy func() *tsk.PatternIndex) lookupResult {
+	term = strings.TrimSpace(tsk.PhraseQuery(term))
+	r := lookupResult{Word: term}
+	folded := tsk.FoldCase(term)
+	if _, ok := glosses[term]; !ok {
+		if _, ok := glosses[folded]; ok {
+			r.Word = folded
+		}
+	}
+	if g, ok := glosses[r.Word]; ok {
+		r.Status = lookupFound
+		r.BaseForms = []string{r.Word}
+		r.Glosses = g
+	} else if standards, notes := colloquialStandards(folded, glosses); len(standards) > 0 {
+		// A spoken form or abbreviation reads like an inflected form of
+		// its standard word, with the note in place of the case.
+		r.Status = lookupInflected
+		r.BaseForms, r.Forms = standards, notes
+		for _, word := range standards {
+			r.Glosses = append(r.Glosses, glosses[word]...)
+		}
+	} else if analyses := tsk.AnalyzeWord(folded, glosses); len(analyses) > 0 {
+		r.Status = lookupInflected
+		r.BaseForms, r.Forms = tsk.GroupAnalyses(analyses)
+		for _, lemma := range r.BaseForms {
+			r.Glosses = append(r.Glosses, glosses[lemma]...)
+		}
+	} else {
+		r.Status = lookupMissing
+		if index := fuzzy(); index != nil {
+			// Only suggest words that would be found, which with --pos
+			// leaves out the other parts of speech.
+			for _, w := range index.Similar(folded, 5) {
+				if _, ok := glosses[w]; ok {
+					r.Suggestions = append(r.Suggestions, w)
+				}
+			}
+		}
+	}
+	return r
+}
+
+// splitSearchTerms splits text into the terms to look up: its words, except
+// that "double quotes" keep a phrase like "hyvää päivää" together.
+func splitSearchTerms(text string) []string {
+	var terms []string
+	for i, part := range strings.Split(text, `"`) {
+		if i%2 == 1 {
+			// Inside quotes.
+			if phrase := strings.Join(strings.Fields(part), " "); phrase != "" {
+				terms = append(terms, phrase)
+			}
+			continue
+		}
+		terms = append(terms, strings.Fields(part)...)
+	}
+	return terms
+}
+
+// posFilters are the parts of speech the TUI's filter key steps through,
+// after which it lists all words again.
+var posFilters = []string{"noun", "verb", "adj", "adv"}
+
+// checkPos returns an error if no word in glosses is the part of speech
+// pos, which is most likely a typo for one of posFilters.
+func checkPos(glosses map[string][]tsk.Gloss, pos string) error {
+	for _, g := range glosses {
+		if tsk.HasPos(g, pos) {
+			return nil
+		}
+	}
+	return fmt.Errorf("no words are tagged '%s' (try %s)", pos, strings.Join(posFilters, ", "))
+}
+
+// filterGlossesByPos keeps only the glosses that are the part of speech
+// pos, dropping the words left with none.
+func filterGlossesByPos(glosses map[string][]tsk.Gloss, pos string) map[string][]tsk.Gloss {
+	kept := make(map[string][]tsk.Gloss)
+	for word, g := range glosses {
+		if g = tsk.FilterPos(g, pos); len(g) > 0 {
+			kept[word] = g
+		}
+	}
+	return kept
+}
+
+// writeLookupText writes r the way `tsk WORD...` prints it.
+func writeLookupText(w io.Writer, r lookupResult, glosses map[string][]tsk.Gloss) {
+	switch r.Status {
+	case lookupFound:
+		fmt.Fprintln(w, stripColorTags(generateGlossText(r.Word, glosses)))
+	case lookupInflected:
+		for j, lemma := range r.BaseForms {
+			if j > 0 {
+				fmt.Fprintln(w)
+			}
+			fmt.Fprintf(w, "%s ~> %s (%s)\n\n", r.Word, lemma, strings.Join(r.Forms[lemma], ", "))
+			fmt.Fprintln(w, stripColorTags(generateGlossText(lemma, glosses)))
+		}
+	default:
+		fmt.Fprintf(w, "'%s' not found.\n", r.Word)
+		if len(r.Suggestions) > 0 {
+			fmt.Fprintf(w, "Did you mean: %s?\n", strings.Join(r.Suggestions, ", "))
+		}
+	}
+}
+
+// batchFormat picks the output format for lookups: --format if given,
+// otherwise template if there is a --template, otherwise from the --out
+// file's extension, otherwise text.
+func batchFormat(format, out string) (string, error) {
+	if format == "" && lookupTemplate != nil {
+		format = "template"
+	}
+	if format == "" {
+		switch strings.ToLower(filepath.Ext(out)) {
+		case ".jsonl":
+			format = "jsonl"
+		case ".csv":
+			format = "csv"
+		case ".tsv":
+			format = "tsv"
+		default:
+			format = "text"
+		}
+	}
+	switch format {
+	case "text", "jsonl", "csv", "tsv", "quizlet":
+		if lookupTemplate != nil {
+			return "", fmt.Errorf("--template can't be used with --format %s", format)
+		}
+		return format, nil
+	case "template":
+		if lookupTemplate == nil {
+			return "", fmt.Errorf("--format template needs a --template file")
+		}
+		return format, nil
+	}
+	return "", fmt.Errorf("unknown format '%s' (choose from text, jsonl, csv, tsv, quizlet, template)", format)
+}
+
+// templateFuncs are the functions --template files can use besides
+// text/template's own.
+var templateFuncs = template.FuncMap{
+	"join":  strings.Join,
+	"upper": strings.ToUpper,
+	"lower": strings.ToLower,
+}
+
+// parseLookupTemplate reads a --template file.
+func parseLookupTemplate(path string) (*template.Template, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return template.New(filepath.Base(path)).Funcs(templateFuncs).Parse(string(data))
+}
+
+// templateEntry is what --template is executed with, once per word and
+// part of speech found, e.g. {{.Word}} ({{.Pos}}): {{join .Meanings "; "}}
+type templateEntry struct {
+	Term     string   // what was looked up
+	Status   string   // found or inflected
+	Word     string   // the base form found
+	Pos      string   // its part of speech
+	Forms    []string // what Term is of Word, if it's inflected
+	Meanings []string
+	// Deeper holds the glosses of the words the meanings are forms of,
+	// such as omena for "genitive singular of omena".
+	Deeper []tsk.Gloss
+
+	lw *lookupWriter
+}
+
+// Examples returns up to EXAMPLES_PER_ROW example sentences of the word.
+// The sentences are only opened if a template asks for them.
+func (e templateEntry) Examples() ([]tsk.Example, error) {
+	if e.lw.sentences == nil {
+		db, err := openExamplesDB()
+		if err != nil {
+			return nil, err
+		}
+		e.lw.examplesDB, e.lw.sentences = db, tsk.New(nil, nil)
+		e.lw.sentences.SetExamples(db)
+	}
+	return e.lw.sentences.Examples(e.Word, EXAMPLES_PER_ROW, 0)
+}
+
+// Note returns the user's note on the word, if any. The notes too are only
+// read if a template asks for them.
+func (e templateEntry) Note() (string, error) {
+	if e.lw.notes == nil {
+		if err := unlockUserData(); err != nil {
+			return "", fmt.Errorf("unlocking your data: %w", err)
+		}
+		notes, err := loadNotes()
+		if err != nil {
+			return "", err
+		}
+		e.lw.notes = notes
+	}
+	return e.lw.notes[e.Word], nil
+}
+
+// deeperGlosses returns the glosses of the words meanings point to, and of
+// the words their meanings point to in turn, as deep as Word Details goes.
+func deeperGlosses(meanings []string, glosses map[string][]tsk.Gloss) []tsk.Gloss {
+	var found []tsk.Gloss
+	seen := make(map[string]bool)
+	var follow func(meanings []string, level int)
+	follow = func(meanings []string, level int) {
+		if level > 2 {
+			return
+		}
+		for _, meaning := range meanings {
+			target, ok := deeperTarget(meaning)
+			if !ok || seen[target] {
+				continue
+			}
+			seen[target] = true
+			for _, g := range glosses[target] {
+				found = append(found, g)
+				follow(g.Meanings, level+1)
+			}
+		}
+	}
+	follow(meanings, 1)
+	return found
+}
+
+// lookupColumns are the columns --columns can pick for csv and tsv output,
+// besides meaning1, meaning2 and so on for a single meaning each.
+var lookupColumns = []string{"term", "status", "word", "pos", "forms", "meanings", "frequency", "examples",
+	"etymology", "synonyms", "antonyms", "derived", "note"}
+
+// EXAMPLES_PER_ROW is how many example sentences the examples column holds.
+const EXAMPLES_PER_ROW = 3
+
+// parseColumns reads a --columns list like "word,pos,meaning1,examples".
+func parseColumns(list string) ([]string, error) {
+	var columns []string
+	for _, column := range strings.Split(list, ",") {
+		column = strings.ToLower(strings.TrimSpace(column))
+		if column == "" {
+			continue
+		}
+		known := false
+		for _, c := range lookupColumns {
+			if c == column {
+				known = true
+			}
+		}
+		if n, err := strconv.Atoi(strings.TrimPrefix(column, "meaning")); err == nil && n >= 1 && strings.HasPrefix(column, "meaning") {
+			known = true
+		}
+		if !known {
+			return nil, fmt.Errorf("unknown column '%s' (choose from %s, or meaning1, meaning2...)", column, strings.Join(lookupColumns, ", "))
+		}
+		columns = append(columns, column)
+	}
+	if len(columns) == 0 {
+		return nil, fmt.Errorf("no columns given")
+	}
+	return columns, nil
+}
+
+// lookupWriter writes lookup results in one of the batch formats. Without
+// columns, csv and tsv have one row per term looked up; with them, one row
+// per word and part of speech found, and none for missing terms.
+type lookupWriter struct {
+	w       io.Writer
+	out     *errorWriter // w, for the first error writing to it
+	format  string
+	columns []string
+	glosses map[string][]tsk.Gloss
+	tmpl    *template.Template // for the template format
+	csv     *csv.Writer
+	json    *json.Encoder
+	written int
+
+	// Only loaded if one of the columns needs them.
+	frequencies map[string]wordFrequency
+	sentences   *tsk.Dictionary
+	examplesDB  *sql.DB
+	notes       map[string]string
+}
+
+// newLookupWriter starts writing results to w, with the header for the
+// format. tmpl is only used by the template format.
+func newLookupWriter(w io.Writer, format string, columns []string, glosses map[string][]tsk.Gloss, tmpl *template.Template) (*lookupWriter, error) {
+	if len(columns) > 0 && format != "csv" && format != "tsv" {
+		return nil, fmt.Errorf("--columns only works with --format csv or tsv")
+	}
+	if format == "template" && tmpl == nil {
+		return nil, fmt.Errorf("--format template needs a --template file")
+	}
+	out := &errorWriter{w: w}
+	lw := &lookupWriter{w: out, out: out, format: format, columns: columns, glosses: glosses, tmpl: tmpl}
+	for _, column := range columns {
+		switch {
+		case column == "frequency" && lw.frequencies == nil:
+			frequencies, err := loadFrequencies()
+			if err != nil {
+				return nil, fmt.Errorf("loading frequencies: %w", err)
+			}
+			lw.frequencies = frequencies
+		case column == "examples" && lw.sentences == nil:
+			db, err := openExamplesDB()
+			if err != nil {
+				return nil, err
+			}
+			// Only the example sentences of this dictionary are used.
+			lw.examplesDB, lw.sentences = db, tsk.New(nil, nil)
+			lw.sentences.SetExamples(db)
+		case column == "note" && lw.notes == nil:
+			if err := unlockUserData(); err != nil {
+				return nil, fmt.Errorf("unlocking your data: %w", err)
+			}
+			notes, err := loadNotes()
+			if err != nil {
+				return nil, fmt.Errorf("loading notes: %w", err)
+			}
+			lw.notes = notes
+		}
+	}
+
+	switch format {
+	case "text":
+		fmt.Fprintln(lw.w, "===")
+	case "jsonl":
+		lw.json = json.NewEncoder(lw.w)
+	case "csv", "tsv":
+		lw.csv = csv.NewWriter(lw.w)
+		if format == "tsv" {
+			lw.csv.Comma = '\t'
+		}
+		if len(columns) > 0 {
+			lw.csv.Write(columns)
+		} else {
+			lw.csv.Write([]string{"Word", "Status", "Base Forms", "Definition", "Suggestions"})
+		}
+	}
+	return lw, nil
+}
+
+// Write writes the result of one lookup.
+func (lw *lookupWriter) Write(r lookupResult) error {
+	defer func() { lw.written++ }()
+	switch lw.format {
+	case "text":
+		// Separate results, but don't lead with a separator.
+		if lw.written > 0 {
+			fmt.Fprintln(lw.w, "---")
+		}
+		writeLookupText(lw.w, r, lw.glosses)
+	case "jsonl":
+		return lw.json.Encode(r)
+	case "quizlet":
+		// A card with nothing on the back is no use, so missing words
+		// are left out.
+		if definition := quizletDefinition(r, lw.glosses); definition != "" {
+			_, err := fmt.Fprintf(lw.w, "%s,%s\n", quizletField(r.Word), quizletField(definition))
+			return err
+		}
+	case "template":
+		for _, lemma := range r.BaseForms {
+			for _, g := range lw.glosses[lemma] {
+				entry := templateEntry{Term: r.Word, Status: r.Status, Word: lemma, Pos: g.Pos,
+					Forms: r.Forms[lemma], Meanings: g.Meanings, Deeper: deeperGlosses(g.Meanings, lw.glosses), lw: lw}
+				if err := lw.tmpl.Execute(lw.w, entry); err != nil {
+					return err
+				}
+			}
+		}
+	default:
+		if len(lw.columns) == 0 {
+			var definitions []string
+			for _, lemma := range r.BaseForms {
+				definitions = append(definitions, strings.TrimSpace(stripColorTags(generateGlossText(lemma, lw.glosses))))
+			}
+			return lw.csv.Write([]string{r.Word, r.Status, strings.Join(r.BaseForms, ", "), strings.Join(definitions, "\n\n"), strings.Join(r.Suggestions, ", ")})
+		}
+		for _, lemma := range r.BaseForms {
+			for _, g := range lw.glosses[lemma] {
+				if err := lw.csv.Write(lw.row(r, lemma, g)); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// row fills in the columns for the gloss g of lemma, which r found.
+func (lw *lookupWriter) row(r lookupResult, lemma string, g tsk.Gloss) []string {
+	row := make([]string, len(lw.columns))
+	for i, column := range lw.columns {
+		switch column {
+		case "term":
+			row[i] = r.Word
+		case "status":
+			row[i] = r.Status
+		case "word":
+			row[i] = lemma
+		case "pos":
+			row[i] = g.Pos
+		case "forms":
+			row[i] = strings.Join(r.Forms[lemma], ", ")
+		case "meanings":
+			row[i] = strings.Join(g.Meanings, "; ")
+		case "frequency":
+			if freq, ok := lw.frequencies[lemma]; ok {
+				row[i] = strconv.Itoa(freq.Rank)
+			}
+		case "examples":
+			examples, _ := lw.sentences.Examples(lemma, EXAMPLES_PER_ROW, 0)
+			var lines []string
+			for _, e := range examples {
+				lines = append(lines, e.Finnish+" — "+e.English)
+			}
+			row[i] = strings.Join(lines, "\n")
+		case "note":
+			row[i] = lw.notes[lemma]
+		case "etymology":
+			row[i] = g.Etymology
+		case "synonyms":
+			row[i] = strings.Join(g.Synonyms, ", ")
+		case "antonyms":
+			row[i] = strings.Join(g.Antonyms, ", ")
+		case "derived":
+			row[i] = strings.Join(g.Derived, ", ")
+		default:
+			// meaningN, checked by parseColumns.
+			n, _ := strconv.Atoi(strings.TrimPrefix(column, "meaning"))
+			if n <= len(g.Meanings) {
+				row[i] = g.Meanings[n-1]
+			}
+		}
+	}
+	return row
+}
+
+// quizletDefinition is the back of r's card in the quizlet format: the
+// meanings of each of its base forms, on one line, as Quizlet starts a new
+// card at each line break, like "(noun) house; building | (verb) to house".
+// A base form other than the word itself leads its meanings.
+func quizletDefinition(r lookupResult, glosses map[string][]tsk.Gloss) string {
+	var parts []string
+	for _, lemma := range r.BaseForms {
+		for _, g := range glosses[lemma] {
+			part := fmt.Sprintf("(%s) %s", g.Pos, strings.Join(g.Meanings, "; "))
+			if lemma != r.Word {
+				part = lemma + " " + part
+			}
+			parts = append(parts, part)
+		}
+	}
+	return strings.Join(parts, " | ")
+}
+
+// quizletField quotes a field for Quizlet's importer when it has a comma,
+// semicolon or quote in it, doubling the quotes inside, as Quizlet can be
+// told to split terms from definitions on either separator. Tabs and line
+// breaks become spaces, since Quizlet splits on tabs by default and takes
+// each line as a card of its own.
+func quizletField(field string) string {
+	field = strings.Join(strings.Fields(field), " ")
+	if !strings.ContainsAny(field, ",;\"") {
+		return field
+	}
+	return `"` + strings.ReplaceAll(field, `"`, `""`) + `"`
+}
+
+// Close finishes the output, returning the first error writing any of it.
+func (lw *lookupWriter) Close() error {
+	if lw.examplesDB != nil {
+		lw.examplesDB.Close()
+	}
+	switch lw.format {
+	case "text":
+		fmt.Fprintln(lw.w, "===")
+	case "csv", "tsv":
+		lw.csv.Flush()
+	}
+	return lw.out.err
+}
+
+// errorWriter passes writes on to w until one fails, and keeps that error,
+// so that text written with fmt.Fprint and friends is checked once at the
+// end.
+type errorWriter struct {
+	w   io.Writer
+	err error
+}
+
+func (ew *errorWriter) Write(p []byte) (int, error) {
+	if ew.err != nil {
+		return 0, ew.err
+	}
+	n, err := ew.w.Write(p)
+	ew.err = err
+	return n, err
+}
+
+// runBatchLookup looks up every word in the list at path, which may be
+// anything readWordList understands, and writes the results to out (or
+// stdout) in format, with columns if it's csv or tsv. A summary of found
+// and missing words follows.
+func runBatchLookup(path, format, out string, columns []string) error {
+	format, err := batchFormat(format, out)
+	if err != nil {
+		return err
+	}
+	terms, err := readWordList(path)
+	if err != nil {
+		return fmt.Errorf("reading word list: %w", err)
+	}
+	glosses, err := loadGlosses()
+	if err != nil {
+		return fmt.Errorf("loading glosses: %w", err)
+	}
+	if posOnly != "" {
+		if err := checkPos(glosses, posOnly); err != nil {
+			return err
+		}
+		glosses = filterGlossesByPos(glosses, posOnly)
+	}
+	if err := initDeeperPrefixes(); err != nil {
+		return fmt.Errorf("initializing deeper prefixes: %w", err)
+	}
+
+	var w io.Writer = os.Stdout
+	var f *os.File
+	if out != "" {
+		if f, err = os.Create(out); err != nil {
+			return err
+		}
+		// Closed below once the results are written, where an error
+		// counts. This is for returning early.
+		defer f.Close()
+		w = f
+	}
+
+	// Built on first use, to suggest words for terms that aren't found.
+	var fuzzyIndex *tsk.PatternIndex
+	fuzzy := func() *tsk.PatternIndex {
+		if fuzzyIndex == nil {
+			if words, err := loadWords(); err == nil {
+				fuzzyIndex = tsk.NewPatternIndex(words)
+			}
+		}
+		return fuzzyIndex
+	}
+
+	lw, err := newLookupWriter(w, format, columns, glosses, lookupTemplate)
+	if err != nil {
+		return err
+	}
+	counts := make(map[string]int)
+	var missing []string
+	for _, term := range terms {
+		r := lookupWord(term, glosses, fuzzy)
+		if senseOnly > 0 {
+			if r, err = keepSense(r, glosses, senseOnly); err != nil {
+				fmt.Fprintln(os.Stderr, "Error:", err)
+				continue
+			}
+		}
+		counts[r.Status]++
+		if r.Status == lookupMissing {
+			missing = append(missing, term)
+		}
+		if err := lw.Write(r); err != nil {
+			return err
+		}
+	}
+	if err := lw.Close(); err != nil {
+		return err
+	}
+	// A full disk may only show when the file is closed.
+	if f != nil {
+		if err := f.Close(); err != nil {
+			return fmt.Errorf("writing to %s: %w", out, err)
+		}
+	}
+
+	// The summary goes to the terminal, not into the results.
+	summary := os.Stdout
+	if out == "" {
+		summary = os.Stderr
+	}
+	fmt.Fprintf(summary, "Looked up %d words: %d found, %d found as inflected forms, %d missing.\n",
+		len(terms), counts[lookupFound], counts[lookupInflected], counts[lookupMissing])
+	if len(missing) > 0 {
+		fmt.Fprintf(summary, "Missing: %s\n", strings.Join(missing, ", "))
+	}
+	if out != "" {
+		fmt.Printf("Wrote the results to %s\n", out)
+	}
+	return nil
+}
+
+// ----------------------
+// OCR Input (`tsk ocr`)
+// ----------------------
+
+// runOCRCommand runs Tesseract over an image and feeds the recognised text
+// into the same report as `tsk read`. Tesseract and its Finnish language
+// pack (usually packaged as tesseract-ocr-fin) must be installed separately.
+func runOCRCommand(args []string) error {
+	fs := newCommandFlags("ocr")
+	lang := fs.String("lang", "fin", "Tesseract language pack to recognise the image with")
+	showText := fs.Bool("show-text", false, "print the recognised text before the report")
+	fs.Parse(args)
+
+	if fs.NArg() != 1 {
+		return fmt.Errorf("usage: tsk ocr [--lang fin] [--show-text] IMAGE")
+	}
+	image := fs.Arg(0)
+
+	if _, err := exec.LookPath("tesseract"); err != nil {
+		return fmt.Errorf("tesseract not found in PATH. Please install Tesseract and its Finnish language pack")
+	}
+
+	var stderr bytes.Buffer
+	cmd := exec.Command("tesseract", image, "stdout", "-l", *lang)
+	cmd.Stderr = &stderr
+	text, err := cmd.Output()
+	if err != nil {
+		return fmt.Errorf("tesseract failed: %v: %s", err, strings.TrimSpace(stderr.String()))
+	}
+
+	glosses, err := loadGlosses()
+	if err != nil {
+		return fmt.Errorf("loading glosses: %w", err)
+	}
+
+	if *showText {
+		fmt.Println(strings.TrimSpace(string(text)))
+	}
+	printReadingReport(buildReadingReport(string(text), glosses), glosses)
+	return nil
+}
+
+// ----------------------
+// Tagged Word Lists (`tsk import-words`, `tsk tags`)
+// ----------------------
+
+// Users can import vocabulary lists, like a textbook's chapter by chapter,
+// with a tag for each word. TAGS_FILE keeps them as "word<TAB>tag<TAB>
+// meaning" lines. Words the dictionary lacks are added to the word list,
+// and a meaning from the list becomes the gloss of a word without one, so
+// plain lookups read the file too. Like the overrides, it is never
+// encrypted, so they can do that without asking for the passphrase.
+
+// TAGGED_POS is the part of speech of a meaning from a word list, which
+// doesn't say what it is.
+const TAGGED_POS = "vocabulary"
+
+// taggedWord is one line of TAGS_FILE.
+type taggedWord struct {
+	Word, Tag, Meaning string
+}
+
+// loadTaggedWords reads TAGS_FILE, if there is one.
+func loadTaggedWords() ([]taggedWord, error) {
+	dir, err := userDataDir()
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(filepath.Join(dir, TAGS_FILE))
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	var tagged []taggedWord
+	for i, line := range strings.Split(string(data), "\n") {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		fields := strings.SplitN(line, "\t", 3)
+		if len(fields) < 2 {
+			return nil, fmt.Errorf("%s:%d: want a word and a tag", TAGS_FILE, i+1)
+		}
+		t := taggedWord{Word: fields[0], Tag: fields[1]}
+		if len(fields) == 3 {
+			t.Meaning = fields[2]
+		}
+		tagged = append(tagged, t)
+	}
+	return tagged, nil
+}
+
+// saveTaggedWords writes tagged to TAGS_FILE, replacing what was there.
+func saveTaggedWords(tagged []taggedWord) error {
+	dir, err := userDataDir()
+	if err != nil {
+		return err
+	}
+	var b strings.Builder
+	for _, t := range tagged {
+		fmt.Fprintf(&b, "%s\t%s\t%s\n", t.Word, t.Tag, t.Meaning)
+	}
+	return os.WriteFile(filepath.Join(dir, TAGS_FILE), []byte(b.String()), 0o644)
+}
+
+// tsvField tidies a field for a TSV line: no tabs or line breaks, and no
+// spaces around it.
+func tsvField(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
+
+// wordTags maps each tagged word to its tags, in the order imported.
+func wordTags(tagged []taggedWord) map[string][]string {
+	tags := make(map[string][]string)
+	for _, t := range tagged {
+		if !slices.Contains(tags[t.Word], t.Tag) {
+			tags[t.Word] = append(tags[t.Word], t.Tag)
+		}
+	}
+	return tags
+}
+
+// taggedWith returns the words tagged tag, each once, in the order
+// imported.
+func taggedWith(tagged []taggedWord, tag string) []string {
+	var words []string
+	for _, t := range tagged {
+		if t.Tag == tag && !slices.Contains(words, t.Word) {
+			words = append(words, t.Word)
+		}
+	}
+	return words
+}
+
+// withTaggedWords adds the tagged words missing from words to the end.
+func withTaggedWords(words []string) ([]string, error) {
+	tagged, err := loadTaggedWords()
+	if err != nil || len(tagged) == 0 {
+		return words, err
+	}
+	known := make(map[string]bool, len(words))
+	for _, w := range words {
+		known[w] = true
+	}
+	for _, t := range tagged {
+		if !known[t.Word] {
+			known[t.Word] = true
+			words = append(words, t.Word)
+		}
+	}
+	return words, nil
+}
+
+// withTaggedGlosses gives the tagged words without glosses the meanings
+// their lists have for them.
+func withTaggedGlosses(glosses map[string][]tsk.Gloss) error {
+	tagged, err := loadTaggedWords()
+	if err != nil {
+		return err
+	}
+	listed := make(map[string]*tsk.Gloss)
+	for _, t := range tagged {
+		if t.Meaning == "" {
+			continue
+		}
+		if _, ok := glosses[t.Word]; ok && listed[t.Word] == nil {
+			continue
+		}
+		if listed[t.Word] == nil {
+			glosses[t.Word] = []tsk.Gloss{{Word: t.Word, Pos: TAGGED_POS}}
+			listed[t.Word] = &glosses[t.Word][0]
+		}
+		if g := listed[t.Word]; !slices.Contains(g.Meanings, t.Meaning) {
+			g.Meanings = append(g.Meanings, t.Meaning)
+		}
+	}
+	return nil
+}
+
+// tagQuery splits a search like "#kappale3 ta" into the tag and the
+// prefix the words tagged with it must start with, if it is one.
+func tagQuery(text string) (tag, prefix string, ok bool) {
+	rest, ok := strings.CutPrefix(strings.TrimSpace(text), "#")
+	if !ok || rest == "" {
+		return "", "", false
+	}
+	tag, prefix, _ = strings.Cut(rest, " ")
+	return tag, strings.TrimSpace(prefix), true
+}
+
+// tagMatches returns the words tagged tag that start with prefix, the
+// most frequent first and the rest alphabetically.
+func tagMatches(tagged []taggedWord, tag, prefix string, frequencies map[string]wordFrequency) []string {
+	var words []string
+	for _, w := range taggedWith(tagged, tag) {
+		if strings.HasPrefix(w, prefix) {
+			words = append(words, w)
+		}
+	}
+	rank := func(w string) int {
+		if freq, ok := frequencies[w]; ok {
+			return freq.Rank
+		}
+		return math.MaxInt
+	}
+	sort.SliceStable(words, func(i, j int) bool {
+		if ri, rj := rank(words[i]), rank(words[j]); ri != rj {
+			return ri < rj
+		}
+		return tsk.CompareFinnish(words[i], words[j]) < 0
+	})
+	return words
+}
+
+// tagsText is the line of Word Details naming word's tags, or "".
+func tagsText(tags []string) string {
+	if len(tags) == 0 {
+		return ""
+	}
+	return fmt.Sprintf("[teal]Tags: #%s[white]\n", tview.Escape(strings.Join(tags, ", #")))
+}
+
+// runImportWordsCommand imports a vocabulary list, e.g. `tsk import-words
+// chapter1.csv` with "word,tag[,meaning]" rows, or `tsk import-words --tag
+// kappale1 chapter1.txt` with a word, and optionally its meaning, a row.
+// Importing a word with a tag again updates its meaning.
+func runImportWordsCommand(args []string) error {
+	fs := newCommandFlags("import-words")
+	tagFlag := fs.String("tag", "", "tag every word with this `name`, the second column then being the meaning")
+	fs.Parse(args)
+
+	if fs.NArg() != 1 {
+		return fmt.Errorf("usage: tsk import-words [--tag NAME] FILE.csv|FILE.tsv|FILE.txt")
+	}
+	path := fs.Arg(0)
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	reader := csv.NewReader(f)
+	reader.FieldsPerRecord = -1
+	reader.Comment = '#'
+	if ext := strings.ToLower(filepath.Ext(path)); ext == ".tsv" || ext == ".txt" {
+		reader.Comma = '\t'
+		reader.LazyQuotes = true
+	}
+
+	tagged, err := loadTaggedWords()
+	if err != nil {
+		return err
+	}
+	index := make(map[[2]string]int)
+	for i, t := range tagged {
+		index[[2]string{t.Word, t.Tag}] = i
+	}
+	imported, skipped := 0, 0
+	tags := make(map[string]bool)
+	for line := 1; ; line++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+		for i := range record {
+			record[i] = tsvField(record[i])
+		}
+		// Skip an optional header row.
+		if line == 1 && len(record) > 0 && strings.EqualFold(record[0], "word") {
+			continue
+		}
+		t := taggedWord{Tag: tsvField(*tagFlag)}
+		if len(record) > 0 {
+			t.Word = record[0]
+		}
+		rest := record[min(1, len(record)):]
+		if t.Tag == "" && len(rest) > 0 {
+			t.Tag, rest = strings.TrimPrefix(rest[0], "#"), rest[1:]
+		}
+		if len(rest) > 0 {
+			t.Meaning = rest[0]
+		}
+		if t.Word == "" || t.Tag == "" {
+			skipped++
+			continue
+		}
+		if i, ok := index[[2]string{t.Word, t.Tag}]; ok {
+			if t.Meaning != "" {
+				tagged[i].Meaning = t.Meaning
+			}
+		} else {
+			index[[2]string{t.Word, t.Tag}] = len(tagged)
+			tagged = append(tagged, t)
+		}
+		tags[t.Tag] = true
+		imported++
+	}
+	if err := saveTaggedWords(tagged); err != nil {
+		return err
+	}
+
+	fmt.Printf("Imported %d words from %s, tagged %s.\n", imported, path, "#"+strings.Join(slices.Sorted(maps.Keys(tags)), ", #"))
+	if skipped > 0 {
+		fmt.Printf("Skipped %d rows without both a word and a tag (give one with --tag).\n", skipped)
+	}
+	fmt.Println("Search #TAG in the TUI to list them, or run `tsk quiz --tag TAG` to review them.")
+	return nil
+}
+
+// runTagsCommand lists the tags of the imported word lists and how many
+// words each has, e.g. `tsk tags`, or with --remove forgets a tag.
+func runTagsCommand(args []string) error {
+	fs := newCommandFlags("tags")
+	remove := fs.String("remove", "", "forget the words' `tag`, and any words only it added")
+	fs.Parse(args)
+
+	tagged, err := loadTaggedWords()
+	if err != nil {
+		return err
+	}
+	if *remove != "" {
+		tag := strings.TrimPrefix(*remove, "#")
+		kept := slices.DeleteFunc(slices.Clone(tagged), func(t taggedWord) bool { return t.Tag == tag })
+		if len(kept) == len(tagged) {
+			return fmt.Errorf("no words are tagged '%s' (see tsk tags)", tag)
+		}
+		if err := saveTaggedWords(kept); err != nil {
+			return err
+		}
+		fmt.Printf("Removed the tag #%s from %d words.\n", tag, len(tagged)-len(kept))
+		return nil
+	}
+
+	if len(tagged) == 0 {
+		fmt.Println("No word lists imported yet. Run `tsk import-words --tag NAME FILE` to import one.")
+		return nil
+	}
+	counts := make(map[string]int)
+	var order []string
+	for _, t := range tagged {
+		if counts[t.Tag] == 0 {
+			order = append(order, t.Tag)
+		}
+		counts[t.Tag]++
+	}
+	fmt.Println("===")
+	for _, tag := range order {
+		fmt.Printf("#%-20s %d words\n", tag, counts[tag])
+	}
+	fmt.Println("===")
+	return nil
+}
+
+// ----------------------
+// Profiling (`--pprof`)
+// ----------------------
+
+// pprofAddr is where --pprof serves Go's profiler, or "" not to.
+var pprofAddr string
+
+// startPprof serves the runtime profiles on addr under /debug/pprof/, e.g.
+// for `go tool pprof http://localhost:6060/debug/pprof/heap` while the TUI
+// or a server runs. The handlers get a mux of their own, so they never end
+// up on a server tsk runs for something else. The profiles show the command
+// line and what is in memory, so addr has to be a loopback address.
+func startPprof(addr string) error {
+	if !isLoopbackAddr(addr) {
+		return fmt.Errorf("--pprof: anyone who can reach %s could read tsk's memory; use a loopback address like localhost:6060", addr)
+	}
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return fmt.Errorf("--pprof: %w", err)
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/debug/pprof/", pprof.Index)
+	mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+	mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+	mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+	mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+	go func() {
+		if err := http.Serve(ln, mux); err != nil && debug {
+			log.Printf("pprof server stopped: %v", err)
+		}
+	}()
+	return nil
+}
+
+// ----------------------
+// Shell Completion (`tsk completion`)
+// ----------------------
+
+// The scripts hand the words typed so far to `tsk __complete`, which
+// answers with subcommands and headwords, so `tsk kiss<Tab>` offers kissa,
+// kissanpentu and so on straight from the dictionary. Both commands print
+// only what the shell reads. Typed first, they run before main reads the
+// flags, config or data packs, as __complete runs on every Tab press.
+
+// quietSubcommands print only their own output, for a shell to read or to
+// run from its startup file, so main prints no banner before them.
+var quietSubcommands = map[string]func(args []string) error{
+	"completion": runCompletionCommand,
+	"__complete": runCompleteCommand,
+	"wotd":       runWotdCommand,
+}
+
+// The completion commands list the subcommands, so they can only join
+// the map once it exists.
+func init() {
+	maps.Copy(subcommands, quietSubcommands)
+}
+
+const bashCompletion = `# bash completion for tsk. Load it with
+#   source <(tsk completion bash)
+_tsk() {
+	local IFS=$'\n'
+	COMPREPLY=($(tsk __complete "${COMP_WORDS[@]:1:COMP_CWORD}" 2>/dev/null))
+}
+complete -o default -F _tsk tsk
+`
+
+const zshCompletion = `#compdef tsk
+# zsh completion for tsk. Load it with
+#   source <(tsk completion zsh)
+_tsk() {
+	local -a candidates
+	candidates=(${(f)"$(tsk __complete "${(@)words[2,CURRENT]}" 2>/dev/null)"})
+	if (( ${#candidates} )); then
+		compadd -- $candidates
+	else
+		_files
+	fi
+}
+compdef _tsk tsk
+`
+
+// fishCompletion is a template: %s is the list of subcommands, which take
+// file names rather than headwords.
+const fishCompletion = `# fish completion for tsk. Load it with
+#   tsk completion fish | source
+complete -c tsk -f -n 'not __fish_seen_subcommand_from %s' -a '(tsk __complete (commandline -opc)[2..] (commandline -ct) 2>/dev/null)'
+`
+
+// subcommandNames lists the subcommands users can type, sorted.
+func subcommandNames() []string {
+	names := []string{"help", "lookup"}
+	for name := range subcommands {
+		if !strings.HasPrefix(name, "__") {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// runCompletionCommand prints the completion script for a shell, e.g.
+// `tsk completion bash`.
+func runCompletionCommand(args []string) error {
+	fs := newCommandFlags("completion")
+	fs.Parse(args)
+	args = fs.Args()
+	if len(args) != 1 {
+		return fmt.Errorf("usage: tsk completion bash|zsh|fish")
+	}
+	switch args[0] {
+	case "bash":
+		fmt.Print(bashCompletion)
+	case "zsh":
+		fmt.Print(zshCompletion)
+	case "fish":
+		// Words after lookup are headwords, like words after no subcommand.
+		names := slices.DeleteFunc(subcommandNames(), func(name string) bool { return name == "lookup" })
+		fmt.Printf(fishCompletion, strings.Join(names, " "))
+	default:
+		return fmt.Errorf("unknown shell '%s' (choose from bash, zsh, fish)", args[0])
+	}
+	return nil
+}
+
+// runCompleteCommand prints the completions of the last of args, the word
+// being typed, one per line. The words before it decide what fits: the
+// first word can be a subcommand or a headword, and the arguments of a
+// subcommand are left to the shell's own file completion.
+func runCompleteCommand(args []string) error {
+	if len(args) == 0 {
+		return nil
+	}
+	current, previous := args[len(args)-1], args[:len(args)-1]
+	if strings.HasPrefix(current, "-") {
+		return nil
+	}
+	first := true
+	for _, arg := range previous {
+		if strings.HasPrefix(arg, "-") {
+			continue
+		}
+		if arg == "help" && first {
+			for _, name := range subcommandNames() {
+				if strings.HasPrefix(name, current) {
+					fmt.Println(name)
+				}
+			}
+			return nil
+		}
+		if _, ok := subcommands[arg]; ok {
+			return nil
+		}
+		first = false
+	}
+
+	if first {
+		for _, name := range subcommandNames() {
+			if strings.HasPrefix(name, current) && current != "" {
+				fmt.Println(name)
+			}
+		}
+	}
+	if current == "" {
+		return nil
+	}
+
+	// Honour the default dictionary pack, but stay quiet if it's missing:
+	// nobody reads errors in the middle of a Tab press.
+	if config, err := loadUserConfig(); err == nil && config.Dict != "" {
+		if pack, err := openDictionaryPack(config.Dict); err == nil {
+			activePack = pack
+		}
+	}
+	words, err := loadWords()
+	if err != nil {
+		return err
+	}
+	frequencies, err := loadFrequencies()
+	if err != nil {
+		return err
+	}
+	for _, word := range headwordCompletions(current, words, frequencies) {
+		fmt.Println(word)
+	}
+	return nil
+}
+
+// headwordCompletions returns up to tsk.MaxResults headwords starting with
+// prefix, the most common first. Phrases are left out, since the shell
+// would split them into separate words.
+func headwordCompletions(prefix string, words []string, frequencies map[string]wordFrequency) []string {
+	var matches []string
+	seen := make(map[string]struct{})
+	for _, word := range words {
+		if !strings.HasPrefix(word, prefix) || strings.Contains(word, " ") {
+			continue
+		}
+		if _, ok := seen[word]; !ok {
+			seen[word] = struct{}{}
+			matches = append(matches, word)
+		}
+	}
+	sort.SliceStable(matches, func(i, j int) bool {
+		ri, iRanked := frequencies[matches[i]]
+		rj, jRanked := frequencies[matches[j]]
+		if iRanked != jRanked {
+			return iRanked
+		}
+		if iRanked && ri.Rank != rj.Rank {
+			return ri.Rank < rj.Rank
+		}
+		return matches[i] < matches[j]
+	})
+	if len(matches) > tsk.MaxResults {
+		matches = matches[:tsk.MaxResults]
+	}
+	return matches
+}
+
+// ----------------------
+// Popup Lookup (`tsk --oneshot WORD`)
+// ----------------------
+
+// oneshotText is the Word Details of term, whether it's a base form or an
+// inflected one, or what it might be a typo of.
+func oneshotText(term string, glosses map[string][]tsk.Gloss) string {
+	r := lookupWord(term, glosses, func() *tsk.PatternIndex {
+		words, err := loadWords()
+		if err != nil {
+			return nil
+		}
+		return tsk.NewPatternIndex(words)
+	})
+	switch r.Status {
+	case lookupFound:
+		return generateGlossText(r.Word, glosses)
+	case lookupInflected:
+		var b strings.Builder
+		for i, lemma := range r.BaseForms {
+			if i > 0 {
+				b.WriteString("\n")
+			}
+			fmt.Fprintf(&b, "[gray]%s ~> %s (%s)[white]\n\n", tview.Escape(r.Word), tview.Escape(lemma), strings.Join(r.Forms[lemma], ", "))
+			b.WriteString(generateGlossText(lemma, glosses))
+		}
+		return b.String()
+	}
+	return notFoundStatus(r.Word, r.Suggestions).Text(false)
+}
+
+// runOneshot shows only the Word Details of one word, filling the whole
+// terminal, and exits on the first key that isn't for scrolling. It is
+// meant for a popup, e.g. from tmux:
+//
+//	bind-key -T copy-mode-vi d display-popup -E "tsk --oneshot '#{copy_cursor_word}'"
+func runOneshot(word string) error {
+	glosses, err := loadGlosses()
+	if err != nil {
+		return fmt.Errorf("loading glosses: %w", err)
+	}
+	if err := initDeeperPrefixes(); err != nil {
+		return fmt.Errorf("initializing deeper prefixes: %w", err)
+	}
+
+	app := tview.NewApplication()
+	textView := themedTextView{tview.NewTextView()}
+	textView.SetDynamicColors(true)
+	textView.SetWrap(true)
+	textView.SetWordWrap(true)
+	textView.SetBorder(true)
+	textView.SetTitle(fmt.Sprintf("%s (↑/↓ to scroll, any other key to close)", word))
+	textView.SetBorderColor(theme.Details)
+	textView.SetTitleColor(theme.Details)
+	textView.SetText(oneshotText(word, glosses))
+	textView.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		switch event.Key() {
+		case tcell.KeyUp, tcell.KeyDown, tcell.KeyPgUp, tcell.KeyPgDn, tcell.KeyHome, tcell.KeyEnd:
+			return event
+		case tcell.KeyTab:
+			row, col := textView.GetScrollOffset()
+			textView.ScrollTo(row+1, col)
+			return nil
+		case tcell.KeyBacktab:
+			row, col := textView.GetScrollOffset()
+			textView.ScrollTo(max(0, row-1), col)
+			return nil
+		}
+		app.Stop()
+		return nil
+	})
+	if err := colorScreen(app); err != nil {
+		return err
+	}
+	return app.SetRoot(textView, true).Run()
+}
+
+// ----------------------
+// Interactive Prompt (`tsk -i`)
+// ----------------------
+
+// interactive reads words at a prompt and prints their glosses, as `tsk
+// WORD` does, instead of starting the TUI: for dumb terminals, and SSH
+// sessions whose TERM the TUI can't draw on.
+var interactive bool
+
+// REPL_PROMPT is printed before each line the prompt reads.
+const REPL_PROMPT = "tsk> "
+
+// replCommands are the prompt's colon commands, with their short forms, as
+// :help lists them.
+var replCommands = []struct{ name, short, args, summary string }{
+	{"examples", "e", "[WORD]", "example sentences of the word, or of the last one looked up"},
+	{"mark", "m", "[WORD]", "mark or unmark the word, or the last one looked up"},
+	{"marked", "l", "", "list the marked words"},
+	{"export", "w", "[PROFILE]", "save the marked words and sentences, as quitting the TUI does"},
+	{"search", "s", "QUERY", "list the words the TUI's search bar would, e.g. :s talo*"},
+	{"reverse", "r", "QUERY", "find words by their English meanings, e.g. :r \"look after\""},
+	{"help", "h", "", "list these commands"},
+	{"quit", "q", "", "leave the prompt, as Ctrl-D does"},
+}
+
+// runREPL reads a word, or a colon command, from each line of in and
+// writes what it finds to out as plain text, until the end of in or :quit.
+// Marks are saved as soon as they change.
+func runREPL(dict *Dictionary, in io.Reader, out io.Writer) error {
+	glosses := dict.Glosses()
+	marked, err := loadMarked()
+	if err != nil {
+		return fmt.Errorf("loading marked words: %w", err)
+	}
+	var fuzzyIndex *tsk.PatternIndex
+	fuzzy := func() *tsk.PatternIndex {
+		if fuzzyIndex == nil {
+			fuzzyIndex = tsk.NewPatternIndex(dict.Words())
+		}
+		return fuzzyIndex
+	}
+	// last is the word the commands act on when they aren't given one: the
+	// base form of the last word looked up.
+	var last string
+	wordOr := func(arg string) (string, error) {
+		if arg = strings.TrimSpace(arg); arg != "" {
+			return arg, nil
+		}
+		if last == "" {
+			return "", fmt.Errorf("look a word up first, or name one")
+		}
+		return last, nil
+	}
+
+	fmt.Fprintf(out, "Type a word to look it up, or :help for the commands.\n")
+	scanner := bufio.NewScanner(in)
+	for fmt.Fprint(out, REPL_PROMPT); scanner.Scan(); fmt.Fprint(out, REPL_PROMPT) {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		if !strings.HasPrefix(line, ":") {
+			r := lookupWord(line, glosses, fuzzy)
+			writeLookupText(out, r, glosses)
+			if len(r.BaseForms) > 0 {
+				last = r.BaseForms[0]
+			}
+			continue
+		}
+
+		name, arg, _ := strings.Cut(line[1:], " ")
+		arg = strings.TrimSpace(arg)
+		var command string
+		for _, c := range replCommands {
+			if name == c.name || name == c.short {
+				command = c.name
+			}
+		}
+		switch command {
+		case "quit":
+			return nil
+		case "help":
+			for _, c := range replCommands {
+				usage := fmt.Sprintf(":%s (:%s) %s", c.name, c.short, c.args)
+				fmt.Fprintf(out, "  %-26s %s\n", usage, c.summary)
+			}
+			fmt.Fprintln(out, "  Anything else is looked up, like tsk WORD.")
+		case "examples":
+			word, err := wordOr(arg)
+			if err != nil {
+				fmt.Fprintln(out, "Error:", err)
+				continue
+			}
+			examples, err := dict.Examples(word, examplesPerPage, 0)
+			if err != nil {
+				fmt.Fprintln(out, "Error:", err)
+				continue
+			}
+			if len(examples) == 0 {
+				fmt.Fprintf(out, "No example sentences of '%s'.\n", word)
+			}
+			for _, e := range examples {
+				fmt.Fprintf(out, "%s\n  %s\n", e.Finnish, e.English)
+			}
+		case "mark":
+			word, err := wordOr(arg)
+			if err != nil {
+				fmt.Fprintln(out, "Error:", err)
+				continue
+			}
+			if _, ok := glosses[word]; !ok {
+				fmt.Fprintf(out, "'%s' isn't a headword, so it can't be marked.\n", word)
+				continue
+			}
+			if _, present := marked[word]; present {
+				delete(marked, word)
+				logStudyEvent("unmark", word)
+				fmt.Fprintf(out, "Unmarked '%s' (%d marked).\n", word, len(marked))
+			} else {
+				marked[word] = nil
+				logStudyEvent("mark", word)
+				fmt.Fprintf(out, "Marked '%s' (%d marked).\n", word, len(marked))
+			}
+			if err := saveREPLMarks(marked, word); err != nil {
+				fmt.Fprintln(out, "Error saving marked words:", err)
+			}
+		case "marked":
+			if len(marked) == 0 {
+				fmt.Fprintln(out, "Nothing is marked yet.")
+			}
+			for _, w := range finnishSorted(maps.Keys(marked)) {
+				fmt.Fprintln(out, w)
+			}
+		case "export":
+			if _, ok := exportProfiles[arg]; arg != "" && !ok {
+				fmt.Fprintf(out, "Error: unknown export profile '%s' (choose from %s)\n", arg, strings.Join(slices.Sorted(maps.Keys(exportProfiles)), ", "))
+				continue
+			}
+			sentences, err := loadMarkedSentences()
+			if err != nil {
+				fmt.Fprintln(out, "Error loading marked sentences:", err)
+				continue
+			}
+			if len(marked) == 0 && len(sentences) == 0 {
+				fmt.Fprintln(out, "Nothing is marked yet, so there is nothing to export.")
+				continue
+			}
+			if err := saveMarkedExports(marked, sentences, glosses, dict, arg, ""); err != nil {
+				fmt.Fprintln(out, "Error:", err)
+			}
+		case "search", "reverse":
+			if arg == "" {
+				fmt.Fprintf(out, "Error: :%s needs a query.\n", command)
+				continue
+			}
+			var words []string
+			if command == "search" {
+				words = dict.Search(arg).Words
+			} else {
+				words = dict.ReverseFind(arg, maxResults)
+			}
+			if len(words) == 0 {
+				fmt.Fprintf(out, "Nothing found for '%s'.\n", arg)
+				continue
+			}
+			fmt.Fprintln(out, strings.Join(words, ", "))
+		default:
+			fmt.Fprintf(out, "Unknown command ':%s'; :help lists them.\n", name)
+		}
+	}
+	fmt.Fprintln(out)
+	return scanner.Err()
+}
+
+// saveREPLMarks saves the marks after word was marked or unmarked at the
+// prompt, putting it in the --collection, if there is one, as the TUI's
+// mark key does, or out of every collection if it was unmarked.
+func saveREPLMarks(marked map[string]senseSet, word string) error {
+	if err := saveMarked(marked); err != nil {
+		return err
+	}
+	collections, err := loadCollections()
+	if err != nil {
+		return err
+	}
+	if _, present := marked[word]; present && markCollection != "" {
+		collections[markCollection] = append(collections[markCollection], word)
+	} else if !pruneCollections(collections, marked) {
+		return nil
+	}
+	return saveCollections(collections)
+}
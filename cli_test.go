@@ -0,0 +1,162 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"text/template"
+
+	"github.com/hiAndrewQuinn/tsk/pkg/tsk"
+)
+
+func TestLookupWordIgnoresCase(t *testing.T) {
+	glosses := map[string][]tsk.Gloss{
+		"äiti": {{Word: "äiti", Pos: "noun", Meanings: []string{"mother"}}},
+		"talo": {{Word: "talo", Pos: "noun", Meanings: []string{"house"}}},
+	}
+	noSuggestions := func() *tsk.PatternIndex { return nil }
+	tests := []struct {
+		term, status, word string
+		baseForms          []string
+	}{
+		{"äiti", lookupFound, "äiti", []string{"äiti"}},
+		{"Äiti", lookupFound, "äiti", []string{"äiti"}},
+		{"ÄITI", lookupFound, "äiti", []string{"äiti"}},
+		{"äiti", lookupFound, "äiti", []string{"äiti"}},
+		{"Talossa", lookupInflected, "Talossa", []string{"talo"}},
+		{"Äippä", lookupMissing, "Äippä", nil},
+	}
+	for _, tt := range tests {
+		r := lookupWord(tt.term, glosses, noSuggestions)
+		if r.Status != tt.status || r.Word != tt.word || !slices.Equal(r.BaseForms, tt.baseForms) {
+			t.Errorf("lookupWord(%q) = %s %q %q, want %s %q %q",
+				tt.term, r.Status, r.Word, r.BaseForms, tt.status, tt.word, tt.baseForms)
+		}
+	}
+}
+
+// The quiet subcommands must run after global flags too, as in
+// `tsk --plain wotd`, so they have to be in subcommands.
+func TestQuietSubcommandsAreSubcommands(t *testing.T) {
+	for name := range quietSubcommands {
+		if subcommands[name] == nil {
+			t.Errorf("%s is not in subcommands", name)
+		}
+	}
+	names := subcommandNames()
+	for _, name := range []string{"completion", "wotd", "help", "lookup"} {
+		if !slices.Contains(names, name) {
+			t.Errorf("subcommandNames() = %q, missing %s", names, name)
+		}
+	}
+	if slices.Contains(names, "__complete") {
+		t.Errorf("subcommandNames() = %q, want __complete hidden", names)
+	}
+}
+
+// Notes in --columns and --template come from an encrypted profile too,
+// once its passphrase is given.
+func TestLookupNotesFromEncryptedProfile(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", home)
+	t.Setenv("HOME", home)
+	t.Setenv("AppData", home)
+	oldRead, oldKey := readPassphrase, userDataKey
+	t.Cleanup(func() { readPassphrase, userDataKey = oldRead, oldKey })
+	readPassphrase = func(string) (string, error) { return "salasana", nil }
+	userDataKey = nil
+
+	if err := enableEncryption(); err != nil {
+		t.Fatal(err)
+	}
+	if err := saveNotes(map[string]string{"tuuli": "confusable with tuli"}); err != nil {
+		t.Fatal(err)
+	}
+	dir, err := userDataDir()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if data, err := os.ReadFile(filepath.Join(dir, NOTES_FILE)); err != nil || !bytes.HasPrefix(data, []byte(encryptedMagic)) {
+		t.Fatalf("%s is not encrypted: %v", NOTES_FILE, err)
+	}
+
+	glosses := map[string][]tsk.Gloss{"tuuli": {{Word: "tuuli", Pos: "noun", Meanings: []string{"wind"}}}}
+	result := lookupResult{Word: "tuuli", Status: lookupFound, BaseForms: []string{"tuuli"}, Glosses: glosses["tuuli"]}
+	tmpl := template.Must(template.New("notes").Funcs(templateFuncs).Parse("{{.Word}}: {{.Note}}\n"))
+	tests := []struct {
+		name    string
+		format  string
+		columns []string
+		tmpl    *template.Template
+		want    string
+	}{
+		{"columns", "tsv", []string{"word", "note"}, nil, "word\tnote\ntuuli\tconfusable with tuli\n"},
+		{"template", "template", nil, tmpl, "tuuli: confusable with tuli\n"},
+	}
+	for _, tt := range tests {
+		userDataKey = nil // as in a new tsk, asked for the passphrase again
+		var buf bytes.Buffer
+		lw, err := newLookupWriter(&buf, tt.format, tt.columns, glosses, tt.tmpl)
+		if err != nil {
+			t.Errorf("%s: newLookupWriter: %v", tt.name, err)
+			continue
+		}
+		if err := lw.Write(result); err != nil {
+			t.Errorf("%s: Write: %v", tt.name, err)
+		}
+		if err := lw.Close(); err != nil {
+			t.Errorf("%s: Close: %v", tt.name, err)
+		}
+		if got := buf.String(); !strings.Contains(got, tt.want) {
+			t.Errorf("%s: wrote %q, want it to contain %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestNotesMatchingSortsFinnish(t *testing.T) {
+	notes := map[string]string{"öljy": "oil", "Zeta": "letter", "ankka": "duck", "Aalto": "architect"}
+	want := []string{"Aalto", "ankka", "Zeta", "öljy"}
+	if got := notesMatching(notes, ""); !slices.Equal(got, want) {
+		t.Errorf("notesMatching = %q, want %q", got, want)
+	}
+}
+
+// failingWriter fails every write, like a full disk.
+type failingWriter struct{}
+
+func (failingWriter) Write([]byte) (int, error) { return 0, errors.New("no space left on device") }
+
+// The text format writes with fmt.Fprint, so its errors show in Close.
+func TestLookupWriterReportsWriteErrors(t *testing.T) {
+	glosses := map[string][]tsk.Gloss{"talo": {{Word: "talo", Pos: "noun", Meanings: []string{"house"}}}}
+	result := lookupResult{Word: "talo", Status: lookupFound, BaseForms: []string{"talo"}, Glosses: glosses["talo"]}
+	for _, format := range []string{"text", "csv", "jsonl", "quizlet"} {
+		lw, err := newLookupWriter(failingWriter{}, format, nil, glosses, nil)
+		if err != nil {
+			t.Fatalf("%s: %v", format, err)
+		}
+		err = lw.Write(result)
+		if closeErr := lw.Close(); err == nil {
+			err = closeErr
+		}
+		if err == nil {
+			t.Errorf("%s: writing to a full disk: no error", format)
+		}
+	}
+}
+
+// The profiles show what is in memory, so --pprof stays on this machine.
+func TestStartPprofOnlyOnLoopback(t *testing.T) {
+	for _, addr := range []string{":0", "0.0.0.0:0", "[::]:0"} {
+		if err := startPprof(addr); err == nil {
+			t.Errorf("startPprof(%q): no error", addr)
+		}
+	}
+	if err := startPprof("localhost:0"); err != nil {
+		t.Errorf(`startPprof("localhost:0"): %v`, err)
+	}
+}
@@ -0,0 +1,1081 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"database/sql"
+	"encoding/gob"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"maps"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/hiAndrewQuinn/tsk/pkg/tsk"
+	"github.com/rivo/tview"
+	"golang.org/x/term"
+)
+
+// ----------------------
+// Utility to load words from embedded data
+// ----------------------
+
+func loadWords() ([]string, error) {
+	scanner := bufio.NewScanner(strings.NewReader(activePack.Words))
+	var words []string
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		line = strings.Trim(line, "\"")
+		if line != "" {
+			words = append(words, line)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return withTaggedWords(words)
+}
+
+// ----------------------
+// Word Frequencies
+// ----------------------
+
+// wordFrequency is how common a word form is in the example sentences: its
+// rank (1 is the most common) and how many times it occurs.
+type wordFrequency struct {
+	Rank  int
+	Count int
+}
+
+// loadFrequencies reads the embedded frequency list, built from the example
+// sentences by buildfrequencies.go. Lines are "word<TAB>count", most common
+// first, so a word's line number is its rank.
+func loadFrequencies() (map[string]wordFrequency, error) {
+	scanner := bufio.NewScanner(strings.NewReader(activePack.Frequencies))
+	frequencies := make(map[string]wordFrequency)
+	for scanner.Scan() {
+		word, countText, ok := strings.Cut(scanner.Text(), "\t")
+		if !ok {
+			continue
+		}
+		count, err := strconv.Atoi(countText)
+		if err != nil {
+			return nil, fmt.Errorf("bad count for %q: %w", word, err)
+		}
+		frequencies[word] = wordFrequency{Rank: len(frequencies) + 1, Count: count}
+	}
+	return frequencies, scanner.Err()
+}
+
+// filterByFrequency keeps the words seen at least minCount times in the
+// example sentences. A minCount of zero or less keeps every word.
+func filterByFrequency(words []string, frequencies map[string]wordFrequency, minCount int) []string {
+	if minCount <= 0 {
+		return words
+	}
+	var kept []string
+	for _, word := range words {
+		if frequencies[word].Count >= minCount {
+			kept = append(kept, word)
+		}
+	}
+	return kept
+}
+
+// frequencyBands are the badges frequencyBadge gives words, by the most
+// common rank each one goes down to. Words past the last band, or not in
+// the frequency list at all, are rare.
+var frequencyBands = []struct {
+	maxRank int
+	name    string
+	badge   string
+}{
+	{1000, "top1k", "[green]top 1k[-]"},
+	{5000, "top5k", "[aqua]top 5k[-]"},
+}
+
+// RARE_BAND names the words past the last of frequencyBands.
+const RARE_BAND = "rare"
+
+// frequencyBandNames lists the bands a random word can be picked from.
+func frequencyBandNames() []string {
+	var names []string
+	for _, band := range frequencyBands {
+		names = append(names, band.name)
+	}
+	return append(names, RARE_BAND)
+}
+
+// frequencyBand names the band word is in, as in frequencyBandNames.
+func frequencyBand(word string, frequencies map[string]wordFrequency) string {
+	if freq, ok := frequencies[word]; ok {
+		for _, band := range frequencyBands {
+			if freq.Rank <= band.maxRank {
+				return band.name
+			}
+		}
+	}
+	return RARE_BAND
+}
+
+// frequencyBadge is a colored badge saying how common word is in the
+// example sentences, to tell which of several results are worth learning
+// first. It is empty for dictionary packs without a frequency list.
+func frequencyBadge(word string, frequencies map[string]wordFrequency) string {
+	if len(frequencies) == 0 {
+		return ""
+	}
+	if freq, ok := frequencies[word]; ok {
+		for _, band := range frequencyBands {
+			if freq.Rank <= band.maxRank {
+				return band.badge
+			}
+		}
+	}
+	return "[gray]rare[-]"
+}
+
+// ----------------------
+// Utility: Strip tview color tags
+// ----------------------
+
+func stripColorTags(s string) string {
+	// This regex matches any sequence like `[<color>]` or `[<color>:<bgcolor>]`
+	re := regexp.MustCompile(`\[[^\]]*\]`)
+	return re.ReplaceAllString(s, "")
+}
+
+// ----------------------
+// Gloss Data Structures & Loader
+// ----------------------
+
+// loadGlosses loads the active pack's glosses, with the user's own glosses
+// (see OVERRIDES_FILE) in place of the pack's for the words they edited,
+// and the meanings of imported words the pack has none for (see TAGS_FILE).
+func loadGlosses() (map[string][]tsk.Gloss, error) {
+	glosses, err := activePack.loadGlosses()
+	if err != nil {
+		return nil, err
+	}
+	if err := withTaggedGlosses(glosses); err != nil {
+		return nil, err
+	}
+	overrides, err := loadOverrides()
+	if err != nil {
+		return nil, err
+	}
+	maps.Copy(glosses, overrides)
+	return glosses, nil
+}
+
+// loadGlosses decodes the pack's glosses.gob, which for the built-in pack
+// `make` builds from glosses.jsonl with buildglossgob.go. Packs without a
+// usable gob are read from their glosses.jsonl instead. For the built-in
+// pack, e.g. in a development build where the gob hasn't been generated
+// yet, that is glosses.jsonl in the working directory.
+func (p *DictionaryPack) loadGlosses() (map[string][]tsk.Gloss, error) {
+	var gobErr error
+	if len(p.GlossesGob) > 0 {
+		// Create a reader from the embedded byte slice.
+		reader := bytes.NewReader(p.GlossesGob)
+
+		// Create a new decoder.
+		decoder := gob.NewDecoder(reader)
+
+		// Declare the map to decode into.
+		var glosses map[string][]tsk.Gloss
+
+		// Decode the gob data into the map.
+		if gobErr = decoder.Decode(&glosses); gobErr == nil {
+			return glosses, nil
+		}
+	} else {
+		gobErr = fmt.Errorf("%s is empty", GLOSSES_FILE)
+	}
+
+	if p.GlossesJSONL != nil {
+		return tsk.ParseGlossesJSONL(bytes.NewReader(p.GlossesJSONL), GLOSSES_SOURCE)
+	}
+	if p.Path != "" {
+		return nil, fmt.Errorf("decoding %s: %w", GLOSSES_FILE, gobErr)
+	}
+
+	log.Printf("Could not decode %s (%v); falling back to %s.", GLOSSES_FILE, gobErr, GLOSSES_SOURCE)
+	f, err := os.Open(GLOSSES_SOURCE)
+	if err != nil {
+		return nil, fmt.Errorf("decoding %s: %v; reading %s: %w", GLOSSES_FILE, gobErr, GLOSSES_SOURCE, err)
+	}
+	defer f.Close()
+	return tsk.ParseGlossesJSONL(f, GLOSSES_SOURCE)
+}
+
+// getDeeperGlosses is a recursive helper that looks for linkable phrases in a meaning string,
+// fetches their definitions, and formats them with the appropriate indentation and color
+// based on the recursion depth. It recurses one level deep to handle nested definitions.
+// A meaning of a pos gloss goes to the target's glosses of the same part of speech, if it
+// has any, so a noun's form of kuusi leads to the spruce rather than the number six. The
+// target's senses keep the numbers its own Word Details give them.
+func getDeeperGlosses(text, pos string, glosses map[string][]tsk.Gloss, level int) string {
+	// Base case: We only go two levels deep (level 1 and level 2).
+	if level > 2 {
+		return ""
+	}
+
+	var builder strings.Builder
+
+	// Define formatting based on recursion level to match the original output.
+	var glossFormat, meaningFormat string
+	if level == 1 {
+		glossFormat = "[lightgray]  ~> %s (%s)[white]\n"
+		meaningFormat = "[lightgray]      %s %s[white]\n"
+	} else { // level == 2
+		glossFormat = "[gray]         ~> %s (%s)[white]\n"
+		meaningFormat = "[gray]            %s %s[white]\n"
+	}
+
+	// Main logic: find the target, look up its glosses, and format.
+	if target, found := deeperTarget(text); found {
+		if targetGlosses, ok := glosses[target]; ok {
+			samePos := tsk.HasPos(targetGlosses, pos)
+			numbers := senseNumbers(targetGlosses)
+			for i, tg := range targetGlosses {
+				if samePos && tg.Pos != pos {
+					continue
+				}
+				builder.WriteString(fmt.Sprintf(glossFormat, homographLabel(targetGlosses, i), tg.Pos))
+				for j, tm := range tg.Meanings {
+					builder.WriteString(fmt.Sprintf(meaningFormat, numbers(i, j), tm))
+					// Recursive call for the next level deep.
+					builder.WriteString(getDeeperGlosses(tm, tg.Pos, glosses, level+1))
+				}
+			}
+		}
+	}
+
+	return builder.String()
+}
+
+// deeperTarget returns the word a meaning like "genitive singular of omena"
+// points to, if it starts with one of the go-deeper phrases.
+func deeperTarget(meaning string) (string, bool) {
+	prefix, found := findLongestPrefix(meaning)
+	if !found {
+		return "", false
+	}
+	target := strings.TrimRight(strings.TrimSpace(strings.TrimPrefix(meaning, prefix)), ".,:;!?")
+	if idx := strings.Index(target, "("); idx != -1 {
+		target = strings.TrimSpace(target[:idx])
+	}
+	if idx := strings.Index(target, ";"); idx != -1 {
+		target = strings.TrimSpace(target[:idx])
+	}
+	return target, true
+}
+
+// generateGlossText creates the formatted string for a word's details.
+// This is used by both the main view and the reverse-find modal.
+func generateGlossText(word string, glosses map[string][]tsk.Gloss) string {
+	if glossSlice, ok := glosses[word]; ok {
+		var formatted string
+		numbers := senseNumbers(glossSlice)
+
+		for i, gloss := range glossSlice {
+			if debug {
+				log.Printf("generateGlossText: processing gloss[%d]: %s (%s)", i, gloss.Word, gloss.Pos)
+			}
+			if i > 0 {
+				formatted += "\n"
+			}
+			formatted += fmt.Sprintf("[white]%s [yellow](%s)[white]\n\n", homographLabel(glossSlice, i), gloss.Pos)
+			// Verbs show the case they take before their meanings.
+			if gloss.Pos == "verb" {
+				if rections := rectionText(gloss.Word); rections != "" {
+					formatted += rections + "\n"
+				}
+			}
+			for j, meaning := range gloss.Meanings {
+				if debug {
+					log.Printf("generateGlossText: processing meaning: %s", meaning)
+				}
+				formatted += fmt.Sprintf("%s %s\n", numbers(i, j), meaning)
+				for _, example := range gloss.Examples[meaning] {
+					formatted += fmt.Sprintf("   [gray::i]%s[-::-]\n", tview.Escape(example))
+				}
+
+				// Call the recursive helper function to get all deeper glosses.
+				formatted += getDeeperGlosses(meaning, gloss.Pos, glosses, 1)
+			}
+		}
+		return colloquialText(word) + formatted
+	}
+
+	if debug {
+		log.Printf("generateGlossText: no gloss available for word: %s", word)
+	}
+	return fmt.Sprintf("%s\n\nNo gloss available.", word)
+}
+
+// superscriptDigits are the digits homographLabel numbers homographs with.
+var superscriptDigits = []rune("⁰¹²³⁴⁵⁶⁷⁸⁹")
+
+// homographLabel is the heading of glossSlice[i]: the word, numbered like
+// kuusi¹ and kuusi² if more than one of its glosses is the same part of
+// speech, as with words from different roots that are spelled alike.
+func homographLabel(glossSlice []tsk.Gloss, i int) string {
+	var n, of int
+	for j, g := range glossSlice {
+		if g.Pos == glossSlice[i].Pos {
+			of++
+			if j <= i {
+				n = of
+			}
+		}
+	}
+	if of < 2 {
+		return glossSlice[i].Word
+	}
+	var digits []rune
+	for _, d := range strconv.Itoa(n) {
+		digits = append(digits, superscriptDigits[d-'0'])
+	}
+	return glossSlice[i].Word + string(digits)
+}
+
+// senseNumbers returns what goes before meaning j of glossSlice[i]: its
+// number among all the word's senses, as wordSenses orders them and
+// --sense counts them, or a dash if the word has only one.
+func senseNumbers(glossSlice []tsk.Gloss) func(i, j int) string {
+	var total int
+	first := make([]int, len(glossSlice))
+	for i, g := range glossSlice {
+		first[i] = total
+		total += len(g.Meanings)
+	}
+	return func(i, j int) string {
+		if total < 2 {
+			return "-"
+		}
+		return fmt.Sprintf("%d.", first[i]+j+1)
+	}
+}
+
+// relatedGlossText is the etymology and related words section of the Word
+// Details for glossSlice: a one-line summary, or every section if expanded.
+// It is empty if there is nothing to show.
+func relatedGlossText(glossSlice []tsk.Gloss, expanded bool) string {
+	var hasEtymology bool
+	var synonyms, antonyms, derived int
+	for _, g := range glossSlice {
+		hasEtymology = hasEtymology || g.Etymology != ""
+		synonyms += len(g.Synonyms)
+		antonyms += len(g.Antonyms)
+		derived += len(g.Derived)
+	}
+	var summary []string
+	if hasEtymology {
+		summary = append(summary, "etymology")
+	}
+	for _, count := range []struct {
+		n    int
+		name string
+	}{{synonyms, "synonyms"}, {antonyms, "antonyms"}, {derived, "derived terms"}} {
+		if count.n > 0 {
+			summary = append(summary, fmt.Sprintf("%d %s", count.n, count.name))
+		}
+	}
+	if len(summary) == 0 {
+		return ""
+	}
+
+	key := keymap.Label(actionRelated)
+	if !expanded {
+		return fmt.Sprintf("\n[gray]▸ %s (%s to show)[white]\n", strings.Join(summary, ", "), key)
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "\n[gray]▾ Etymology and related words (%s to hide)[white]\n", key)
+	for _, g := range glossSlice {
+		if !g.HasRelated() {
+			continue
+		}
+		fmt.Fprintf(&b, "\n[white]%s [yellow](%s)[white]\n", g.Word, g.Pos)
+		if g.Etymology != "" {
+			fmt.Fprintf(&b, "  [orange]Etymology:[white] %s\n", tview.Escape(g.Etymology))
+		}
+		for _, section := range []struct {
+			name  string
+			words []string
+		}{{"Synonyms", g.Synonyms}, {"Antonyms", g.Antonyms}, {"Derived terms", g.Derived}} {
+			if len(section.words) > 0 {
+				fmt.Fprintf(&b, "  [orange]%s:[white] %s\n", section.name, tview.Escape(strings.Join(section.words, ", ")))
+			}
+		}
+	}
+	return b.String()
+}
+
+// ----------------------
+// Sense Selection
+// ----------------------
+
+// senseKey identifies one meaning of a word: the index of its Gloss in
+// glosses[word] and the index of the meaning within that Gloss.
+type senseKey struct {
+	Gloss   int
+	Meaning int
+}
+
+// senseSet holds the senses picked for a marked word. A nil set means the
+// whole word is marked, which is what plain Ctrl-S does.
+type senseSet map[senseKey]struct{}
+
+// wordSenses lists every sense of a word in display order.
+func wordSenses(word string, glosses map[string][]tsk.Gloss) []senseKey {
+	var senses []senseKey
+	for gi, gloss := range glosses[word] {
+		for mi := range gloss.Meanings {
+			senses = append(senses, senseKey{gi, mi})
+		}
+	}
+	return senses
+}
+
+// senseOnly is the sense given with --sense, counted from 1 as Word
+// Details numbers them. Lookups on the command line print only that sense
+// of each word. Zero prints them all.
+var senseOnly int
+
+// keepSense trims a lookup down to sense n of the words found, in r and in
+// glosses, which the lookup's writer takes the text from. An inflected
+// form keeps the base forms that have a sense n.
+func keepSense(r lookupResult, glosses map[string][]tsk.Gloss, n int) (lookupResult, error) {
+	if r.Status == lookupMissing {
+		return r, nil
+	}
+	var kept []string
+	r.Glosses = nil
+	for _, word := range r.BaseForms {
+		senses := wordSenses(word, glosses)
+		if n > len(senses) {
+			continue
+		}
+		glosses[word] = selectedGlosses(word, glosses, senseSet{senses[n-1]: {}})
+		r.Glosses = append(r.Glosses, glosses[word]...)
+		kept = append(kept, word)
+	}
+	if len(kept) == 0 {
+		if r.Status == lookupFound {
+			return r, fmt.Errorf("'%s' has %d senses, not %d", r.Word, len(wordSenses(r.Word, glosses)), n)
+		}
+		return r, fmt.Errorf("no base form of '%s' has %d senses", r.Word, n)
+	}
+	r.BaseForms = kept
+	return r, nil
+}
+
+// selectedGlosses returns the glosses of a marked word trimmed down to the
+// picked senses. Glosses with no picked meanings are left out entirely.
+func selectedGlosses(word string, glosses map[string][]tsk.Gloss, senses senseSet) []tsk.Gloss {
+	if senses == nil {
+		return glosses[word]
+	}
+	var selected []tsk.Gloss
+	for gi, gloss := range glosses[word] {
+		trimmed := tsk.Gloss{Word: gloss.Word, Pos: gloss.Pos}
+		for mi, meaning := range gloss.Meanings {
+			if _, ok := senses[senseKey{gi, mi}]; ok {
+				trimmed.Meanings = append(trimmed.Meanings, meaning)
+			}
+		}
+		if len(trimmed.Meanings) > 0 {
+			selected = append(selected, trimmed)
+		}
+	}
+	return selected
+}
+
+// ----------------------
+// Go Deeper Loader and Prefix Lookup
+// ----------------------
+
+func loadDeeperPhrases() ([]string, error) {
+	scanner := bufio.NewScanner(strings.NewReader(activePack.GoDeeper))
+	var phrases []string
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line != "" {
+			phrases = append(phrases, line)
+		}
+	}
+	return phrases, scanner.Err()
+}
+
+var (
+	deeperPrefixMap     map[string]struct{}
+	deeperPrefixLengths []int
+)
+
+// initDeeperPrefixes builds a hashmap for lookups where the keys are each phrase
+// from go-deeper.txt with an appended space. It also builds a slice of key lengths,
+// sorted in descending order so that the longest (most precise) prefix is matched first.
+func initDeeperPrefixes() error {
+	phrases, err := loadDeeperPhrases()
+	if err != nil {
+		return err
+	}
+	deeperPrefixMap = make(map[string]struct{}, len(phrases))
+	lengthSet := make(map[int]struct{})
+	for _, phrase := range phrases {
+		key := phrase + " "
+		deeperPrefixMap[key] = struct{}{}
+		lengthSet[len(key)] = struct{}{}
+	}
+	for l := range lengthSet {
+		deeperPrefixLengths = append(deeperPrefixLengths, l)
+	}
+	// Sort lengths in descending order.
+	sort.Sort(sort.Reverse(sort.IntSlice(deeperPrefixLengths)))
+	return nil
+}
+
+func findLongestPrefix(s string) (string, bool) {
+	if debug {
+		log.Printf("findLongestPrefix: Checking for prefixes which match '%s'", s)
+	}
+
+	// Split the input string into words.
+	words := strings.Fields(s)
+
+	// Start with the full set of words and remove one word at a time.
+	for i := len(words); i > 0; i-- {
+		// Join the first i words with a space and add a trailing space.
+		candidate := strings.Join(words[:i], " ") + " "
+		if debug {
+			log.Printf("findLongestPrefix: Is '%s' in deeperPrefixMap?", candidate)
+		}
+
+		if _, ok := deeperPrefixMap[candidate]; ok {
+			if debug {
+				log.Printf("findLongestPrefix: Yes! Returning '%s' from deeperPrefixMap.", candidate)
+			}
+			return candidate, true
+		}
+	}
+
+	return "", false
+}
+
+// ----------------------
+// Verb Rection (rection.tsv)
+// ----------------------
+
+// rection is the case, or infinitive, a verb's complement takes, as in
+// tykätä + elative: tykätä jostakin, "to like something".
+type rection struct {
+	Case    string // e.g. "elative" or "3rd infinitive illative"
+	Pattern string // the verb with a placeholder complement, e.g. "tykätä jostakin"
+	Example string // a sentence using it, e.g. "Tykkään kahvista."
+}
+
+// caseEndings are the endings of the cases and infinitives in rection.tsv,
+// shown next to the case's name as a reminder.
+var caseEndings = map[string]string{
+	"genitive":                "-n",
+	"partitive":               "-a/-ä, -ta/-tä",
+	"inessive":                "-ssa/-ssä",
+	"elative":                 "-sta/-stä",
+	"illative":                "-Vn, -hVn, -seen",
+	"adessive":                "-lla/-llä",
+	"ablative":                "-lta/-ltä",
+	"allative":                "-lle",
+	"essive":                  "-na/-nä",
+	"translative":             "-ksi",
+	"1st infinitive":          "-a/-ä, -da/-dä",
+	"3rd infinitive illative": "-maan/-mään",
+	"3rd infinitive elative":  "-masta/-mästä",
+	"3rd infinitive inessive": "-massa/-mässä",
+}
+
+// parseRections reads a rection file: verb, case, pattern and example
+// separated by tabs, one rection per line, with # starting a comment line.
+// Lines with too few fields are skipped.
+func parseRections(text string) map[string][]rection {
+	rections := make(map[string][]rection)
+	for line := range strings.Lines(text) {
+		line = strings.TrimRight(line, "\r\n")
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := strings.Split(line, "\t")
+		if len(fields) < 3 {
+			if debug {
+				log.Printf("parseRections: skipping %q", line)
+			}
+			continue
+		}
+		r := rection{Case: fields[1], Pattern: fields[2]}
+		if len(fields) > 3 {
+			r.Example = fields[3]
+		}
+		rections[fields[0]] = append(rections[fields[0]], r)
+	}
+	return rections
+}
+
+// verbRections returns the active pack's rections by verb, read on first
+// use, after the pack has been picked.
+var verbRections = sync.OnceValue(func() map[string][]rection {
+	return parseRections(activePack.Rection)
+})
+
+// rectionText is the Word Details lines for verb's rections, one per case
+// it takes, or "" if it has none.
+func rectionText(verb string) string {
+	var b strings.Builder
+	for _, r := range verbRections()[verb] {
+		label := r.Case
+		if ending, ok := caseEndings[r.Case]; ok {
+			label += " (" + ending + ")"
+		}
+		fmt.Fprintf(&b, "[orange]+ %s:[white] %s", tview.Escape(label), tview.Escape(r.Pattern))
+		if r.Example != "" {
+			fmt.Fprintf(&b, " [gray]— %s[white]", tview.Escape(r.Example))
+		}
+		b.WriteString("\n")
+	}
+	return b.String()
+}
+
+// ----------------------
+// Colloquial Forms (colloquial.tsv)
+// ----------------------
+
+// Learners meet spoken Finnish (puhekieli) and abbreviations in real text
+// all the time: mä for minä, meiän for meidän, esim. for esimerkiksi.
+// colloquial.tsv maps each to its standard word with a note. A form that is
+// a headword of its own says what it stands for above its glosses, and
+// lookups of one that isn't lead to the standard word instead.
+
+// colloquialForms returns the active pack's colloquial forms, read on
+// first use, after the pack has been picked.
+var colloquialForms = sync.OnceValue(func() map[string][]tsk.Colloquial {
+	return tsk.ParseColloquial(activePack.Colloquial)
+})
+
+// colloquialText is the Word Details lines saying what word stands for, if
+// it's a colloquial form, or "".
+func colloquialText(word string) string {
+	var b strings.Builder
+	for _, c := range colloquialForms()[word] {
+		fmt.Fprintf(&b, "[teal]%s → %s[white]", tview.Escape(c.Form), tview.Escape(c.Standard))
+		if c.Note != "" {
+			fmt.Fprintf(&b, " [gray](%s)[white]", tview.Escape(c.Note))
+		}
+		b.WriteString("\n")
+	}
+	if b.Len() > 0 {
+		b.WriteString("\n")
+	}
+	return b.String()
+}
+
+// colloquialStandards returns the standard words of a colloquial form that
+// have glosses, each with the notes on the form, as lookups of inflected
+// forms give their base forms.
+func colloquialStandards(word string, glosses map[string][]tsk.Gloss) ([]string, map[string][]string) {
+	var standards []string
+	notes := make(map[string][]string)
+	for _, c := range colloquialForms()[word] {
+		if _, ok := glosses[c.Standard]; !ok {
+			continue
+		}
+		if _, ok := notes[c.Standard]; !ok {
+			standards = append(standards, c.Standard)
+		}
+		notes[c.Standard] = append(notes[c.Standard], c.Note)
+	}
+	return standards, notes
+}
+
+// ----------------------
+// Gloss Overrides (Ctrl-J, `tsk overrides`)
+// ----------------------
+
+// Users can fix or extend a word's glosses for themselves. Each edit is
+// appended to OVERRIDES_FILE as one glossOverride, and the latest for each
+// word takes the place of the pack's glosses whenever they are loaded. The
+// file is never encrypted: glosses aren't private, and plain lookups load
+// them without asking for the passphrase.
+
+// glossOverride is one line of OVERRIDES_FILE.
+type glossOverride struct {
+	Word    string      `json:"word"`
+	Glosses []tsk.Gloss `json:"glosses"` // none to go back to the pack's own
+	Time    time.Time   `json:"time"`
+}
+
+// loadOverrides reads OVERRIDES_FILE, if there is one, returning the latest
+// glosses of each word in it.
+func loadOverrides() (map[string][]tsk.Gloss, error) {
+	dir, err := userDataDir()
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.Open(filepath.Join(dir, OVERRIDES_FILE))
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	overrides := make(map[string][]tsk.Gloss)
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		text := bytes.TrimSpace(scanner.Bytes())
+		if len(text) == 0 {
+			continue
+		}
+		var o glossOverride
+		if err := json.Unmarshal(text, &o); err != nil {
+			return nil, fmt.Errorf("%s:%d: %w", OVERRIDES_FILE, line, err)
+		}
+		if len(o.Glosses) == 0 {
+			delete(overrides, o.Word)
+		} else {
+			overrides[o.Word] = o.Glosses
+		}
+	}
+	return overrides, scanner.Err()
+}
+
+// saveOverride appends word's new glosses to OVERRIDES_FILE. No glosses
+// give the word back the pack's own.
+func saveOverride(word string, glosses []tsk.Gloss) error {
+	dir, err := userDataDir()
+	if err != nil {
+		return err
+	}
+	data, err := json.Marshal(glossOverride{Word: word, Glosses: glosses, Time: time.Now().UTC()})
+	if err != nil {
+		return err
+	}
+	f, err := os.OpenFile(filepath.Join(dir, OVERRIDES_FILE), os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(append(data, '\n')); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// glossEditText writes glosses for the edit buffer: each part of speech on
+// a line of its own, followed by its meanings, one per line after "- ".
+func glossEditText(glosses []tsk.Gloss) string {
+	var b strings.Builder
+	for _, gloss := range glosses {
+		b.WriteString(gloss.Pos + "\n")
+		for _, meaning := range gloss.Meanings {
+			b.WriteString("- " + meaning + "\n")
+		}
+	}
+	return b.String()
+}
+
+// parseGlossEdit reads the edit buffer back into glosses of word. Each part
+// of speech keeps the etymology, synonyms and such it had in original.
+// Parts of speech left without meanings are dropped.
+func parseGlossEdit(word, text string, original []tsk.Gloss) ([]tsk.Gloss, error) {
+	var glosses []tsk.Gloss
+	used := make(map[int]bool)
+	for i, line := range strings.Split(text, "\n") {
+		line = strings.TrimSpace(line)
+		switch {
+		case line == "":
+		case strings.HasPrefix(line, "-"):
+			if len(glosses) == 0 {
+				return nil, fmt.Errorf("line %d: a meaning needs its part of speech on a line above it", i+1)
+			}
+			if meaning := strings.TrimSpace(strings.TrimPrefix(line, "-")); meaning != "" {
+				last := &glosses[len(glosses)-1]
+				last.Meanings = append(last.Meanings, meaning)
+			}
+		default:
+			gloss := tsk.Gloss{Word: word, Pos: line}
+			for oi, o := range original {
+				if o.Pos == line && !used[oi] {
+					used[oi] = true
+					gloss = o
+					gloss.Meanings = nil
+					break
+				}
+			}
+			glosses = append(glosses, gloss)
+		}
+	}
+	return slices.DeleteFunc(glosses, func(g tsk.Gloss) bool { return len(g.Meanings) == 0 }), nil
+}
+
+// runOverridesCommand prints the words whose glosses the user edited, as
+// JSON lines with both the pack's glosses and theirs, e.g. to attach to an
+// issue suggesting the fixes upstream.
+func runOverridesCommand(args []string) error {
+	fs := newCommandFlags("overrides")
+	out := fs.String("out", "", "write them to this `file` instead of standard output")
+	fs.Parse(args)
+
+	overrides, err := loadOverrides()
+	if err != nil {
+		return err
+	}
+	original, err := activePack.loadGlosses()
+	if err != nil {
+		return err
+	}
+
+	w := io.Writer(os.Stdout)
+	if *out != "" {
+		f, err := os.Create(*out)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		w = f
+	}
+	enc := json.NewEncoder(w)
+	enc.SetEscapeHTML(false)
+	for _, word := range slices.Sorted(maps.Keys(overrides)) {
+		entry := struct {
+			Word     string      `json:"word"`
+			Original []tsk.Gloss `json:"original"`
+			Glosses  []tsk.Gloss `json:"glosses"`
+		}{word, original[word], overrides[word]}
+		if err := enc.Encode(entry); err != nil {
+			return err
+		}
+	}
+	if *out != "" {
+		fmt.Printf("Wrote %d edited words to %s\n", len(overrides), *out)
+	}
+	return nil
+}
+
+// ----------------------
+// Dictionary Loading
+// ----------------------
+
+// Dictionary bundles the read-only data and indexes the TUI searches. It is
+// loaded once and can be shared by any number of TUI sessions. The search
+// itself is pkg/tsk's; this adds what only the TUI shows, like frequencies.
+type Dictionary struct {
+	*tsk.Dictionary
+	frequencies map[string]wordFrequency
+}
+
+// loadingProgress reports the steps of loading as they finish, in whatever
+// order that is. On a terminal it also keeps a line at the bottom naming
+// the steps still running, rewritten as each one finishes.
+type loadingProgress struct {
+	mu       sync.Mutex
+	w        io.Writer
+	live     bool
+	total    int
+	pending  []string
+	lastLine int // length of the status line on screen, to blank it out
+}
+
+func newLoadingProgress(w *os.File, steps ...string) *loadingProgress {
+	p := &loadingProgress{
+		w:       w,
+		live:    term.IsTerminal(int(w.Fd())),
+		total:   len(steps),
+		pending: steps,
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.drawStatus()
+	return p
+}
+
+// Done marks step finished and prints a line about it.
+func (p *loadingProgress) Done(step, format string, args ...any) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.pending = slices.DeleteFunc(p.pending, func(s string) bool { return s == step })
+	p.clearStatus()
+	fmt.Fprintf(p.w, format+"\n", args...)
+	p.drawStatus()
+}
+
+// Finish takes the status line away, whatever is left in it.
+func (p *loadingProgress) Finish() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.clearStatus()
+}
+
+func (p *loadingProgress) drawStatus() {
+	if !p.live || len(p.pending) == 0 {
+		return
+	}
+	line := fmt.Sprintf("[%d/%d] Loading %s...", p.total-len(p.pending), p.total, strings.Join(p.pending, ", "))
+	fmt.Fprint(p.w, line)
+	p.lastLine = utf8.RuneCountInString(line)
+}
+
+// clearStatus blanks the status line with spaces rather than an escape
+// code, which older Windows consoles would print as it is.
+func (p *loadingProgress) clearStatus() {
+	if p.lastLine > 0 {
+		fmt.Fprintf(p.w, "\r%s\r", strings.Repeat(" ", p.lastLine))
+		p.lastLine = 0
+	}
+}
+
+// loadDictionary loads the embedded data and builds every search index,
+// printing how long each step took. The steps run at once, except that the
+// search indexes wait for the words and glosses they are built from, so
+// tsk is ready about as soon as the slowest of them is.
+func loadDictionary() (*Dictionary, error) {
+	start := time.Now()
+	progress := newLoadingProgress(os.Stdout,
+		"words", "glosses", "search indexes", "frequencies", "deeper lookup prefixes", "example sentences")
+
+	var (
+		wg   sync.WaitGroup
+		mu   sync.Mutex
+		errs []error
+	)
+	run := func(load func() error) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := load(); err != nil {
+				mu.Lock()
+				errs = append(errs, err)
+				mu.Unlock()
+			}
+		}()
+	}
+
+	var (
+		words                    []string
+		glosses                  map[string][]tsk.Gloss
+		wordsOK, glossesOK       bool
+		wordsReady, glossesReady = make(chan struct{}), make(chan struct{})
+		indexes                  *tsk.Dictionary
+		frequencies              map[string]wordFrequency
+		exampleDB                *sql.DB
+	)
+
+	run(func() error {
+		defer close(wordsReady)
+		stepStart := time.Now()
+		var err error
+		if words, err = loadWords(); err != nil {
+			return fmt.Errorf("loading words: %w", err)
+		}
+		wordsOK = true
+		progress.Done("words", "Loaded %d words from %s in %v", len(words), WORD_LIST_FILE, time.Since(stepStart))
+		return nil
+	})
+	run(func() error {
+		defer close(glossesReady)
+		stepStart := time.Now()
+		var err error
+		if glosses, err = loadGlosses(); err != nil {
+			return fmt.Errorf("loading glosses: %w", err)
+		}
+		glossesOK = true
+		progress.Done("glosses", "Loaded word glosses in %v", time.Since(stepStart))
+		return nil
+	})
+
+	// Build the trie, the reversed trie for `$ending` searches, the suffix
+	// array for `*kirja*`, the length buckets for `s.n.` patterns, and the
+	// index of English meanings for reverse-find.
+	run(func() error {
+		<-wordsReady
+		<-glossesReady
+		if !wordsOK || !glossesOK {
+			return nil // the loader that failed has said why
+		}
+		stepStart := time.Now()
+		indexes = tsk.New(words, glosses)
+		progress.Done("search indexes", "Built search indexes in %v (%d English words indexed)", time.Since(stepStart), indexes.MeaningIndex().Len())
+		return nil
+	})
+
+	run(func() error {
+		stepStart := time.Now()
+		var err error
+		if frequencies, err = loadFrequencies(); err != nil {
+			return fmt.Errorf("loading word frequencies: %w", err)
+		}
+		progress.Done("frequencies", "Loaded %d word frequencies from %s in %v", len(frequencies), FREQUENCIES_FILE, time.Since(stepStart))
+		return nil
+	})
+	run(func() error {
+		stepStart := time.Now()
+		if err := initDeeperPrefixes(); err != nil {
+			return fmt.Errorf("initializing deeper prefixes: %w", err)
+		}
+		progress.Done("deeper lookup prefixes", "Initialized deeper lookup prefixes from go-deeper.txt in %v", time.Since(stepStart))
+		return nil
+	})
+	run(func() error {
+		stepStart := time.Now()
+		var err error
+		if exampleDB, err = openExamplesDB(); err != nil {
+			return err
+		}
+		progress.Done("example sentences", "Opened example sentences in %v", time.Since(stepStart))
+		return nil
+	})
+
+	wg.Wait()
+	progress.Finish()
+	if len(errs) > 0 {
+		if exampleDB != nil {
+			exampleDB.Close()
+		}
+		return nil, errors.Join(errs...)
+	}
+
+	// Rank the trie's words by how common they are.
+	dict := &Dictionary{Dictionary: indexes, frequencies: frequencies}
+	ranks := make(map[string]int, len(frequencies))
+	for word, freq := range frequencies {
+		ranks[word] = freq.Rank
+	}
+	dict.SetRanks(ranks)
+	dict.SetMaxResults(maxResults)
+	dict.SetExamples(exampleDB)
+	dict.SetColloquial(colloquialForms())
+	fmt.Printf("Ready in %v\n", time.Since(start))
+
+	// Debug info.
+	if debug {
+		totalNodes := dict.Trie().CountNodes()
+		memory := dict.Trie().SizeBytes()
+
+		log.Printf("Debug: Trie has %d nodes\n", totalNodes)
+		log.Printf("Debug: Trie memory usage: %d bytes (~%.2f MB)\n",
+			memory, float64(memory)/(1024*1024))
+	}
+
+	return dict, nil
+}
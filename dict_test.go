@@ -0,0 +1,12 @@
+package main
+
+import "testing"
+
+func BenchmarkLoadGlosses(b *testing.B) {
+	b.ReportAllocs()
+	for b.Loop() {
+		if _, err := loadGlosses(); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
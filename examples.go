@@ -0,0 +1,719 @@
+package main
+
+import (
+	"cmp"
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/hiAndrewQuinn/tsk/pkg/tsk"
+	"github.com/rivo/tview"
+)
+
+// ----------------------
+// User Sentence Import (`tsk import-sentences`)
+// ----------------------
+
+// The user's own sentence pairs live in a per-profile SQLite database with the
+// same FTS5 setup as the embedded Tatoeba one, plus an unindexed label saying
+// where each pair came from. Being a database, it is not covered by --encrypt.
+const userSentencesSchema = `
+CREATE VIRTUAL TABLE IF NOT EXISTS user_sentences USING fts5(
+  finnish,
+  english,
+  source UNINDEXED,
+  tokenize = "unicode61 remove_diacritics 0"
+)`
+
+// openUserSentencesDB opens the user's sentence database, creating it first if
+// create is set. It returns a nil DB if it doesn't exist and create is unset.
+func openUserSentencesDB(create bool) (*sql.DB, error) {
+	dir, err := userDataDir()
+	if err != nil {
+		return nil, err
+	}
+	path := filepath.Join(dir, USER_SENTENCES_FILE)
+	if _, err := os.Stat(path); os.IsNotExist(err) && !create {
+		return nil, nil
+	}
+
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := db.Exec(userSentencesSchema); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return db, nil
+}
+
+// sentencePair is a Finnish sentence and its English translation, with where
+// it came from: Tatoeba, or the source given when it was imported.
+type sentencePair struct {
+	Finnish, English, Source string
+}
+
+// findUserSentences returns the user's imported sentences using word, or
+// any phrase, in either language. They are few enough to fetch in full.
+func findUserSentences(word string) ([]sentencePair, error) {
+	if userSentencesDB == nil {
+		return nil, nil
+	}
+	rows, err := userSentencesDB.Query(
+		"SELECT finnish, english, source FROM user_sentences WHERE user_sentences MATCH ?", tsk.MatchPhrase(word))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var sentences []sentencePair
+	for rows.Next() {
+		var s sentencePair
+		if err := rows.Scan(&s.Finnish, &s.English, &s.Source); err != nil {
+			continue
+		}
+		sentences = append(sentences, s)
+	}
+	return sentences, rows.Err()
+}
+
+// runImportSentencesCommand imports aligned Finnish/English sentence pairs
+// from a CSV or TSV file (Finnish first, English second) so that Ctrl-T shows
+// them alongside the Tatoeba examples.
+func runImportSentencesCommand(args []string) error {
+	fs := newCommandFlags("import-sentences")
+	source := fs.String("source", "", "label shown next to each imported sentence (default: the file name)")
+	fs.Parse(args)
+
+	if fs.NArg() != 1 {
+		return fmt.Errorf("usage: tsk import-sentences [--source LABEL] FILE.csv|FILE.tsv")
+	}
+	path := fs.Arg(0)
+	label := *source
+	if label == "" {
+		label = strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	reader := csv.NewReader(f)
+	reader.FieldsPerRecord = -1
+	if strings.EqualFold(filepath.Ext(path), ".tsv") {
+		reader.Comma = '\t'
+		reader.LazyQuotes = true
+	}
+
+	db, err := openUserSentencesDB(true)
+	if err != nil {
+		return fmt.Errorf("opening your sentence database: %w", err)
+	}
+	defer db.Close()
+
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	stmt, err := tx.Prepare("INSERT INTO user_sentences (finnish, english, source) VALUES (?, ?, ?)")
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	imported, skipped := 0, 0
+	for line := 1; ; line++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+
+		// Skip an optional header row.
+		if line == 1 && len(record) > 0 && strings.EqualFold(strings.TrimSpace(record[0]), "finnish") {
+			continue
+		}
+		if len(record) < 2 || strings.TrimSpace(record[0]) == "" || strings.TrimSpace(record[1]) == "" {
+			skipped++
+			continue
+		}
+
+		if _, err := stmt.Exec(strings.TrimSpace(record[0]), strings.TrimSpace(record[1]), label); err != nil {
+			return fmt.Errorf("%s line %d: %w", path, line, err)
+		}
+		imported++
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	fmt.Printf("Imported %d sentence pairs from %s as '%s'.\n", imported, path, label)
+	if skipped > 0 {
+		fmt.Printf("Skipped %d rows without both a Finnish and an English sentence.\n", skipped)
+	}
+	return nil
+}
+
+// ----------------------
+// Example Sentences (Ctrl-T)
+// ----------------------
+
+// openExamplesDB opens the active pack's example sentences. SQLite only
+// opens files, so a pack's database is copied to the cache directory once
+// (see cachedExamplesDB) rather than on every start. A pack with only a TSV
+// file gets a database built in memory instead.
+func openExamplesDB() (*sql.DB, error) {
+	if len(activePack.ExamplesDB) == 0 {
+		exampleDB, err := sql.Open("sqlite", ":memory:")
+		if err != nil {
+			return nil, fmt.Errorf("could not open example sentences DB: %w", err)
+		}
+		// Every connection to ":memory:" is a database of its own, so the
+		// pool is held to the one the sentences are loaded into.
+		exampleDB.SetMaxOpenConns(1)
+		exampleDB.SetConnMaxLifetime(0)
+		if err := fillExamplesDB(exampleDB, activePack.ExamplesTSV); err != nil {
+			exampleDB.Close()
+			return nil, fmt.Errorf("loading example sentences: %w", err)
+		}
+		return exampleDB, nil
+	}
+
+	path, err := cachedExamplesDB(activePack.ExamplesDB)
+	if err != nil {
+		return nil, fmt.Errorf("could not cache example sentences DB: %w", err)
+	}
+	exampleDB, err := sql.Open("sqlite", sqliteFileURI(path, "immutable=1"))
+	if err != nil {
+		return nil, fmt.Errorf("could not open example sentences DB: %w", err)
+	}
+	return exampleDB, nil
+}
+
+// EXAMPLES_CACHE_MAX_AGE is how long a cached copy of an example sentences
+// database is kept after tsk last opened it.
+const EXAMPLES_CACHE_MAX_AGE = 30 * 24 * time.Hour
+
+// cachedExamplesDB returns the path of a copy of the database data in the
+// cache directory, named by its checksum, writing it first if it isn't
+// there yet. Each pack, and each version of the built-in one, has a copy
+// of its own, so switching between packs with --dict finds its copy still
+// there. Copies go once no tsk has opened them for EXAMPLES_CACHE_MAX_AGE:
+// they don't pile up as tsk is updated, and a copy a running tsk has
+// open is never removed from under it.
+func cachedExamplesDB(data []byte) (string, error) {
+	cacheDir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	dir := filepath.Join(cacheDir, "tsk")
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(data)
+	path := filepath.Join(dir, fmt.Sprintf("example-sentences-%x.sqlite", sum[:8]))
+	defer pruneExamplesCache(dir, path)
+
+	// The copy is opened immutable, which tells SQLite it can't change, so
+	// one that was cut short or altered must not be taken for the real one.
+	if fileHasSum(path, sum) {
+		return path, nil
+	}
+
+	// Write under another name and rename, so that a second tsk starting
+	// at the same time never opens half a file.
+	tmp, err := os.CreateTemp(dir, "example-sentences-*.tmp")
+	if err != nil {
+		return "", err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return "", err
+	}
+	if err := tmp.Close(); err != nil {
+		return "", err
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		// Windows won't replace a file another tsk has open. If that file
+		// is whole, it's as good as this one.
+		if !fileHasSum(path, sum) {
+			return "", err
+		}
+	}
+	return path, nil
+}
+
+// fileHasSum reports whether the file at path exists and has the SHA-256
+// sum sum.
+func fileHasSum(path string, sum [sha256.Size]byte) bool {
+	f, err := os.Open(path)
+	if err != nil {
+		return false
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return false
+	}
+	return [sha256.Size]byte(h.Sum(nil)) == sum
+}
+
+// pruneExamplesCache marks the copy at path as just used, and removes the
+// other copies in dir that no tsk has used for EXAMPLES_CACHE_MAX_AGE.
+func pruneExamplesCache(dir, path string) {
+	now := time.Now()
+	os.Chtimes(path, now, now)
+	copies, _ := filepath.Glob(filepath.Join(dir, "example-sentences-*.sqlite"))
+	for _, f := range copies {
+		if info, err := os.Stat(f); err == nil && f != path && now.Sub(info.ModTime()) > EXAMPLES_CACHE_MAX_AGE {
+			os.Remove(f)
+		}
+	}
+}
+
+// sentencePage names the sentence search's page. It suspends the main key
+// bindings while open, so the mark key marks sentences instead of words.
+const sentencePage = "sentences"
+
+// readAloudPause is the silence left between sentences read aloud, to say
+// one over again in.
+const readAloudPause = 800 * time.Millisecond
+
+// prefetchDelay is how long a word has to stay selected before its
+// example sentences are looked up in the background, so that scrolling
+// through the list doesn't query every word passed on the way.
+const prefetchDelay = 150 * time.Millisecond
+
+// sentenceResults is what the sentence search shows first for a query:
+// the user's own sentences, which come first, how many of Tatoeba's there
+// are, and those of them filling the rest of the first page.
+type sentenceResults struct {
+	own     []sentencePair
+	tatoeba int
+	first   []tsk.Example
+}
+
+// findSentences runs the sentence search's queries for its first page,
+// counting Tatoeba's sentences while fetching them.
+func findSentences(dict *Dictionary, query string) (sentenceResults, error) {
+	var found sentenceResults
+	own, err := findUserSentences(query)
+	if err != nil {
+		return found, fmt.Errorf("querying your sentences: %w", err)
+	}
+	found.own = own
+
+	var wg sync.WaitGroup
+	var countErr, examplesErr error
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		found.tatoeba, countErr = dict.CountExamples(query)
+	}()
+	if n := examplesPerPage - len(own); n > 0 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			found.first, examplesErr = dict.Examples(query, n, 0)
+		}()
+	}
+	wg.Wait()
+	if err := cmp.Or(countErr, examplesErr); err != nil {
+		return found, fmt.Errorf("querying examples: %w", err)
+	}
+	return found, nil
+}
+
+// sentenceCache keeps the first page of the latest sentence searches, so
+// that the examples key shows a word's sentences at once when they were
+// prefetched as it was selected, or searched for a little while ago. It
+// is shared by the UI and the prefetching goroutines.
+type sentenceCache struct {
+	mu      sync.Mutex
+	size    int
+	queries []string // least recently used first
+	results map[string]sentenceResults
+}
+
+func newSentenceCache(size int) *sentenceCache {
+	return &sentenceCache{size: size, results: make(map[string]sentenceResults)}
+}
+
+// find returns the first page of query's sentences, from the cache if it
+// is there. Errors aren't kept, so the query is tried again next time.
+func (c *sentenceCache) find(dict *Dictionary, query string) (sentenceResults, error) {
+	c.mu.Lock()
+	found, ok := c.results[query]
+	if ok {
+		c.use(query)
+	}
+	c.mu.Unlock()
+	if ok {
+		return found, nil
+	}
+
+	found, err := findSentences(dict, query)
+	if err != nil {
+		return found, err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, ok := c.results[query]; !ok && len(c.queries) >= c.size {
+		delete(c.results, c.queries[0])
+		c.queries = c.queries[1:]
+	}
+	c.results[query] = found
+	c.use(query)
+	return found, nil
+}
+
+// use moves query to the most recently used end of the queue. c.mu must
+// be held.
+func (c *sentenceCache) use(query string) {
+	if i := slices.Index(c.queries, query); i >= 0 {
+		c.queries = slices.Delete(c.queries, i, i+1)
+	}
+	c.queries = append(c.queries, query)
+}
+
+// showSentenceSearchModal searches all the example sentences, the user's own
+// and then Tatoeba's, for a phrase in Finnish or English, whatever word is
+// selected in the main view. A query given is searched for right away, as
+// the examples key does with the selected word. Enter searches, PgDn/PgUp
+// or the examples key page through the matches, and the mark key or Enter
+// on a sentence marks or unmarks it. onMark is called with the marked
+// sentences whenever they change. The copy key hands the sentence pair to
+// onCopy, and the Wiktionary key a Tatoeba sentence's page to onOpen; what
+// they return goes in the footer. The speak key reads the Finnish aloud
+// with say, from the selected sentence on to the last page, for listening
+// practice; + and - change the speed, *rate, and the speak key again
+// stops it.
+// The first page of a search comes from cache if it's there.
+func showSentenceSearchModal(pages *tview.Pages, app *tview.Application, dict *Dictionary, cache *sentenceCache, editor *lineEditor,
+	marked []sentencePair, query string, returnFocus tview.Primitive,
+	onMark func([]sentencePair), onCopy func(text string) string, onOpen func(link string) string,
+	rate *int, say func(ctx context.Context, text string, rate int) error) {
+	input := tview.NewInputField().
+		SetLabel("Finnish or English: ").
+		SetLabelColor(theme.Examples).
+		SetText(query)
+	list := tview.NewList()
+	footer := themedTextView{tview.NewTextView().SetDynamicColors(true)}
+	footer.SetWrap(true)
+	footerText := fmt.Sprintf("[gray]Enter = search, Tab = switch to the list, PgDn/PgUp or %s = page, %s or Enter = mark, %s = copy, %s = open on tatoeba.org, %s = read aloud, +/- = speed, Esc = close\n"+
+		"Tatoeba's sentences are under CC BY 2.0 FR.",
+		keymap.Label(actionExamples), keymap.Label(actionMark), keymap.Label(actionCopy), keymap.Label(actionWiktionary), keymap.Label(actionSpeak))
+	footer.SetText(footerText)
+
+	layout := tview.NewFlex().SetDirection(tview.FlexRow).
+		AddItem(input, 1, 0, true).
+		AddItem(list, 0, 1, false).
+		AddItem(footer, 3, 0, false)
+	layout.SetBorder(true).
+		SetTitle("Sentence search (Tatoeba and your own sentences)").
+		SetBorderColor(theme.Examples).
+		SetTitleColor(theme.Examples)
+
+	indexOf := func(s sentencePair) int {
+		for i, m := range marked {
+			if m == s {
+				return i
+			}
+		}
+		return -1
+	}
+	itemText := func(s sentencePair) (string, string) {
+		mark := "  "
+		if indexOf(s) >= 0 {
+			mark = "[yellow]*[white] "
+		}
+		return theme.Recolor(mark + "[teal]" + tview.Escape(s.Finnish)),
+			theme.Recolor("  [pink]" + tview.Escape(s.English) + " [gray](" + tview.Escape(s.Source) + ")")
+	}
+
+	var (
+		page, pageCount int
+		total           int
+		results         []sentencePair
+	)
+	setTitle := func() {
+		layout.SetTitle(fmt.Sprintf("%d sentences with '%s' (page %d of %d, %d marked)",
+			total, query, page+1, pageCount, len(marked)))
+	}
+	showPage := func(p int) {
+		list.Clear()
+		results = nil
+		footer.SetText(footerText)
+		found, err := cache.find(dict, query)
+		if err != nil {
+			list.AddItem(theme.Recolor(fmt.Sprintf("[red]Error %v[white]", err)), "", 0, nil)
+			return
+		}
+		own := found.own
+		total = len(own) + found.tatoeba
+		if total == 0 {
+			layout.SetTitle(fmt.Sprintf("No sentences found for '%s'", query))
+			list.AddItem(theme.Recolor("[red]No sentences found.[white]"), "", 0, nil)
+			return
+		}
+
+		pageCount = (total + examplesPerPage - 1) / examplesPerPage
+		page = max(0, min(p, pageCount-1))
+		first := page * examplesPerPage
+		last := min(first+examplesPerPage, total)
+		for i := first; i < last && i < len(own); i++ {
+			results = append(results, own[i])
+		}
+		if last > len(own) {
+			// The first page's are found with the count, and may have
+			// been prefetched.
+			examples := found.first
+			if page > 0 {
+				offset := max(0, first-len(own))
+				examples, err = dict.Examples(query, last-len(own)-offset, offset)
+				if err != nil {
+					list.AddItem(theme.Recolor(fmt.Sprintf("[red]Error querying examples: %v[white]", err)), "", 0, nil)
+					return
+				}
+			}
+			for _, e := range examples {
+				results = append(results, sentencePair{e.Finnish, e.English, "Tatoeba"})
+			}
+		}
+		for _, s := range results {
+			main, secondary := itemText(s)
+			list.AddItem(main, secondary, 0, nil)
+		}
+		setTitle()
+	}
+	toggle := func() {
+		idx := list.GetCurrentItem()
+		if idx >= len(results) {
+			return
+		}
+		s := results[idx]
+		if i := indexOf(s); i >= 0 {
+			marked = append(marked[:i:i], marked[i+1:]...)
+		} else {
+			marked = append(marked, s)
+		}
+		onMark(marked)
+		main, secondary := itemText(s)
+		list.SetItemText(idx, main, secondary)
+		setTitle()
+	}
+
+	// reading is the context of the sentences being read aloud, if they
+	// are, and stopReading ends it.
+	var (
+		reading     context.Context
+		stopReading context.CancelFunc
+	)
+	readingText := func(n int) string {
+		return fmt.Sprintf("[gray]Reading sentence %d of %d aloud at %d%% speed (+/- = speed, %s = stop)",
+			page*examplesPerPage+n+1, total, *rate, keymap.Label(actionSpeak))
+	}
+	stop := func() {
+		if stopReading != nil {
+			stopReading()
+		}
+		reading, stopReading = nil, nil
+	}
+	// readAloud reads from the selected sentence on, moving the selection
+	// along as it goes, so another can be picked to carry on from.
+	readAloud := func() {
+		ctx, cancel := context.WithCancel(context.Background())
+		reading, stopReading = ctx, cancel
+		go func() {
+			defer cancel()
+			first := true
+			for {
+				// The list and speed belong to the UI, so the next sentence
+				// is picked there.
+				type next struct {
+					text string
+					rate int
+				}
+				picked := make(chan next, 1)
+				app.QueueUpdateDraw(func() {
+					if reading != ctx {
+						picked <- next{}
+						return
+					}
+					if !first {
+						if i := list.GetCurrentItem(); i+1 < len(results) {
+							list.SetCurrentItem(i + 1)
+						} else if page+1 < pageCount {
+							showPage(page + 1)
+						} else {
+							footer.SetText(fmt.Sprintf("[gray]Read to the last sentence. %s = read again from the selected one.", keymap.Label(actionSpeak)))
+							stop()
+							picked <- next{}
+							return
+						}
+					}
+					i := list.GetCurrentItem()
+					if i >= len(results) {
+						stop()
+						picked <- next{}
+						return
+					}
+					footer.SetText(readingText(i))
+					picked <- next{results[i].Finnish, *rate}
+				})
+				n := <-picked
+				if n.text == "" {
+					return
+				}
+				first = false
+				if err := say(ctx, n.text, n.rate); err != nil {
+					app.QueueUpdateDraw(func() {
+						if reading == ctx {
+							footer.SetText("[gray]" + tview.Escape(fmt.Sprintf("Could not read the sentence aloud: %v", err)))
+							stop()
+						}
+					})
+					return
+				}
+				select {
+				case <-ctx.Done():
+					return
+				case <-time.After(readAloudPause):
+				}
+			}
+		}()
+	}
+
+	closeModal := func() {
+		stop()
+		pages.RemovePage(sentencePage)
+		app.SetFocus(returnFocus)
+	}
+	input.SetDoneFunc(func(key tcell.Key) {
+		if key != tcell.KeyEnter {
+			return
+		}
+		query = strings.TrimSpace(input.GetText())
+		if query == "" {
+			return
+		}
+		showPage(0)
+		if len(results) > 0 {
+			app.SetFocus(list)
+		}
+	})
+	list.SetSelectedFunc(func(int, string, string, rune) { toggle() })
+	layout.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		if editor.Owns(app.GetFocus(), event) {
+			return event
+		}
+		if action, ok := keymap.Action(event); ok && app.GetFocus() == list && list.GetCurrentItem() < len(results) {
+			s := results[list.GetCurrentItem()]
+			switch action {
+			case actionMark:
+				toggle()
+				return nil
+			case actionCopy:
+				footer.SetText("[gray]" + tview.Escape(onCopy(s.Finnish+"\n"+s.English+"\n")))
+				return nil
+			case actionWiktionary:
+				if s.Source != "Tatoeba" {
+					footer.SetText("[gray]Only Tatoeba's sentences are on tatoeba.org.")
+				} else {
+					footer.SetText("[gray]" + tview.Escape(onOpen(tatoebaURL(s.Finnish))))
+				}
+				return nil
+			case actionExamples:
+				// Pressed again, it moves on to the next page, wrapping round.
+				showPage((page + 1) % max(pageCount, 1))
+				return nil
+			case actionSpeak:
+				if reading != nil {
+					stop()
+					footer.SetText(footerText)
+				} else {
+					readAloud()
+				}
+				return nil
+			}
+		}
+		if app.GetFocus() == list && (event.Rune() == '+' || event.Rune() == '-') {
+			// The new speed is taken up from the next sentence on.
+			step := SPEECH_RATE_STEP
+			if event.Rune() == '-' {
+				step = -step
+			}
+			*rate = min(max(*rate+step, MIN_SPEECH_RATE), MAX_SPEECH_RATE)
+			if reading != nil {
+				footer.SetText(readingText(list.GetCurrentItem()))
+			} else {
+				footer.SetText(fmt.Sprintf("[gray]Sentences are read aloud at %d%% speed.", *rate))
+			}
+			return nil
+		}
+		switch event.Key() {
+		case tcell.KeyEsc:
+			closeModal()
+			return nil
+		case tcell.KeyTab, tcell.KeyBacktab:
+			if app.GetFocus() == list {
+				app.SetFocus(input)
+			} else if len(results) > 0 {
+				app.SetFocus(list)
+			}
+			return nil
+		case tcell.KeyPgDn:
+			if query != "" {
+				showPage(page + 1)
+			}
+			return nil
+		case tcell.KeyPgUp:
+			if query != "" {
+				showPage(page - 1)
+			}
+			return nil
+		}
+		return event
+	})
+	editor.Attach(input)
+
+	modal := tview.NewFlex().
+		AddItem(nil, 0, 1, false).
+		AddItem(tview.NewFlex().SetDirection(tview.FlexRow).
+			AddItem(nil, 0, 1, false).
+			AddItem(layout, 0, 6, true).
+			AddItem(nil, 0, 1, false), 0, 6, true).
+		AddItem(nil, 0, 1, false)
+
+	pages.AddPage(sentencePage, modal, true, true)
+	app.SetFocus(input)
+	if query != "" {
+		showPage(0)
+		if len(results) > 0 {
+			app.SetFocus(list)
+		}
+	}
+}
+
+// tatoebaURL is the page of a search of tatoeba.org for a Finnish sentence,
+// as near as tsk can link to the sentence itself: the example sentences
+// database keeps only their text.
+func tatoebaURL(finnish string) string {
+	return "https://tatoeba.org/en/sentences/search?from=fin&query=" + url.QueryEscape(`"`+finnish+`"`)
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/hiAndrewQuinn/tsk/pkg/tsk"
+)
+
+// benchSentenceWords are words with many example sentences and few.
+var benchSentenceWords = []string{"on", "talo", "kirja", "syödä", "kaunis"}
+
+// BenchmarkFindSentences counts and fetches the first page of the example
+// sentences of a few words, as the examples key does.
+func BenchmarkFindSentences(b *testing.B) {
+	db, err := openExamplesDB()
+	if err != nil {
+		b.Fatal(err)
+	}
+	defer db.Close()
+	dict := &Dictionary{Dictionary: tsk.New(nil, nil)}
+	dict.SetExamples(db)
+	b.ReportAllocs()
+	for b.Loop() {
+		for _, word := range benchSentenceWords {
+			if _, err := findSentences(dict, word); err != nil {
+				b.Fatal(err)
+			}
+		}
+	}
+}
+
+func TestCachedExamplesDB(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("XDG_CACHE_HOME", home)
+	t.Setenv("HOME", home)
+	t.Setenv("LocalAppData", home)
+	cacheDir, err := os.UserCacheDir()
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := filepath.Join(cacheDir, "tsk")
+
+	data := []byte("SQLite format 3\x00 the sentences")
+	path, err := cachedExamplesDB(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, err := os.ReadFile(path); err != nil || !bytes.Equal(got, data) {
+		t.Fatalf("cached copy = %q, %v; want %q", got, err, data)
+	}
+
+	// A copy of the same size that isn't the same is written over.
+	if err := os.WriteFile(path, bytes.Repeat([]byte("x"), len(data)), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if again, err := cachedExamplesDB(data); err != nil || again != path {
+		t.Fatalf("cachedExamplesDB again = %q, %v; want %q", again, err, path)
+	}
+	if got, _ := os.ReadFile(path); !bytes.Equal(got, data) {
+		t.Errorf("altered copy was kept: %q", got)
+	}
+
+	// Another pack's copy stays until it hasn't been used for a while.
+	other, err := cachedExamplesDB([]byte("another pack"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	stale := filepath.Join(dir, "example-sentences-0123456789abcdef.sqlite")
+	if err := os.WriteFile(stale, []byte("an old version"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	long := time.Now().Add(-EXAMPLES_CACHE_MAX_AGE - time.Hour)
+	if err := os.Chtimes(stale, long, long); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := cachedExamplesDB(data); err != nil {
+		t.Fatal(err)
+	}
+	for _, f := range []string{path, other} {
+		if _, err := os.Stat(f); err != nil {
+			t.Errorf("%s was removed: %v", filepath.Base(f), err)
+		}
+	}
+	if _, err := os.Stat(stale); !os.IsNotExist(err) {
+		t.Errorf("stale copy wasn't removed: %v", err)
+	}
+}
@@ -0,0 +1,1041 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"maps"
+	"math"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/hiAndrewQuinn/tsk/pkg/tsk"
+	"github.com/jung-kurt/gofpdf"
+	"github.com/rivo/tview"
+)
+
+// ----------------------
+// E-reader Dictionaries (`tsk build-stardict`)
+// ----------------------
+
+// StarDict is the dictionary format KOReader, and many other e-reader and
+// desktop programs, install from a directory of three files: the .dict
+// holds every entry's text one after another, the .idx says where each
+// headword's entry starts and how long it is, and the .ifo describes the
+// lot. Kindles want MOBI instead, which tools like PyGlossary convert
+// StarDict dictionaries to.
+
+// stardictCompare orders headwords the way StarDict looks them up in the
+// .idx: ignoring the case of ASCII letters, then byte by byte.
+func stardictCompare(a, b string) int {
+	lowerASCII := func(c byte) byte {
+		if 'A' <= c && c <= 'Z' {
+			return c + 'a' - 'A'
+		}
+		return c
+	}
+	for i := 0; i < len(a) && i < len(b); i++ {
+		if ca, cb := lowerASCII(a[i]), lowerASCII(b[i]); ca != cb {
+			return int(ca) - int(cb)
+		}
+	}
+	if len(a) != len(b) {
+		return len(a) - len(b)
+	}
+	return strings.Compare(a, b)
+}
+
+// runBuildStardictCommand writes the glosses as a StarDict dictionary, with
+// the same text as `tsk WORD` prints for each headword, e.g.
+// `tsk build-stardict --out ~/koreader/data/dict/tsk`.
+func runBuildStardictCommand(args []string) error {
+	fs := newCommandFlags("build-stardict")
+	out := fs.String("out", "tsk-stardict", "write the dictionary's files to this `directory`")
+	name := fs.String("name", "", "the dictionary's title on the e-reader (default from the dictionary pack)")
+	fs.Parse(args)
+
+	glosses, err := loadGlosses()
+	if err != nil {
+		return fmt.Errorf("loading glosses: %w", err)
+	}
+	if err := initDeeperPrefixes(); err != nil {
+		return fmt.Errorf("initializing deeper prefixes: %w", err)
+	}
+	if *name == "" {
+		*name = fmt.Sprintf("%s %s-%s", activePack.Meta.Name, activePack.Meta.Language, activePack.Meta.GlossLanguage)
+	}
+
+	headwords := slices.SortedFunc(maps.Keys(glosses), stardictCompare)
+
+	var dict, idx bytes.Buffer
+	for _, word := range headwords {
+		text := strings.TrimSpace(stripColorTags(generateGlossText(word, glosses)))
+		idx.WriteString(word)
+		idx.WriteByte(0)
+		binary.Write(&idx, binary.BigEndian, uint32(dict.Len()))
+		binary.Write(&idx, binary.BigEndian, uint32(len(text)))
+		dict.WriteString(text)
+	}
+	if dict.Len() > math.MaxUint32 {
+		return fmt.Errorf("the glosses are too big for a StarDict dictionary")
+	}
+
+	var ifo strings.Builder
+	fmt.Fprintf(&ifo, "StarDict's dict ifo file\nversion=2.4.2\n")
+	fmt.Fprintf(&ifo, "wordcount=%d\nidxfilesize=%d\n", len(headwords), idx.Len())
+	fmt.Fprintf(&ifo, "bookname=%s\n", strings.ReplaceAll(*name, "\n", " "))
+	fmt.Fprintf(&ifo, "description=Made by tsk %s from Wiktionary. License: %s\n", version, activePack.Meta.License)
+	fmt.Fprintf(&ifo, "date=%s\nsametypesequence=m\n", time.Now().Format("2006.01.02"))
+
+	if err := os.MkdirAll(*out, 0755); err != nil {
+		return err
+	}
+	base := filepath.Join(*out, filepath.Base(*out))
+	for ext, data := range map[string][]byte{".ifo": []byte(ifo.String()), ".idx": idx.Bytes(), ".dict": dict.Bytes()} {
+		if err := os.WriteFile(base+ext, data, 0644); err != nil {
+			return err
+		}
+	}
+	fmt.Printf("Wrote %d entries to %s.{ifo,idx,dict}\n", len(headwords), base)
+	return nil
+}
+
+// ----------------------
+// Printable Vocabulary Sheets (`tsk export-pdf`)
+// ----------------------
+
+// A vocabulary sheet is an A4 handout of words in two columns: each word
+// with its parts of speech, its first few meanings and an example
+// sentence, for teachers to print. It uses the PDF core fonts, which cover
+// Finnish and the Western European languages glosses are mostly in;
+// characters outside Windows-1252 come out as '?'.
+
+const (
+	PDF_MARGIN    = 15.0 // mm around the page
+	PDF_GUTTER    = 8.0  // mm between the columns
+	PDF_LINE      = 4.4  // mm per line of meanings
+	PDF_SMALLLINE = 3.9  // mm per line of the example sentence
+	PDF_ENTRY_GAP = 3.0  // mm between words
+)
+
+// vocabEntry is one word on a vocabulary sheet.
+type vocabEntry struct {
+	Word     string
+	Pos      string
+	Meanings []string
+	Example  *tsk.Example
+}
+
+// vocabEntries looks up each word for the sheet, taking inflected forms to
+// their base forms, with at most maxMeanings meanings and, if dict has
+// example sentences, the first one.
+func vocabEntries(words []string, glosses map[string][]tsk.Gloss, dict *Dictionary, maxMeanings int) []vocabEntry {
+	var entries []vocabEntry
+	for _, word := range words {
+		e := vocabEntry{Word: word}
+		if headword, ok := lookupHeadword(word, glosses); ok {
+			e.Word = headword
+			e.Pos = strings.Join(tsk.PartsOfSpeech(glosses[headword]), ", ")
+			for _, g := range glosses[headword] {
+				e.Meanings = append(e.Meanings, g.Meanings...)
+			}
+			e.Meanings = e.Meanings[:min(len(e.Meanings), maxMeanings)]
+		}
+		if dict != nil {
+			if examples, err := dict.Examples(e.Word, 1, 0); err == nil && len(examples) > 0 {
+				e.Example = &examples[0]
+			} else if err != nil && debug {
+				log.Printf("vocabEntries: examples of %s: %v", e.Word, err)
+			}
+		}
+		entries = append(entries, e)
+	}
+	return entries
+}
+
+// writeVocabSheet typesets entries as a two-column PDF under title. Each
+// word is kept whole, in one column, so a column ends early rather than
+// split one.
+func writeVocabSheet(path, title string, entries []vocabEntry) error {
+	pdf := gofpdf.New("P", "mm", "A4", "")
+	pdf.SetTitle(title, true)
+	pdf.SetCreator("tsk "+version, true)
+	pdf.SetMargins(PDF_MARGIN, PDF_MARGIN, PDF_MARGIN)
+	pdf.SetAutoPageBreak(false, PDF_MARGIN)
+	tr := pdf.UnicodeTranslatorFromDescriptor("") // UTF-8 to Windows-1252
+	pageW, pageH := pdf.GetPageSize()
+	colW := (pageW - 2*PDF_MARGIN - PDF_GUTTER) / 2
+	bottom := pageH - PDF_MARGIN - 6 // room for the footer
+
+	pdf.SetFooterFunc(func() {
+		pdf.SetY(-PDF_MARGIN + 3)
+		pdf.SetFont("Helvetica", "I", 8)
+		pdf.SetTextColor(128, 128, 128)
+		pdf.CellFormat(0, 4, tr(fmt.Sprintf("%s · %d", title, pdf.PageNo())), "", 0, "C", false, 0, "")
+	})
+
+	pdf.AddPage()
+	pdf.SetFont("Helvetica", "B", 16)
+	pdf.SetTextColor(0, 0, 0)
+	pdf.CellFormat(0, 8, tr(title), "", 1, "L", false, 0, "")
+	pdf.SetDrawColor(160, 160, 160)
+	pdf.Line(PDF_MARGIN, pdf.GetY()+1, pageW-PDF_MARGIN, pdf.GetY()+1)
+	colTop := pdf.GetY() + 5
+	col, y := 0, colTop
+
+	// lines splits text to fit width in the given font.
+	lines := func(text, style string, size, width float64) []string {
+		pdf.SetFont("Helvetica", style, size)
+		var out []string
+		for _, line := range pdf.SplitLines([]byte(tr(text)), width) {
+			out = append(out, string(line))
+		}
+		return out
+	}
+	for _, e := range entries {
+		// Lay the entry out first, to know how tall it is.
+		head := lines(e.Word, "B", 11, colW)
+		pdf.SetFont("Helvetica", "", 10)
+		numW := pdf.GetStringWidth("9. ")
+		var meanings [][]string
+		for _, m := range e.Meanings {
+			meanings = append(meanings, lines(m, "", 10, colW-numW))
+		}
+		if len(meanings) == 0 {
+			meanings = [][]string{lines("No gloss available.", "", 10, colW-numW)}
+		}
+		var finnish, english []string
+		if e.Example != nil {
+			finnish = lines(e.Example.Finnish, "I", 9, colW-3)
+			english = lines(e.Example.English, "", 9, colW-3)
+		}
+		h := float64(len(head))*5.5 + float64(len(finnish)+len(english))*PDF_SMALLLINE
+		for _, m := range meanings {
+			h += float64(len(m)) * PDF_LINE
+		}
+		if e.Example != nil {
+			h += 1
+		}
+
+		// Move on to the next column, or page, if it doesn't fit.
+		if y+h > bottom && y > colTop {
+			if col == 0 {
+				col, y = 1, colTop
+			} else {
+				pdf.AddPage()
+				colTop = PDF_MARGIN
+				col, y = 0, colTop
+			}
+		}
+		x := PDF_MARGIN + float64(col)*(colW+PDF_GUTTER)
+
+		pdf.SetTextColor(0, 0, 0)
+		pdf.SetFont("Helvetica", "B", 11)
+		for i, line := range head {
+			pdf.SetXY(x, y)
+			pdf.CellFormat(colW, 5.5, line, "", 0, "L", false, 0, "")
+			if i == len(head)-1 && e.Pos != "" {
+				// The parts of speech follow the word, if there's room.
+				w := pdf.GetStringWidth(line)
+				pdf.SetFont("Helvetica", "I", 9)
+				pdf.SetTextColor(120, 90, 0)
+				if pos := tr(e.Pos); w+2+pdf.GetStringWidth(pos) <= colW {
+					pdf.SetXY(x+w+2, y+0.4)
+					pdf.CellFormat(colW-w-2, 5.5, pos, "", 0, "L", false, 0, "")
+				}
+				pdf.SetTextColor(0, 0, 0)
+			}
+			y += 5.5
+		}
+		pdf.SetFont("Helvetica", "", 10)
+		for i, m := range meanings {
+			pdf.SetXY(x, y)
+			if len(e.Meanings) > 1 {
+				pdf.CellFormat(numW, PDF_LINE, fmt.Sprintf("%d.", i+1), "", 0, "L", false, 0, "")
+			}
+			for _, line := range m {
+				pdf.SetXY(x+numW, y)
+				pdf.CellFormat(colW-numW, PDF_LINE, line, "", 0, "L", false, 0, "")
+				y += PDF_LINE
+			}
+		}
+		if e.Example != nil {
+			y += 1
+			pdf.SetFont("Helvetica", "I", 9)
+			pdf.SetTextColor(40, 40, 40)
+			for _, line := range finnish {
+				pdf.SetXY(x+3, y)
+				pdf.CellFormat(colW-3, PDF_SMALLLINE, line, "", 0, "L", false, 0, "")
+				y += PDF_SMALLLINE
+			}
+			pdf.SetFont("Helvetica", "", 9)
+			pdf.SetTextColor(110, 110, 110)
+			for _, line := range english {
+				pdf.SetXY(x+3, y)
+				pdf.CellFormat(colW-3, PDF_SMALLLINE, line, "", 0, "L", false, 0, "")
+				y += PDF_SMALLLINE
+			}
+		}
+		y += PDF_ENTRY_GAP
+	}
+	return pdf.OutputFileAndClose(path)
+}
+
+// runExportPDFCommand makes a printable vocabulary sheet of a word list,
+// e.g. `tsk export-pdf --input chapter3.txt`, or of the marked words.
+func runExportPDFCommand(args []string) error {
+	fs := newCommandFlags("export-pdf")
+	input := fs.String("input", "", "make the sheet of the words in this `list` or marked-words export (default your marked words)")
+	out := fs.String("out", "", "write the PDF to this `file` (default tsk-vocab_<timestamp>.pdf)")
+	title := fs.String("title", "Sanasto", "the sheet's `title`")
+	meanings := fs.Int("meanings", 3, "show at most `N` meanings per word")
+	noExamples := fs.Bool("no-examples", false, "leave out the example sentences")
+	sorted := fs.Bool("sort", false, "list the words alphabetically, instead of in the list's order")
+	fs.Parse(args)
+	if fs.NArg() > 0 {
+		return fmt.Errorf("usage: tsk export-pdf [--input LIST] [--out FILE.pdf]")
+	}
+
+	var words []string
+	if *input != "" {
+		var err error
+		if words, err = readWordList(*input); err != nil {
+			return err
+		}
+	} else {
+		if err := unlockUserData(); err != nil {
+			return fmt.Errorf("unlocking your data: %w", err)
+		}
+		marked, err := loadMarked()
+		if err != nil {
+			return fmt.Errorf("loading marked words: %w", err)
+		}
+		words = slices.Sorted(maps.Keys(marked))
+	}
+	if len(words) == 0 {
+		fmt.Println("There are no words to put on the sheet. Mark some, or give a list with --input.")
+		return nil
+	}
+	if *sorted {
+		slices.Sort(words)
+	}
+
+	glosses, err := loadGlosses()
+	if err != nil {
+		return fmt.Errorf("loading glosses: %w", err)
+	}
+	var dict *Dictionary
+	if !*noExamples {
+		exampleDB, err := openExamplesDB()
+		if err != nil {
+			return err
+		}
+		defer exampleDB.Close()
+		dict = &Dictionary{Dictionary: tsk.New(nil, glosses)}
+		dict.SetExamples(exampleDB)
+	}
+
+	path := *out
+	if path == "" {
+		path = fmt.Sprintf("tsk-vocab_%s.pdf", time.Now().Format("2006-01-02-15-04-05"))
+		if profile != "" {
+			path = safeFileName(fmt.Sprintf("tsk-vocab_%s_%s.pdf", profile, time.Now().Format("2006-01-02-15-04-05")))
+		}
+	}
+	if err := writeVocabSheet(path, *title, vocabEntries(words, glosses, dict, max(*meanings, 1))); err != nil {
+		return err
+	}
+	fmt.Printf("Wrote a vocabulary sheet of %d words to %s\n", len(words), path)
+	return nil
+}
+
+// ----------------------
+// Exporting Marked Words
+// ----------------------
+
+// deeperWords returns the words that the marked words' selected senses are
+// forms of, like omena for omenan, then the words those are forms of in
+// turn, and so on, in the order they are reached. Marked words are left
+// out, as they are exported anyway.
+func deeperWords(marked map[string]senseSet, glosses map[string][]tsk.Gloss) []string {
+	var words []string
+	for w := range marked {
+		words = append(words, w)
+	}
+	sort.Strings(words)
+
+	var found []string
+	seen := make(map[string]bool)
+	var follow func([]tsk.Gloss)
+	follow = func(glossSlice []tsk.Gloss) {
+		for _, gloss := range glossSlice {
+			for _, meaning := range gloss.Meanings {
+				target, ok := deeperTarget(meaning)
+				if !ok || seen[target] {
+					continue
+				}
+				seen[target] = true
+				if _, isMarked := marked[target]; isMarked {
+					continue
+				}
+				if targetGlosses, ok := glosses[target]; ok {
+					found = append(found, target)
+					follow(targetGlosses)
+				}
+			}
+		}
+	}
+	for _, w := range words {
+		follow(selectedGlosses(w, glosses, marked[w]))
+	}
+	return found
+}
+
+// markedExamples fetches up to n example sentences for each marked word,
+// for exportMarked. Words whose sentences can't be read go without.
+func markedExamples(dict *Dictionary, marked map[string]senseSet, n int) map[string][]tsk.Example {
+	if n <= 0 {
+		return nil
+	}
+	examples := make(map[string][]tsk.Example, len(marked))
+	for word := range marked {
+		found, err := dict.Examples(word, n, 0)
+		if err != nil {
+			log.Printf("Could not fetch example sentences of %s to export: %v", word, err)
+			continue
+		}
+		examples[word] = found
+	}
+	return examples
+}
+
+// exportedGloss is a line of the marked words' JSONL export: a gloss, the
+// word's example sentences if they are exported, and the user's note on
+// the word, if any.
+type exportedGloss struct {
+	tsk.Gloss
+	Examples []tsk.Example `json:"examples,omitempty"`
+	Note     string        `json:"note,omitempty"`
+}
+
+// exportMarked writes the marked words to a pair of timestamped files in the
+// working directory: the selected glosses of each word as JSONL, followed
+// by every gloss of the deeper words, and the marked words alone as a
+// CSV, one column unless examples holds sentences for them. The files are
+// named after the collection the words are, if it isn't "". It returns the
+// two file names.
+func exportMarked(marked map[string]senseSet, collection string, deeper []string, glosses map[string][]tsk.Gloss, examples map[string][]tsk.Example) (string, string, error) {
+	// Build base filename with timestamp
+	ts := time.Now().Format("2006-01-02-15-04-05")
+	if collection != "" {
+		ts = collection + "_" + ts
+	}
+	base := fmt.Sprintf("tsk-marked_%s", ts)
+	if profile != "" {
+		base = fmt.Sprintf("tsk-marked_%s_%s", profile, ts)
+	}
+	base = safeFileName(base)
+	jsonFile := base + ".jsonl"
+	txtFile := base + ".txt"
+
+	// Collect & sort keys
+	var words []string
+	for w := range marked {
+		words = append(words, w)
+	}
+	tsk.SortFinnish(words)
+	notes, err := loadNotes()
+	if err != nil {
+		return "", "", fmt.Errorf("loading notes: %w", err)
+	}
+
+	// --- JSONL dump ---
+	fj, err := os.Create(jsonFile)
+	if err != nil {
+		return "", "", err
+	}
+	defer fj.Close()
+
+	writeGlosses := func(wform string, glossSlice []tsk.Gloss) error {
+		for _, gloss := range glossSlice {
+			line, err := json.Marshal(exportedGloss{gloss, examples[wform], notes[wform]})
+			if err != nil {
+				log.Printf("Error marshaling gloss for %s: %v", wform, err)
+				continue
+			}
+			if _, err := fj.Write(append(line, '\n')); err != nil {
+				return fmt.Errorf("writing to %s: %w", jsonFile, err)
+			}
+		}
+		return nil
+	}
+	for _, wform := range words {
+		if err := writeGlosses(wform, selectedGlosses(wform, glosses, marked[wform])); err != nil {
+			return "", "", err
+		}
+	}
+	for _, wform := range deeper {
+		if err := writeGlosses(wform, glosses[wform]); err != nil {
+			return "", "", err
+		}
+	}
+
+	// --- TXT (one-column CSV) dump ---
+	// We’ll use encoding/csv to get proper quoting, but it's just one column.
+	ft, err := os.Create(txtFile)
+	if err != nil {
+		return "", "", err
+	}
+	defer ft.Close()
+
+	cw := csv.NewWriter(ft)
+
+	// Header, with a pair of columns for each example sentence exported.
+	columns := 0
+	for _, found := range examples {
+		columns = max(columns, len(found))
+	}
+	header := []string{"Base Form"}
+	for i := 1; i <= columns; i++ {
+		header = append(header, fmt.Sprintf("Example %d (Finnish)", i), fmt.Sprintf("Example %d (English)", i))
+	}
+	cw.Write(header)
+
+	// One row per word
+	for _, w := range words {
+		row := make([]string, 1, len(header))
+		row[0] = w
+		for _, e := range examples[w] {
+			row = append(row, e.Finnish, e.English)
+		}
+		for len(row) < len(header) {
+			row = append(row, "")
+		}
+		cw.Write(row)
+	}
+	cw.Flush()
+	if err := cw.Error(); err != nil {
+		return "", "", fmt.Errorf("writing to %s: %w", txtFile, err)
+	}
+
+	return jsonFile, txtFile, nil
+}
+
+// exportProfile is a named way of saving the marked words, in one of the
+// lookup formats: one of defaultExportProfiles, or from "export_profiles"
+// in the config file, e.g.
+// {"export_profiles": {"vocab": {"format": "tsv", "columns": ["word", "meaning1"]}}}.
+type exportProfile struct {
+	Format   string   `json:"format"`             // text, jsonl, csv, tsv, quizlet or template
+	Columns  []string `json:"columns,omitempty"`  // for csv and tsv, from lookupColumns
+	Template string   `json:"template,omitempty"` // text/template file, for the template format
+	Ext      string   `json:"ext,omitempty"`      // file extension, if not the format's own
+	Batch    int      `json:"batch,omitempty"`    // most words in one file, splitting bigger exports; 0 for no limit
+
+	text string             // a built-in template, instead of a Template file
+	tmpl *template.Template // the template, once parsed
+}
+
+// notesTemplate is the built-in "notes" profile's template: Markdown, with
+// a heading for each word and part of speech and its meanings listed.
+const notesTemplate = `## {{.Word}} ({{.Pos}})
+{{range .Meanings}}
+- {{.}}{{end}}
+
+`
+
+// QUIZLET_BATCH is how many words the quizlet profile puts in each file,
+// as Quizlet's importer struggles with sets much bigger than that.
+const QUIZLET_BATCH = 500
+
+// defaultExportProfiles are the export profiles there are without any in
+// the config file, which can replace them under the same names.
+var defaultExportProfiles = map[string]exportProfile{
+	"anki":    {Format: "csv", Columns: []string{"word", "meanings", "examples"}},
+	"notes":   {Format: "template", Ext: ".md", text: notesTemplate},
+	"quizlet": {Format: "quizlet", Ext: ".csv", Batch: QUIZLET_BATCH},
+	"raw":     {Format: "jsonl"},
+}
+
+// exportProfiles are the export profiles to choose from, set up by
+// useExportProfiles.
+var exportProfiles = defaultExportProfiles
+
+// exportProfileName is the profile marked words are saved with on
+// quitting. Empty means the usual pair of files, from exportMarked.
+var exportProfileName string
+
+// useExportProfiles adds the config file's export profiles to the default
+// ones, checks them, and picks name for saving on quit.
+func useExportProfiles(custom map[string]exportProfile, name string) error {
+	active := maps.Clone(defaultExportProfiles)
+	maps.Copy(active, custom)
+	for profileName, p := range active {
+		switch p.Format {
+		case "text", "jsonl", "csv", "tsv", "quizlet":
+		case "template":
+			if p.text == "" && p.Template == "" {
+				return fmt.Errorf("export profile '%s': the template format needs a template file", profileName)
+			}
+			var err error
+			if p.text != "" {
+				p.tmpl, err = template.New(profileName).Funcs(templateFuncs).Parse(p.text)
+			} else {
+				p.tmpl, err = parseLookupTemplate(p.Template)
+			}
+			if err != nil {
+				return fmt.Errorf("export profile '%s': %w", profileName, err)
+			}
+		default:
+			return fmt.Errorf("export profile '%s': unknown format '%s' (choose from text, jsonl, csv, tsv, quizlet, template)", profileName, p.Format)
+		}
+		if p.Batch < 0 {
+			return fmt.Errorf("export profile '%s': the batch size can't be negative", profileName)
+		}
+		if len(p.Columns) > 0 {
+			if p.Format != "csv" && p.Format != "tsv" {
+				return fmt.Errorf("export profile '%s': columns only work with csv or tsv", profileName)
+			}
+			columns, err := parseColumns(strings.Join(p.Columns, ","))
+			if err != nil {
+				return fmt.Errorf("export profile '%s': %w", profileName, err)
+			}
+			p.Columns = columns
+		}
+		active[profileName] = p
+	}
+	if _, ok := active[name]; name != "" && !ok {
+		return fmt.Errorf("unknown export profile '%s' (choose from %s)", name, strings.Join(slices.Sorted(maps.Keys(active)), ", "))
+	}
+	exportProfiles, exportProfileName = active, name
+	return nil
+}
+
+// exportMarkedAs writes the marked words, then the deeper words, to a
+// timestamped file in the working directory with the export profile name,
+// only the picked senses of each. As with exportMarked, the file is named
+// after the words' collection too, if there is one. A profile with a batch
+// size splits the words between numbered files of that many words at
+// most. It returns the file names.
+func exportMarkedAs(name string, marked map[string]senseSet, collection string, deeper []string, glosses map[string][]tsk.Gloss) ([]string, error) {
+	p := exportProfiles[name]
+	ext := p.Ext
+	if ext == "" {
+		ext = "." + p.Format
+		if p.Format == "text" || p.Format == "template" {
+			ext = ".txt"
+		}
+	}
+	ts := time.Now().Format("2006-01-02-15-04-05")
+	if collection != "" {
+		ts = collection + "_" + ts
+	}
+	base := fmt.Sprintf("tsk-marked_%s_%s", name, ts)
+	if profile != "" {
+		base = fmt.Sprintf("tsk-marked_%s_%s_%s", profile, name, ts)
+	}
+
+	// Only the picked senses of marked words are written.
+	picked := maps.Clone(glosses)
+	for w, senses := range marked {
+		picked[w] = selectedGlosses(w, glosses, senses)
+	}
+
+	words := append(finnishSorted(maps.Keys(marked)), deeper...)
+	batch := max(len(words), 1)
+	if p.Batch > 0 && p.Batch < len(words) {
+		batch = p.Batch
+	}
+	var files []string
+	for part, chunk := range slices.Collect(slices.Chunk(words, batch)) {
+		file := base + ext
+		if batch < len(words) {
+			file = fmt.Sprintf("%s_%d%s", base, part+1, ext)
+		}
+		file = safeFileName(file)
+		if err := writeExportFile(file, p, picked, chunk); err != nil {
+			return files, err
+		}
+		files = append(files, file)
+	}
+	return files, nil
+}
+
+// writeExportFile writes words to file as the export profile p has them.
+func writeExportFile(file string, p exportProfile, glosses map[string][]tsk.Gloss, words []string) error {
+	f, err := os.Create(file)
+	if err != nil {
+		return err
+	}
+	err = writeExportWords(f, p, glosses, words)
+	// A full disk may only show when the file is closed, so that's not done
+	// until the words are all written.
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fmt.Errorf("writing to %s: %w", file, err)
+	}
+	return nil
+}
+
+// writeExportWords writes words to w as the export profile p has them.
+func writeExportWords(w io.Writer, p exportProfile, glosses map[string][]tsk.Gloss, words []string) error {
+	lw, err := newLookupWriter(w, p.Format, p.Columns, glosses, p.tmpl)
+	if err != nil {
+		return err
+	}
+	for _, word := range words {
+		r := lookupResult{Word: word, Status: lookupFound, BaseForms: []string{word}, Glosses: glosses[word]}
+		if err := lw.Write(r); err != nil {
+			return err
+		}
+	}
+	return lw.Close()
+}
+
+// saveMarkedExports writes the marked sentences, and the marked words with
+// the export profile name (or as the usual pair of files if it's ""), then
+// adds the marked words to the quiz deck, saying what it did on stdout.
+// The files are named after collection, if the words are one.
+// It is what quitting the TUI and `tsk export` do.
+func saveMarkedExports(marked map[string]senseSet, markedSentences []sentencePair, glosses map[string][]tsk.Gloss, dict *Dictionary, name, collection string) error {
+	if len(markedSentences) > 0 {
+		tsvFile, err := exportMarkedSentences(markedSentences)
+		if err != nil {
+			return fmt.Errorf("saving marked sentences: %w", err)
+		}
+		fmt.Printf("Saved %d marked sentences to %s\n", len(markedSentences), tsvFile)
+	}
+
+	// 1) If nothing’s marked, there's nothing more to do.
+	if len(marked) == 0 {
+		return nil
+	}
+
+	// 2) Write the exports
+	var deeper []string
+	if exportDeeper {
+		deeper = deeperWords(marked, glosses)
+	}
+	if name != "" {
+		files, err := exportMarkedAs(name, marked, collection, deeper, glosses)
+		if err != nil {
+			return fmt.Errorf("saving marked words: %w", err)
+		}
+		fmt.Printf("Saved %d marked words as %s to %s\n", len(marked), name, strings.Join(files, ", "))
+		if len(deeper) > 0 {
+			fmt.Printf("  along with the glosses of %d words they are forms of\n", len(deeper))
+		}
+	} else {
+		jsonFile, txtFile, err := exportMarked(marked, collection, deeper, glosses, markedExamples(dict, marked, exportExamples))
+		if err != nil {
+			return fmt.Errorf("saving marked words: %w", err)
+		}
+		fmt.Printf("Saved %d words’ gloss entries to %s\n", len(marked), jsonFile)
+		if len(deeper) > 0 {
+			fmt.Printf("  along with the glosses of %d words they are forms of\n", len(deeper))
+		}
+		fmt.Printf("Saved %d marked words to %s\n", len(marked), txtFile)
+	}
+
+	var words []string
+	for w := range marked {
+		words = append(words, w)
+	}
+	tsk.SortFinnish(words)
+
+	// Marked words are what `tsk quiz` drills.
+	if db, err := openQuizDB(); err != nil {
+		fmt.Fprintf(os.Stderr, "Error opening your quiz deck: %v\n", err)
+	} else {
+		if added, err := addQuizCards(db, words); err != nil {
+			fmt.Fprintf(os.Stderr, "Error adding marked words to your quiz deck: %v\n", err)
+		} else if added > 0 {
+			fmt.Printf("Added %d new words to your quiz deck. Run `tsk quiz` to review them.\n", added)
+		}
+		db.Close()
+	}
+	return nil
+}
+
+// runExportCommand saves the marked words and sentences without opening
+// the TUI, e.g. `tsk export --as anki` from a cron job.
+func runExportCommand(args []string) error {
+	fs := newCommandFlags("export")
+	as := fs.String("as", exportProfileName, "export `profile` to use, e.g. anki, quizlet, notes or raw (default --export-profile, else JSONL and a word list)")
+	tag := fs.String("tag", "", "export the words of the word list with this `tag` (see tsk import-words) instead of the marked ones")
+	collection := fs.String("collection", "", "export only the marked words in this `collection`, without the marked sentences")
+	fs.Parse(args)
+	if fs.NArg() > 0 {
+		return fmt.Errorf("usage: tsk export [--as PROFILE] [--tag TAG | --collection NAME]")
+	}
+	if *tag != "" && *collection != "" {
+		return fmt.Errorf("--tag and --collection can't be used together")
+	}
+	if _, ok := exportProfiles[*as]; *as != "" && !ok {
+		return fmt.Errorf("unknown export profile '%s' (choose from %s)", *as, strings.Join(slices.Sorted(maps.Keys(exportProfiles)), ", "))
+	}
+
+	if err := unlockUserData(); err != nil {
+		return fmt.Errorf("unlocking your data: %w", err)
+	}
+	marked, err := loadMarked()
+	if err != nil {
+		return fmt.Errorf("loading marked words: %w", err)
+	}
+	sentences, err := loadMarkedSentences()
+	if err != nil {
+		return fmt.Errorf("loading marked sentences: %w", err)
+	}
+	if *tag != "" {
+		// The list's words, whole, take the place of the marks.
+		*tag = strings.TrimPrefix(*tag, "#")
+		tagged, err := loadTaggedWords()
+		if err != nil {
+			return err
+		}
+		words := taggedWith(tagged, *tag)
+		if len(words) == 0 {
+			return fmt.Errorf("no words are tagged '%s' (see tsk tags)", *tag)
+		}
+		marked, sentences = make(map[string]senseSet, len(words)), nil
+		for _, w := range words {
+			marked[w] = nil
+		}
+	}
+	if *collection != "" {
+		collections, err := loadCollections()
+		if err != nil {
+			return fmt.Errorf("loading collections: %w", err)
+		}
+		if _, ok := collections[*collection]; !ok {
+			return fmt.Errorf("there is no collection '%s' (choose from %s)", *collection, strings.Join(slices.Sorted(maps.Keys(collections)), ", "))
+		}
+		marked, sentences = collectionMarks(marked, collections, *collection), nil
+	}
+	if len(marked) == 0 && *collection != "" {
+		fmt.Printf("The collection '%s' is empty, so there is nothing to export.\n", *collection)
+		return nil
+	}
+	if len(marked) == 0 && len(sentences) == 0 {
+		fmt.Println("Nothing is marked yet, so there is nothing to export.")
+		return nil
+	}
+
+	glosses, err := loadGlosses()
+	if err != nil {
+		return fmt.Errorf("loading glosses: %w", err)
+	}
+	if err := initDeeperPrefixes(); err != nil {
+		return fmt.Errorf("initializing deeper prefixes: %w", err)
+	}
+	// Only the plain export takes example sentences.
+	var dict *Dictionary
+	if exportExamples > 0 && *as == "" {
+		exampleDB, err := openExamplesDB()
+		if err != nil {
+			return err
+		}
+		defer exampleDB.Close()
+		dict = &Dictionary{Dictionary: tsk.New(nil, glosses)}
+		dict.SetExamples(exampleDB)
+	}
+	return saveMarkedExports(marked, sentences, glosses, dict, *as, *collection)
+}
+
+// glossEditPage names the gloss editor's page, opened by the edit-gloss
+// key. It suspends the main key bindings while open, for typing.
+const glossEditPage = "glossEdit"
+
+// showGlossEditModal opens word's glosses in an edit buffer. Ctrl-S hands
+// the edited glosses to onSave and closes it, unless the text can't be read
+// back; Esc closes it without saving.
+func showGlossEditModal(pages *tview.Pages, app *tview.Application, word string, current []tsk.Gloss, returnFocus tview.Primitive, onSave func([]tsk.Gloss)) {
+	title := fmt.Sprintf("Edit '%s' (Ctrl-S to save, Esc to cancel)", word)
+	area := tview.NewTextArea()
+	area.SetText(glossEditText(current), false)
+	area.SetBorder(true).
+		SetTitle(title).
+		SetBorderColor(theme.Details).
+		SetTitleColor(theme.Details)
+
+	hint := themedTextView{tview.NewTextView()}
+	hint.SetDynamicColors(true)
+	hint.SetText("[gray]Each part of speech on a line of its own, then its meanings, one per line after \"- \". Save it empty to get the dictionary's glosses back.")
+
+	closeEditor := func() {
+		pages.RemovePage(glossEditPage)
+		app.SetFocus(returnFocus)
+	}
+	area.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		switch event.Key() {
+		case tcell.KeyEsc:
+			closeEditor()
+			return nil
+		case tcell.KeyCtrlS:
+			edited, err := parseGlossEdit(word, area.GetText(), current)
+			if err != nil {
+				area.SetTitle(fmt.Sprintf("%s: %v", title, err)).SetTitleColor(theme.Error)
+				return nil
+			}
+			closeEditor()
+			onSave(edited)
+			return nil
+		}
+		return event
+	})
+
+	editor := tview.NewFlex().SetDirection(tview.FlexRow).
+		AddItem(area, 0, 1, true).
+		AddItem(hint, 2, 0, false)
+	modal := tview.NewFlex().
+		AddItem(nil, 0, 1, false).
+		AddItem(tview.NewFlex().SetDirection(tview.FlexRow).
+			AddItem(nil, 0, 1, false).
+			AddItem(editor, 0, 3, true).
+			AddItem(nil, 0, 1, false), 0, 3, true).
+		AddItem(nil, 0, 1, false)
+
+	pages.AddPage(glossEditPage, modal, true, true)
+	app.SetFocus(area)
+}
+
+// notePage names the note editor's page, opened by the note key when there
+// is no $EDITOR to open. It suspends the main key bindings while open.
+const notePage = "note"
+
+// showNoteModal opens word's note in an edit box. Ctrl-S hands the note to
+// onSave and closes it, and Esc closes it without saving.
+func showNoteModal(pages *tview.Pages, app *tview.Application, word, note string, returnFocus tview.Primitive, onSave func(string)) {
+	area := tview.NewTextArea()
+	area.SetText(note, true)
+	area.SetPlaceholder("e.g. confusable with tuuli/tuli")
+	area.SetBorder(true).
+		SetTitle(fmt.Sprintf("Note on '%s' (Ctrl-S to save, empty to remove, Esc to cancel)", word)).
+		SetBorderColor(theme.Details).
+		SetTitleColor(theme.Details)
+
+	closeEditor := func() {
+		pages.RemovePage(notePage)
+		app.SetFocus(returnFocus)
+	}
+	area.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		switch event.Key() {
+		case tcell.KeyEsc:
+			closeEditor()
+			return nil
+		case tcell.KeyCtrlS:
+			closeEditor()
+			onSave(area.GetText())
+			return nil
+		}
+		return event
+	})
+
+	modal := tview.NewFlex().
+		AddItem(nil, 0, 1, false).
+		AddItem(tview.NewFlex().SetDirection(tview.FlexRow).
+			AddItem(nil, 0, 1, false).
+			AddItem(area, 8, 0, true).
+			AddItem(nil, 0, 1, false), 0, 2, true).
+		AddItem(nil, 0, 1, false)
+
+	pages.AddPage(notePage, modal, true, true)
+	app.SetFocus(area)
+}
+
+// exportPage names the export menu's page, opened by the save key. It
+// suspends the main key bindings while open.
+const exportPage = "export"
+
+// showExportModal asks which export profile to save the marked words with,
+// offering the usual pair of files first, and calls onSelect with the
+// profile's name, or "" for the pair.
+func showExportModal(pages *tview.Pages, app *tview.Application, returnFocus tview.Primitive, onSelect func(name string)) {
+	list := tview.NewList().ShowSecondaryText(false)
+	list.SetBorder(true).
+		SetTitle("Save marked words as (Enter to save, Esc to close)").
+		SetBorderColor(theme.MarkedList).
+		SetTitleColor(theme.MarkedList)
+
+	names := append([]string{""}, slices.Sorted(maps.Keys(exportProfiles))...)
+	list.AddItem(theme.Recolor("both [gray]glosses as JSONL, and the words as a list[white]"), "", 0, nil)
+	for i, name := range names[1:] {
+		p := exportProfiles[name]
+		detail := p.Format
+		if len(p.Columns) > 0 {
+			detail += ": " + strings.Join(p.Columns, ", ")
+		}
+		list.AddItem(theme.Recolor(fmt.Sprintf("%s [gray]%s[white]", tview.Escape(name), tview.Escape(detail))), "", 0, nil)
+		if name == exportProfileName {
+			list.SetCurrentItem(i + 1)
+		}
+	}
+
+	closeModal := func() {
+		pages.RemovePage(exportPage)
+		app.SetFocus(returnFocus)
+	}
+	list.SetSelectedFunc(func(idx int, _ string, _ string, _ rune) {
+		closeModal()
+		onSelect(names[idx])
+	})
+	list.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		if event.Key() == tcell.KeyEsc {
+			closeModal()
+			return nil
+		}
+		return event
+	})
+
+	modal := tview.NewFlex().
+		AddItem(nil, 0, 1, false).
+		AddItem(tview.NewFlex().SetDirection(tview.FlexRow).
+			AddItem(nil, 0, 1, false).
+			AddItem(list, len(names)+2, 0, true).
+			AddItem(nil, 0, 1, false), 0, 2, true).
+		AddItem(nil, 0, 1, false)
+
+	pages.AddPage(exportPage, modal, true, true)
+	app.SetFocus(list)
+}
+
+// exportMarkedSentences writes the marked sentences to a timestamped TSV file
+// in the working directory, one Finnish, English and source row each, ready
+// for importing into flashcard programs. It returns the file name.
+func exportMarkedSentences(sentences []sentencePair) (string, error) {
+	ts := time.Now().Format("2006-01-02-15-04-05")
+	name := fmt.Sprintf("tsk-marked-sentences_%s.tsv", ts)
+	if profile != "" {
+		name = fmt.Sprintf("tsk-marked-sentences_%s_%s.tsv", profile, ts)
+	}
+
+	f, err := os.Create(name)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	cw := csv.NewWriter(f)
+	cw.Comma = '\t'
+	cw.Write([]string{"Finnish", "English", "Source"})
+	for _, s := range sentences {
+		cw.Write([]string{s.Finnish, s.English, s.Source})
+	}
+	cw.Flush()
+	if err := cw.Error(); err != nil {
+		return "", fmt.Errorf("writing to %s: %w", name, err)
+	}
+	return name, nil
+}
@@ -4,7 +4,11 @@ go 1.24.0
 
 require (
 	github.com/gdamore/tcell/v2 v2.8.1
+	github.com/jung-kurt/gofpdf v1.16.2
 	github.com/rivo/tview v0.0.0-20241227133733-17b7edb88c57
+	golang.org/x/crypto v0.36.0
+	golang.org/x/term v0.30.0
+	golang.org/x/text v0.23.0
 	modernc.org/sqlite v1.37.0
 )
 
@@ -22,8 +26,6 @@ require (
 	github.com/rivo/uniseg v0.4.7 // indirect
 	golang.org/x/exp v0.0.0-20250305212735-054e65f0b394 // indirect
 	golang.org/x/sys v0.31.0 // indirect
-	golang.org/x/term v0.28.0 // indirect
-	golang.org/x/text v0.21.0 // indirect
 	modernc.org/libc v1.62.1 // indirect
 	modernc.org/mathutil v1.7.1 // indirect
 	modernc.org/memory v1.9.1 // indirect
@@ -0,0 +1,509 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/hiAndrewQuinn/tsk/pkg/tsk"
+	"github.com/rivo/tview"
+)
+
+// ----------------------
+// Inflection Tables (Ctrl-D)
+// ----------------------
+
+// Wiktionary lists most inflected forms as entries of their own, glossed as
+// e.g. "inessive plural of talo" or "first-person singular present
+// indicative of tehdä". Read backwards, those glosses give the declension or
+// conjugation table of the base form, with no inflection rules of our own.
+
+// inflectedForm is one form of a base word, as described by its gloss.
+type inflectedForm struct {
+	Word string
+	Form string
+}
+
+// FormIndex lists the known inflected forms of each base word.
+type FormIndex map[string][]inflectedForm
+
+var nominalCases = []string{
+	"nominative", "genitive", "partitive", "accusative",
+	"inessive", "elative", "illative",
+	"adessive", "ablative", "allative",
+	"essive", "translative", "abessive", "instructive", "comitative",
+}
+
+var verbMoods = []string{
+	"present indicative", "past indicative",
+	"present conditional", "present imperative", "present potential",
+}
+
+var verbPersons = []string{"first-person", "second-person", "third-person"}
+
+// formWords are the words a form-of gloss may be made of. Anything else
+// ("Synonym of", "Alternative form of", "A village in") is not a form.
+var formWords = func() map[string]bool {
+	words := make(map[string]bool)
+	for _, w := range append(append([]string{}, nominalCases...), verbPersons...) {
+		words[w] = true
+	}
+	for _, w := range strings.Fields(`singular plural present past indicative
+		conditional imperative potential connegative active passive participle
+		infinitive long first second third fourth fifth form of the agent
+		negative verbal noun comparative superlative degree`) {
+		words[w] = true
+	}
+	return words
+}()
+
+// parseFormOf splits a gloss like "genitive/accusative singular of talo"
+// into its form and base word.
+func parseFormOf(meaning string) (form, lemma string, ok bool) {
+	i := strings.LastIndex(meaning, " of ")
+	if i <= 0 {
+		return "", "", false
+	}
+	form, lemma = meaning[:i], strings.TrimSpace(meaning[i+len(" of "):])
+	if lemma == "" || form == "form" || strings.Contains(form, "possessive") {
+		return "", "", false
+	}
+	for _, w := range strings.FieldsFunc(form, func(r rune) bool { return r == ' ' || r == '/' }) {
+		if !formWords[w] {
+			return "", "", false
+		}
+	}
+	return form, lemma, true
+}
+
+// NewFormIndex collects the form-of glosses of every entry under the base
+// words they point to. Only base words that are headwords themselves count.
+func NewFormIndex(glosses map[string][]tsk.Gloss) FormIndex {
+	index := make(FormIndex)
+	for word, entries := range glosses {
+		for _, g := range entries {
+			for _, meaning := range g.Meanings {
+				form, lemma, ok := parseFormOf(meaning)
+				if !ok || lemma == word {
+					continue
+				}
+				if _, known := glosses[lemma]; !known {
+					continue
+				}
+				index[lemma] = append(index[lemma], inflectedForm{Word: word, Form: form})
+			}
+		}
+	}
+	for _, forms := range index {
+		sort.Slice(forms, func(i, j int) bool {
+			if forms[i].Form != forms[j].Form {
+				return forms[i].Form < forms[j].Form
+			}
+			return forms[i].Word < forms[j].Word
+		})
+	}
+	return index
+}
+
+// Lemma picks the base word whose table to show for word: the word itself
+// if it has forms, else the base word its own glosses or the analyzer
+// point to.
+func (index FormIndex) Lemma(word string, glosses map[string][]tsk.Gloss) (string, bool) {
+	if len(index[word]) > 0 {
+		return word, true
+	}
+	for _, g := range glosses[word] {
+		for _, meaning := range g.Meanings {
+			if _, lemma, ok := parseFormOf(meaning); ok && len(index[lemma]) > 0 {
+				return lemma, true
+			}
+		}
+	}
+	for _, a := range tsk.AnalyzeWord(word, glosses) {
+		if len(index[a.Lemma]) > 0 {
+			return a.Lemma, true
+		}
+	}
+	return "", false
+}
+
+// addCell appends word to a table cell, skipping duplicates.
+func addCell(cells map[string][]string, key, word string) {
+	for _, w := range cells[key] {
+		if w == word {
+			return
+		}
+	}
+	cells[key] = append(cells[key], word)
+}
+
+// inflectionTableText renders the declension table, conjugation tables and
+// remaining forms (participles, infinitives, comparison) of lemma.
+func inflectionTableText(lemma string, forms []inflectedForm) string {
+	isCase := make(map[string]bool)
+	for _, c := range nominalCases {
+		isCase[c] = true
+	}
+
+	// Cells are keyed "case number" and "mood person number" / "mood passive".
+	nominal := make(map[string][]string)
+	finite := make(map[string][]string)
+	var others []inflectedForm
+
+	for _, f := range forms {
+		fields := strings.Fields(f.Form)
+		switch {
+		case len(fields) == 2 && (fields[1] == "singular" || fields[1] == "plural") && allCases(fields[0], isCase):
+			for _, c := range strings.Split(fields[0], "/") {
+				addCell(nominal, c+" "+fields[1], f.Word)
+			}
+		case len(fields) == 1 && isCase[fields[0]]:
+			// Comitative and instructive have no number of their own.
+			addCell(nominal, fields[0]+" plural", f.Word)
+		case !strings.Contains(f.Form, "connegative") && finiteKeys(fields) != nil:
+			for _, key := range finiteKeys(fields) {
+				addCell(finite, key, f.Word)
+			}
+		default:
+			others = append(others, f)
+		}
+	}
+
+	var builder strings.Builder
+	if len(nominal) > 0 {
+		if _, ok := nominal["nominative singular"]; !ok {
+			nominal["nominative singular"] = []string{lemma}
+		}
+		fmt.Fprintf(&builder, "[yellow]Declension of %s[white]\n\n", lemma)
+		width := 0
+		for _, words := range nominal {
+			if n := len([]rune(strings.Join(words, ", "))); n > width {
+				width = n
+			}
+		}
+		fmt.Fprintf(&builder, "[gray]%-12s %-*s %s[white]\n", "", width, "singular", "plural")
+		for _, c := range nominalCases {
+			sg, pl := nominal[c+" singular"], nominal[c+" plural"]
+			if sg == nil && pl == nil {
+				continue
+			}
+			fmt.Fprintf(&builder, "[gray]%-12s[white] %-*s %s\n", c, width,
+				strings.Join(sg, ", "), strings.Join(pl, ", "))
+		}
+		builder.WriteString("\n")
+	}
+
+	if len(finite) > 0 {
+		fmt.Fprintf(&builder, "[yellow]Conjugation of %s[white]\n", lemma)
+		labels := []string{"1st", "2nd", "3rd"}
+		for _, mood := range verbMoods {
+			width := 0
+			for _, person := range verbPersons {
+				if n := len([]rune(strings.Join(finite[mood+" "+person+" singular"], ", "))); n > width {
+					width = n
+				}
+			}
+			var rows strings.Builder
+			for i, person := range verbPersons {
+				sg := finite[mood+" "+person+" singular"]
+				pl := finite[mood+" "+person+" plural"]
+				if sg == nil && pl == nil {
+					continue
+				}
+				fmt.Fprintf(&rows, "[gray]%s sg[white]  %-*s  [gray]%s pl[white]  %s\n", labels[i], width,
+					strings.Join(sg, ", "), labels[i], strings.Join(pl, ", "))
+			}
+			if passive := finite[mood+" passive"]; passive != nil {
+				fmt.Fprintf(&rows, "[gray]passive[white] %s\n", strings.Join(passive, ", "))
+			}
+			if rows.Len() > 0 {
+				fmt.Fprintf(&builder, "\n[gray]%s[white]\n%s", mood, rows.String())
+			}
+		}
+		builder.WriteString("\n")
+	}
+
+	if len(others) > 0 {
+		builder.WriteString("[yellow]Other forms[white]\n\n")
+		// Forms are sorted, so the words of one form are next to each other.
+		for i := 0; i < len(others); {
+			j := i
+			var words []string
+			for ; j < len(others) && others[j].Form == others[i].Form; j++ {
+				words = append(words, others[j].Word)
+			}
+			fmt.Fprintf(&builder, "[gray]%s:[white] %s\n", others[i].Form, strings.Join(words, ", "))
+			i = j
+		}
+	}
+	return builder.String()
+}
+
+// allCases reports whether every '/'-separated part of s is a case name.
+func allCases(s string, isCase map[string]bool) bool {
+	for _, c := range strings.Split(s, "/") {
+		if !isCase[c] {
+			return false
+		}
+	}
+	return true
+}
+
+// finiteKeys maps a finite verb form like "first-person singular
+// present/past indicative" or "passive present indicative" to its
+// conjugation table cells, or nil if it isn't one.
+func finiteKeys(fields []string) []string {
+	var person, number, mood string
+	var tenses []string
+	passive := false
+	for _, field := range fields {
+		switch field {
+		case "first-person", "second-person", "third-person":
+			person = field
+		case "singular", "plural":
+			number = field
+		case "passive":
+			passive = true
+		case "active":
+		case "indicative", "conditional", "imperative", "potential":
+			mood = field
+		default:
+			for _, tense := range strings.Split(field, "/") {
+				if tense != "present" && tense != "past" {
+					return nil
+				}
+				tenses = append(tenses, tense)
+			}
+		}
+	}
+	if mood == "" || len(tenses) == 0 || passive == (person != "") || (person != "") != (number != "") {
+		return nil
+	}
+	var keys []string
+	for _, tense := range tenses {
+		if passive {
+			keys = append(keys, tense+" "+mood+" passive")
+		} else {
+			keys = append(keys, tense+" "+mood+" "+person+" "+number)
+		}
+	}
+	return keys
+}
+
+// ----------------------------------------------------
+// --- NEW --- Inflection Search Modal (Ctrl-I)
+// ----------------------------------------------------
+func showInflectionSearchModal(pages *tview.Pages, glosses map[string][]tsk.Gloss, app *tview.Application, mainInputField *tview.InputField, editor *lineEditor, db *sql.DB) {
+	const modalPageName = "inflectionSearch"
+	if debug {
+		log.Println("showInflectionSearchModal: Function called.")
+	}
+
+	const inflectionHelpText = `[gray]
+	Keybindings:
+
+	Up/Down     = Scroll result list.
+
+	[green]Enter on a result[gray] in the list to select its base form and return to the main view.
+	[red]Esc[gray] or [red]Enter on an empty search bar[gray] to close this window.
+	
+	This feature searches for a word's base form in real-time.
+	A minimum of 3 characters is required to begin a search.
+
+	[white]
+	`
+
+	var (
+		modalBgColor        = theme.Lemmatizer.Background
+		modalHeaderFooterBg = theme.Lemmatizer.HeaderFooter
+		modalDetailsBg      = theme.Lemmatizer.Details
+		modalPrimaryColor   = theme.Lemmatizer.Primary
+		modalAccentColor    = theme.Lemmatizer.Accent
+		modalFieldBgColor   = theme.Lemmatizer.FieldBackground
+		modalListSelectBg   = theme.Lemmatizer.SelectBackground
+		modalListSelectText = theme.Lemmatizer.SelectText
+	)
+
+	// --- Components ---
+	searchInput := tview.NewInputField().
+		SetLabel("Inflected form: ").
+		SetLabelColor(modalAccentColor).
+		SetFieldBackgroundColor(modalFieldBgColor).
+		SetFieldTextColor(modalPrimaryColor).
+		SetFieldWidth(30)
+
+	resultsList := tview.NewList().
+		ShowSecondaryText(false).
+		SetSelectedBackgroundColor(modalListSelectBg).
+		SetSelectedTextColor(modalListSelectText)
+
+	detailsView := themedTextView{tview.NewTextView().
+		SetDynamicColors(true).
+		SetScrollable(true).
+		SetWrap(true).
+		SetWordWrap(true).
+		SetTextColor(modalPrimaryColor)}
+	detailsView.SetText("[blue]Type 3 characters or more to start searching.[white]") // Initial message
+
+	detailsView.SetBorder(true).
+		SetTitle("Base Form Details (Tab/Shift-Tab to scroll)").
+		SetBorderColor(modalAccentColor).
+		SetTitleColor(modalAccentColor)
+	detailsView.SetBackgroundColor(modalDetailsBg)
+
+	// --- Main Layout ---
+	contentFlex := tview.NewFlex().
+		SetDirection(tview.FlexColumn).
+		AddItem(
+			tview.NewFlex().SetDirection(tview.FlexRow).
+				AddItem(searchInput, 3, 1, true).
+				AddItem(resultsList, 0, 4, false),
+			0, 1, true,
+		).
+		AddItem(detailsView, 0, 2, false)
+	contentFlex.SetBackgroundColor(modalBgColor)
+
+	// --- Header & Footer ---
+	header := tview.NewTextView().
+		SetText(fmt.Sprintf("tsk (%s) - Inflection Search", version)).
+		SetTextAlign(tview.AlignCenter).
+		SetTextColor(modalPrimaryColor).
+		SetBackgroundColor(modalHeaderFooterBg)
+
+	footer := tview.NewTextView().
+		SetText("Esc to close. Enter on result to select.").
+		SetTextAlign(tview.AlignCenter).
+		SetTextColor(modalPrimaryColor).
+		SetBackgroundColor(modalHeaderFooterBg)
+
+	// --- Final Modal Layout ---
+	modalLayout := tview.NewFlex().
+		SetDirection(tview.FlexRow).
+		AddItem(header, 1, 0, false).
+		AddItem(nil, 1, 0, false).
+		AddItem(contentFlex, 0, 1, true).
+		AddItem(nil, 1, 0, false).
+		AddItem(footer, 1, 0, false)
+	modalLayout.SetBackgroundColor(modalBgColor)
+
+	// --- Event Handlers ---
+
+	// When selection in list changes, update the details view
+	resultsList.SetChangedFunc(func(index int, mainText, secondaryText string, shortcut rune) {
+		parts := strings.Split(mainText, " ~> ")
+		if len(parts) != 2 {
+			detailsView.SetText(fmt.Sprintf("[red]Error parsing result: %s[white]", mainText))
+			return
+		}
+		inflection, baseWord := parts[0], parts[1]
+
+		var builder strings.Builder
+		builder.WriteString(fmt.Sprintf("[aqua]%s[white] ~> [yellow]%s[white]\n\n", inflection, baseWord))
+		builder.WriteString(generateGlossText(baseWord, glosses))
+
+		detailsView.SetText(builder.String()).ScrollToBeginning()
+	})
+
+	// When a list item is selected with Enter, go back to main view
+	resultsList.SetSelectedFunc(func(index int, mainText, secondaryText string, shortcut rune) {
+		parts := strings.Split(mainText, " ~> ")
+		if len(parts) == 2 {
+			baseWord := parts[1]
+			mainInputField.SetText(baseWord)
+		}
+		pages.RemovePage(modalPageName)
+		app.SetFocus(mainInputField)
+	})
+
+	// When input text changes, run a search
+	searchInput.SetChangedFunc(func(text string) {
+		query := strings.TrimSpace(text)
+		resultsList.Clear()
+		detailsView.Clear().ScrollToBeginning()
+
+		if len(query) < 3 {
+			detailsView.SetText("[blue]Type 3 characters or more to start searching.[white]")
+			return
+		}
+
+		// Prepare and run the FTS5 prefix query
+		ftsQuery := tsk.FoldCase(query) + "*"
+		q := "SELECT inflection, word FROM inflections_fts WHERE inflection MATCH ? ORDER BY RANDOM() LIMIT 50"
+		rows, err := db.Query(q, ftsQuery)
+		if err != nil {
+			detailsView.SetText(fmt.Sprintf("[red]Database query failed: %v[white]", err))
+			return
+		}
+		defer rows.Close()
+
+		found := false
+		for rows.Next() {
+			found = true
+			var inflection, word string
+			if err := rows.Scan(&inflection, &word); err != nil {
+				continue // Skip malformed rows
+			}
+			displayString := fmt.Sprintf("%s ~> %s", inflection, word)
+			resultsList.AddItem(displayString, "", 0, nil)
+		}
+		resultsList.SetCurrentItem(0)
+
+		if !found {
+			detailsView.SetText(fmt.Sprintf("[red]No base form found for '[darkred:%s]'.[white]", query))
+		}
+	})
+
+	// Handle special keys in the input field
+	searchInput.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		switch event.Key() {
+		case tcell.KeyEsc:
+			pages.RemovePage(modalPageName)
+			return nil
+		case tcell.KeyEnter:
+			if searchInput.GetText() == "" {
+				pages.RemovePage(modalPageName)
+			} else {
+				// Transfer focus to list to allow selection
+				app.SetFocus(resultsList)
+			}
+			return nil
+		case tcell.KeyDown:
+			app.SetFocus(resultsList)
+			cur := resultsList.GetCurrentItem()
+			if cur < resultsList.GetItemCount()-1 {
+				resultsList.SetCurrentItem(cur + 1)
+			}
+			return nil
+		case tcell.KeyUp:
+			app.SetFocus(resultsList)
+			cur := resultsList.GetCurrentItem()
+			if cur > 0 {
+				resultsList.SetCurrentItem(cur - 1)
+			}
+			return nil
+		case tcell.KeyTab:
+			app.SetFocus(detailsView)
+			row, col := detailsView.GetScrollOffset()
+			detailsView.ScrollTo(row+1, col)
+			return nil
+		case tcell.KeyBacktab:
+			app.SetFocus(detailsView)
+			row, col := detailsView.GetScrollOffset()
+			newRow := row - 1
+			if newRow < 0 {
+				newRow = 0
+			}
+			detailsView.ScrollTo(newRow, col)
+			return nil
+		}
+		return event
+	})
+
+	editor.Attach(searchInput)
+
+	pages.AddPage(modalPageName, modalLayout, true, true)
+	app.SetFocus(searchInput)
+}
@@ -0,0 +1,427 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+)
+
+// ----------------------
+// Key Bindings (`keys` in config.json)
+// ----------------------
+
+// keyAction names one of the main view's commands, as written in the
+// "keys" object of the config file, e.g. {"keys": {"mark": "Ctrl-B"}}.
+type keyAction string
+
+const (
+	actionLemmatizer     keyAction = "lemmatizer"
+	actionExamples       keyAction = "examples"
+	actionInflections    keyAction = "inflections"
+	actionCopy           keyAction = "copy"
+	actionSpeak          keyAction = "speak"
+	actionMark           keyAction = "mark"
+	actionSave           keyAction = "save"
+	actionSenses         keyAction = "senses"
+	actionHistoryBack    keyAction = "history-back"
+	actionHistoryForward keyAction = "history-forward"
+	actionHistory        keyAction = "history"
+	actionListMarked     keyAction = "list-marked"
+	actionReverseFind    keyAction = "reverse-find"
+	actionPosFilter      keyAction = "pos-filter"
+	actionSentences      keyAction = "sentences"
+	actionRelated        keyAction = "related"
+	actionStats          keyAction = "stats"
+	actionWiktionary     keyAction = "wiktionary"
+	actionFoldDiacritics keyAction = "fold-diacritics"
+	actionCollapseList   keyAction = "collapse-list"
+	actionRhymes         keyAction = "rhymes"
+	actionEditGloss      keyAction = "edit-gloss"
+	actionSurprise       keyAction = "surprise"
+	actionNeighbors      keyAction = "neighbors"
+	actionEnglishSearch  keyAction = "english-search"
+	actionReleaseNotes   keyAction = "release-notes"
+	actionCollect        keyAction = "collect"
+	actionNote           keyAction = "note"
+	actionHelp           keyAction = "help"
+	actionBugReport      keyAction = "bug-report"
+)
+
+// keyBinding is a key a command can be bound to: a Control key (key is
+// KeyCtrlA to KeyCtrlZ), an Alt key (key is KeyRune, r the lowercase
+// letter) or a function key.
+type keyBinding struct {
+	key tcell.Key
+	r   rune
+}
+
+// Keymap binds each command to its key.
+type Keymap map[keyAction]keyBinding
+
+// ctrlKey is the binding for Control and letter.
+func ctrlKey(letter rune) keyBinding {
+	return keyBinding{key: tcell.KeyCtrlA + tcell.Key(letter-'a')}
+}
+
+// defaultKeymap holds the keys tsk has always used.
+var defaultKeymap = Keymap{
+	actionLemmatizer:     ctrlKey('e'),
+	actionExamples:       ctrlKey('t'),
+	actionInflections:    ctrlKey('d'),
+	actionCopy:           ctrlKey('y'),
+	actionSpeak:          ctrlKey('k'),
+	actionMark:           ctrlKey('s'),
+	actionSave:           ctrlKey('w'),
+	actionSenses:         ctrlKey('g'),
+	actionHistoryBack:    ctrlKey('p'),
+	actionHistoryForward: ctrlKey('n'),
+	actionHistory:        ctrlKey('o'),
+	actionListMarked:     ctrlKey('l'),
+	actionReverseFind:    ctrlKey('f'),
+	actionPosFilter:      ctrlKey('v'),
+	actionSentences:      ctrlKey('x'),
+	actionRelated:        ctrlKey('q'),
+	actionStats:          ctrlKey('b'),
+	actionWiktionary:     {key: tcell.KeyRune, r: 'o'},
+	actionFoldDiacritics: {key: tcell.KeyRune, r: 'a'},
+	actionCollapseList:   {key: tcell.KeyRune, r: 'l'},
+	actionRhymes:         {key: tcell.KeyRune, r: 'r'},
+	actionEditGloss:      ctrlKey('j'),
+	actionSurprise:       {key: tcell.KeyRune, r: 's'},
+	actionNeighbors:      {key: tcell.KeyRune, r: 'n'},
+	actionEnglishSearch:  {key: tcell.KeyRune, r: 'g'},
+	actionReleaseNotes:   {key: tcell.KeyRune, r: 'u'},
+	actionCollect:        {key: tcell.KeyRune, r: 'c'},
+	actionNote:           {key: tcell.KeyRune, r: 't'},
+	actionHelp:           ctrlKey('h'),
+	actionBugReport:      ctrlKey('r'),
+}
+
+// keymap is the active keymap, the defaults with the config file's
+// "keys" on top.
+var keymap = defaultKeymap
+
+// reservedCtrlKeys can't be bound: to a terminal Ctrl-I is Tab, Ctrl-M is
+// Enter, and Ctrl-C is how tview is stopped.
+var reservedCtrlKeys = map[rune]bool{'c': true, 'i': true, 'm': true}
+
+// parseKeyBinding reads a key written like "Ctrl-B", "Alt-m" or "F2".
+func parseKeyBinding(s string) (keyBinding, error) {
+	lower := strings.ToLower(strings.TrimSpace(s))
+	name, letter, found := strings.Cut(lower, "-")
+	if found && utf8.RuneCountInString(letter) == 1 {
+		r, _ := utf8.DecodeRuneInString(letter)
+		switch name {
+		case "ctrl", "control":
+			if r < 'a' || r > 'z' {
+				return keyBinding{}, fmt.Errorf("'%s' is not a key tsk can bind: Control only goes with the letters A-Z", s)
+			}
+			if reservedCtrlKeys[r] {
+				return keyBinding{}, fmt.Errorf("'%s' is reserved and can't be bound", s)
+			}
+			return ctrlKey(r), nil
+		case "alt", "meta":
+			return keyBinding{key: tcell.KeyRune, r: r}, nil
+		}
+	}
+	if n, err := strconv.Atoi(strings.TrimPrefix(lower, "f")); err == nil && strings.HasPrefix(lower, "f") && n >= 1 && n <= 12 {
+		return keyBinding{key: tcell.KeyF1 + tcell.Key(n-1)}, nil
+	}
+	return keyBinding{}, fmt.Errorf("'%s' is not a key tsk can bind (try e.g. Ctrl-B, Alt-M or F2)", s)
+}
+
+// String writes the binding the way the help text and titles show it.
+func (b keyBinding) String() string {
+	switch {
+	case b.key == tcell.KeyRune:
+		return "Alt-" + strings.ToUpper(string(b.r))
+	case b.key >= tcell.KeyCtrlA && b.key <= tcell.KeyCtrlZ:
+		return fmt.Sprintf("Ctrl-%c", 'A'+rune(b.key-tcell.KeyCtrlA))
+	default:
+		return tcell.KeyNames[b.key]
+	}
+}
+
+// Label is the key bound to action, as it should be shown.
+func (k Keymap) Label(action keyAction) string {
+	return k[action].String()
+}
+
+// Action returns the command event's key is bound to, if any.
+func (k Keymap) Action(event *tcell.EventKey) (keyAction, bool) {
+	for action, b := range k {
+		if event.Key() != b.key {
+			continue
+		}
+		if b.key == tcell.KeyRune && (event.Modifiers()&tcell.ModAlt == 0 || unicode.ToLower(event.Rune()) != b.r) {
+			continue
+		}
+		return action, true
+	}
+	return "", false
+}
+
+// useKeymap makes the default keymap, rebound by keys, the active one.
+// keys maps action names to keys, as in the config file.
+func useKeymap(keys map[string]string) error {
+	active := make(Keymap, len(defaultKeymap))
+	for action, b := range defaultKeymap {
+		active[action] = b
+	}
+	for name, key := range keys {
+		action := keyAction(name)
+		if _, ok := active[action]; !ok {
+			return fmt.Errorf("unknown key action '%s' (choose from %s)", name, strings.Join(keyActionNames(), ", "))
+		}
+		b, err := parseKeyBinding(key)
+		if err != nil {
+			return fmt.Errorf("keys: %s: %v", name, err)
+		}
+		active[action] = b
+	}
+	bound := make(map[keyBinding]keyAction)
+	for _, name := range keyActionNames() {
+		action := keyAction(name)
+		b := active[action]
+		if other, ok := bound[b]; ok {
+			return fmt.Errorf("keys: %s and %s are both bound to %s", other, action, b)
+		}
+		bound[b] = action
+	}
+	keymap = active
+	return nil
+}
+
+// keyActionNames lists the actions that can be rebound, sorted.
+func keyActionNames() []string {
+	var names []string
+	for action := range defaultKeymap {
+		names = append(names, string(action))
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ----------------------
+// Line Editing (`--editing`)
+// ----------------------
+
+// The search fields are tview InputFields, which already know the readline
+// keys Ctrl-A, Ctrl-E, Ctrl-K, Ctrl-W and Ctrl-U. tsk takes Ctrl-E, Ctrl-K
+// and Ctrl-W for its own commands, though, so by default only Ctrl-A and
+// Ctrl-U reach the field. The "readline" mode gives all five back to the
+// search fields and moves those commands to Alt-E, Alt-K and Alt-W. The
+// "vim" mode does the same, and adds a normal mode entered with Esc.
+
+// DEFAULT_EDITING leaves tsk's own Control keys as they are.
+const DEFAULT_EDITING = "default"
+
+var editingModes = []string{DEFAULT_EDITING, "readline", "vim"}
+
+// editingMode is how the search fields are edited, picked with --editing or
+// "editing" in the config file.
+var editingMode = DEFAULT_EDITING
+
+// useEditingMode makes the named editing mode current.
+func useEditingMode(name string) error {
+	for _, mode := range editingModes {
+		if mode == name {
+			editingMode = name
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown editing mode '%s' (choose from %s)", name, strings.Join(editingModes, ", "))
+}
+
+// readlineKeys are the keys a search field keeps for itself outside the
+// default mode.
+var readlineKeys = map[tcell.Key]bool{
+	tcell.KeyCtrlA: true,
+	tcell.KeyCtrlE: true,
+	tcell.KeyCtrlK: true,
+	tcell.KeyCtrlW: true,
+	tcell.KeyCtrlU: true,
+}
+
+// movedCommands maps the Alt keys that stand in for tsk's commands on the
+// readline keys to those keys.
+var movedCommands = map[rune]tcell.Key{
+	'e': tcell.KeyCtrlE,
+	'k': tcell.KeyCtrlK,
+	'w': tcell.KeyCtrlW,
+}
+
+// lineEditor applies editingMode to the search fields attached to it, and
+// tells the global key handler which keys to leave to them. Each TUI has
+// its own, as each field's vim mode is part of the session.
+type lineEditor struct {
+	normal  map[*tview.InputField]bool // fields in vim's normal mode
+	pending rune                       // a vim operator waiting for its motion, 'c' or 'd'
+}
+
+func newLineEditor() *lineEditor {
+	return &lineEditor{normal: make(map[*tview.InputField]bool)}
+}
+
+// Attach puts field under the editor, ahead of the input capture it
+// already has. Fields start out in insert mode, ready for typing.
+func (e *lineEditor) Attach(field *tview.InputField) {
+	capture := field.GetInputCapture()
+	e.normal[field] = false
+	field.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		if editingMode == "vim" {
+			if event = e.vimKey(field, event); event == nil {
+				return nil
+			}
+		}
+		if capture != nil {
+			return capture(event)
+		}
+		return event
+	})
+}
+
+// Owns reports whether event belongs to the focused search field rather
+// than to tsk's global key bindings.
+func (e *lineEditor) Owns(focus tview.Primitive, event *tcell.EventKey) bool {
+	field, ok := focus.(*tview.InputField)
+	if !ok || editingMode == DEFAULT_EDITING {
+		return false
+	}
+	normal, attached := e.normal[field]
+	if !attached {
+		return false
+	}
+	if editingMode == "vim" && event.Key() == tcell.KeyEsc {
+		// Esc leaves insert mode; in normal mode it quits as usual.
+		return !normal
+	}
+	return readlineKeys[event.Key()]
+}
+
+// Command turns the Alt key standing in for a moved command into the
+// Control key the global key bindings expect.
+func (e *lineEditor) Command(event *tcell.EventKey) *tcell.EventKey {
+	if editingMode == DEFAULT_EDITING || event.Key() != tcell.KeyRune || event.Modifiers()&tcell.ModAlt == 0 {
+		return event
+	}
+	if key, ok := movedCommands[unicode.ToLower(event.Rune())]; ok {
+		return tcell.NewEventKey(key, 0, tcell.ModCtrl)
+	}
+	return event
+}
+
+// vimKey handles event for a field in vim mode. In normal mode the usual
+// motions and edits become the keys the InputField understands, and j/k
+// move through the word list like Down/Up.
+func (e *lineEditor) vimKey(field *tview.InputField, event *tcell.EventKey) *tcell.EventKey {
+	key := func(k tcell.Key) *tcell.EventKey { return tcell.NewEventKey(k, 0, tcell.ModNone) }
+	// The cursor sits between letters, where vim's sits on one, so normal
+	// mode keeps it just before the letter vim would have it on.
+	if !e.normal[field] {
+		if event.Key() == tcell.KeyEsc {
+			e.normal[field] = true
+			return key(tcell.KeyLeft)
+		}
+		return event
+	}
+	if event.Key() != tcell.KeyRune {
+		e.pending = 0
+		return event
+	}
+
+	// keys sends all but the last of several keys to the field, and returns
+	// the last for the caller to pass on.
+	keys := func(events ...*tcell.EventKey) *tcell.EventKey {
+		handle := field.InputHandler()
+		for _, event := range events[:len(events)-1] {
+			handle(event, func(tview.Primitive) {})
+		}
+		return events[len(events)-1]
+	}
+	wordEnd := tcell.NewEventKey(tcell.KeyRight, 0, tcell.ModCtrl)
+	selectWord := tcell.NewEventKey(tcell.KeyRight, 0, tcell.ModCtrl|tcell.ModShift)
+	insert := func(k tcell.Key) *tcell.EventKey {
+		e.normal[field] = false
+		if k == tcell.KeyNUL {
+			return nil
+		}
+		return key(k)
+	}
+
+	r := event.Rune()
+	if op := e.pending; op != 0 {
+		e.pending = 0
+		switch {
+		case r == op && op == 'd':
+			return key(tcell.KeyCtrlU)
+		case r == op && op == 'c':
+			return insert(tcell.KeyCtrlU)
+		case r == '$' && op == 'd':
+			return key(tcell.KeyCtrlK)
+		case r == '$' && op == 'c':
+			return insert(tcell.KeyCtrlK)
+		case r == 'b' && op == 'd':
+			return key(tcell.KeyCtrlW)
+		case r == 'b' && op == 'c':
+			return insert(tcell.KeyCtrlW)
+		case r == 'w' && op == 'd':
+			// Unlike the others, dw takes the space after the word too.
+			return keys(selectWord, tcell.NewEventKey(tcell.KeyRight, 0, tcell.ModShift), key(tcell.KeyDelete))
+		case r == 'e' && op == 'd':
+			return keys(selectWord, key(tcell.KeyDelete))
+		case (r == 'w' || r == 'e') && op == 'c':
+			e.normal[field] = false
+			return keys(selectWord, key(tcell.KeyDelete))
+		}
+		return nil
+	}
+
+	switch r {
+	case 'i':
+		return insert(tcell.KeyNUL)
+	case 'a':
+		return insert(tcell.KeyRight)
+	case 'I':
+		return insert(tcell.KeyHome)
+	case 'A':
+		return insert(tcell.KeyEnd)
+	case 'h':
+		return key(tcell.KeyLeft)
+	case 'l':
+		return key(tcell.KeyRight)
+	case '0', '^':
+		return key(tcell.KeyHome)
+	case '$':
+		return keys(key(tcell.KeyEnd), key(tcell.KeyLeft))
+	case 'e':
+		return wordEnd
+	case 'w':
+		return keys(wordEnd, key(tcell.KeyRight), key(tcell.KeyRight))
+	case 'b':
+		return tcell.NewEventKey(tcell.KeyLeft, 0, tcell.ModCtrl)
+	case 'x':
+		return key(tcell.KeyDelete)
+	case 'X':
+		return key(tcell.KeyBackspace2)
+	case 'D':
+		return key(tcell.KeyCtrlK)
+	case 'C':
+		return insert(tcell.KeyCtrlK)
+	case 'S':
+		return insert(tcell.KeyCtrlU)
+	case 'd', 'c':
+		e.pending = r
+	case 'j':
+		return key(tcell.KeyDown)
+	case 'k':
+		return key(tcell.KeyUp)
+	}
+	return nil
+}
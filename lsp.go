@@ -0,0 +1,391 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf16"
+	"unicode/utf8"
+
+	"github.com/hiAndrewQuinn/tsk/pkg/tsk"
+)
+
+// ----------------------
+// Editor Integration (`tsk lsp`)
+// ----------------------
+
+// `tsk lsp` speaks just enough of the Language Server Protocol over
+// standard input and output for an editor to show tsk's glosses when
+// hovering over a Finnish word, and to complete Finnish words as they're
+// typed. Any editor with an LSP client (Neovim, VS Code, Helix, Emacs)
+// can start it for text or Markdown files.
+
+// lspMessage is a JSON-RPC request or notification from the editor.
+// Notifications have no ID.
+type lspMessage struct {
+	ID     json.RawMessage `json:"id,omitempty"`
+	Method string          `json:"method"`
+	Params json.RawMessage `json:"params,omitempty"`
+}
+
+// lspResponse answers a request. Result is always sent, as null if there is
+// nothing to say, since the protocol takes a missing result to be an error.
+type lspResponse struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id"`
+	Result  any             `json:"result"`
+}
+
+type lspErrorResponse struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id"`
+	Error   lspError        `json:"error"`
+}
+
+type lspError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+// JSON-RPC error codes.
+const (
+	lspParseError     = -32700
+	lspMethodNotFound = -32601
+	lspInvalidRequest = -32600
+)
+
+// lspPosition counts characters in UTF-16 code units, as the protocol does.
+type lspPosition struct {
+	Line      int `json:"line"`
+	Character int `json:"character"`
+}
+
+type lspRange struct {
+	Start lspPosition `json:"start"`
+	End   lspPosition `json:"end"`
+}
+
+type lspTextDocumentPosition struct {
+	TextDocument struct {
+		URI string `json:"uri"`
+	} `json:"textDocument"`
+	Position lspPosition `json:"position"`
+}
+
+type lspDidOpen struct {
+	TextDocument struct {
+		URI  string `json:"uri"`
+		Text string `json:"text"`
+	} `json:"textDocument"`
+}
+
+type lspDidChange struct {
+	TextDocument struct {
+		URI string `json:"uri"`
+	} `json:"textDocument"`
+	ContentChanges []struct {
+		Text string `json:"text"`
+	} `json:"contentChanges"`
+}
+
+type lspHover struct {
+	Contents struct {
+		Kind  string `json:"kind"`
+		Value string `json:"value"`
+	} `json:"contents"`
+	Range lspRange `json:"range"`
+}
+
+type lspCompletionItem struct {
+	Label  string `json:"label"`
+	Kind   int    `json:"kind"`
+	Detail string `json:"detail,omitempty"`
+}
+
+type lspCompletionList struct {
+	IsIncomplete bool                `json:"isIncomplete"`
+	Items        []lspCompletionItem `json:"items"`
+}
+
+// lspCompletionText is the CompletionItemKind for plain words.
+const lspCompletionText = 1
+
+// LSP_MAX_MESSAGE is the most bytes a message may have, far more than an
+// editor sends for any text file, so that a wrong Content-Length can't
+// make tsk try to allocate gigabytes.
+const LSP_MAX_MESSAGE = 8 << 20
+
+// errLSPTooLarge is what readLSPMessage returns for a message over
+// LSP_MAX_MESSAGE, after skipping it.
+var errLSPTooLarge = fmt.Errorf("message over %d bytes", LSP_MAX_MESSAGE)
+
+// readLSPMessage reads one message, framed by a Content-Length header.
+func readLSPMessage(r *bufio.Reader) ([]byte, error) {
+	length := -1
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return nil, err
+		}
+		line = strings.TrimSpace(line)
+		if line == "" {
+			break
+		}
+		name, value, ok := strings.Cut(line, ":")
+		if ok && strings.EqualFold(name, "Content-Length") {
+			if length, err = strconv.Atoi(strings.TrimSpace(value)); err != nil {
+				return nil, fmt.Errorf("bad Content-Length: %q", value)
+			}
+		}
+	}
+	if length < 0 {
+		return nil, fmt.Errorf("message without a Content-Length")
+	}
+	if length > LSP_MAX_MESSAGE {
+		if _, err := io.CopyN(io.Discard, r, int64(length)); err != nil {
+			return nil, err
+		}
+		return nil, errLSPTooLarge
+	}
+	body := make([]byte, length)
+	if _, err := io.ReadFull(r, body); err != nil {
+		return nil, err
+	}
+	return body, nil
+}
+
+func writeLSPMessage(w io.Writer, message any) error {
+	body, err := json.Marshal(message)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "Content-Length: %d\r\n\r\n%s", len(body), body)
+	return err
+}
+
+// lspWordAt finds the word of text under pos, returning it and its range,
+// and how much of it comes before pos. ok is false if pos isn't in or just
+// after a word.
+func lspWordAt(text string, pos lspPosition) (word, before string, where lspRange, ok bool) {
+	lines := strings.Split(text, "\n")
+	if pos.Line < 0 || pos.Line >= len(lines) {
+		return "", "", lspRange{}, false
+	}
+	line := strings.TrimSuffix(lines[pos.Line], "\r")
+
+	// Turn the UTF-16 offset into a byte offset, and back again for the
+	// ends of the word.
+	offset, units := len(line), 0
+	for i, r := range line {
+		if units >= pos.Character {
+			offset = i
+			break
+		}
+		units += utf16.RuneLen(r)
+	}
+	utf16Len := func(s string) int {
+		n := 0
+		for _, r := range s {
+			n += utf16.RuneLen(r)
+		}
+		return n
+	}
+
+	for _, m := range annotationWordPattern.FindAllStringIndex(line, -1) {
+		if m[0] <= offset && offset <= m[1] {
+			start := utf16Len(line[:m[0]])
+			where = lspRange{
+				Start: lspPosition{pos.Line, start},
+				End:   lspPosition{pos.Line, start + utf16Len(line[m[0]:m[1]])},
+			}
+			return line[m[0]:m[1]], line[m[0]:offset], where, true
+		}
+	}
+	return "", "", lspRange{}, false
+}
+
+// lspHoverText is what hovering over word shows: the same text as
+// `tsk WORD`, which finds a capitalised word in lowercase too.
+func lspHoverText(word string, glosses map[string][]tsk.Gloss) (string, bool) {
+	r := lookupWord(word, glosses, func() *tsk.PatternIndex { return nil })
+	if r.Status == lookupMissing {
+		return "", false
+	}
+	var b strings.Builder
+	writeLookupText(&b, r, glosses)
+	return strings.TrimSpace(b.String()), true
+}
+
+// lspCompletions offers headwords starting with the part of a word typed
+// so far, keeping a capital first letter if it had one.
+func lspCompletions(prefix string, words []string, frequencies map[string]wordFrequency, glosses map[string][]tsk.Gloss) lspCompletionList {
+	list := lspCompletionList{Items: []lspCompletionItem{}}
+	if utf8.RuneCountInString(prefix) < 2 {
+		// One letter matches far too much to be any use, and more typing
+		// will ask again.
+		list.IsIncomplete = true
+		return list
+	}
+	first, size := utf8.DecodeRuneInString(prefix)
+	capitalised := unicode.IsUpper(first)
+	matches := headwordCompletions(strings.ToLower(prefix), words, frequencies)
+	list.IsIncomplete = len(matches) == tsk.MaxResults
+	for _, word := range matches {
+		label := word
+		if capitalised {
+			_, n := utf8.DecodeRuneInString(word)
+			label = prefix[:size] + word[n:]
+		}
+		list.Items = append(list.Items, lspCompletionItem{
+			Label:  label,
+			Kind:   lspCompletionText,
+			Detail: shortMeaning(firstMeaning(word, glosses)),
+		})
+	}
+	return list
+}
+
+func runLSPCommand(args []string) error {
+	fs := newCommandFlags("lsp")
+	fs.Bool("stdio", true, "talk over standard input and output (the only transport; accepted because editors pass it)")
+	fs.Parse(args)
+
+	glosses, err := loadGlosses()
+	if err != nil {
+		return fmt.Errorf("loading glosses: %w", err)
+	}
+	words, err := loadWords()
+	if err != nil {
+		return fmt.Errorf("loading words: %w", err)
+	}
+	frequencies, err := loadFrequencies()
+	if err != nil {
+		return fmt.Errorf("loading frequencies: %w", err)
+	}
+
+	documents := make(map[string]string) // URI -> text
+	reader := bufio.NewReader(os.Stdin)
+	out := bufio.NewWriter(os.Stdout)
+	reply := func(id json.RawMessage, result any) error {
+		if err := writeLSPMessage(out, lspResponse{"2.0", id, result}); err != nil {
+			return err
+		}
+		return out.Flush()
+	}
+	fail := func(id json.RawMessage, code int, message string) error {
+		if err := writeLSPMessage(out, lspErrorResponse{"2.0", id, lspError{code, message}}); err != nil {
+			return err
+		}
+		return out.Flush()
+	}
+
+	shutdown := false
+	for {
+		body, err := readLSPMessage(reader)
+		if err == io.EOF {
+			return nil
+		}
+		// Which request a message was can't be told without reading it,
+		// so the error goes out with a null ID, as JSON-RPC says.
+		if err == errLSPTooLarge {
+			if err := fail(nil, lspInvalidRequest, err.Error()); err != nil {
+				return err
+			}
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		var msg lspMessage
+		if err := json.Unmarshal(body, &msg); err != nil {
+			if err := fail(nil, lspParseError, err.Error()); err != nil {
+				return err
+			}
+			continue
+		}
+		isRequest := len(msg.ID) > 0
+
+		var result any
+		switch msg.Method {
+		case "initialize":
+			result = map[string]any{
+				"capabilities": map[string]any{
+					"textDocumentSync":   1, // the whole text on every change
+					"hoverProvider":      true,
+					"completionProvider": map[string]any{},
+				},
+				"serverInfo": map[string]string{"name": "tsk"},
+			}
+		case "shutdown":
+			shutdown = true
+		case "exit":
+			if !shutdown {
+				return fmt.Errorf("exited without a shutdown request")
+			}
+			return nil
+		case "textDocument/didOpen":
+			var p lspDidOpen
+			if err := json.Unmarshal(msg.Params, &p); err == nil {
+				documents[p.TextDocument.URI] = p.TextDocument.Text
+			}
+		case "textDocument/didChange":
+			var p lspDidChange
+			if err := json.Unmarshal(msg.Params, &p); err == nil && len(p.ContentChanges) > 0 {
+				documents[p.TextDocument.URI] = p.ContentChanges[len(p.ContentChanges)-1].Text
+			}
+		case "textDocument/didClose":
+			var p lspTextDocumentPosition
+			if err := json.Unmarshal(msg.Params, &p); err == nil {
+				delete(documents, p.TextDocument.URI)
+			}
+		case "textDocument/hover":
+			var p lspTextDocumentPosition
+			if err := json.Unmarshal(msg.Params, &p); err != nil {
+				if err := fail(msg.ID, lspInvalidRequest, err.Error()); err != nil {
+					return err
+				}
+				continue
+			}
+			word, _, where, ok := lspWordAt(documents[p.TextDocument.URI], p.Position)
+			if !ok {
+				break
+			}
+			if text, ok := lspHoverText(word, glosses); ok {
+				var hover lspHover
+				hover.Contents.Kind = "plaintext"
+				hover.Contents.Value = text
+				hover.Range = where
+				result = hover
+			}
+		case "textDocument/completion":
+			var p lspTextDocumentPosition
+			if err := json.Unmarshal(msg.Params, &p); err != nil {
+				if err := fail(msg.ID, lspInvalidRequest, err.Error()); err != nil {
+					return err
+				}
+				continue
+			}
+			_, before, _, _ := lspWordAt(documents[p.TextDocument.URI], p.Position)
+			result = lspCompletions(before, words, frequencies, glosses)
+		default:
+			// Unknown notifications, like $/cancelRequest, are ignored;
+			// unknown requests get an error so the editor isn't left waiting.
+			if isRequest {
+				if err := fail(msg.ID, lspMethodNotFound, "tsk doesn't handle "+msg.Method); err != nil {
+					return err
+				}
+			}
+			continue
+		}
+		if isRequest {
+			if err := reply(msg.ID, result); err != nil {
+				return err
+			}
+		}
+	}
+}
@@ -0,0 +1,26 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestReadLSPMessage(t *testing.T) {
+	frame := func(body string) string {
+		return fmt.Sprintf("Content-Length: %d\r\n\r\n%s", len(body), body)
+	}
+	huge := strings.Repeat(" ", LSP_MAX_MESSAGE+1)
+	r := bufio.NewReader(strings.NewReader(frame(`{"method":"a"}`) + frame(huge) + frame(`{"method":"b"}`)))
+
+	for _, want := range []string{`{"method":"a"}`, "", `{"method":"b"}`} {
+		body, err := readLSPMessage(r)
+		switch {
+		case want == "" && err != errLSPTooLarge:
+			t.Errorf("readLSPMessage of %d bytes = %d bytes, %v; want errLSPTooLarge", len(huge), len(body), err)
+		case want != "" && (err != nil || string(body) != want):
+			t.Errorf("readLSPMessage = %q, %v; want %q", body, err, want)
+		}
+	}
+}
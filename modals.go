@@ -0,0 +1,618 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"maps"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/hiAndrewQuinn/tsk/pkg/tsk"
+	"github.com/rivo/tview"
+)
+
+// ----------------------
+// Reverse-Find by English Meaning
+// ----------------------
+
+// showMeaningSearchModal creates and displays a modal window for searching word meanings.
+// This modal is designed to look and feel like the main application window, with a
+// two-pane layout for search/results and details.
+// MODIFIED: Added mainInputField to the function signature to allow interaction with the main view.
+//
+// Enter on a result calls onSelect with the English query, all its
+// matches and the word picked, for the main view to list them.
+func showMeaningSearchModal(pages *tview.Pages, glosses map[string][]tsk.Gloss, meaningIndex *tsk.MeaningIndex, app *tview.Application, mainInputField *tview.InputField, editor *lineEditor,
+	onSelect func(query string, matches []string, word string)) {
+	if debug {
+		log.Println("showMeaningSearchModal: Function called.")
+	}
+
+	// --- NEW: Help text specific to this modal ---
+	const reverseFindHelpText = `[gray]
+	Keybindings:
+
+	Enter       = Search for the English term.
+	Up/Down     = Scroll result list.
+
+	Results must use every word you search for, as whole words, e.g. [green]big dog[gray]. The best matches
+	come first. [green]walk*[gray] also finds "walking", "walked" and "walker", and [green]"look after"[gray] in quotes
+	finds just that phrase. [green]dog OR hound[gray] finds either; each side of OR can have several words.
+
+	[green]Enter on a result[gray] in the list to select it and return to the main view, which then
+	lists every result, so Up/Down browse the rest without searching again.
+	[red]Enter on an empty search bar[gray] to close this window and return to the main view.
+	
+	Unlike the normal Finnish lookup, this mode does *not* search as you type,
+	unless tsk was started with [green]--reverse-find-live[gray].
+	You aren't supposed to stay here for long...
+
+	[white]
+	`
+
+	// --- Color Theme for Modal ---
+	var (
+		modalBgColor        = theme.ReverseFind.Background
+		modalHeaderFooterBg = theme.ReverseFind.HeaderFooter
+		modalDetailsBg      = theme.ReverseFind.Details
+		modalPrimaryColor   = theme.ReverseFind.Primary
+		modalAccentColor    = theme.ReverseFind.Accent
+		modalFieldBgColor   = theme.ReverseFind.FieldBackground
+		modalListSelectBg   = theme.ReverseFind.SelectBackground
+		modalListSelectText = theme.ReverseFind.SelectText
+	)
+
+	// --- Components ---
+
+	// Left Pane: Search Input & Results List
+	searchInput := tview.NewInputField().
+		SetLabel("English term: ").
+		SetLabelColor(modalAccentColor).            // NEW: Color
+		SetFieldBackgroundColor(modalFieldBgColor). // NEW: Color
+		SetFieldTextColor(modalPrimaryColor).       // NEW: Color
+		SetFieldWidth(30)
+
+	resultsList := tview.NewList().
+		ShowSecondaryText(false).
+		SetSelectedBackgroundColor(modalListSelectBg). // NEW: Color
+		SetSelectedTextColor(modalListSelectText)      // NEW: Color
+
+	// Right Pane: Details Display
+	detailsView := themedTextView{tview.NewTextView().
+		SetDynamicColors(true).
+		SetScrollable(true).
+		SetWrap(true).
+		SetWordWrap(true).
+		SetTextColor(modalPrimaryColor)}
+
+	detailsView.SetBorder(true).
+		SetTitle("Word Details (Tab/Shift-Tab to scroll)").
+		SetBorderColor(modalAccentColor). // NEW: Color
+		SetTitleColor(modalAccentColor)   // NEW: Color
+
+	// Set the initial help text for this modal.
+	detailsView.SetText(reverseFindHelpText)
+
+	detailsView.SetBackgroundColor(modalDetailsBg)
+
+	// --- Main Content Layout (The two panes) ---
+	contentFlex := tview.NewFlex().
+		SetDirection(tview.FlexColumn).
+		AddItem(
+			tview.NewFlex().SetDirection(tview.FlexRow).
+				AddItem(searchInput, 3, 1, true).
+				AddItem(resultsList, 0, 4, false),
+			0, 1, true,
+		).
+		AddItem(detailsView, 0, 2, false)
+	contentFlex.SetBackgroundColor(modalBgColor) // NEW: Set overall background
+
+	// --- Header ---
+	headerLeft := tview.NewTextView().
+		SetText(fmt.Sprintf("tsk (%s) - Reverse-Find by English Meaning", version)).
+		SetTextAlign(tview.AlignLeft).
+		SetTextColor(modalPrimaryColor) // NEW: Color
+	headerLeft.SetBackgroundColor(modalHeaderFooterBg)
+
+	headerRight := tview.NewButton("[::u]https://github.com/hiAndrewQuinn/tsk[::-]")
+	headerRight.SetLabelColor(modalPrimaryColor) // NEW: Color
+	headerRight.SetSelectedFunc(func() {
+		if err := openBrowser("https://github.com/hiAndrewQuinn/tsk"); err != nil {
+			fmt.Fprintf(os.Stderr, "Error opening browser: %v\n", err)
+		}
+	})
+
+	headerFlex := tview.NewFlex().SetDirection(tview.FlexColumn)
+	headerFlex.SetBackgroundColor(modalHeaderFooterBg) // NEW: Color
+	headerFlex.
+		AddItem(headerLeft, 0, 1, false).
+		AddItem(headerRight, 40, 0, false)
+
+	// --- Footer ---
+	footerLeft := tview.NewTextView().
+		SetText("Esc to close. Enter to search. Up/Down to scroll results.").
+		SetTextAlign(tview.AlignLeft).
+		SetTextColor(modalPrimaryColor) // NEW: Color
+	footerLeft.SetBackgroundColor(modalHeaderFooterBg)
+
+	footerRight := tview.NewButton("[::u]https://andrew-quinn.me/[::-]")
+	footerRight.SetLabelColor(modalPrimaryColor) // NEW: Color
+	footerRight.SetSelectedFunc(func() {
+		if err := openBrowser("https://andrew-quinn.me/"); err != nil {
+			fmt.Fprintf(os.Stderr, "Error opening browser: %v\n", err)
+		}
+	})
+
+	footerFlex := tview.NewFlex().SetDirection(tview.FlexColumn)
+	footerFlex.SetBackgroundColor(modalHeaderFooterBg) // NEW: Color
+	footerFlex.
+		AddItem(footerLeft, 0, 1, false).
+		AddItem(footerRight, 40, 0, false)
+
+	// --- Final Modal Layout (mimicking mainFlex) ---
+	modalLayout := tview.NewFlex().
+		SetDirection(tview.FlexRow).
+		AddItem(headerFlex, 1, 0, false).
+		AddItem(nil, 1, 0, false). // Spacer
+		AddItem(contentFlex, 0, 1, true).
+		AddItem(nil, 1, 0, false). // Spacer
+		AddItem(footerFlex, 1, 0, false)
+	modalLayout.SetBackgroundColor(modalBgColor) // NEW: Set overall background
+
+	// --- Logic & Event Handlers ---
+
+	// query and matches are the last search's, for onSelect.
+	var query string
+	var matches []string
+
+	searchAction := func() {
+		if debug {
+			log.Println("showMeaningSearchModal: searchAction triggered.")
+		}
+		// Not lowercased, so OR stays an operator; the words are
+		// matched ignoring case anyway.
+		query = strings.TrimSpace(searchInput.GetText())
+		if debug {
+			log.Printf("showMeaningSearchModal: Cleaned query: '%s'", query)
+		}
+
+		resultsList.Clear()
+		detailsView.Clear().ScrollToBeginning()
+
+		// This check is now redundant because SetDoneFunc handles the empty case,
+		// but it's harmless to leave as a safeguard.
+		if query == "" {
+			detailsView.SetText(reverseFindHelpText)
+			return
+		}
+
+		matches = meaningIndex.Search(query, 0)
+		if len(matches) == 0 {
+			detailsView.SetText(fmt.Sprintf("[red]No words found with meanings using '[darkred:%s]'.[white]", query))
+		} else {
+			for _, match := range matches {
+				resultsList.AddItem(match, "", 0, nil)
+			}
+			resultsList.SetCurrentItem(0)
+		}
+	}
+
+	resultsList.SetChangedFunc(func(index int, mainText, secondaryText string, shortcut rune) {
+		glossText := generateGlossText(mainText, glosses)
+		detailsView.SetText(glossText).ScrollToBeginning()
+	})
+
+	// NEW: Add a selection handler to the list.
+	// When the user presses Enter on a list item, this function is called.
+	resultsList.SetSelectedFunc(func(index int, mainText, secondaryText string, shortcut rune) {
+		// Close the modal, and list the results in the main view.
+		pages.RemovePage("meaningSearch")
+		onSelect(query, matches, mainText)
+		// Set focus back to the main input field for a seamless transition.
+		app.SetFocus(mainInputField)
+	})
+
+	// MODIFIED: Updated the DoneFunc to handle exiting on an empty search.
+	searchInput.SetDoneFunc(func(key tcell.Key) {
+		if key == tcell.KeyEnter {
+			query := strings.TrimSpace(searchInput.GetText())
+			if query == "" {
+				// If the search bar is empty, just close the modal.
+				pages.RemovePage("meaningSearch")
+			} else {
+				// Otherwise, perform the search.
+				searchAction()
+			}
+		}
+	})
+
+	// With --reverse-find-live, the search runs whenever typing pauses,
+	// not for every letter: a short English word matches a lot of glosses.
+	if reverseFindLive {
+		var pending *time.Timer
+		searchInput.SetChangedFunc(func(text string) {
+			if pending != nil {
+				pending.Stop()
+			}
+			pending = time.AfterFunc(REVERSE_FIND_DEBOUNCE, func() {
+				app.QueueUpdateDraw(func() {
+					if searchInput.GetText() == text {
+						searchAction()
+					}
+				})
+			})
+		})
+	}
+
+	searchInput.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		// MODIFIED: Give focus to the list on Down/Up arrow keys to enable selection.
+		switch event.Key() {
+		case tcell.KeyEsc:
+			pages.RemovePage("meaningSearch")
+			return nil
+		case tcell.KeyDown:
+			app.SetFocus(resultsList)
+			cur := resultsList.GetCurrentItem()
+			if cur < resultsList.GetItemCount()-1 {
+				resultsList.SetCurrentItem(cur + 1)
+			}
+			return nil
+		case tcell.KeyUp:
+			app.SetFocus(resultsList)
+			cur := resultsList.GetCurrentItem()
+			if cur > 0 {
+				resultsList.SetCurrentItem(cur - 1)
+			}
+			return nil
+		case tcell.KeyTab:
+			row, col := detailsView.GetScrollOffset()
+			detailsView.ScrollTo(row+1, col)
+			return nil
+		case tcell.KeyBacktab:
+			row, col := detailsView.GetScrollOffset()
+			newRow := row - 1
+			if newRow < 0 {
+				newRow = 0
+			}
+			detailsView.ScrollTo(newRow, col)
+			return nil
+		}
+		return event
+	})
+
+	editor.Attach(searchInput)
+
+	// --- FIX #1: Add the modal to the pages view to make it visible. ---
+	if debug {
+		log.Println("showMeaningSearchModal: Adding 'meaningSearch' page to pages container.")
+	}
+	pages.AddPage("meaningSearch", modalLayout, true, true)
+}
+
+// sensePickerPage names the sense picker's page. The main key bindings are
+// suspended while it is open, so Esc closes the picker rather than tsk.
+const sensePickerPage = "sensePicker"
+
+// showSensePickerModal lets the user pick individual senses of a word to mark.
+// onDone gets the picked set when the modal closes: nil if every sense was
+// picked (the whole word), or an empty set if none were.
+func showSensePickerModal(pages *tview.Pages, app *tview.Application, word string, glosses map[string][]tsk.Gloss, current senseSet, returnFocus tview.Primitive, onDone func(senseSet)) {
+	senses := wordSenses(word, glosses)
+	picked := make(senseSet)
+	for _, sense := range senses {
+		if _, ok := current[sense]; ok || current == nil {
+			picked[sense] = struct{}{}
+		}
+	}
+
+	list := tview.NewList().ShowSecondaryText(false)
+	list.SetBorder(true).
+		SetTitle(fmt.Sprintf("Senses of '%s' (Enter to toggle, Esc when done)", word)).
+		SetBorderColor(theme.Marked).
+		SetTitleColor(theme.Marked)
+
+	// The senses are numbered as in Word Details.
+	numbers := senseNumbers(glosses[word])
+	itemText := func(sense senseKey) string {
+		gloss := glosses[word][sense.Gloss]
+		box := "[ ]"
+		if _, ok := picked[sense]; ok {
+			box = "[x]"
+		}
+		return fmt.Sprintf("%s %s [yellow](%s)[white] %s", tview.Escape(box), numbers(sense.Gloss, sense.Meaning),
+			gloss.Pos, tview.Escape(gloss.Meanings[sense.Meaning]))
+	}
+	for _, sense := range senses {
+		list.AddItem(theme.Recolor(itemText(sense)), "", 0, nil)
+	}
+
+	list.SetSelectedFunc(func(idx int, _ string, _ string, _ rune) {
+		sense := senses[idx]
+		if _, ok := picked[sense]; ok {
+			delete(picked, sense)
+		} else {
+			picked[sense] = struct{}{}
+		}
+		list.SetItemText(idx, itemText(sense), "")
+	})
+
+	list.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		if event.Key() != tcell.KeyEsc {
+			return event
+		}
+		pages.RemovePage(sensePickerPage)
+		app.SetFocus(returnFocus)
+		if len(picked) == len(senses) {
+			onDone(nil)
+		} else {
+			onDone(picked)
+		}
+		return nil
+	})
+
+	// Center the list over the main view.
+	modal := tview.NewFlex().
+		AddItem(nil, 0, 1, false).
+		AddItem(tview.NewFlex().SetDirection(tview.FlexRow).
+			AddItem(nil, 0, 1, false).
+			AddItem(list, len(senses)+2, 0, true).
+			AddItem(nil, 0, 1, false), 0, 3, true).
+		AddItem(nil, 0, 1, false)
+
+	pages.AddPage(sensePickerPage, modal, true, true)
+	app.SetFocus(list)
+}
+
+// historyPage names the search history's page. Like the sense picker, it
+// suspends the main key bindings while open.
+const historyPage = "history"
+
+// showHistoryModal lists the words looked up so far, newest first. Enter
+// on one calls onSelect with it; Esc just closes the list.
+func showHistoryModal(pages *tview.Pages, app *tview.Application, recent []historyEntry, returnFocus tview.Primitive, onSelect func(word string)) {
+	list := tview.NewList().ShowSecondaryText(false)
+	list.SetBorder(true).
+		SetTitle(fmt.Sprintf("Search history (%d words, Enter to look up, Esc to close)", len(recent))).
+		SetBorderColor(theme.History).
+		SetTitleColor(theme.History)
+
+	if len(recent) == 0 {
+		list.AddItem(theme.Recolor("[gray]Nothing looked up yet.[white]"), "", 0, nil)
+	}
+	for _, e := range recent {
+		list.AddItem(theme.Recolor(fmt.Sprintf("%s [gray]%s[white]", tview.Escape(e.Word), e.Time.Format("2006-01-02 15:04"))), "", 0, nil)
+	}
+
+	closeModal := func() {
+		pages.RemovePage(historyPage)
+		app.SetFocus(returnFocus)
+	}
+	list.SetSelectedFunc(func(idx int, _ string, _ string, _ rune) {
+		closeModal()
+		if idx < len(recent) {
+			onSelect(recent[idx].Word)
+		}
+	})
+	list.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		if event.Key() == tcell.KeyEsc {
+			closeModal()
+			return nil
+		}
+		return event
+	})
+
+	modal := tview.NewFlex().
+		AddItem(nil, 0, 1, false).
+		AddItem(tview.NewFlex().SetDirection(tview.FlexRow).
+			AddItem(nil, 0, 1, false).
+			AddItem(list, 0, 3, true).
+			AddItem(nil, 0, 1, false), 0, 2, true).
+		AddItem(nil, 0, 1, false)
+
+	pages.AddPage(historyPage, modal, true, true)
+	app.SetFocus(list)
+}
+
+// markedPage names the marked-words manager's page, opened with a second
+// Ctrl-L. It too suspends the main key bindings while open.
+const markedPage = "marked"
+
+// showMarkedModal lists the marked words for pruning: Enter looks a word up,
+// Delete or Backspace unmarks it, and the last item clears the whole
+// collection after a second Enter to confirm. With collections, Left and
+// Right step through names, where "" is all the marked words, listing the
+// words wordsIn gives for each; there Delete only takes a word out of the
+// collection shown, and the last item deletes the collection, leaving its
+// words marked. onShow is told of each collection shown.
+func showMarkedModal(pages *tview.Pages, app *tview.Application, names []string, shown string, wordsIn func(collection string) []string,
+	returnFocus tview.Primitive, onSelect func(word string), onUnmark func(collection, word string), onClear func(collection string), onShow func(collection string)) {
+	list := tview.NewList().ShowSecondaryText(false)
+	list.SetBorder(true).
+		SetBorderColor(theme.MarkedList).
+		SetTitleColor(theme.MarkedList)
+
+	current := max(slices.Index(names, shown), 0)
+	var words []string
+	clearText := func() string {
+		if names[current] == "" {
+			return "[red]Clear all marked words[white]"
+		}
+		return fmt.Sprintf("[red]Delete the collection '%s'[white]", tview.Escape(names[current]))
+	}
+	confirming := false
+	refresh := func() {
+		title := fmt.Sprintf("Marked words (%d, Enter to look up, Delete to unmark, Esc to close)", len(words))
+		if names[current] != "" {
+			title = fmt.Sprintf("%s (%d, Enter to look up, Delete to take out, Esc to close)", names[current], len(words))
+		}
+		if len(names) > 1 {
+			title += fmt.Sprintf(" ◂ %d/%d ▸", current+1, len(names))
+		}
+		list.SetTitle(title)
+	}
+	load := func() {
+		words = wordsIn(names[current])
+		confirming = false
+		list.Clear()
+		for _, w := range words {
+			list.AddItem(tview.Escape(w), "", 0, nil)
+		}
+		list.AddItem(clearText(), "", 0, nil)
+		refresh()
+	}
+	load()
+
+	closeModal := func() {
+		pages.RemovePage(markedPage)
+		app.SetFocus(returnFocus)
+	}
+	list.SetSelectedFunc(func(idx int, _ string, _ string, _ rune) {
+		if idx < len(words) {
+			closeModal()
+			onSelect(words[idx])
+			return
+		}
+		if !confirming {
+			confirming = true
+			if names[current] == "" {
+				list.SetItemText(idx, fmt.Sprintf("[red]Press Enter again to unmark all %d words[white]", len(words)), "")
+			} else {
+				list.SetItemText(idx, fmt.Sprintf("[red]Press Enter again to delete '%s'; its %d words stay marked[white]", tview.Escape(names[current]), len(words)), "")
+			}
+			return
+		}
+		closeModal()
+		onClear(names[current])
+	})
+	list.SetChangedFunc(func(idx int, _ string, _ string, _ rune) {
+		// Moving away cancels a pending clear.
+		if confirming && idx < len(words) {
+			confirming = false
+			list.SetItemText(len(words), clearText(), "")
+		}
+	})
+	list.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		switch event.Key() {
+		case tcell.KeyEsc:
+			closeModal()
+			return nil
+		case tcell.KeyLeft, tcell.KeyRight:
+			if len(names) > 1 {
+				step := 1
+				if event.Key() == tcell.KeyLeft {
+					step = len(names) - 1
+				}
+				current = (current + step) % len(names)
+				load()
+				onShow(names[current])
+			}
+			return nil
+		case tcell.KeyDelete, tcell.KeyBackspace, tcell.KeyBackspace2:
+			idx := list.GetCurrentItem()
+			if idx < len(words) {
+				onUnmark(names[current], words[idx])
+				words = append(words[:idx], words[idx+1:]...)
+				list.RemoveItem(idx)
+				refresh()
+			}
+			return nil
+		}
+		return event
+	})
+
+	modal := tview.NewFlex().
+		AddItem(nil, 0, 1, false).
+		AddItem(tview.NewFlex().SetDirection(tview.FlexRow).
+			AddItem(nil, 0, 1, false).
+			AddItem(list, 0, 3, true).
+			AddItem(nil, 0, 1, false), 0, 2, true).
+		AddItem(nil, 0, 1, false)
+
+	pages.AddPage(markedPage, modal, true, true)
+	app.SetFocus(list)
+}
+
+// collectionPage names the collection picker's page, opened by the collect
+// key. It suspends the main key bindings while open, for typing names.
+const collectionPage = "collection"
+
+// showCollectionModal lists the collections of marked words, ticking the
+// ones word is in: Enter puts it in a collection, or takes it out, by
+// calling onToggle with the collection's name and whether word should be
+// in it. The last item names a new collection to put word in.
+func showCollectionModal(pages *tview.Pages, app *tview.Application, word string, collections map[string][]string,
+	returnFocus tview.Primitive, onToggle func(collection string, in bool)) {
+	list := tview.NewList().ShowSecondaryText(false)
+	list.SetBorder(true).
+		SetTitle(fmt.Sprintf("Collections of '%s' (Enter to put in or take out, Esc to close)", word)).
+		SetBorderColor(theme.MarkedList).
+		SetTitleColor(theme.MarkedList)
+	input := tview.NewInputField().SetLabel("New collection: ")
+	input.SetBorder(true).SetBorderColor(theme.MarkedList)
+
+	names := slices.Sorted(maps.Keys(collections))
+	itemText := func(name string) string {
+		tick := "[ ]"
+		if slices.Contains(collections[name], word) {
+			tick = "[green][x][white]"
+		}
+		return fmt.Sprintf("%s %s [gray](%d)[white]", tick, tview.Escape(name), len(collections[name]))
+	}
+	for _, name := range names {
+		list.AddItem(itemText(name), name, 0, nil)
+	}
+	list.AddItem("[yellow]+ New collection[white]", "", 0, nil)
+
+	closeModal := func() {
+		pages.RemovePage(collectionPage)
+		app.SetFocus(returnFocus)
+	}
+	list.SetSelectedFunc(func(idx int, _ string, name string, _ rune) {
+		if name == "" {
+			app.SetFocus(input)
+			return
+		}
+		onToggle(name, !slices.Contains(collections[name], word))
+		list.SetItemText(idx, itemText(name), name)
+	})
+	list.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		if event.Key() == tcell.KeyEsc {
+			closeModal()
+			return nil
+		}
+		return event
+	})
+	input.SetDoneFunc(func(key tcell.Key) {
+		name := strings.TrimSpace(input.GetText())
+		switch {
+		case key == tcell.KeyEnter && name != "":
+			closeModal()
+			onToggle(name, true)
+		case key == tcell.KeyEnter:
+			// Nothing typed yet.
+		default:
+			input.SetText("")
+			app.SetFocus(list)
+		}
+	})
+
+	modal := tview.NewFlex().
+		AddItem(nil, 0, 1, false).
+		AddItem(tview.NewFlex().SetDirection(tview.FlexRow).
+			AddItem(nil, 0, 1, false).
+			AddItem(list, 0, 3, true).
+			AddItem(input, 3, 0, false).
+			AddItem(nil, 0, 1, false), 0, 2, true).
+		AddItem(nil, 0, 1, false)
+
+	pages.AddPage(collectionPage, modal, true, true)
+	if len(names) == 0 {
+		// There's nothing to pick yet, only a name to type.
+		app.SetFocus(input)
+		return
+	}
+	app.SetFocus(list)
+}
@@ -0,0 +1,1298 @@
+package main
+
+import (
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"cmp"
+	"compress/gzip"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base64"
+	"encoding/gob"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"maps"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/hiAndrewQuinn/tsk/pkg/tsk"
+)
+
+// ----------------------
+// Dictionary Packs (`--dict`)
+// ----------------------
+
+// A dictionary pack is the data tsk searches: a word list and its glosses,
+// plus optional word frequencies, go-deeper phrases, verb rections,
+// colloquial forms and example sentences.
+// The Finnish-English data embedded in the binary is the built-in pack.
+// --dict, or the "dict" entry of config.json, loads another one from a
+// directory or zip file using the same file names:
+//
+//	pack.json                 name and languages (PackMeta), optional
+//	glosses.jsonl             required, or glosses.gob
+//	words.txt                 optional, defaults to every glossed word
+//	frequencies.txt           optional
+//	go-deeper.txt             optional
+//	rection.tsv               optional
+//	colloquial.tsv            optional
+//	example-sentences.sqlite  optional, or example-sentences.tsv
+//
+// The Finnish-specific helpers (inflected forms, Ctrl-D tables) simply find
+// nothing for other languages.
+
+// PackMeta describes a dictionary pack, as read from PACK_META_FILE.
+type PackMeta struct {
+	Name          string `json:"name"`
+	Language      string `json:"language"`       // the language looked up, e.g. "Estonian"
+	GlossLanguage string `json:"gloss_language"` // the language of the glosses, e.g. "English"
+	License       string `json:"license,omitempty"`
+}
+
+// DictionaryPack holds the raw data of a pack. Path is empty for the
+// built-in pack.
+type DictionaryPack struct {
+	Path         string
+	Meta         PackMeta
+	Words        string
+	GlossesGob   []byte
+	GlossesJSONL []byte
+	Frequencies  string
+	GoDeeper     string
+	Rection      string
+	Colloquial   string
+	ExamplesDB   []byte
+	ExamplesTSV  []byte
+}
+
+// activePack is the pack loadWords, loadGlosses and friends read from.
+var activePack = &DictionaryPack{
+	Meta:        PackMeta{Name: "tsk", Language: "Finnish", GlossLanguage: "English", License: "CC BY-SA"},
+	Words:       wordsTxt,
+	GlossesGob:  glossesGob,
+	Frequencies: frequenciesTxt,
+	GoDeeper:    goDeeperTxt,
+	Rection:     rectionTsv,
+	Colloquial:  colloquialTsv,
+	ExamplesDB:  embeddedDB,
+}
+
+// openDictionaryPack reads a pack from a directory or a zip file with the
+// pack's files at its top level.
+func openDictionaryPack(path string) (*DictionaryPack, error) {
+	fsys, closePack, err := packFS(path)
+	if err != nil {
+		return nil, err
+	}
+	defer closePack()
+
+	pack, err := readPackFiles(fsys)
+	if err != nil {
+		return nil, err
+	}
+	pack.Path = path
+	if pack.Meta.Name == "" {
+		pack.Meta.Name = strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	}
+	if pack.GlossesGob == nil && pack.GlossesJSONL == nil {
+		return nil, fmt.Errorf("%s has no %s or %s", path, GLOSSES_SOURCE, GLOSSES_FILE)
+	}
+	if pack.Words == "" {
+		if err := pack.listGlossedWords(); err != nil {
+			return nil, err
+		}
+	}
+	return pack, nil
+}
+
+// readPackFiles reads whichever of a pack's files fsys has. Missing files
+// are left nil or empty. If the pack has a CHECKSUMS_FILE, the files read
+// must match it.
+func readPackFiles(fsys fs.FS) (*DictionaryPack, error) {
+	var readErr error
+	files := make(map[string][]byte)
+	read := func(name string) []byte {
+		data, err := fs.ReadFile(fsys, name)
+		if err != nil && !errors.Is(err, fs.ErrNotExist) && readErr == nil {
+			readErr = fmt.Errorf("%s: %w", name, err)
+		}
+		files[name] = data
+		return data
+	}
+
+	pack := &DictionaryPack{
+		Words:        string(read(WORD_LIST_FILE)),
+		GlossesGob:   read(GLOSSES_FILE),
+		GlossesJSONL: read(GLOSSES_SOURCE),
+		Frequencies:  string(read(FREQUENCIES_FILE)),
+		GoDeeper:     string(read(GO_DEEPER_FILE)),
+		Rection:      string(read(RECTION_FILE)),
+		Colloquial:   string(read(COLLOQUIAL_FILE)),
+		ExamplesDB:   read(EXAMPLES_FILE),
+		ExamplesTSV:  read(EXAMPLES_SOURCE),
+	}
+	meta := read(PACK_META_FILE)
+	if read(CHECKSUMS_FILE) != nil && readErr == nil {
+		readErr = checkPackSums(files)
+	}
+	if readErr != nil {
+		return nil, readErr
+	}
+	if meta != nil {
+		if err := json.Unmarshal(meta, &pack.Meta); err != nil {
+			return nil, fmt.Errorf("%s: %w", PACK_META_FILE, err)
+		}
+	}
+	return pack, nil
+}
+
+// listGlossedWords sets the pack's word list to every word it has glosses
+// for, for packs without a words.txt.
+func (p *DictionaryPack) listGlossedWords() error {
+	glosses, err := p.loadGlosses()
+	if err != nil {
+		return err
+	}
+	words := make([]string, 0, len(glosses))
+	for word := range glosses {
+		words = append(words, word)
+	}
+	sort.Strings(words)
+	p.Words = strings.Join(words, "\n")
+	return nil
+}
+
+// DATA_DIR_ENV names the environment variable --data-dir defaults to.
+const DATA_DIR_ENV = "TSK_DATA_DIR"
+
+// overlayPack returns base with every file that the directory dir also
+// has replaced by dir's copy, for --data-dir. New glosses without a new
+// words.txt bring their own word list, so that added words can be found.
+func overlayPack(base *DictionaryPack, dir string) (*DictionaryPack, error) {
+	over, err := readPackFiles(os.DirFS(dir))
+	if err != nil {
+		return nil, err
+	}
+	pack := *base
+	pack.Path = dir
+	if over.Meta.Name != "" {
+		pack.Meta = over.Meta
+	}
+	if over.GlossesGob != nil || over.GlossesJSONL != nil {
+		pack.GlossesGob, pack.GlossesJSONL = over.GlossesGob, over.GlossesJSONL
+		if over.Words == "" {
+			if err := pack.listGlossedWords(); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if over.Words != "" {
+		pack.Words = over.Words
+	}
+	if over.Frequencies != "" {
+		pack.Frequencies = over.Frequencies
+	}
+	if over.GoDeeper != "" {
+		pack.GoDeeper = over.GoDeeper
+	}
+	if over.Rection != "" {
+		pack.Rection = over.Rection
+	}
+	if over.Colloquial != "" {
+		pack.Colloquial = over.Colloquial
+	}
+	if over.ExamplesDB != nil || over.ExamplesTSV != nil {
+		pack.ExamplesDB, pack.ExamplesTSV = over.ExamplesDB, over.ExamplesTSV
+	}
+	return &pack, nil
+}
+
+// Describe names the pack and its languages for startup messages.
+func (p *DictionaryPack) Describe() string {
+	if p.Meta.Language == "" || p.Meta.GlossLanguage == "" {
+		return p.Meta.Name
+	}
+	return fmt.Sprintf("%s (%s-%s)", p.Meta.Name, p.Meta.Language, p.Meta.GlossLanguage)
+}
+
+// fillExamplesDB creates the sentences table in an empty database and loads
+// tab-separated sentence pairs into it, for packs that ship
+// example-sentences.tsv instead of a ready-made database.
+func fillExamplesDB(db *sql.DB, tsv []byte) error {
+	if _, err := db.Exec(tsk.ExamplesSchema); err != nil {
+		return err
+	}
+	if len(tsv) == 0 {
+		return nil
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	stmt, err := tx.Prepare("INSERT INTO sentences (finnish, english) VALUES (?, ?)")
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	scanner := bufio.NewScanner(bytes.NewReader(tsv))
+	for scanner.Scan() {
+		source, gloss, ok := strings.Cut(strings.TrimRight(scanner.Text(), "\r"), "\t")
+		if !ok || strings.TrimSpace(source) == "" || strings.TrimSpace(gloss) == "" {
+			continue
+		}
+		if _, err := stmt.Exec(source, gloss); err != nil {
+			return err
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// userConfig holds the settings in CONFIG_FILE, a small JSON file in the
+// profile's data directory, e.g. {"dict": "/home/me/tsk-packs/estonian.zip"}.
+type userConfig struct {
+	Dict            string            `json:"dict,omitempty"`              // dictionary pack to use by default
+	ExamplesPerPage int               `json:"examples_per_page,omitempty"` // Ctrl-T page size
+	NoPrefetch      bool              `json:"no_prefetch,omitempty"`       // don't look up the selected word's sentences in the background
+	Theme           string            `json:"theme,omitempty"`             // color scheme, see themes
+	Editing         string            `json:"editing,omitempty"`           // search field keys, see editingModes
+	Keys            map[string]string `json:"keys,omitempty"`              // rebound commands, see defaultKeymap
+	NoMouse         bool              `json:"no_mouse,omitempty"`          // leave the mouse to the terminal
+	ExportDeeper    bool              `json:"export_deeper,omitempty"`     // export the words marked words are forms of
+	ExportExamples  int               `json:"export_examples,omitempty"`   // example sentences exported per marked word
+	NoResume        bool              `json:"no_resume,omitempty"`         // start at the start screen, not the last view
+	Wiktionary      string            `json:"wiktionary,omitempty"`        // Wiktionary edition to open words in
+	FoldDiacritics  bool              `json:"fold_diacritics,omitempty"`   // search ignoring diacritics
+	ListWidth       int               `json:"list_width,omitempty"`        // percent of the width the word list takes
+	ListCollapsed   bool              `json:"list_collapsed,omitempty"`    // hide the word list, for full-width Word Details
+	ReverseFindLive bool              `json:"reverse_find_live,omitempty"` // search as you type in reverse-find
+	SurpriseBand    string            `json:"surprise_band,omitempty"`     // how common random words are, see frequencyBands
+	NoColor         bool              `json:"no_color,omitempty"`          // monochrome TUI, as with NO_COLOR
+	MaxResults      int               `json:"max_results,omitempty"`       // words a search lists at most
+	SpeechRate      int               `json:"speech_rate,omitempty"`       // percent of normal speed words are said at
+	StudyLog        bool              `json:"study_log,omitempty"`         // log lookups and marks for tsk report
+	CheckUpdates    bool              `json:"check_updates,omitempty"`     // say in the TUI when there's a newer tsk
+	Collection      string            `json:"collection,omitempty"`        // collection marked words go in
+
+	ExportProfile  string                   `json:"export_profile,omitempty"`  // export profile to save marked words with on quit
+	ExportProfiles map[string]exportProfile `json:"export_profiles,omitempty"` // more export profiles, see defaultExportProfiles
+}
+
+// loadUserConfig reads CONFIG_FILE, if there is one. A relative "dict"
+// path is taken relative to the data directory.
+func loadUserConfig() (userConfig, error) {
+	var config userConfig
+	dir, err := userDataDir()
+	if err != nil {
+		return config, err
+	}
+	data, err := os.ReadFile(filepath.Join(dir, CONFIG_FILE))
+	if os.IsNotExist(err) {
+		return config, nil
+	} else if err != nil {
+		return config, err
+	}
+	if err := json.Unmarshal(data, &config); err != nil {
+		return config, fmt.Errorf("%s: %w", CONFIG_FILE, err)
+	}
+	if config.Dict != "" && !filepath.IsAbs(config.Dict) {
+		config.Dict = filepath.Join(dir, config.Dict)
+	}
+	return config, nil
+}
+
+// saveListLayout writes the word list's width and whether it's collapsed
+// into CONFIG_FILE, leaving the rest of the file as it is.
+func saveListLayout(width int, collapsed bool) error {
+	dir, err := userDataDir()
+	if err != nil {
+		return err
+	}
+	path := filepath.Join(dir, CONFIG_FILE)
+	config := make(map[string]json.RawMessage)
+	data, err := os.ReadFile(path)
+	if err == nil {
+		if err := json.Unmarshal(data, &config); err != nil {
+			return fmt.Errorf("%s: %w", CONFIG_FILE, err)
+		}
+	} else if !os.IsNotExist(err) {
+		return err
+	}
+	config["list_width"], _ = json.Marshal(width)
+	if collapsed {
+		config["list_collapsed"] = json.RawMessage("true")
+	} else {
+		delete(config, "list_collapsed")
+	}
+	data, err = json.MarshalIndent(config, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0644)
+}
+
+// ----------------------
+// Pack Integrity (`tsk verify-data`)
+// ----------------------
+
+// A pack can list the SHA-256 sums of its files in CHECKSUMS_FILE, the way
+// `sha256sum * > checksums.txt` writes them, and tsk won't load a pack
+// whose files don't match: that is what a corrupted download looks like.
+// Its author can also sign CHECKSUMS_FILE with an Ed25519 key, the
+// signature going in SIGNATURE_FILE. `tsk verify-data` checks every file
+// and says whether the pack was signed by one of the keys in
+// TRUSTED_KEYS_FILE, so that users can tell a third-party pack is the one
+// its author published.
+
+// parseChecksums reads CHECKSUMS_FILE into the sum of each file it names.
+func parseChecksums(data []byte) (map[string]string, error) {
+	sums := make(map[string]string)
+	for i, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		sum, name, ok := strings.Cut(line, " ")
+		// sha256sum marks files it read in binary mode with a '*'.
+		name = strings.TrimPrefix(strings.TrimSpace(name), "*")
+		if _, err := hex.DecodeString(sum); !ok || err != nil || len(sum) != 2*sha256.Size || name == "" {
+			return nil, fmt.Errorf("%s:%d: want a SHA-256 sum and a file name", CHECKSUMS_FILE, i+1)
+		}
+		sums[name] = strings.ToLower(sum)
+	}
+	return sums, nil
+}
+
+// fileSum is data's SHA-256 sum as CHECKSUMS_FILE writes it.
+func fileSum(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// checkPackSums checks the files a pack was loaded from against its
+// CHECKSUMS_FILE. files maps each file tried to its contents, nil if the
+// pack doesn't have it; files the checksums list but tsk doesn't read,
+// like a README, are left to `tsk verify-data`. A file tsk reads that the
+// checksums leave out is refused too, or anyone could add a glosses.gob,
+// which is read in place of the listed glosses.jsonl.
+func checkPackSums(files map[string][]byte) error {
+	sums, err := parseChecksums(files[CHECKSUMS_FILE])
+	if err != nil {
+		return err
+	}
+	for _, name := range slices.Sorted(maps.Keys(files)) {
+		if _, listed := sums[name]; !listed && files[name] != nil && name != CHECKSUMS_FILE {
+			return fmt.Errorf("%s isn't listed in %s; the pack may have been tampered with, so download it again", name, CHECKSUMS_FILE)
+		}
+	}
+	for _, name := range slices.Sorted(maps.Keys(sums)) {
+		data, tried := files[name]
+		switch {
+		case !tried:
+		case data == nil:
+			return fmt.Errorf("%s is listed in %s but missing; the pack may be incomplete, so download it again", name, CHECKSUMS_FILE)
+		case fileSum(data) != sums[name]:
+			return fmt.Errorf("%s doesn't match its sum in %s; the pack may be corrupted, so download it again", name, CHECKSUMS_FILE)
+		}
+	}
+	return nil
+}
+
+// trustedKey is a public key the user trusts to sign packs, and the name
+// they gave it.
+type trustedKey struct {
+	Key  ed25519.PublicKey
+	Name string
+}
+
+// parsePublicKey reads a base64-encoded Ed25519 public key.
+func parsePublicKey(s string) (ed25519.PublicKey, error) {
+	key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(s))
+	if err != nil || len(key) != ed25519.PublicKeySize {
+		return nil, fmt.Errorf("'%s' is not a base64-encoded Ed25519 public key", s)
+	}
+	return ed25519.PublicKey(key), nil
+}
+
+// loadTrustedKeys reads TRUSTED_KEYS_FILE, "KEY NAME" lines with the key
+// in base64, if there is one.
+func loadTrustedKeys() ([]trustedKey, error) {
+	dir, err := tskConfigDir()
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(filepath.Join(dir, TRUSTED_KEYS_FILE))
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	var keys []trustedKey
+	for i, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		encoded, name, _ := strings.Cut(line, " ")
+		key, err := parsePublicKey(encoded)
+		if err != nil {
+			return nil, fmt.Errorf("%s:%d: %w", TRUSTED_KEYS_FILE, i+1, err)
+		}
+		keys = append(keys, trustedKey{Key: key, Name: strings.TrimSpace(name)})
+	}
+	return keys, nil
+}
+
+// signedBy returns the trusted key that made signature, a base64-encoded
+// signature of checksums.
+func signedBy(checksums, signature []byte, keys []trustedKey) (trustedKey, bool) {
+	sig, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(signature)))
+	if err != nil || len(sig) != ed25519.SignatureSize {
+		return trustedKey{}, false
+	}
+	for _, k := range keys {
+		if ed25519.Verify(k.Key, checksums, sig) {
+			return k, true
+		}
+	}
+	return trustedKey{}, false
+}
+
+// packFS opens a pack directory or zip file for reading, with a func to
+// close it when done.
+func packFS(path string) (fs.FS, func() error, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	if info.IsDir() {
+		return os.DirFS(path), func() error { return nil }, nil
+	}
+	zr, err := zip.OpenReader(path)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%s is neither a directory nor a zip file: %w", path, err)
+	}
+	return zr, zr.Close, nil
+}
+
+// writeChecksums writes CHECKSUMS_FILE for the pack directory dir, listing
+// every regular file at its top level but the checksums and signature.
+func writeChecksums(dir string) ([]byte, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	var b strings.Builder
+	for _, e := range entries {
+		if !e.Type().IsRegular() || e.Name() == CHECKSUMS_FILE || e.Name() == SIGNATURE_FILE {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(dir, e.Name()))
+		if err != nil {
+			return nil, err
+		}
+		fmt.Fprintf(&b, "%s  %s\n", fileSum(data), e.Name())
+	}
+	checksums := []byte(b.String())
+	return checksums, os.WriteFile(filepath.Join(dir, CHECKSUMS_FILE), checksums, 0o644)
+}
+
+// readSigningKey reads a private key written by `tsk verify-data
+// --new-key`: the base64-encoded seed of an Ed25519 key.
+func readSigningKey(path string) (ed25519.PrivateKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	seed, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(data)))
+	if err != nil || len(seed) != ed25519.SeedSize {
+		return nil, fmt.Errorf("%s is not a signing key made by `tsk verify-data --new-key`", path)
+	}
+	return ed25519.NewKeyFromSeed(seed), nil
+}
+
+// runVerifyDataCommand checks a pack against its checksums and signature,
+// e.g. `tsk verify-data estonian.zip`, by default the pack in use. Pack
+// authors can also make a signing key, and write and sign the checksums;
+// users can add the author's public key to the keys they trust.
+func runVerifyDataCommand(args []string) error {
+	// Not fs, which would hide the io/fs package used below.
+	flags := newCommandFlags("verify-data")
+	write := flags.Bool("write", false, "write the pack directory's "+CHECKSUMS_FILE+", for pack authors")
+	keyFile := flags.String("key", "", "with --write, also sign the checksums with the private key in this `file`")
+	newKey := flags.String("new-key", "", "make a signing key, saving the private key to this `file` and printing the public one")
+	trust := flags.String("trust", "", "trust packs signed by this base64 public `key`")
+	name := flags.String("name", "", "with --trust, the `name` to show for the key, e.g. its author")
+	flags.Parse(args)
+
+	switch {
+	case *newKey != "":
+		if _, err := os.Stat(*newKey); err == nil {
+			return fmt.Errorf("%s already exists; not overwriting a key", *newKey)
+		}
+		pub, priv, err := ed25519.GenerateKey(rand.Reader)
+		if err != nil {
+			return err
+		}
+		if err := os.WriteFile(*newKey, []byte(base64.StdEncoding.EncodeToString(priv.Seed())+"\n"), 0o600); err != nil {
+			return err
+		}
+		fmt.Printf("Saved your private signing key to %s. Keep it secret.\n", *newKey)
+		fmt.Printf("Your public key, for your pack's users to pass to `tsk verify-data --trust`:\n\n%s\n", base64.StdEncoding.EncodeToString(pub))
+		return nil
+
+	case *trust != "":
+		key, err := parsePublicKey(*trust)
+		if err != nil {
+			return err
+		}
+		keys, err := loadTrustedKeys()
+		if err != nil {
+			return err
+		}
+		for _, k := range keys {
+			if k.Key.Equal(key) {
+				fmt.Printf("You already trust that key (%s).\n", k.Name)
+				return nil
+			}
+		}
+		dir, err := tskConfigDir()
+		if err != nil {
+			return err
+		}
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return err
+		}
+		f, err := os.OpenFile(filepath.Join(dir, TRUSTED_KEYS_FILE), os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+		if err != nil {
+			return err
+		}
+		label := strings.Join(strings.Fields(*name), " ")
+		if label == "" {
+			label = "unnamed"
+		}
+		if _, err := fmt.Fprintf(f, "%s %s\n", base64.StdEncoding.EncodeToString(key), label); err != nil {
+			f.Close()
+			return err
+		}
+		if err := f.Close(); err != nil {
+			return err
+		}
+		fmt.Printf("Packs signed by %s are now trusted.\n", label)
+		return nil
+	}
+
+	path := activePack.Path
+	if flags.NArg() > 1 {
+		return fmt.Errorf("usage: tsk verify-data [--write [--key FILE]] [PACK]")
+	} else if flags.NArg() == 1 {
+		path = flags.Arg(0)
+	}
+	if path == "" {
+		fmt.Println("tsk is using its built-in data, which is part of the binary. Give a pack to check, e.g. tsk verify-data estonian.zip")
+		return nil
+	}
+
+	if *write {
+		if info, err := os.Stat(path); err != nil || !info.IsDir() {
+			return fmt.Errorf("--write needs a pack directory; unzip %s first", path)
+		}
+		checksums, err := writeChecksums(path)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Wrote %s, listing %d files.\n", filepath.Join(path, CHECKSUMS_FILE), bytes.Count(checksums, []byte("\n")))
+		if *keyFile != "" {
+			key, err := readSigningKey(*keyFile)
+			if err != nil {
+				return err
+			}
+			sig := base64.StdEncoding.EncodeToString(ed25519.Sign(key, checksums))
+			if err := os.WriteFile(filepath.Join(path, SIGNATURE_FILE), []byte(sig+"\n"), 0o644); err != nil {
+				return err
+			}
+			fmt.Printf("Signed it in %s.\n", filepath.Join(path, SIGNATURE_FILE))
+		}
+		return nil
+	}
+
+	fsys, closePack, err := packFS(path)
+	if err != nil {
+		return err
+	}
+	defer closePack()
+	checksums, err := fs.ReadFile(fsys, CHECKSUMS_FILE)
+	if errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("%s has no %s, so there is nothing to check it against", path, CHECKSUMS_FILE)
+	} else if err != nil {
+		return err
+	}
+	sums, err := parseChecksums(checksums)
+	if err != nil {
+		return err
+	}
+
+	fmt.Println("===")
+	bad := 0
+	for _, file := range slices.Sorted(maps.Keys(sums)) {
+		data, err := fs.ReadFile(fsys, file)
+		switch {
+		case errors.Is(err, fs.ErrNotExist):
+			fmt.Printf("MISSING  %s\n", file)
+			bad++
+		case err != nil:
+			fmt.Printf("ERROR    %s: %v\n", file, err)
+			bad++
+		case fileSum(data) != sums[file]:
+			fmt.Printf("CHANGED  %s\n", file)
+			bad++
+		default:
+			fmt.Printf("OK       %s\n", file)
+		}
+	}
+	if entries, err := fs.ReadDir(fsys, "."); err == nil {
+		for _, e := range entries {
+			if _, ok := sums[e.Name()]; !ok && !e.IsDir() && e.Name() != CHECKSUMS_FILE && e.Name() != SIGNATURE_FILE {
+				fmt.Printf("UNLISTED %s\n", e.Name())
+				bad++
+			}
+		}
+	}
+	fmt.Println("===")
+
+	signature, err := fs.ReadFile(fsys, SIGNATURE_FILE)
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+		fmt.Println("The pack isn't signed, so there's no telling who made it.")
+	case err != nil:
+		return err
+	default:
+		keys, err := loadTrustedKeys()
+		if err != nil {
+			return err
+		}
+		if k, ok := signedBy(checksums, signature, keys); ok {
+			fmt.Printf("Signed by %s, whom you trust.\n", k.Name)
+		} else {
+			fmt.Println("The pack is signed, but not by a key you trust. If you know its author's key, add it with tsk verify-data --trust KEY.")
+			bad++
+		}
+	}
+	if bad > 0 {
+		return fmt.Errorf("%s failed verification", path)
+	}
+	fmt.Printf("%s is intact.\n", path)
+	return nil
+}
+
+// ----------------------
+// Updating the Data (`tsk update-data`)
+// ----------------------
+
+// Wiktionary changes every day, and the data embedded in a release only as
+// often as there are releases. `tsk update-data` rebuilds the word list,
+// glosses and go-deeper phrases from the latest Wiktextract dump of
+// Finnish Wiktionary, and tsk then prefers them to the embedded copies.
+
+// WIKTEXTRACT_URL is kaikki.org's Wiktextract dump of the Finnish entries.
+const WIKTEXTRACT_URL = "https://kaikki.org/dictionary/Finnish/kaikki.org-dictionary-Finnish.jsonl"
+
+// UPDATED_DATA_DIR holds the output of `tsk update-data`, in the same
+// layout as a dictionary pack. It is shared by every profile.
+const UPDATED_DATA_DIR = "wiktionary"
+
+// MIN_DEEPER_USES is how many "form of" senses must share a phrase, like
+// "genitive singular of", before it goes in go-deeper.txt.
+const MIN_DEEPER_USES = 3
+
+// MAX_SENSE_EXAMPLES is how many of a sense's usage examples are kept.
+// Common senses have dozens of quotations, which would bury the meanings.
+const MAX_SENSE_EXAMPLES = 2
+
+// wiktextractEntry is the part of a Wiktextract entry tsk uses.
+type wiktextractEntry struct {
+	Word      string           `json:"word"`
+	Pos       string           `json:"pos"`
+	Etymology string           `json:"etymology_text"`
+	Synonyms  []wiktextractRef `json:"synonyms"`
+	Antonyms  []wiktextractRef `json:"antonyms"`
+	Derived   []wiktextractRef `json:"derived"`
+	Senses    []struct {
+		Glosses  []string         `json:"glosses"`
+		FormOf   []wiktextractRef `json:"form_of"`
+		AltOf    []wiktextractRef `json:"alt_of"`
+		Synonyms []wiktextractRef `json:"synonyms"`
+		Antonyms []wiktextractRef `json:"antonyms"`
+		Examples []struct {
+			Text        string `json:"text"`
+			English     string `json:"english"`     // older dumps
+			Translation string `json:"translation"` // newer ones
+			Type        string `json:"type"`        // "example" or "quotation"
+		} `json:"examples"`
+	} `json:"senses"`
+}
+
+// wiktextractRef is how Wiktextract refers to another word.
+type wiktextractRef struct {
+	Word string `json:"word"`
+}
+
+// refWords returns the words refs refer to.
+func refWords(refs []wiktextractRef) []string {
+	var words []string
+	for _, ref := range refs {
+		if word := strings.TrimSpace(ref.Word); word != "" {
+			words = append(words, word)
+		}
+	}
+	return words
+}
+
+// appendWords adds more onto words, skipping any already there.
+func appendWords(words []string, more ...string) []string {
+	for _, word := range more {
+		if !slices.Contains(words, word) {
+			words = append(words, word)
+		}
+	}
+	return words
+}
+
+// updatedDataDir returns the directory `tsk update-data` writes to.
+func updatedDataDir() (string, error) {
+	dir, err := tskConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, UPDATED_DATA_DIR), nil
+}
+
+// openUpdatedData returns the built-in pack with the word list, glosses
+// and go-deeper phrases from `tsk update-data`, or nil if it hasn't been
+// run. The frequencies and example sentences stay the embedded ones.
+func openUpdatedData() (*DictionaryPack, error) {
+	dir, err := updatedDataDir()
+	if err != nil {
+		return nil, err
+	}
+	if _, err := os.Stat(dir); os.IsNotExist(err) {
+		return nil, nil
+	}
+	return overlayPack(activePack, dir)
+}
+
+// convertWiktextract reads a Wiktextract dump and returns its glosses the
+// way glosses.jsonl has them, one Gloss per word and part of speech, and
+// the go-deeper phrases: what is left of a "form of" sense's gloss once
+// the word it points to is taken off the end.
+func convertWiktextract(r io.Reader) (map[string][]tsk.Gloss, []string, error) {
+	glosses := make(map[string][]tsk.Gloss)
+	deeperUses := make(map[string]int)
+	scanner := bufio.NewScanner(r)
+	// Entries for common words run to megabytes, with all their forms.
+	scanner.Buffer(make([]byte, 0, 1024*1024), 64*1024*1024)
+	for lineNum := 1; scanner.Scan(); lineNum++ {
+		var entry wiktextractEntry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			return nil, nil, fmt.Errorf("line %d: %w", lineNum, err)
+		}
+		if entry.Word == "" || entry.Pos == "" {
+			continue
+		}
+		var meanings []string
+		examples := make(map[string][]string)
+		synonyms := appendWords(nil, refWords(entry.Synonyms)...)
+		antonyms := appendWords(nil, refWords(entry.Antonyms)...)
+		for _, sense := range entry.Senses {
+			synonyms = appendWords(synonyms, refWords(sense.Synonyms)...)
+			antonyms = appendWords(antonyms, refWords(sense.Antonyms)...)
+			if len(sense.Glosses) == 0 {
+				continue
+			}
+			// Subsenses repeat their parent's gloss first; the last is
+			// the sense's own.
+			meaning := strings.TrimSpace(sense.Glosses[len(sense.Glosses)-1])
+			if meaning == "" {
+				continue
+			}
+			meanings = append(meanings, meaning)
+			// Short made-up examples teach more than quotations from old
+			// books, so they go first.
+			for _, quotations := range []bool{false, true} {
+				for _, ex := range sense.Examples {
+					text := strings.Join(strings.Fields(ex.Text), " ")
+					if text == "" || (ex.Type == "quotation") != quotations || len(examples[meaning]) >= MAX_SENSE_EXAMPLES {
+						continue
+					}
+					if english := strings.Join(strings.Fields(cmp.Or(ex.Translation, ex.English)), " "); english != "" {
+						text += " — " + english
+					}
+					examples[meaning] = appendWords(examples[meaning], text)
+				}
+			}
+			for _, target := range append(sense.FormOf, sense.AltOf...) {
+				rest := strings.TrimRight(meaning, ":.")
+				if phrase := strings.TrimSpace(strings.TrimSuffix(rest, target.Word)); phrase != rest && phrase != "" {
+					deeperUses[phrase]++
+				}
+			}
+		}
+		if len(meanings) == 0 {
+			continue
+		}
+		etymology := strings.TrimSpace(entry.Etymology)
+		derived := appendWords(nil, refWords(entry.Derived)...)
+		// Entries for the same word and part of speech, e.g. from
+		// different etymologies, are merged.
+		merged := false
+		for i, g := range glosses[entry.Word] {
+			if g.Pos == entry.Pos {
+				g.Meanings = append(g.Meanings, meanings...)
+				for meaning, texts := range examples {
+					if g.Examples == nil {
+						g.Examples = make(map[string][]string)
+					}
+					g.Examples[meaning] = appendWords(g.Examples[meaning], texts...)
+				}
+				if g.Etymology == "" {
+					g.Etymology = etymology
+				} else if etymology != "" && g.Etymology != etymology {
+					g.Etymology += "\n\n" + etymology
+				}
+				g.Synonyms = appendWords(g.Synonyms, synonyms...)
+				g.Antonyms = appendWords(g.Antonyms, antonyms...)
+				g.Derived = appendWords(g.Derived, derived...)
+				glosses[entry.Word][i] = g
+				merged = true
+				break
+			}
+		}
+		if !merged {
+			if len(examples) == 0 {
+				examples = nil
+			}
+			glosses[entry.Word] = append(glosses[entry.Word], tsk.Gloss{
+				Word: entry.Word, Pos: entry.Pos, Meanings: meanings,
+				Etymology: etymology, Synonyms: synonyms, Antonyms: antonyms, Derived: derived,
+				Examples: examples,
+			})
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, nil, err
+	}
+
+	for _, gs := range glosses {
+		for i := range gs {
+			gs[i].Meanings = sortedMeanings(gs[i].Meanings)
+		}
+	}
+	var phrases []string
+	for phrase, uses := range deeperUses {
+		if uses >= MIN_DEEPER_USES {
+			phrases = append(phrases, phrase)
+		}
+	}
+	sort.Strings(phrases)
+	return glosses, phrases, nil
+}
+
+// sortedMeanings drops repeated meanings and sorts the rest, ignoring
+// case, as in the embedded glosses.
+func sortedMeanings(meanings []string) []string {
+	seen := make(map[string]bool)
+	var unique []string
+	for _, m := range meanings {
+		if !seen[m] {
+			seen[m] = true
+			unique = append(unique, m)
+		}
+	}
+	sort.SliceStable(unique, func(i, j int) bool {
+		return strings.ToLower(unique[i]) < strings.ToLower(unique[j])
+	})
+	return unique
+}
+
+// writeUpdatedData writes glosses and phrases to dir as a dictionary pack:
+// words.txt, glosses.jsonl, glosses.gob, go-deeper.txt and pack.json.
+func writeUpdatedData(dir string, glosses map[string][]tsk.Gloss, phrases []string) error {
+	words := make([]string, 0, len(glosses))
+	for word := range glosses {
+		words = append(words, word)
+	}
+	sort.Strings(words)
+
+	var jsonl bytes.Buffer
+	enc := json.NewEncoder(&jsonl)
+	enc.SetEscapeHTML(false)
+	for _, word := range words {
+		for _, g := range glosses[word] {
+			if err := enc.Encode(g); err != nil {
+				return err
+			}
+		}
+	}
+	var gobData bytes.Buffer
+	if err := gob.NewEncoder(&gobData).Encode(glosses); err != nil {
+		return err
+	}
+	meta, err := json.MarshalIndent(PackMeta{
+		Name:          "tsk (Wiktionary " + time.Now().Format("2006-01-02") + ")",
+		Language:      "Finnish",
+		GlossLanguage: "English",
+		License:       "CC BY-SA",
+	}, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	files := map[string][]byte{
+		WORD_LIST_FILE: []byte(strings.Join(words, "\n") + "\n"),
+		GLOSSES_SOURCE: jsonl.Bytes(),
+		GLOSSES_FILE:   gobData.Bytes(),
+		GO_DEEPER_FILE: []byte(strings.Join(phrases, "\n") + "\n"),
+		PACK_META_FILE: append(meta, '\n'),
+	}
+	for name, data := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), data, 0644); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// openWiktextractDump opens the dump at path, or downloads it from url if
+// path is empty, and gunzips it if need be.
+func openWiktextractDump(path, url string) (io.ReadCloser, error) {
+	var body io.ReadCloser
+	if path != "" {
+		f, err := os.Open(path)
+		if err != nil {
+			return nil, err
+		}
+		body = f
+		fmt.Printf("Reading %s...\n", path)
+	} else {
+		fmt.Printf("Downloading %s...\n", url)
+		resp, err := http.Get(url)
+		if err != nil {
+			return nil, err
+		}
+		if resp.StatusCode != http.StatusOK {
+			resp.Body.Close()
+			return nil, fmt.Errorf("downloading %s: %s", url, resp.Status)
+		}
+		body = resp.Body
+	}
+	// Dumps are often kept gzipped; tell by the magic number.
+	br := bufio.NewReader(body)
+	if magic, _ := br.Peek(2); len(magic) == 2 && magic[0] == 0x1f && magic[1] == 0x8b {
+		gz, err := gzip.NewReader(br)
+		if err != nil {
+			body.Close()
+			return nil, err
+		}
+		return struct {
+			io.Reader
+			io.Closer
+		}{gz, body}, nil
+	}
+	return struct {
+		io.Reader
+		io.Closer
+	}{br, body}, nil
+}
+
+// runUpdateDataCommand downloads the Wiktextract dump, or reads one given
+// with --from, and replaces the updated data with what it converts to.
+func runUpdateDataCommand(args []string) error {
+	fs := newCommandFlags("update-data")
+	from := fs.String("from", "", "convert this already downloaded `dump` (.jsonl or .jsonl.gz) instead of downloading one")
+	url := fs.String("url", WIKTEXTRACT_URL, "download the Wiktextract dump from this `URL`")
+	remove := fs.Bool("remove", false, "delete the updated data and go back to the embedded copies")
+	fs.Parse(args)
+
+	dir, err := updatedDataDir()
+	if err != nil {
+		return err
+	}
+	if *remove {
+		if err := os.RemoveAll(dir); err != nil {
+			return err
+		}
+		fmt.Println("Removed the updated data. tsk uses its embedded copies again.")
+		return nil
+	}
+
+	r, err := openWiktextractDump(*from, *url)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+
+	start := time.Now()
+	glosses, phrases, err := convertWiktextract(r)
+	if err != nil {
+		return fmt.Errorf("converting the dump: %w", err)
+	}
+	if len(glosses) == 0 {
+		return fmt.Errorf("the dump had no glosses in it")
+	}
+
+	// Write next to the old data and swap it in at the end, so a failed
+	// update leaves the old data as it was.
+	if err := os.MkdirAll(filepath.Dir(dir), 0755); err != nil {
+		return err
+	}
+	tmp, err := os.MkdirTemp(filepath.Dir(dir), UPDATED_DATA_DIR+"-*")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(tmp)
+	if err := writeUpdatedData(tmp, glosses, phrases); err != nil {
+		return err
+	}
+	if err := os.RemoveAll(dir); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, dir); err != nil {
+		return err
+	}
+	fmt.Printf("Converted %d words and %d go-deeper phrases in %v.\n", len(glosses), len(phrases), time.Since(start).Round(time.Second))
+	fmt.Printf("Saved to %s. tsk will use them from now on.\n", dir)
+	return nil
+}
+
+// ----------------------
+// Audio Pack (`tsk update-audio`)
+// ----------------------
+
+// Many Wiktionary entries link a recording of a native speaker saying the
+// word. `tsk update-audio` downloads them into AUDIO_DIR, and Ctrl-K then
+// plays the recording of a word instead of synthesizing it, if there is
+// one. The pack is just the audio files and AUDIO_INDEX_FILE, lines of
+// "word<TAB>file<TAB>source URL", so packs from elsewhere, like Forvo, can
+// be made by hand. Like the updated data, it is shared by every profile.
+const (
+	AUDIO_DIR        = "audio"
+	AUDIO_INDEX_FILE = "audio.tsv"
+)
+
+// audioPack maps headwords to their recordings.
+type audioPack struct {
+	dir   string
+	files map[string]string // word -> file name in dir
+	urls  map[string]string // word -> where its file was downloaded from
+}
+
+// audioPackDir returns the directory `tsk update-audio` writes to.
+func audioPackDir() (string, error) {
+	dir, err := tskConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, AUDIO_DIR), nil
+}
+
+// openAudioPack reads the audio pack in dir. It returns nil if there is
+// none.
+func openAudioPack(dir string) (*audioPack, error) {
+	data, err := os.ReadFile(filepath.Join(dir, AUDIO_INDEX_FILE))
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	p := &audioPack{dir: dir, files: make(map[string]string), urls: make(map[string]string)}
+	for _, line := range strings.Split(string(data), "\n") {
+		fields := strings.Split(line, "\t")
+		if len(fields) < 2 || fields[0] == "" || fields[1] == "" {
+			continue
+		}
+		p.files[fields[0]] = fields[1]
+		if len(fields) > 2 {
+			p.urls[fields[0]] = fields[2]
+		}
+	}
+	return p, nil
+}
+
+// Recording returns the path of word's recording, if the pack has one.
+func (p *audioPack) Recording(word string) (string, bool) {
+	if p == nil {
+		return "", false
+	}
+	file, ok := p.files[word]
+	if !ok {
+		return "", false
+	}
+	return filepath.Join(p.dir, file), true
+}
+
+// wiktextractAudio returns the URL of an MP3 recording of each word in a
+// Wiktextract dump that has one. Wikimedia transcodes every recording to
+// MP3, which every platform's players can handle, unlike the originals.
+func wiktextractAudio(r io.Reader) (map[string]string, error) {
+	urls := make(map[string]string)
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 1024*1024), 64*1024*1024)
+	for lineNum := 1; scanner.Scan(); lineNum++ {
+		var entry struct {
+			Word   string `json:"word"`
+			Sounds []struct {
+				Mp3URL string `json:"mp3_url"`
+			} `json:"sounds"`
+		}
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			return nil, fmt.Errorf("line %d: %w", lineNum, err)
+		}
+		if entry.Word == "" || urls[entry.Word] != "" {
+			continue
+		}
+		for _, sound := range entry.Sounds {
+			if sound.Mp3URL != "" {
+				urls[entry.Word] = sound.Mp3URL
+				break
+			}
+		}
+	}
+	return urls, scanner.Err()
+}
+
+// downloadFile saves url to path. Wikimedia asks for a User-Agent that
+// says who is downloading.
+func downloadFile(url, path string) error {
+	req, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("User-Agent", fmt.Sprintf("tsk/%s (https://github.com/hiAndrewQuinn/tsk)", version))
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s", resp.Status)
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(f, resp.Body); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// runUpdateAudioCommand downloads the recordings the Wiktextract dump links
+// to and replaces the audio pack with them. Recordings already in the old
+// pack from the same URL are kept rather than downloaded again.
+func runUpdateAudioCommand(args []string) error {
+	fs := newCommandFlags("update-audio")
+	from := fs.String("from", "", "read the recordings' links from this already downloaded `dump` (.jsonl or .jsonl.gz)")
+	url := fs.String("url", WIKTEXTRACT_URL, "download the Wiktextract dump from this `URL`")
+	limit := fs.Int("limit", 0, "only get the recordings of the first `N` words, in alphabetical order (0 for all)")
+	remove := fs.Bool("remove", false, "delete the audio pack, so Ctrl-K goes back to speech synthesis")
+	fs.Parse(args)
+
+	dir, err := audioPackDir()
+	if err != nil {
+		return err
+	}
+	if *remove {
+		if err := os.RemoveAll(dir); err != nil {
+			return err
+		}
+		fmt.Println("Removed the audio pack. Ctrl-K synthesizes every word again.")
+		return nil
+	}
+
+	r, err := openWiktextractDump(*from, *url)
+	if err != nil {
+		return err
+	}
+	urls, err := wiktextractAudio(r)
+	r.Close()
+	if err != nil {
+		return fmt.Errorf("reading the dump: %w", err)
+	}
+	if len(urls) == 0 {
+		return fmt.Errorf("the dump had no recordings in it")
+	}
+	words := make([]string, 0, len(urls))
+	for word := range urls {
+		words = append(words, word)
+	}
+	sort.Strings(words)
+	if *limit > 0 && *limit < len(words) {
+		words = words[:*limit]
+	}
+
+	old, err := openAudioPack(dir)
+	if err != nil {
+		return err
+	}
+	// As with update-data, build the new pack next to the old one.
+	if err := os.MkdirAll(filepath.Dir(dir), 0755); err != nil {
+		return err
+	}
+	tmp, err := os.MkdirTemp(filepath.Dir(dir), AUDIO_DIR+"-*")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(tmp)
+
+	start := time.Now()
+	var index strings.Builder
+	var saved, kept, failed int
+	for i, word := range words {
+		file := fmt.Sprintf("%06d.mp3", i)
+		path := filepath.Join(tmp, file)
+		if oldPath, ok := old.Recording(word); ok && old.urls[word] == urls[word] && os.Rename(oldPath, path) == nil {
+			kept++
+		} else if err := downloadFile(urls[word], path); err != nil {
+			fmt.Fprintf(os.Stderr, "Could not download the recording of '%s': %v\n", word, err)
+			failed++
+			continue
+		}
+		fmt.Fprintf(&index, "%s\t%s\t%s\n", word, file, urls[word])
+		if saved++; saved%100 == 0 {
+			fmt.Printf("%d of %d recordings...\n", saved, len(words))
+		}
+	}
+	if saved == 0 {
+		return fmt.Errorf("none of the %d recordings could be downloaded", len(words))
+	}
+	if err := os.WriteFile(filepath.Join(tmp, AUDIO_INDEX_FILE), []byte(index.String()), 0644); err != nil {
+		return err
+	}
+	if err := os.RemoveAll(dir); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, dir); err != nil {
+		return err
+	}
+	fmt.Printf("Saved %d recordings (%d kept from before, %d failed) in %v.\n", saved, kept, failed, time.Since(start).Round(time.Second))
+	fmt.Printf("Saved to %s. Ctrl-K will play them from now on.\n", dir)
+	return nil
+}
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestReadPackFilesChecksSums(t *testing.T) {
+	glosses := `{"word": "kala", "pos": "noun", "meanings": ["fish"]}` + "\n"
+	checksums := fileSum([]byte(glosses)) + "  " + GLOSSES_SOURCE + "\n"
+	tests := []struct {
+		name  string
+		files fstest.MapFS
+		err   string
+	}{
+		{"intact", fstest.MapFS{
+			GLOSSES_SOURCE: {Data: []byte(glosses)},
+			CHECKSUMS_FILE: {Data: []byte(checksums)},
+		}, ""},
+		{"no checksums", fstest.MapFS{
+			GLOSSES_SOURCE: {Data: []byte(glosses)},
+			GLOSSES_FILE:   {Data: []byte("gob")},
+		}, ""},
+		{"changed", fstest.MapFS{
+			GLOSSES_SOURCE: {Data: []byte(strings.Replace(glosses, "fish", "dish", 1))},
+			CHECKSUMS_FILE: {Data: []byte(checksums)},
+		}, "doesn't match"},
+		{"missing", fstest.MapFS{
+			CHECKSUMS_FILE: {Data: []byte(checksums)},
+		}, "missing"},
+		{"unlisted", fstest.MapFS{
+			GLOSSES_SOURCE: {Data: []byte(glosses)},
+			GLOSSES_FILE:   {Data: []byte("gob")},
+			CHECKSUMS_FILE: {Data: []byte(checksums)},
+		}, "isn't listed"},
+	}
+	for _, tt := range tests {
+		_, err := readPackFiles(tt.files)
+		switch {
+		case tt.err == "" && err != nil:
+			t.Errorf("%s: readPackFiles: %v", tt.name, err)
+		case tt.err != "" && (err == nil || !strings.Contains(err.Error(), tt.err)):
+			t.Errorf("%s: readPackFiles error = %v, want one saying %q", tt.name, err, tt.err)
+		}
+	}
+}
@@ -0,0 +1,69 @@
+package tsk
+
+import (
+	"bytes"
+	"sort"
+	"strings"
+	"sync"
+
+	"golang.org/x/text/collate"
+	"golang.org/x/text/language"
+)
+
+// finnish collates as a Finnish dictionary does. A Collator keeps buffers
+// between calls, so it is shared behind a lock.
+var (
+	finnishMu sync.Mutex
+	finnish   = collate.New(language.Finnish)
+)
+
+// SortFinnish sorts words in Finnish alphabetical order: case and accents
+// only break ties between otherwise equal words, so "Aalto" sits by
+// "aalto", and å, ä and ö come after z, in that order. Words collating
+// the same are left in byte order, so the result is always the same.
+func SortFinnish(words []string) {
+	if len(words) < 2 {
+		return
+	}
+	keys := make([][]byte, len(words))
+	var buf collate.Buffer
+	finnishMu.Lock()
+	for i, w := range words {
+		// The keys stay valid until buf is reset, which it never is.
+		keys[i] = finnish.KeyFromString(&buf, w)
+	}
+	finnishMu.Unlock()
+	sort.Sort(collated{words, keys})
+}
+
+// CompareFinnish compares two words in the order SortFinnish puts them
+// in, returning -1, 0 or +1.
+func CompareFinnish(a, b string) int {
+	finnishMu.Lock()
+	c := finnish.CompareString(a, b)
+	finnishMu.Unlock()
+	if c != 0 {
+		return c
+	}
+	return strings.Compare(a, b)
+}
+
+// collated sorts words by their collation keys.
+type collated struct {
+	words []string
+	keys  [][]byte
+}
+
+func (c collated) Len() int { return len(c.words) }
+
+func (c collated) Less(i, j int) bool {
+	if k := bytes.Compare(c.keys[i], c.keys[j]); k != 0 {
+		return k < 0
+	}
+	return c.words[i] < c.words[j]
+}
+
+func (c collated) Swap(i, j int) {
+	c.words[i], c.words[j] = c.words[j], c.words[i]
+	c.keys[i], c.keys[j] = c.keys[j], c.keys[i]
+}
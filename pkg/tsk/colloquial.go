@@ -0,0 +1,88 @@
+package tsk
+
+import "strings"
+
+// Colloquial is a spoken form or an abbreviation, such as mä or esim., and
+// the standard word or phrase it stands for, such as minä or esimerkiksi,
+// with a note on what it is.
+type Colloquial struct {
+	Form     string
+	Standard string
+	Note     string
+}
+
+// ParseColloquial reads colloquial forms as colloquial.tsv has them: the
+// form, the standard word and a note separated by tabs, one per line, with
+// # starting a comment line. Lines with too few fields are skipped, and a
+// form may have several lines, like mut for both minut and mutta.
+func ParseColloquial(text string) map[string][]Colloquial {
+	forms := make(map[string][]Colloquial)
+	for line := range strings.Lines(text) {
+		line = strings.TrimRight(line, "\r\n")
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := strings.Split(line, "\t")
+		if len(fields) < 2 || fields[0] == "" || fields[1] == "" {
+			continue
+		}
+		c := Colloquial{Form: fields[0], Standard: fields[1]}
+		if len(fields) > 2 {
+			c.Note = fields[2]
+		}
+		forms[c.Form] = append(forms[c.Form], c)
+	}
+	return forms
+}
+
+// SetColloquial gives the dictionary colloquial forms to resolve, as read
+// by ParseColloquial. Search then lists the standard words right after a
+// form typed in full, and finds them even if the form isn't a headword.
+func (d *Dictionary) SetColloquial(forms map[string][]Colloquial) {
+	d.colloquial = forms
+}
+
+// Colloquial returns what word stands for, if it's a known spoken form or
+// abbreviation.
+func (d *Dictionary) Colloquial(word string) []Colloquial {
+	return d.colloquial[word]
+}
+
+// colloquialResult adds the standard words of query's colloquial readings
+// to the prefix search result, right after query itself if it's a word.
+// Their notes go in Forms, as an inflected form's readings do. With no
+// prefix matches, the result is a SearchColloquial one.
+func (d *Dictionary) colloquialResult(query string, readings []Colloquial, prefix SearchResult) SearchResult {
+	result := SearchResult{Kind: prefix.Kind, Forms: make(map[string][]string)}
+	if len(prefix.Words) == 0 {
+		result.Kind = SearchColloquial
+	}
+	seen := make(map[string]bool)
+	add := func(word string) {
+		if !seen[word] {
+			seen[word] = true
+			result.Words = append(result.Words, word)
+		}
+	}
+	if len(prefix.Words) > 0 && prefix.Words[0] == query {
+		add(query)
+	}
+	for _, c := range readings {
+		if _, ok := d.glosses[c.Standard]; ok && c.Standard != query {
+			add(c.Standard)
+			result.Forms[c.Standard] = append(result.Forms[c.Standard], c.Note)
+		}
+	}
+	// The standard words always stay; the prefix matches fill the rest.
+	limit := max(d.maxResults, len(result.Words))
+	extra := len(result.Words)
+	for _, w := range prefix.Words {
+		if seen[w] {
+			extra--
+		} else if len(result.Words) < limit {
+			add(w)
+		}
+	}
+	result.Total = max(prefix.Total, len(prefix.Words)) + extra
+	return result
+}
@@ -0,0 +1,74 @@
+package tsk
+
+import (
+	"unicode/utf8"
+)
+
+// Finnish writes compounds as one word, and plenty of them aren't
+// headwords: "kirjakauppias" is, but "sanakirjakauppias" isn't. The
+// splitter reads such a word as a run of headwords, so the glosses of the
+// parts can stand in for the missing one.
+
+// minCompoundPart is the shortest part, in letters, SplitCompound will use.
+// Shorter headwords ("ja", "se") fit inside too many words by accident.
+const minCompoundPart = 3
+
+// prefixLengths returns the byte length of every word in the trie that
+// is a prefix of s, shortest first.
+func (t *Trie) prefixLengths(s string) []int {
+	var lengths []int
+	node := 0
+	for i, ch := range s {
+		if node = t.child(node, ch); node < 0 {
+			break
+		}
+		if t.ends[node] {
+			lengths = append(lengths, i+utf8.RuneLen(ch))
+		}
+	}
+	return lengths
+}
+
+// SplitCompound splits word into the headwords it is made of, such as
+// "sanakirja" + "kauppias", or returns nil if it can't be done with two or
+// more parts. Of the possible splits it prefers the fewest parts, then the
+// longest first part.
+func (d *Dictionary) SplitCompound(word string) []string {
+	// best[i] is the fewest parts word[i:] splits into, or 0 if it can't;
+	// next[i] is where the first of those parts ends.
+	best := make([]int, len(word)+1)
+	next := make([]int, len(word)+1)
+	for i := len(word) - 1; i >= 0; i-- {
+		if !utf8.RuneStart(word[i]) {
+			continue
+		}
+		for _, n := range d.trie.prefixLengths(word[i:]) {
+			end := i + n
+			if utf8.RuneCountInString(word[i:end]) < minCompoundPart {
+				continue
+			}
+			var parts int
+			switch {
+			case end == len(word):
+				parts = 1
+			case best[end] > 0:
+				parts = best[end] + 1
+			default:
+				continue
+			}
+			// Lengths come shortest first, so <= keeps the longest part
+			// among equally short splits.
+			if best[i] == 0 || parts <= best[i] {
+				best[i], next[i] = parts, end
+			}
+		}
+	}
+	if best[0] < 2 {
+		return nil
+	}
+	var parts []string
+	for i := 0; i < len(word); i = next[i] {
+		parts = append(parts, word[i:next[i]])
+	}
+	return parts
+}
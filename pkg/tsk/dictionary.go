@@ -0,0 +1,371 @@
+package tsk
+
+import (
+	"database/sql"
+	"strings"
+	"sync"
+	"unicode"
+)
+
+// Dictionary bundles the headwords, their glosses and every search index
+// over them. It is read-only once built, so it can be shared freely.
+type Dictionary struct {
+	words     []string
+	glosses   map[string][]Gloss
+	trie      *Trie
+	suffix    *SuffixIndex
+	substring *SubstringIndex
+	pattern   *PatternIndex
+	meaning   *MeaningIndex
+	folded    *FoldedIndex
+	caseless  *FoldedIndex
+	examples  *sql.DB
+
+	// colloquial maps spoken forms and abbreviations to their standard
+	// words, if SetColloquial was called.
+	colloquial map[string][]Colloquial
+
+	// maxResults is how many words Search returns at most.
+	maxResults int
+
+	// related is built by the first call to Neighbors.
+	related     *relatedIndex
+	relatedOnce sync.Once
+}
+
+// New builds the search indexes over words and their glosses. Words
+// without glosses can still be found, e.g. inflected forms in the word list.
+// The indexes don't depend on each other, so they are built at once.
+func New(words []string, glosses map[string][]Gloss) *Dictionary {
+	d := &Dictionary{words: words, glosses: glosses, maxResults: MaxResults}
+	var wg sync.WaitGroup
+	build := func(f func()) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			f()
+		}()
+	}
+	build(func() { d.trie = NewTrie(words) })
+	build(func() { d.suffix = NewSuffixIndex(words) })
+	build(func() { d.substring = NewSubstringIndex(words) })
+	build(func() { d.pattern = NewPatternIndex(words) })
+	build(func() { d.meaning = NewMeaningIndex(glosses) })
+	build(func() { d.folded = NewFoldedIndex(words) })
+	build(func() { d.caseless = NewCaselessIndex(words) })
+	wg.Wait()
+	return d
+}
+
+// SetRanks gives words their frequency ranks, 1 being the most common, so
+// prefix searches list common words first.
+func (d *Dictionary) SetRanks(ranks map[string]int) {
+	for word, rank := range ranks {
+		d.trie.SetRank(word, rank)
+		d.folded.SetRank(word, rank)
+		d.caseless.SetRank(word, rank)
+	}
+}
+
+// SetMaxResults changes how many words Search and SearchFolded return at
+// most from MaxResults to n, or back to MaxResults if n is less than one.
+func (d *Dictionary) SetMaxResults(n int) {
+	if n < 1 {
+		n = MaxResults
+	}
+	d.maxResults = n
+}
+
+// SetExamples sets the database Examples reads from. It has to have the
+// sentences table of ExamplesSchema.
+func (d *Dictionary) SetExamples(db *sql.DB) {
+	d.examples = db
+}
+
+func (d *Dictionary) Words() []string             { return d.words }
+func (d *Dictionary) Glosses() map[string][]Gloss { return d.glosses }
+func (d *Dictionary) Trie() *Trie                 { return d.trie }
+func (d *Dictionary) MeaningIndex() *MeaningIndex { return d.meaning }
+func (d *Dictionary) PatternIndex() *PatternIndex { return d.pattern }
+func (d *Dictionary) ExamplesDB() *sql.DB         { return d.examples }
+
+// Lookup returns the glosses of a headword, or nil if it isn't one. See
+// AnalyzeWord for finding the headwords of an inflected form.
+func (d *Dictionary) Lookup(word string) []Gloss {
+	return d.glosses[word]
+}
+
+// SearchKind says how Search read a query.
+type SearchKind int
+
+const (
+	SearchPrefix     SearchKind = iota // "kis", or "kis*": words starting with it
+	SearchSuffix                       // "$sto" or "*sto": words ending in it
+	SearchSubstring                    // "*kirja*": words containing it
+	SearchPattern                      // "k___a" or "s.n.": crossword patterns
+	SearchGlob                         // "k?ssa" or "ta*o": '?' for a letter, '*' for anything
+	SearchInflected                    // "taloissa": the base forms of an inflected word
+	SearchCompound                     // "sanakirjakauppias": the words a compound is made of
+	SearchEnglish                      // "big dog": headwords by their English meanings
+	SearchFuzzy                        // nothing else matched: the closest words
+	SearchColloquial                   // "meiän": the standard words of a spoken form or abbreviation
+)
+
+// SearchResult is what Search found, and how.
+type SearchResult struct {
+	Words []string
+	Kind  SearchKind
+	// Forms maps each base form to what the query is of it, such as
+	// "inessive plural", for SearchInflected. For a colloquial form, it
+	// maps each standard word to the note on the form, such as
+	// "puhekieli", whatever the Kind.
+	Forms map[string][]string
+	// Parts is the query split into headwords, for SearchCompound. Words
+	// is then the query itself followed by its parts.
+	Parts []string
+	// Total is how many words matched in all, more than len(Words) when
+	// there were too many to return. Typo suggestions, inflected forms
+	// and compounds are never cut short.
+	Total int
+}
+
+// Search looks query up the way tsk's search bar does, returning up to
+// MaxResults words, or as many as SetMaxResults allows. A plain query is a
+// prefix, with the standard words of a colloquial form listed first (see
+// SetColloquial); if nothing starts with it, it is tried as a crossword
+// pattern, an inflected form, a compound, English, and finally as a typo
+// of a headword. Case doesn't matter, so "Äiti" finds äiti (see FoldCase).
+func (d *Dictionary) Search(query string) SearchResult {
+	return d.search(query, d.caseless)
+}
+
+// SearchFolded is Search with the prefix searches ignoring diacritics as
+// well as case, so "oljy" finds öljy as well as any words spelled oljy.
+func (d *Dictionary) SearchFolded(query string) SearchResult {
+	return d.search(query, d.folded)
+}
+
+// SearchMeanings looks query up in English only, as Search does once
+// nothing Finnish matches, for a search bar switched to English. The words
+// come best match first.
+func (d *Dictionary) SearchMeanings(query string) SearchResult {
+	query = strings.Join(strings.Fields(query), " ")
+	if query == "" {
+		return SearchResult{Kind: SearchEnglish}
+	}
+	words := d.meaning.Search(query, d.maxResults)
+	total := len(words)
+	if total == d.maxResults {
+		total = len(d.meaning.Search(query, 0))
+	}
+	return SearchResult{Words: words, Kind: SearchEnglish, Total: total}
+}
+
+// prefixIndex is an index prefix searches can go to: a Trie, or a
+// FoldedIndex to ignore diacritics.
+type prefixIndex interface {
+	FindWordsN(prefix string, limit int) []string
+	Count(prefix string) int
+}
+
+// search is Search with prefixes looked up in prefixes.
+func (d *Dictionary) search(query string, prefixes prefixIndex) SearchResult {
+	result := d.searchWords(query, prefixes)
+	if result.Total == 0 {
+		result.Total = len(result.Words)
+	}
+	return result
+}
+
+// searchWords does the work of search, leaving Total unset where it's
+// just how many words were found.
+func (d *Dictionary) searchWords(query string, prefixes prefixIndex) SearchResult {
+	limit := d.maxResults
+	// total counts every match with count if the words found reached the
+	// limit, as there may be more.
+	total := func(words []string, count func() int) int {
+		if len(words) < limit {
+			return len(words)
+		}
+		return count()
+	}
+	suffixResult := func(ending string) SearchResult {
+		ending = FoldCase(ending)
+		words := d.suffix.FindWordsN(ending, limit)
+		return SearchResult{Words: sortedWords(words), Kind: SearchSuffix,
+			Total: total(words, func() int { return d.suffix.Count(ending) })}
+	}
+	prefixResult := func(prefix string) SearchResult {
+		words := prefixes.FindWordsN(prefix, limit)
+		return SearchResult{Words: words, Kind: SearchPrefix,
+			Total: total(words, func() int { return prefixes.Count(prefix) })}
+	}
+
+	query = PhraseQuery(query)
+	if query == "" {
+		return SearchResult{}
+	}
+	if ending, ok := suffixQuery(query); ok {
+		return suffixResult(ending)
+	}
+	if globQuery(query) {
+		words := d.caseless.Glob(query, limit)
+		return SearchResult{Words: words, Kind: SearchGlob,
+			Total: total(words, func() int { return len(d.caseless.Glob(query, 0)) })}
+	}
+	if q, leading, trailing := wildcardQuery(query); leading && trailing {
+		// Every match is found before they are sorted anyway.
+		words := d.substring.FindWords(q, 0)
+		return SearchResult{Words: words[:min(len(words), limit)], Kind: SearchSubstring, Total: len(words)}
+	} else if leading {
+		return suffixResult(q)
+	} else if trailing {
+		return prefixResult(q)
+	}
+
+	readings := d.colloquial[query]
+	if len(readings) == 0 {
+		// "Mä" at the start of a sentence is mä all the same.
+		readings = d.colloquial[FoldCase(query)]
+	}
+	if len(readings) > 0 {
+		if result := d.colloquialResult(query, readings, prefixResult(query)); len(result.Words) > 0 {
+			return result
+		}
+	}
+	if result := prefixResult(query); len(result.Words) > 0 {
+		return result
+	}
+	if word, ok := strings.CutSuffix(query, " "); ok {
+		// No phrase starts with the words typed, so look them up alone.
+		return d.searchWords(word, prefixes)
+	}
+	// Abbreviations like "eaa." contain dots too, so only treat the query
+	// as a pattern once the literal prefix search comes up empty.
+	if isPatternQuery(query) {
+		if words := d.pattern.Match(query, limit); len(words) > 0 {
+			return SearchResult{Words: sortedWords(words), Kind: SearchPattern,
+				Total: total(words, func() int { return len(d.pattern.Match(query, 0)) })}
+		}
+	}
+	// Inflected forms and compound parts are in lower case.
+	query = FoldCase(query)
+	if analyses := AnalyzeWord(query, d.glosses); len(analyses) > 0 {
+		words, forms := GroupAnalyses(analyses)
+		return SearchResult{Words: words, Kind: SearchInflected, Forms: forms}
+	}
+	if parts := d.SplitCompound(query); parts != nil {
+		words := []string{query}
+		seen := map[string]bool{query: true}
+		for _, part := range parts {
+			if !seen[part] {
+				seen[part] = true
+				words = append(words, part)
+			}
+		}
+		return SearchResult{Words: words, Kind: SearchCompound, Parts: parts}
+	}
+	if LooksEnglish(query, d.meaning) {
+		if words := d.meaning.Search(query, limit); len(words) > 0 {
+			return SearchResult{Words: words, Kind: SearchEnglish,
+				Total: total(words, func() int { return len(d.meaning.Search(query, 0)) })}
+		}
+	}
+	return SearchResult{Words: d.pattern.Similar(query, limit), Kind: SearchFuzzy}
+}
+
+// Rhymes returns up to MaxResults words other than word ending in its last
+// n syllables, as for learning the words that inflect alike or writing
+// verse. Words sharing more of the ending come next to each other.
+func (d *Dictionary) Rhymes(word string, n int) []string {
+	var words []string
+	for _, w := range d.suffix.FindWords(RhymeEnding(word, n)) {
+		if w != word {
+			words = append(words, w)
+		}
+	}
+	return words
+}
+
+// PhraseQuery tidies the spaces in a query, so that a phrase like "hyvää
+// päivää" is found however it was typed: none in front, one between words,
+// and at most one after the last, which asks for phrases starting with it.
+func PhraseQuery(query string) string {
+	fields := strings.Fields(query)
+	phrase := strings.Join(fields, " ")
+	if len(fields) > 0 && strings.TrimRightFunc(query, unicode.IsSpace) != query {
+		phrase += " "
+	}
+	return phrase
+}
+
+// sortedWords puts words in Finnish alphabetical order; see SortFinnish.
+func sortedWords(words []string) []string {
+	SortFinnish(words)
+	return words
+}
+
+// ReverseFind returns up to limit headwords whose meanings use every word
+// of the English query, best matches first. Words match whole words, and
+// the query can have "quoted phrases", walk* for words starting with walk,
+// and alternatives separated by OR; see MeaningIndex.Search. A limit of
+// zero or less returns every match.
+func (d *Dictionary) ReverseFind(query string, limit int) []string {
+	return d.meaning.Search(query, limit)
+}
+
+// ExamplesSchema is the table Examples reads from: sentence pairs in a
+// full-text index. The columns are called finnish and english whatever
+// the languages are.
+const ExamplesSchema = `
+CREATE VIRTUAL TABLE IF NOT EXISTS sentences USING fts5(
+  finnish,
+  english,
+  tokenize = "unicode61 remove_diacritics 0"
+)`
+
+// Example is a sentence and its translation.
+type Example struct {
+	Finnish string `json:"finnish"`
+	English string `json:"english"`
+}
+
+// MatchPhrase quotes word as an FTS5 phrase, trimming the punctuation
+// around it and folding it with FoldCase, for a sentences MATCH query.
+func MatchPhrase(word string) string {
+	trimmed := strings.TrimFunc(FoldCase(word), func(r rune) bool { return !unicode.IsLetter(r) })
+	return `"` + strings.ReplaceAll(trimmed, `"`, `""`) + `"`
+}
+
+// CountExamples returns how many example sentences use word.
+func (d *Dictionary) CountExamples(word string) (int, error) {
+	if d.examples == nil {
+		return 0, nil
+	}
+	var count int
+	err := d.examples.QueryRow("SELECT COUNT(*) FROM sentences WHERE sentences MATCH ?", MatchPhrase(word)).Scan(&count)
+	return count, err
+}
+
+// Examples returns up to limit example sentences using word, skipping the
+// first offset of them, for paging through common words.
+func (d *Dictionary) Examples(word string, limit, offset int) ([]Example, error) {
+	if d.examples == nil {
+		return nil, nil
+	}
+	rows, err := d.examples.Query(
+		"SELECT finnish, english FROM sentences WHERE sentences MATCH ? LIMIT ? OFFSET ?", MatchPhrase(word), limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var examples []Example
+	for rows.Next() {
+		var e Example
+		if err := rows.Scan(&e.Finnish, &e.English); err != nil {
+			continue
+		}
+		examples = append(examples, e)
+	}
+	return examples, rows.Err()
+}
@@ -0,0 +1,88 @@
+package tsk
+
+import (
+	"bufio"
+	"errors"
+	"io/fs"
+	"maps"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// The benchmarks search the repository's own data, which the tsk binary
+// embeds.
+const (
+	benchGlosses     = "../../glosses.jsonl"
+	benchFrequencies = "../../frequencies.txt"
+)
+
+// benchPrefixes and benchMeanings are what the search benchmarks look
+// for: short and long prefixes, and common and rare meanings.
+var (
+	benchPrefixes = []string{"k", "ka", "kirj", "talo", "epä", "öljy"}
+	benchMeanings = []string{"house", "to eat", "beautiful", "quickly", "the day after tomorrow"}
+)
+
+// loadBenchData reads the glosses and frequency ranks once for all the
+// benchmarks.
+var loadBenchData = sync.OnceValues(func() (*Dictionary, error) {
+	f, err := os.Open(benchGlosses)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	glosses, err := ParseGlossesJSONL(f, benchGlosses)
+	if err != nil {
+		return nil, err
+	}
+	d := New(slices.Sorted(maps.Keys(glosses)), glosses)
+
+	f, err = os.Open(benchFrequencies)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	ranks := make(map[string]int)
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		if word, _, ok := strings.Cut(scanner.Text(), "\t"); ok {
+			ranks[word] = len(ranks) + 1
+		}
+	}
+	d.SetRanks(ranks)
+	return d, scanner.Err()
+})
+
+// benchDictionary is the dictionary of the repository's data, skipping
+// the benchmark if the data isn't there.
+func benchDictionary(b *testing.B) *Dictionary {
+	b.Helper()
+	d, err := loadBenchData()
+	if errors.Is(err, fs.ErrNotExist) {
+		b.Skip(err)
+	} else if err != nil {
+		b.Fatal(err)
+	}
+	return d
+}
+
+func BenchmarkNew(b *testing.B) {
+	d := benchDictionary(b)
+	b.ReportAllocs()
+	for b.Loop() {
+		New(d.Words(), d.Glosses())
+	}
+}
+
+func BenchmarkReverseFind(b *testing.B) {
+	d := benchDictionary(b)
+	b.ReportAllocs()
+	for b.Loop() {
+		for _, query := range benchMeanings {
+			d.ReverseFind(query, 0)
+		}
+	}
+}
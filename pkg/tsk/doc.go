@@ -0,0 +1,18 @@
+// Package tsk is the dictionary behind the tsk command: headword search by
+// prefix, with or without diacritics, ending, substring, crossword
+// pattern and glob, typo-tolerant suggestions, base forms of inflected Finnish words, reverse-find by
+// English meaning, related words, and example sentences.
+//
+// The tsk binary embeds its data files, so a program using this package
+// loads them itself and hands them to New:
+//
+//	f, _ := os.Open("glosses.jsonl")
+//	glosses, _ := tsk.ParseGlossesJSONL(f, "glosses.jsonl")
+//	d := tsk.New(words, glosses)
+//	for _, g := range d.Lookup("talo") {
+//		fmt.Println(g.Pos, g.Meanings)
+//	}
+//
+// Example sentences need an SQLite database with ExamplesSchema (and a
+// driver with FTS5, such as modernc.org/sqlite), passed to SetExamples.
+package tsk
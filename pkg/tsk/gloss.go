@@ -0,0 +1,89 @@
+package tsk
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Gloss is one part of speech of a headword and its meanings, as in
+// glosses.jsonl. A headword may have several.
+type Gloss struct {
+	Word     string   `json:"word"`
+	Pos      string   `json:"pos"`
+	Meanings []string `json:"meanings"`
+
+	// Only in data from `tsk update-data`, and only where Wiktionary has
+	// them.
+	Etymology string   `json:"etymology,omitempty"`
+	Synonyms  []string `json:"synonyms,omitempty"`
+	Antonyms  []string `json:"antonyms,omitempty"`
+	Derived   []string `json:"derived,omitempty"`
+	// Examples holds Wiktionary's usage examples of a meaning, keyed by
+	// the meaning, so they stay with it however the meanings are sorted
+	// or picked. Each is the Finnish, then " — " and its translation if
+	// it has one.
+	Examples map[string][]string `json:"examples,omitempty"`
+}
+
+// ParseGlossesJSONL reads one Gloss per line, grouping them by word the same
+// way buildglossgob.go does. name is used in error messages.
+func ParseGlossesJSONL(r io.Reader, name string) (map[string][]Gloss, error) {
+	glosses := make(map[string][]Gloss)
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for lineNum := 1; scanner.Scan(); lineNum++ {
+		if strings.TrimSpace(scanner.Text()) == "" {
+			continue
+		}
+		var g Gloss
+		if err := json.Unmarshal(scanner.Bytes(), &g); err != nil {
+			return nil, fmt.Errorf("%s line %d: %w", name, lineNum, err)
+		}
+		glosses[g.Word] = append(glosses[g.Word], g)
+	}
+	return glosses, scanner.Err()
+}
+
+// HasPos reports whether any of glosses is the part of speech pos, such as
+// "verb" or "noun".
+func HasPos(glosses []Gloss, pos string) bool {
+	for _, g := range glosses {
+		if g.Pos == pos {
+			return true
+		}
+	}
+	return false
+}
+
+// FilterPos returns the glosses that are the part of speech pos.
+func FilterPos(glosses []Gloss, pos string) []Gloss {
+	var kept []Gloss
+	for _, g := range glosses {
+		if g.Pos == pos {
+			kept = append(kept, g)
+		}
+	}
+	return kept
+}
+
+// PartsOfSpeech returns the parts of speech among glosses, each once, in
+// the order they first appear.
+func PartsOfSpeech(glosses []Gloss) []string {
+	var parts []string
+	seen := make(map[string]bool)
+	for _, g := range glosses {
+		if !seen[g.Pos] {
+			seen[g.Pos] = true
+			parts = append(parts, g.Pos)
+		}
+	}
+	return parts
+}
+
+// HasRelated reports whether g has an etymology or any related words.
+func (g Gloss) HasRelated() bool {
+	return g.Etymology != "" || len(g.Synonyms) > 0 || len(g.Antonyms) > 0 || len(g.Derived) > 0
+}
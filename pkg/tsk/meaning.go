@@ -0,0 +1,303 @@
+package tsk
+
+import (
+	"math"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// MeaningIndex is an inverted index from the English words used in the
+// meanings to the headwords whose meanings use them, so reverse-find
+// doesn't have to scan every meaning of every word.
+type MeaningIndex struct {
+	headwords []string
+	postings  map[string][]meaningPosting
+	// tokens are the keys of postings, sorted, for "walk*" to find every
+	// word starting with walk.
+	tokens []string
+	// glosses are read again to check that a quoted phrase's words come
+	// in order.
+	glosses map[string][]Gloss
+}
+
+// meaningPosting records how one headword uses one English word. Headwords
+// are stored by their position in MeaningIndex.headwords to keep the index
+// small.
+type meaningPosting struct {
+	word     int32
+	count    uint16 // how many of the word's meanings use it
+	shortest uint16 // length in words of the shortest such meaning
+}
+
+// englishTokens splits text into lowercase ASCII words.
+func englishTokens(text string) []string {
+	return strings.FieldsFunc(FoldCase(text), func(r rune) bool {
+		return r < 'a' || r > 'z'
+	})
+}
+
+// meaningParts splits a meaning into the comma- or semicolon-separated
+// parts it is often a list of ("to make, to do").
+func meaningParts(meaning string) []string {
+	return strings.FieldsFunc(meaning, func(r rune) bool { return r == ',' || r == ';' })
+}
+
+func NewMeaningIndex(glosses map[string][]Gloss) *MeaningIndex {
+	idx := &MeaningIndex{postings: make(map[string][]meaningPosting), glosses: glosses}
+	for word, glossSlice := range glosses {
+		id := int32(len(idx.headwords))
+		idx.headwords = append(idx.headwords, word)
+
+		counts := make(map[string]uint16)
+		shortest := make(map[string]uint16)
+		for _, gloss := range glossSlice {
+			for _, meaning := range gloss.Meanings {
+				seen := make(map[string]bool)
+				// Lengths are measured per part of the meaning.
+				for _, part := range meaningParts(meaning) {
+					tokens := englishTokens(part)
+					// "to walk" is as short a meaning as "walk" is.
+					length := len(tokens)
+					if length > 1 && tokens[0] == "to" {
+						length--
+					}
+					length = min(length, math.MaxUint16)
+					for _, token := range tokens {
+						if s, ok := shortest[token]; !ok || uint16(length) < s {
+							shortest[token] = uint16(length)
+						}
+						if seen[token] {
+							continue
+						}
+						seen[token] = true
+						if counts[token] < math.MaxUint16 {
+							counts[token]++
+						}
+					}
+				}
+			}
+		}
+		for token, count := range counts {
+			idx.postings[token] = append(idx.postings[token], meaningPosting{id, count, shortest[token]})
+		}
+	}
+	idx.tokens = make([]string, 0, len(idx.postings))
+	for token := range idx.postings {
+		idx.tokens = append(idx.tokens, token)
+	}
+	sort.Strings(idx.tokens)
+	return idx
+}
+
+// Len returns how many different English words are indexed.
+func (idx *MeaningIndex) Len() int {
+	return len(idx.postings)
+}
+
+// Has reports whether any meaning uses the English word token.
+func (idx *MeaningIndex) Has(token string) bool {
+	_, ok := idx.postings[FoldCase(token)]
+	return ok
+}
+
+// meaningTerm is one term of a reverse-find query: a word, a word ending
+// in * for every word starting with it, or a quoted phrase of words that
+// have to come in that order.
+type meaningTerm struct {
+	words  []string
+	prefix bool
+}
+
+// parseMeaningQuery reads a reverse-find query as alternatives separated
+// by OR (or |), each a list of terms that all have to match. Quoted
+// phrases are single terms, and an unclosed quote runs to the end.
+func parseMeaningQuery(query string) [][]meaningTerm {
+	var alternatives [][]meaningTerm
+	var terms []meaningTerm
+	next := func() {
+		if len(terms) > 0 {
+			alternatives = append(alternatives, terms)
+		}
+		terms = nil
+	}
+	for rest := query; rest != ""; {
+		rest = strings.TrimLeft(rest, " \t")
+		var field string
+		switch {
+		case rest == "":
+			continue
+		case rest[0] == '"':
+			field, rest, _ = strings.Cut(rest[1:], `"`)
+			if words := englishTokens(field); len(words) > 0 {
+				terms = append(terms, meaningTerm{words: words})
+			}
+			continue
+		case rest[0] == '|':
+			next()
+			rest = rest[1:]
+			continue
+		}
+		end := strings.IndexAny(rest, " \t\"|")
+		if end < 0 {
+			end = len(rest)
+		}
+		field, rest = rest[:end], rest[end:]
+		if field == "OR" {
+			next()
+			continue
+		}
+		prefix := strings.HasSuffix(field, "*")
+		// "ice-cream" is a phrase, as its words are indexed apart.
+		if words := englishTokens(field); len(words) > 0 {
+			terms = append(terms, meaningTerm{words: words, prefix: prefix && len(words) == 1})
+		}
+	}
+	next()
+	return alternatives
+}
+
+// Search returns the headwords whose meanings match query, most relevant
+// first. A query's words have to match whole words of a meaning, so "run"
+// doesn't find "running water", unless they end in *, as in "run*". Every
+// word has to match, unless the query lists alternatives with OR, as in
+// "dog OR hound", and a phrase in quotes, as in "\"look after\"", has to be
+// found as it is. Rare words count for more than common ones (tf-idf), and
+// a word matching in a short meaning ("cat") beats one matching in a long
+// one ("cat's cradle, a string game"). A limit of zero or less returns
+// every match.
+func (idx *MeaningIndex) Search(query string, limit int) []string {
+	scores := make(map[int32]float64)
+	for _, terms := range parseMeaningQuery(query) {
+		for id, score := range idx.searchAll(terms) {
+			scores[id] = max(scores[id], score)
+		}
+	}
+
+	ids := make([]int32, 0, len(scores))
+	for id := range scores {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool {
+		if scores[ids[i]] != scores[ids[j]] {
+			return scores[ids[i]] > scores[ids[j]]
+		}
+		return idx.headwords[ids[i]] < idx.headwords[ids[j]]
+	})
+	if limit > 0 && len(ids) > limit {
+		ids = ids[:limit]
+	}
+
+	matches := make([]string, len(ids))
+	for i, id := range ids {
+		matches[i] = idx.headwords[id]
+	}
+	return matches
+}
+
+// searchAll scores the headwords matching every one of terms.
+func (idx *MeaningIndex) searchAll(terms []meaningTerm) map[int32]float64 {
+	var scores map[int32]float64
+	for _, term := range terms {
+		next := make(map[int32]float64)
+		for id, score := range idx.termScores(term) {
+			if prev, ok := scores[id]; ok || scores == nil {
+				next[id] = prev + score
+			}
+		}
+		if len(next) == 0 {
+			return nil
+		}
+		scores = next
+	}
+	return scores
+}
+
+// termScores scores the headwords matching one term.
+func (idx *MeaningIndex) termScores(term meaningTerm) map[int32]float64 {
+	if term.prefix {
+		// The best of the words starting with it counts.
+		scores := make(map[int32]float64)
+		start := sort.SearchStrings(idx.tokens, term.words[0])
+		for _, token := range idx.tokens[start:] {
+			if !strings.HasPrefix(token, term.words[0]) {
+				break
+			}
+			for id, score := range idx.wordScores(token) {
+				scores[id] = max(scores[id], score)
+			}
+		}
+		return scores
+	}
+	var scores map[int32]float64
+	for i, word := range term.words {
+		next := make(map[int32]float64)
+		for id, score := range idx.wordScores(word) {
+			if prev, ok := scores[id]; ok || i == 0 {
+				next[id] = prev + score
+			}
+		}
+		scores = next
+	}
+	if len(term.words) > 1 {
+		for id := range scores {
+			if !idx.hasPhrase(id, term.words) {
+				delete(scores, id)
+			}
+		}
+	}
+	return scores
+}
+
+// wordScores scores the headwords whose meanings use the English word.
+func (idx *MeaningIndex) wordScores(word string) map[int32]float64 {
+	postings := idx.postings[word]
+	if len(postings) == 0 {
+		return nil
+	}
+	idf := math.Log(1 + float64(len(idx.headwords))/float64(len(postings)))
+	scores := make(map[int32]float64, len(postings))
+	for _, p := range postings {
+		scores[p.word] = idf*(1+math.Log(float64(p.count))) + 1/float64(p.shortest)
+	}
+	return scores
+}
+
+// hasPhrase reports whether one of the headword's meanings has words, one
+// after another, in one part.
+func (idx *MeaningIndex) hasPhrase(id int32, words []string) bool {
+	for _, gloss := range idx.glosses[idx.headwords[id]] {
+		for _, meaning := range gloss.Meanings {
+			for _, part := range meaningParts(meaning) {
+				tokens := englishTokens(part)
+				for i := 0; i+len(words) <= len(tokens); i++ {
+					if slices.Equal(tokens[i:i+len(words)], words) {
+						return true
+					}
+				}
+			}
+		}
+	}
+	return false
+}
+
+// LooksEnglish reports whether a query that found no Finnish headwords is
+// probably English: plain ASCII letters only (no ä or ö), with every word of
+// it appearing somewhere in the English meanings.
+func LooksEnglish(query string, index *MeaningIndex) bool {
+	tokens := strings.Fields(FoldCase(query))
+	if len(tokens) == 0 {
+		return false
+	}
+	for _, token := range tokens {
+		for _, r := range token {
+			if r < 'a' || r > 'z' {
+				return false
+			}
+		}
+		if !index.Has(token) {
+			return false
+		}
+	}
+	return true
+}
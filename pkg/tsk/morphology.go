@@ -0,0 +1,195 @@
+package tsk
+
+import (
+	"strings"
+)
+
+// Only base forms are headwords, so "taloissa" finds nothing by itself. The
+// analyzer strips case and number endings, undoes the usual stem changes,
+// and keeps every candidate base form that is actually a headword. It is a
+// heuristic, not a full morphology: a form may get several readings, and
+// irregular words may get none.
+
+// inflectionEnding is one case/number ending, written with back vowels.
+// Front-vowel variants ("issä" for "issa") are derived automatically.
+type inflectionEnding struct {
+	ending string
+	form   string
+	plural bool // the ending follows the plural stem (talo-i-ssa, talo-j-a)
+}
+
+var inflectionEndings = []inflectionEnding{
+	{"n", "genitive singular", false},
+	{"a", "partitive singular", false},
+	{"ta", "partitive singular", false},
+	{"ssa", "inessive singular", false},
+	{"sta", "elative singular", false},
+	{"lla", "adessive singular", false},
+	{"lta", "ablative singular", false},
+	{"lle", "allative singular", false},
+	{"na", "essive singular", false},
+	{"ksi", "translative singular", false},
+	{"tta", "abessive singular", false},
+	{"t", "nominative plural", false},
+	{"ien", "genitive plural", true},
+	{"jen", "genitive plural", true},
+	{"iden", "genitive plural", true},
+	{"ia", "partitive plural", true},
+	{"ja", "partitive plural", true},
+	{"ita", "partitive plural", true},
+	{"issa", "inessive plural", true},
+	{"ista", "elative plural", true},
+	{"ihin", "illative plural", true},
+	{"illa", "adessive plural", true},
+	{"ilta", "ablative plural", true},
+	{"ille", "allative plural", true},
+	{"ina", "essive plural", true},
+	{"iksi", "translative plural", true},
+	{"itta", "abessive plural", true},
+	{"ine", "comitative", true},
+	{"in", "instructive", true},
+}
+
+// gradationPairs maps weak consonant grades back to strong ones, as in
+// kuka-ssa -> kukka, lavan -> lapa, kadun -> katu.
+var gradationPairs = [][2]string{
+	{"k", "kk"}, {"p", "pp"}, {"t", "tt"}, {"v", "p"}, {"d", "t"},
+	{"ng", "nk"}, {"mm", "mp"}, {"nn", "nt"}, {"ll", "lt"}, {"rr", "rt"},
+}
+
+// Analysis is one reading of an inflected word.
+type Analysis struct {
+	Lemma string
+	Form  string
+}
+
+func isFinnishVowel(r rune) bool {
+	return strings.ContainsRune("aeiouyäö", r)
+}
+
+// frontVowels converts back vowels to their front counterparts, for endings
+// attached to words like "kylä" (kylässä, not kylassa).
+func frontVowels(s string) string {
+	return strings.NewReplacer("a", "ä", "o", "ö", "u", "y").Replace(s)
+}
+
+// strengthenGrade undoes consonant gradation at the last consonant cluster
+// of a stem, returning each possible strong-grade form.
+func strengthenGrade(stem string) []string {
+	runes := []rune(stem)
+	end := len(runes)
+	for end > 0 && isFinnishVowel(runes[end-1]) {
+		end--
+	}
+	if end == len(runes) || end == 0 {
+		return nil
+	}
+	head, tail := string(runes[:end]), string(runes[end:])
+	var forms []string
+	for _, pair := range gradationPairs {
+		if strings.HasSuffix(head, pair[0]) {
+			forms = append(forms, strings.TrimSuffix(head, pair[0])+pair[1]+tail)
+		}
+	}
+	return forms
+}
+
+// lemmaCandidates lists plausible base forms for a stem left after
+// stripping an ending. Plural stems lose or change their final vowel
+// (koira -> koiri-, kissa -> kisso-, kivi -> kivi-), and -nen words use an
+// -se-/-s- stem (ihminen -> ihmise-, ihmis-).
+func lemmaCandidates(stem string, plural bool) []string {
+	candidates := []string{stem}
+	switch {
+	case strings.HasSuffix(stem, "se"):
+		candidates = append(candidates, strings.TrimSuffix(stem, "se")+"nen")
+	case strings.HasSuffix(stem, "e"):
+		candidates = append(candidates, strings.TrimSuffix(stem, "e")+"i")
+	}
+	if plural {
+		candidates = append(candidates, stem+"a", stem+"ä", stem+"i", stem+"e")
+		if strings.HasSuffix(stem, "s") {
+			candidates = append(candidates, strings.TrimSuffix(stem, "s")+"nen")
+		}
+		if strings.HasSuffix(stem, "o") {
+			candidates = append(candidates, strings.TrimSuffix(stem, "o")+"a")
+		}
+		if strings.HasSuffix(stem, "ö") {
+			candidates = append(candidates, strings.TrimSuffix(stem, "ö")+"ä")
+		}
+	}
+	var all []string
+	for _, c := range candidates {
+		all = append(all, c)
+		all = append(all, strengthenGrade(c)...)
+	}
+	return all
+}
+
+// AnalyzeWord returns the readings of word as an inflected form of some
+// headword. The word itself is never returned as its own lemma.
+func AnalyzeWord(word string, glosses map[string][]Gloss) []Analysis {
+	word = FoldCase(word)
+	seen := make(map[Analysis]bool)
+	var analyses []Analysis
+	add := func(lemma, form string) {
+		if lemma == word || lemma == "" {
+			return
+		}
+		if _, ok := glosses[lemma]; !ok {
+			return
+		}
+		a := Analysis{Lemma: lemma, Form: form}
+		if !seen[a] {
+			seen[a] = true
+			analyses = append(analyses, a)
+		}
+	}
+
+	for _, e := range inflectionEndings {
+		for _, ending := range []string{e.ending, frontVowels(e.ending)} {
+			if !strings.HasSuffix(word, ending) || len(word) <= len(ending)+1 {
+				continue
+			}
+			stem := strings.TrimSuffix(word, ending)
+			for _, lemma := range lemmaCandidates(stem, e.plural) {
+				add(lemma, e.form)
+			}
+			if ending == e.ending && frontVowels(ending) == ending {
+				break // no vowels to harmonise, e.g. "n" or "t"
+			}
+		}
+	}
+
+	// Illative singular lengthens the final vowel and adds n (taloon, kylään),
+	// with an h for long-vowel words (maahan, tiehen).
+	runes := []rune(word)
+	if n := len(runes); n >= 4 && runes[n-1] == 'n' {
+		v := runes[n-2]
+		if isFinnishVowel(v) && runes[n-3] == v {
+			stem := string(runes[:n-2])
+			for _, lemma := range lemmaCandidates(stem, false) {
+				add(lemma, "illative singular")
+			}
+		}
+		if isFinnishVowel(v) && runes[n-3] == 'h' {
+			add(string(runes[:n-3]), "illative singular")
+		}
+	}
+
+	return analyses
+}
+
+// GroupAnalyses collects the readings of a word by lemma, keeping lemmas in
+// the order they were first found.
+func GroupAnalyses(analyses []Analysis) ([]string, map[string][]string) {
+	var lemmas []string
+	forms := make(map[string][]string)
+	for _, a := range analyses {
+		if _, ok := forms[a.Lemma]; !ok {
+			lemmas = append(lemmas, a.Lemma)
+		}
+		forms[a.Lemma] = append(forms[a.Lemma], a.Form)
+	}
+	return lemmas, forms
+}
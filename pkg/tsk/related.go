@@ -0,0 +1,133 @@
+package tsk
+
+import (
+	"sort"
+	"strings"
+)
+
+// maxSharedMeaning is how many headwords can share a meaning before it is
+// too vague, like "to be" or "thing", to count as relating them.
+const maxSharedMeaning = 12
+
+// relatedIndex links headwords through their glosses: the synonyms they
+// list, read both ways, and the meanings they share word for word.
+type relatedIndex struct {
+	synonymOf map[string][]string // headword -> the headwords listing it as a synonym
+	byMeaning map[string][]string // meaningKeys of a meaning -> the headwords with it
+}
+
+func newRelatedIndex(glosses map[string][]Gloss) *relatedIndex {
+	idx := &relatedIndex{
+		synonymOf: make(map[string][]string),
+		byMeaning: make(map[string][]string),
+	}
+	words := make([]string, 0, len(glosses))
+	for word := range glosses {
+		words = append(words, word)
+	}
+	for _, word := range sortedWords(words) {
+		for _, g := range glosses[word] {
+			for _, s := range g.Synonyms {
+				idx.synonymOf[s] = appendNew(idx.synonymOf[s], word)
+			}
+			for _, m := range g.Meanings {
+				for _, key := range meaningKeys(m) {
+					idx.byMeaning[key] = appendNew(idx.byMeaning[key], word)
+				}
+			}
+		}
+	}
+	return idx
+}
+
+// appendNew appends word to words unless it is already the last one, which
+// is where a repeat would be, as words are added in order.
+func appendNew(words []string, word string) []string {
+	if len(words) > 0 && words[len(words)-1] == word {
+		return words
+	}
+	return append(words, word)
+}
+
+// meaningKeys splits a meaning into the comparable parts it lists, so
+// "house, home (dwelling)" gives "house" and "home". Parenthesized labels
+// are dropped and case is ignored.
+func meaningKeys(meaning string) []string {
+	var b strings.Builder
+	depth := 0
+	for _, r := range strings.ToLower(meaning) {
+		switch {
+		case r == '(':
+			depth++
+		case r == ')':
+			depth = max(depth-1, 0)
+		case depth == 0:
+			b.WriteRune(r)
+		}
+	}
+	var keys []string
+	for _, part := range strings.FieldsFunc(b.String(), func(r rune) bool { return r == ',' || r == ';' }) {
+		key := strings.Trim(strings.Join(strings.Fields(part), " "), ".:!?")
+		if key != "" {
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
+
+// Neighbors returns the headwords related to word, closest first: its
+// synonyms, the words listing it as one, and then the words sharing one
+// of its meanings, like talo and rakennus, both "building", those sharing
+// most first. It returns at most limit words, or all of them if limit is
+// zero or less. The links are worked out on the first call.
+func (d *Dictionary) Neighbors(word string, limit int) []string {
+	d.relatedOnce.Do(func() { d.related = newRelatedIndex(d.glosses) })
+
+	seen := map[string]bool{word: true}
+	var neighbors []string
+	add := func(w string) {
+		if _, ok := d.glosses[w]; ok && !seen[w] {
+			seen[w] = true
+			neighbors = append(neighbors, w)
+		}
+	}
+	for _, g := range d.glosses[word] {
+		for _, s := range g.Synonyms {
+			add(s)
+		}
+	}
+	for _, w := range d.related.synonymOf[word] {
+		add(w)
+	}
+
+	shared := make(map[string]int)
+	for _, g := range d.glosses[word] {
+		for _, m := range g.Meanings {
+			for _, key := range meaningKeys(m) {
+				if words := d.related.byMeaning[key]; len(words) <= maxSharedMeaning {
+					for _, w := range words {
+						shared[w]++
+					}
+				}
+			}
+		}
+	}
+	byShared := make([]string, 0, len(shared))
+	for w := range shared {
+		byShared = append(byShared, w)
+	}
+	sort.Slice(byShared, func(i, j int) bool {
+		if shared[byShared[i]] != shared[byShared[j]] {
+			return shared[byShared[i]] > shared[byShared[j]]
+		}
+		return byShared[i] < byShared[j]
+	})
+	for _, w := range byShared {
+		add(w)
+	}
+
+	if limit > 0 && len(neighbors) > limit {
+		neighbors = neighbors[:limit]
+	}
+	return neighbors
+}
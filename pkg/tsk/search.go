@@ -0,0 +1,511 @@
+package tsk
+
+import (
+	"bytes"
+	"index/suffixarray"
+	"slices"
+	"sort"
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// SuffixIndex answers "which words end in X?" by storing every word reversed
+// in an ordinary Trie, so a suffix search becomes a prefix search.
+type SuffixIndex struct {
+	trie *Trie
+}
+
+func NewSuffixIndex(words []string) *SuffixIndex {
+	reversed := make([]string, len(words))
+	for i, word := range words {
+		reversed[i] = reverseString(word)
+	}
+	return &SuffixIndex{trie: NewTrie(reversed)}
+}
+
+// FindWords returns up to MaxResults words ending in suffix.
+func (idx *SuffixIndex) FindWords(suffix string) []string {
+	return idx.FindWordsN(suffix, MaxResults)
+}
+
+// FindWordsN is FindWords returning up to limit words instead. A limit of
+// zero or less returns every match.
+func (idx *SuffixIndex) FindWordsN(suffix string, limit int) []string {
+	reversed := idx.trie.FindWordsN(reverseString(suffix), limit)
+	words := make([]string, len(reversed))
+	for i, r := range reversed {
+		words[i] = reverseString(r)
+	}
+	return words
+}
+
+// Count returns how many words end in suffix.
+func (idx *SuffixIndex) Count(suffix string) int {
+	return idx.trie.Count(reverseString(suffix))
+}
+
+func reverseString(s string) string {
+	runes := []rune(s)
+	for i, j := 0, len(runes)-1; i < j; i, j = i+1, j-1 {
+		runes[i], runes[j] = runes[j], runes[i]
+	}
+	return string(runes)
+}
+
+// suffixQuery reports whether a search bar query uses the `$ending` syntax,
+// returning the ending itself if so.
+func suffixQuery(text string) (string, bool) {
+	if !strings.HasPrefix(text, "$") {
+		return "", false
+	}
+	return strings.TrimPrefix(text, "$"), true
+}
+
+// finnishDiphthongs are the vowel pairs that share a syllable. The last
+// three only do so in a word's first syllable.
+var finnishDiphthongs = []string{
+	"ai", "ei", "oi", "ui", "yi", "äi", "öi",
+	"au", "eu", "iu", "ou", "ey", "iy", "äy", "öy",
+	"ie", "uo", "yö",
+}
+
+func isDiphthong(a, b rune, firstSyllable bool) bool {
+	i := slices.Index(finnishDiphthongs, string([]rune{a, b}))
+	return i >= 0 && (i < len(finnishDiphthongs)-3 || firstSyllable)
+}
+
+// Syllables splits a Finnish word into syllables, as in ta-lo, kis-sa and
+// hert-tu-ai-nen: a consonant followed by a vowel starts a syllable, and
+// so does a vowel after another one, unless the two make a long vowel or
+// a diphthong. Anything that isn't a vowel counts as a consonant.
+func Syllables(word string) []string {
+	runes := []rune(strings.ToLower(word))
+	var syllables []string
+	start, run := 0, 0 // run is how many vowels in a row end at i
+	seenVowel := false
+	for i, r := range runes {
+		if !isFinnishVowel(r) {
+			run = 0
+			continue
+		}
+		switch {
+		case run == 0 && seenVowel:
+			syllables = append(syllables, string(runes[start:i-1]))
+			start = i - 1
+		case run == 1 && (runes[i-1] == r || isDiphthong(runes[i-1], r, len(syllables) == 0)):
+		case run >= 1:
+			syllables = append(syllables, string(runes[start:i]))
+			start, run = i, 0
+		}
+		seenVowel = true
+		run++
+	}
+	return append(syllables, string(runes[start:]))
+}
+
+// RhymeEnding is the ending words rhyming with word share: its last n
+// syllables, or all of it if it has no more than n.
+func RhymeEnding(word string, n int) string {
+	syllables := Syllables(word)
+	return strings.Join(syllables[max(0, len(syllables)-n):], "")
+}
+
+// SubstringIndex answers "which words contain X?", e.g. every compound with
+// "kirja" in it. The words are joined into one NUL-separated text with a
+// suffix array over it, so a lookup costs a binary search rather than a
+// scan of the whole word list. The text has the words folded with
+// FoldCase, so that case doesn't matter.
+type SubstringIndex struct {
+	text      []byte
+	starts    []int          // offset of each word in text, ascending
+	originals map[int]string // word number -> the word, where folding changed it
+	array     *suffixarray.Index
+}
+
+func NewSubstringIndex(words []string) *SubstringIndex {
+	idx := &SubstringIndex{starts: make([]int, len(words)), originals: make(map[int]string)}
+	var b bytes.Buffer
+	for i, word := range words {
+		b.WriteByte(0)
+		idx.starts[i] = b.Len()
+		folded := FoldCase(word)
+		if folded != word {
+			idx.originals[i] = word
+		}
+		b.WriteString(folded)
+	}
+	idx.text = b.Bytes()
+	idx.array = suffixarray.New(idx.text)
+	return idx
+}
+
+// FindWords returns the first limit words containing sub, whatever its
+// case, in alphabetical order. A limit of zero or less returns every
+// match.
+func (idx *SubstringIndex) FindWords(sub string, limit int) []string {
+	if sub == "" || strings.IndexByte(sub, 0) != -1 {
+		return nil
+	}
+	sub = FoldCase(sub)
+	seen := make(map[int]bool)
+	var words []string
+	for _, offset := range idx.array.Lookup([]byte(sub), -1) {
+		// The word holding the match is the last one starting at or
+		// before it.
+		i := sort.SearchInts(idx.starts, offset+1) - 1
+		if i < 0 || seen[i] {
+			continue
+		}
+		seen[i] = true
+		if word, ok := idx.originals[i]; ok {
+			words = append(words, word)
+			continue
+		}
+		start, end := idx.starts[i], len(idx.text)
+		if i+1 < len(idx.starts) {
+			end = idx.starts[i+1] - 1
+		}
+		words = append(words, string(idx.text[start:end]))
+	}
+	SortFinnish(words)
+	if limit > 0 && len(words) > limit {
+		words = words[:limit]
+	}
+	return words
+}
+
+// wildcardQuery reports whether a search bar query uses `*` to say where
+// the rest of the word goes: "*kirja*" for words containing kirja,
+// "*kauppa" for words ending in kauppa and "kirja*" for words starting
+// with it. It returns the query without its stars.
+func wildcardQuery(text string) (query string, leading, trailing bool) {
+	leading, trailing = strings.HasPrefix(text, "*"), strings.HasSuffix(text, "*")
+	return strings.Trim(text, "*"), leading, trailing
+}
+
+// globQuery reports whether a search bar query is a glob for Trie.Glob:
+// it has a '?', or a '*' somewhere other than its ends, which wildcardQuery
+// already covers.
+func globQuery(text string) bool {
+	return strings.ContainsRune(text, '?') || strings.ContainsRune(strings.Trim(text, "*"), '*')
+}
+
+// PatternIndex buckets words by their length in runes, so that fixed-length
+// patterns like "s.n." or "k___a" only have to be checked against words of
+// the right length instead of the whole word list.
+type PatternIndex struct {
+	byLength map[int][]string
+}
+
+func NewPatternIndex(words []string) *PatternIndex {
+	idx := &PatternIndex{byLength: make(map[int][]string)}
+	for _, word := range words {
+		n := len([]rune(word))
+		idx.byLength[n] = append(idx.byLength[n], word)
+	}
+	return idx
+}
+
+// isPatternWildcard reports whether r stands for "exactly one letter".
+func isPatternWildcard(r rune) bool {
+	return r == '.' || r == '_'
+}
+
+// isPatternQuery reports whether text contains at least one wildcard.
+func isPatternQuery(text string) bool {
+	return strings.IndexFunc(text, isPatternWildcard) != -1
+}
+
+// Match returns the words matching pattern, in the order they appear in the
+// word list. A limit of zero or less returns every match.
+func (idx *PatternIndex) Match(pattern string, limit int) []string {
+	want := []rune(pattern)
+	var matches []string
+	for _, word := range idx.byLength[len(want)] {
+		if matchesPattern([]rune(word), want) {
+			matches = append(matches, word)
+			if limit > 0 && len(matches) >= limit {
+				break
+			}
+		}
+	}
+	return matches
+}
+
+func matchesPattern(word, pattern []rune) bool {
+	if len(word) != len(pattern) {
+		return false
+	}
+	for i, p := range pattern {
+		if isPatternWildcard(p) {
+			if !unicode.IsLetter(word[i]) {
+				return false
+			}
+		} else if p != word[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// maxTypos is how many edits away from the query a fuzzy match may be.
+// Short queries get less slack, or nearly every short word would match.
+func maxTypos(queryLen int) int {
+	if queryLen <= 4 {
+		return 1
+	}
+	return 2
+}
+
+// foldFinnishRune lowercases r and drops the dots from ä, ö and å, the
+// letters most often typed without them on non-Finnish keyboards.
+func foldFinnishRune(r rune) rune {
+	switch r = unicode.ToLower(r); r {
+	case 'ä', 'å':
+		return 'a'
+	case 'ö':
+		return 'o'
+	}
+	return r
+}
+
+// editDistance returns the Levenshtein distance between a and b, comparing
+// runes with foldFinnishRune so that "kayda" and "käydä" are equal. Once
+// the distance is certain to exceed limit it stops and returns limit+1.
+func editDistance(a, b []rune, limit int) int {
+	prev := make([]int, len(b)+1)
+	curr := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		curr[0] = i
+		rowMin := curr[0]
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if foldFinnishRune(a[i-1]) == foldFinnishRune(b[j-1]) {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+			rowMin = min(rowMin, curr[j])
+		}
+		if rowMin > limit {
+			return limit + 1
+		}
+		prev, curr = curr, prev
+	}
+	return prev[len(b)]
+}
+
+// Similar returns up to limit words within maxTypos edits of query, closest
+// first. Only words of nearby lengths can be that close, so only those
+// buckets of the index are scanned.
+func (idx *PatternIndex) Similar(query string, limit int) []string {
+	q := []rune(query)
+	typos := maxTypos(len(q))
+
+	type candidate struct {
+		word      string
+		distance  int
+		sameStart bool // typos rarely hit the first letter
+	}
+	var candidates []candidate
+	for n := len(q) - typos; n <= len(q)+typos; n++ {
+		for _, word := range idx.byLength[n] {
+			w := []rune(word)
+			if d := editDistance(q, w, typos); d <= typos {
+				candidates = append(candidates, candidate{word, d, len(q) > 0 && w[0] == q[0]})
+			}
+		}
+	}
+
+	sort.Slice(candidates, func(i, j int) bool {
+		a, b := candidates[i], candidates[j]
+		if a.distance != b.distance {
+			return a.distance < b.distance
+		}
+		if a.sameStart != b.sameStart {
+			return a.sameStart
+		}
+		return CompareFinnish(a.word, b.word) < 0
+	})
+	var matches []string
+	for _, c := range candidates {
+		if limit > 0 && len(matches) >= limit {
+			break
+		}
+		matches = append(matches, c.word)
+	}
+	return matches
+}
+
+// diacriticFolds maps the letters with diacritics found in Finnish words,
+// loanwords included, to the letters they are typed as without them.
+var diacriticFolds = map[rune]rune{
+	'ä': 'a', 'å': 'a', 'á': 'a', 'à': 'a', 'â': 'a',
+	'ö': 'o', 'ó': 'o', 'ô': 'o', 'õ': 'o',
+	'é': 'e', 'è': 'e', 'ê': 'e', 'ë': 'e',
+	'ü': 'u', 'ú': 'u', 'í': 'i', 'ï': 'i',
+	'š': 's', 'ž': 'z', 'ç': 'c', 'ñ': 'n',
+	'Ä': 'A', 'Å': 'A', 'Ö': 'O', 'Š': 'S', 'Ž': 'Z',
+}
+
+// FoldDiacritics drops the diacritics from s, so "öljyä" becomes "oljya".
+func FoldDiacritics(s string) string {
+	return strings.Map(func(r rune) rune {
+		if folded, ok := diacriticFolds[r]; ok {
+			return folded
+		}
+		return r
+	}, s)
+}
+
+// FoldCase folds the case of s, so that "Äiti" and "ÄITI" match äiti and
+// "suomi" matches Suomi. Finnish has no casing rules of its own, unlike
+// Turkish with its dotless i, so Unicode's lowercasing is the Finnish one;
+// what it misses is an ä or ö typed, or pasted from a Mac, as a plain
+// letter followed by a combining diaeresis, so those are composed into the
+// single letters the word list has.
+func FoldCase(s string) string {
+	return norm.NFC.String(strings.ToLower(s))
+}
+
+// FoldedIndex answers prefix searches whatever the case of the query, and
+// with NewFoldedIndex, typed without diacritics, so "Oljyn" finds öljyn.
+// Its trie holds every word alongside a folded copy of it, where that
+// differs, and unfold leads each folded copy back to the words it stands
+// for. Queries are folded for case, but a query with its diacritics still
+// matches as usual.
+type FoldedIndex struct {
+	trie   *Trie
+	fold   func(string) string
+	unfold map[string][]string // folded copy -> the words folding to it, itself too if it's a word
+}
+
+// NewFoldedIndex indexes words to be found regardless of case and
+// diacritics.
+func NewFoldedIndex(words []string) *FoldedIndex {
+	return newFoldedIndex(words, func(s string) string { return FoldDiacritics(FoldCase(s)) })
+}
+
+// NewCaselessIndex indexes words to be found regardless of case, but not
+// of diacritics: "Äiti" finds äiti, but "aiti" doesn't.
+func NewCaselessIndex(words []string) *FoldedIndex {
+	return newFoldedIndex(words, FoldCase)
+}
+
+func newFoldedIndex(words []string, fold func(string) string) *FoldedIndex {
+	idx := &FoldedIndex{fold: fold, unfold: make(map[string][]string)}
+	isWord := make(map[string]bool, len(words))
+	all := make([]string, 0, len(words))
+	for _, word := range words {
+		isWord[word] = true
+		all = append(all, word)
+		for _, folded := range idx.copies(word) {
+			idx.unfold[folded] = append(idx.unfold[folded], word)
+			all = append(all, folded)
+		}
+	}
+	for folded, originals := range idx.unfold {
+		if isWord[folded] {
+			originals = append(originals, folded)
+		}
+		sort.Strings(originals)
+		idx.unfold[folded] = slices.Compact(originals)
+	}
+	idx.trie = NewTrie(all)
+	return idx
+}
+
+// copies returns the folded copies of word the trie holds: the word in
+// lower case, for queries with their diacritics, and fully folded, if
+// those differ from the word.
+func (idx *FoldedIndex) copies(word string) []string {
+	var copies []string
+	for _, folded := range []string{FoldCase(word), idx.fold(word)} {
+		if folded != word && !slices.Contains(copies, folded) {
+			copies = append(copies, folded)
+		}
+	}
+	return copies
+}
+
+// SetRank records the frequency rank of a word, which its folded copies
+// share unless a more common word folds to the same.
+func (idx *FoldedIndex) SetRank(word string, rank int) {
+	idx.trie.SetRank(word, rank)
+	for _, folded := range idx.copies(word) {
+		if node := idx.trie.find(folded); node >= 0 && (idx.trie.rank[node] == 0 || int32(rank) < idx.trie.rank[node]) {
+			idx.trie.SetRank(folded, rank)
+		}
+	}
+}
+
+// FindWords returns up to MaxResults words starting with prefix, read as
+// the index folds words, in the order Trie.FindWords would, but for
+// prefix itself coming first if it is a word.
+func (idx *FoldedIndex) FindWords(prefix string) []string {
+	return idx.FindWordsN(prefix, MaxResults)
+}
+
+// FindWordsN is FindWords returning up to limit words instead. A limit of
+// zero or less returns every match.
+func (idx *FoldedIndex) FindWordsN(prefix string, limit int) []string {
+	folded := FoldCase(prefix)
+	words := idx.unfoldMatches(limit, func(fetch int) []string {
+		return idx.trie.FindWordsN(folded, fetch)
+	})
+	// The word typed, in the case typed, is the best match.
+	if i := slices.Index(words, prefix); i > 0 {
+		copy(words[1:i+1], words[:i])
+		words[0] = prefix
+	}
+	return words
+}
+
+// Glob is Trie.Glob, with the pattern read as FindWords reads a prefix.
+func (idx *FoldedIndex) Glob(pattern string, limit int) []string {
+	folded := FoldCase(pattern)
+	words := idx.unfoldMatches(limit, func(fetch int) []string {
+		return idx.trie.Glob(folded, fetch)
+	})
+	SortFinnish(words)
+	return words
+}
+
+// unfoldMatches leads the trie's matches, from find, back to up to limit
+// words they stand for, in the order found. A limit of zero or less
+// returns every match.
+func (idx *FoldedIndex) unfoldMatches(limit int, find func(fetch int) []string) []string {
+	// A word and its folded copy can both match, making one word, so
+	// fetch more matches from the trie until there are limit words.
+	for fetch := limit; ; fetch *= 2 {
+		matches := find(fetch)
+		seen := make(map[string]bool)
+		var words []string
+		for _, match := range matches {
+			originals, ok := idx.unfold[match]
+			if !ok {
+				originals = []string{match}
+			}
+			for _, word := range originals {
+				if !seen[word] && !full(words, limit) {
+					seen[word] = true
+					words = append(words, word)
+				}
+			}
+		}
+		if full(words, limit) || !full(matches, fetch) {
+			return words
+		}
+	}
+}
+
+// Count returns how many words start with prefix, read as FindWords
+// reads it. A word and its folded copy both matching count once, so this
+// has to list them all.
+func (idx *FoldedIndex) Count(prefix string) int {
+	return len(idx.FindWordsN(prefix, 0))
+}
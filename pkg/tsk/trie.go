@@ -0,0 +1,304 @@
+package tsk
+
+import (
+	"container/heap"
+	"slices"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// MaxResults is how many words a search returns at most, unless
+// Dictionary.SetMaxResults says otherwise.
+const MaxResults = 50
+
+// Trie holds the headwords for prefix search. Words given a frequency rank
+// with SetRank come first in FindWords, most common first.
+//
+// The nodes are numbered breadth first, with each node's children in
+// code point order, so the children of node i are the nodes first[i] up
+// to first[i+1]. A node is then only a few numbers in flat arrays, rather
+// than a map of pointers: several times smaller and quick to build.
+type Trie struct {
+	labels []rune   // the letter leading to each node; the root's is 0
+	first  []uint32 // first child of each node, plus one past the last node
+	ends   []bool   // whether a word ends at each node
+	rank   []int32  // frequency rank of the word ending at each node; 0 if unranked
+	best   []int32  // best (lowest) rank anywhere in each subtree; 0 if none
+}
+
+// NewTrie builds a trie of words, which needn't be sorted or unique.
+func NewTrie(words []string) *Trie {
+	sorted := slices.Clone(words)
+	slices.Sort(sorted)
+	sorted = slices.Compact(sorted)
+
+	// Each node covers the sorted words in [lo, hi), which all share the
+	// node's first depth bytes. Visiting the nodes a level at a time, in
+	// order, numbers the children of each one straight after the children
+	// of the one before.
+	type span struct{ lo, hi, depth int }
+	level, next := []span{{0, len(sorted), 0}}, []span{}
+	t := &Trie{labels: []rune{0}}
+	for len(level) > 0 {
+		for _, s := range level {
+			ends := s.lo < s.hi && len(sorted[s.lo]) == s.depth
+			if ends {
+				s.lo++
+			}
+			t.ends = append(t.ends, ends)
+			t.first = append(t.first, uint32(len(t.labels)))
+			for lo := s.lo; lo < s.hi; {
+				ch, size := utf8.DecodeRuneInString(sorted[lo][s.depth:])
+				end := s.depth + size
+				hi := lo + 1
+				for hi < s.hi && strings.HasPrefix(sorted[hi][s.depth:], sorted[lo][s.depth:end]) {
+					hi++
+				}
+				t.labels = append(t.labels, ch)
+				next = append(next, span{lo, hi, end})
+				lo = hi
+			}
+		}
+		level, next = next, level[:0]
+	}
+	t.first = append(t.first, uint32(len(t.labels)))
+	t.rank = make([]int32, len(t.labels))
+	t.best = make([]int32, len(t.labels))
+	return t
+}
+
+// child returns the child of node reached by ch, or -1 if there is none.
+func (t *Trie) child(node int, ch rune) int {
+	lo, hi := int(t.first[node]), int(t.first[node+1])
+	i := lo + sort.Search(hi-lo, func(i int) bool { return t.labels[lo+i] >= ch })
+	if i < hi && t.labels[i] == ch {
+		return i
+	}
+	return -1
+}
+
+// find returns the node word leads to, or -1 if no word starts with it.
+func (t *Trie) find(word string) int {
+	node := 0
+	for _, ch := range word {
+		if node = t.child(node, ch); node < 0 {
+			return -1
+		}
+	}
+	return node
+}
+
+// SetRank records the frequency rank of a word already in the trie, so that
+// FindWords can offer the most common completions first.
+func (t *Trie) SetRank(word string, rank int) {
+	if node := t.find(word); node < 0 || !t.ends[node] {
+		return
+	}
+	node := 0
+	for _, ch := range word {
+		if t.best[node] == 0 || int32(rank) < t.best[node] {
+			t.best[node] = int32(rank)
+		}
+		node = t.child(node, ch)
+	}
+	t.rank[node] = int32(rank)
+	if t.best[node] == 0 || int32(rank) < t.best[node] {
+		t.best[node] = int32(rank)
+	}
+}
+
+// full reports whether words has reached limit, where a limit of zero or
+// less is no limit at all.
+func full(words []string, limit int) bool {
+	return limit > 0 && len(words) >= limit
+}
+
+// collectWords gathers the unranked words below node, in code point
+// order, until there are limit words. Ranked words are collected by
+// collectRanked instead.
+func (t *Trie) collectWords(node int, prefix []rune, words *[]string, limit int) {
+	if full(*words, limit) {
+		return
+	}
+	if t.ends[node] && t.rank[node] == 0 {
+		*words = append(*words, string(prefix))
+		if full(*words, limit) {
+			return
+		}
+	}
+	for c := int(t.first[node]); c < int(t.first[node+1]); c++ {
+		t.collectWords(c, append(prefix, t.labels[c]), words, limit)
+		if full(*words, limit) {
+			return
+		}
+	}
+}
+
+// trieItem is either a subtree to explore, keyed by the best rank in it, or
+// a ranked word ready to be returned, keyed by its own rank.
+type trieItem struct {
+	key    int32
+	node   int
+	prefix string
+	isWord bool
+}
+
+type trieQueue []trieItem
+
+func (q trieQueue) Len() int           { return len(q) }
+func (q trieQueue) Less(i, j int) bool { return q[i].key < q[j].key }
+func (q trieQueue) Swap(i, j int)      { q[i], q[j] = q[j], q[i] }
+func (q *trieQueue) Push(x any)        { *q = append(*q, x.(trieItem)) }
+func (q *trieQueue) Pop() any {
+	old := *q
+	item := old[len(old)-1]
+	*q = old[:len(old)-1]
+	return item
+}
+
+// collectRanked gathers the ranked words below node (not node itself), most
+// frequent first, with a best-first search over the subtrees' best ranks,
+// until there are limit words.
+func (t *Trie) collectRanked(node int, prefix string, words *[]string, limit int) {
+	queue := &trieQueue{}
+	push := func(n int, p string) {
+		for c := int(t.first[n]); c < int(t.first[n+1]); c++ {
+			if t.best[c] > 0 {
+				heap.Push(queue, trieItem{t.best[c], c, p + string(t.labels[c]), false})
+			}
+		}
+	}
+	push(node, prefix)
+	for queue.Len() > 0 && !full(*words, limit) {
+		item := heap.Pop(queue).(trieItem)
+		if item.isWord {
+			*words = append(*words, item.prefix)
+			continue
+		}
+		if t.rank[item.node] > 0 {
+			heap.Push(queue, trieItem{t.rank[item.node], item.node, item.prefix, true})
+		}
+		push(item.node, item.prefix)
+	}
+}
+
+// FindWords returns up to MaxResults words starting with prefix:
+// the prefix itself if it's a word, then the completions with a known
+// frequency, most common first, then the rest alphabetically.
+func (t *Trie) FindWords(prefix string) []string {
+	return t.FindWordsN(prefix, MaxResults)
+}
+
+// FindWordsN is FindWords returning up to limit words instead. A limit of
+// zero or less returns every match.
+func (t *Trie) FindWordsN(prefix string, limit int) []string {
+	node := t.find(prefix)
+	if node < 0 {
+		return []string{}
+	}
+	var words []string
+	if t.ends[node] {
+		words = append(words, prefix)
+	}
+	t.collectRanked(node, prefix, &words, limit)
+	ranked := len(words)
+	buf := []rune(prefix)
+	for c := int(t.first[node]); c < int(t.first[node+1]) && !full(words, limit); c++ {
+		t.collectWords(c, append(buf, t.labels[c]), &words, limit)
+	}
+	// The trie holds its letters in code point order, which puts "Aalto"
+	// far from "aalto" and ä before å, so the unranked words are put in
+	// Finnish order once collected.
+	SortFinnish(words[ranked:])
+	return words
+}
+
+// Count returns how many words start with prefix, the prefix itself
+// included, however many of them FindWords would return.
+func (t *Trie) Count(prefix string) int {
+	node := t.find(prefix)
+	if node < 0 {
+		return 0
+	}
+	count := 0
+	for stack := []int{node}; len(stack) > 0; {
+		n := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		if t.ends[n] {
+			count++
+		}
+		for c := int(t.first[n]); c < int(t.first[n+1]); c++ {
+			stack = append(stack, c)
+		}
+	}
+	return count
+}
+
+// Glob returns up to limit words matching pattern, alphabetically, where
+// '?' stands for any one letter and '*' for anything at all, even nothing:
+// "k?ssa", "ta*o". Rather than trying every word, it walks the trie
+// keeping the places in pattern the letters so far could have reached, and
+// leaves a branch once there are none. A limit of zero or less returns
+// every match.
+func (t *Trie) Glob(pattern string, limit int) []string {
+	p := []rune(pattern)
+	// reach adds place i to places, along with the places after any stars
+	// starting there, since a star may match nothing.
+	reach := func(places []int, i int) []int {
+		for {
+			if !slices.Contains(places, i) {
+				places = append(places, i)
+			}
+			if i == len(p) || p[i] != '*' {
+				return places
+			}
+			i++
+		}
+	}
+	var words []string
+	var walk func(node int, prefix []rune, places []int) bool
+	walk = func(node int, prefix []rune, places []int) bool {
+		if t.ends[node] && slices.Contains(places, len(p)) {
+			words = append(words, string(prefix))
+			if limit > 0 && len(words) >= limit {
+				return false
+			}
+		}
+		for c := int(t.first[node]); c < int(t.first[node+1]); c++ {
+			ch := t.labels[c]
+			var next []int
+			for _, i := range places {
+				switch {
+				case i == len(p):
+				case p[i] == '*':
+					next = reach(next, i)
+				case p[i] == ch, p[i] == '?' && unicode.IsLetter(ch):
+					next = reach(next, i+1)
+				}
+			}
+			if len(next) > 0 && !walk(c, append(prefix, ch), next) {
+				return false
+			}
+		}
+		return true
+	}
+	walk(0, nil, reach(nil, 0))
+	SortFinnish(words)
+	return words
+}
+
+// CountNodes is how many nodes the trie has, counting the root.
+func (t *Trie) CountNodes() int {
+	return len(t.labels)
+}
+
+// trieNodeSize is the bytes a node takes: its label, first child, end
+// flag, rank and best rank.
+const trieNodeSize = 4 + 4 + 1 + 4 + 4
+
+// SizeBytes is roughly how much memory the trie's nodes take up.
+func (t *Trie) SizeBytes() int {
+	return len(t.labels) * trieNodeSize
+}
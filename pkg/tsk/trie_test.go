@@ -0,0 +1,21 @@
+package tsk
+
+import "testing"
+
+func BenchmarkNewTrie(b *testing.B) {
+	words := benchDictionary(b).Words()
+	b.ReportAllocs()
+	for b.Loop() {
+		NewTrie(words)
+	}
+}
+
+func BenchmarkFindWords(b *testing.B) {
+	t := benchDictionary(b).Trie()
+	b.ReportAllocs()
+	for b.Loop() {
+		for _, prefix := range benchPrefixes {
+			t.FindWords(prefix)
+		}
+	}
+}
@@ -0,0 +1,236 @@
+// Package tskui is tsk's dictionary as a tview widget: a search bar over a
+// list of the matching headwords, next to the selected word's glosses, for
+// flashcard apps, readers and other terminal programs to put in their own
+// layout.
+//
+//	d := tsk.New(words, glosses)
+//	w := tskui.NewDictionaryWidget(d).
+//		SetSelectedFunc(func(word string) { addCard(word) })
+//	flex.AddItem(w, 0, 1, true)
+//
+// The widget searches as tsk's own search bar does, by prefix, ending,
+// pattern, inflected form and English meaning (see tsk.Dictionary.Search).
+// Up/Down and PgUp/PgDn in the search bar move through the list, Tab and
+// Shift-Tab scroll the glosses, Enter picks the selected word and Esc is
+// handed to the done func.
+package tskui
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/hiAndrewQuinn/tsk/pkg/tsk"
+	"github.com/rivo/tview"
+)
+
+const (
+	searchLabel = "Search: "
+	fuzzyLabel  = "Did you mean: "
+)
+
+// DictionaryWidget is a search bar and word list next to the selected
+// word's glosses. It is a tview.Primitive, so it goes in a Flex, Grid or
+// Pages like any other, and it can be given a border and title as a Box.
+type DictionaryWidget struct {
+	*tview.Flex
+	dict    *tsk.Dictionary
+	input   *tview.InputField
+	list    *tview.List
+	details *tview.TextView
+
+	result   tsk.SearchResult
+	query    string
+	selected func(word string)
+	changed  func(word string)
+	done     func(key tcell.Key)
+}
+
+// NewDictionaryWidget returns a widget searching dict, with an empty
+// search bar.
+func NewDictionaryWidget(dict *tsk.Dictionary) *DictionaryWidget {
+	w := &DictionaryWidget{
+		dict:    dict,
+		input:   tview.NewInputField().SetLabel(searchLabel),
+		list:    tview.NewList().ShowSecondaryText(false),
+		details: tview.NewTextView(),
+	}
+	w.list.SetHighlightFullLine(true)
+	w.details.SetDynamicColors(true).
+		SetWrap(true).
+		SetWordWrap(true).
+		SetBorder(true).
+		SetTitle("Word Details")
+
+	w.input.SetChangedFunc(w.search)
+	w.input.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		switch event.Key() {
+		case tcell.KeyUp, tcell.KeyDown, tcell.KeyPgUp, tcell.KeyPgDn:
+			// The focus stays in the search bar, so typing goes on
+			// searching; the arrows move through the list from there.
+			w.list.InputHandler()(event, func(tview.Primitive) {})
+			return nil
+		case tcell.KeyTab, tcell.KeyBacktab:
+			row, col := w.details.GetScrollOffset()
+			if event.Key() == tcell.KeyTab {
+				row++
+			} else {
+				row = max(row-1, 0)
+			}
+			w.details.ScrollTo(row, col)
+			return nil
+		}
+		return event
+	})
+	w.input.SetDoneFunc(func(key tcell.Key) {
+		switch key {
+		case tcell.KeyEnter:
+			if word := w.Word(); word != "" && w.selected != nil {
+				w.selected(word)
+			}
+		case tcell.KeyEscape:
+			if w.done != nil {
+				w.done(key)
+			}
+		}
+	})
+	w.list.SetChangedFunc(func(_ int, _ string, word string, _ rune) {
+		w.show(word)
+	})
+	w.list.SetSelectedFunc(func(_ int, _ string, word string, _ rune) {
+		if w.selected != nil {
+			w.selected(word)
+		}
+	})
+
+	left := tview.NewFlex().SetDirection(tview.FlexRow).
+		AddItem(w.input, 1, 0, true).
+		AddItem(w.list, 0, 1, false)
+	w.Flex = tview.NewFlex().
+		AddItem(left, 0, 1, true).
+		AddItem(w.details, 0, 2, false)
+	return w
+}
+
+// SetQuery puts query in the search bar and searches for it, as if it had
+// been typed.
+func (w *DictionaryWidget) SetQuery(query string) *DictionaryWidget {
+	w.input.SetText(query)
+	return w
+}
+
+// Query returns what is in the search bar.
+func (w *DictionaryWidget) Query() string {
+	return w.input.GetText()
+}
+
+// Word returns the headword selected in the list, or "" if the list is
+// empty.
+func (w *DictionaryWidget) Word() string {
+	if w.list.GetItemCount() == 0 {
+		return ""
+	}
+	_, word := w.list.GetItemText(w.list.GetCurrentItem())
+	return word
+}
+
+// Result returns the search the list shows, with how the query was taken.
+func (w *DictionaryWidget) Result() tsk.SearchResult {
+	return w.result
+}
+
+// SetSelectedFunc sets the handler called with the selected word when
+// Enter is pressed, or a word in the list is clicked twice.
+func (w *DictionaryWidget) SetSelectedFunc(handler func(word string)) *DictionaryWidget {
+	w.selected = handler
+	return w
+}
+
+// SetChangedFunc sets the handler called with each word whose glosses the
+// widget goes on to show.
+func (w *DictionaryWidget) SetChangedFunc(handler func(word string)) *DictionaryWidget {
+	w.changed = handler
+	return w
+}
+
+// SetDoneFunc sets the handler called when Esc is pressed in the search
+// bar, for instance to close the widget.
+func (w *DictionaryWidget) SetDoneFunc(handler func(key tcell.Key)) *DictionaryWidget {
+	w.done = handler
+	return w
+}
+
+// search lists the words matching query and shows the first one.
+func (w *DictionaryWidget) search(query string) {
+	w.query = query
+	w.result = w.dict.Search(query)
+	w.list.Clear()
+	w.details.Clear()
+	w.input.SetLabel(searchLabel)
+	if w.result.Kind == tsk.SearchFuzzy && len(w.result.Words) > 0 {
+		w.input.SetLabel(fuzzyLabel)
+	}
+	for _, word := range w.result.Words {
+		display := tview.Escape(word)
+		if parts := tsk.PartsOfSpeech(w.dict.Lookup(word)); len(parts) > 0 {
+			display += " [yellow]" + tview.Escape(strings.Join(parts, "/")) + "[-]"
+		}
+		// The first word added is selected, and shown by the changed func.
+		w.list.AddItem(display, word, 0, nil)
+	}
+}
+
+// show puts word's glosses in the details pane.
+func (w *DictionaryWidget) show(word string) {
+	var b strings.Builder
+	if forms := w.result.Forms[word]; len(forms) > 0 && w.query != word {
+		fmt.Fprintf(&b, "[gray]%s ~> %s (%s)[-]\n\n", tview.Escape(strings.TrimSpace(w.query)), tview.Escape(word), tview.Escape(strings.Join(forms, ", ")))
+	}
+	b.WriteString(GlossText(word, w.dict.Lookup(word)))
+	w.details.SetText(b.String())
+	w.details.ScrollToBeginning()
+	if w.changed != nil {
+		w.changed(word)
+	}
+}
+
+// GlossText lays out a word's glosses with tview color tags, as tsk's Word
+// Details do: each part of speech under a heading, with the senses
+// numbered if there is more than one.
+func GlossText(word string, glosses []tsk.Gloss) string {
+	if len(glosses) == 0 {
+		return tview.Escape(word) + "\n\nNo gloss available."
+	}
+	senses := 0
+	for _, g := range glosses {
+		senses += len(g.Meanings)
+	}
+	var b strings.Builder
+	n := 0
+	for i, g := range glosses {
+		if i > 0 {
+			b.WriteString("\n")
+		}
+		fmt.Fprintf(&b, "[::b]%s[::-] [yellow](%s)[-]\n\n", tview.Escape(g.Word), tview.Escape(g.Pos))
+		for _, meaning := range g.Meanings {
+			n++
+			bullet := "-"
+			if senses > 1 {
+				bullet = fmt.Sprintf("%d.", n)
+			}
+			fmt.Fprintf(&b, "%s %s\n", bullet, tview.Escape(meaning))
+			for _, example := range g.Examples[meaning] {
+				fmt.Fprintf(&b, "  [gray::i]%s[-::-]\n", tview.Escape(example))
+			}
+		}
+		for _, related := range []struct {
+			name  string
+			words []string
+		}{{"Synonyms", g.Synonyms}, {"Antonyms", g.Antonyms}} {
+			if len(related.words) > 0 {
+				fmt.Fprintf(&b, "[gray]%s: %s[-]\n", related.name, tview.Escape(strings.Join(related.words, ", ")))
+			}
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,411 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+)
+
+// ----------------------
+// Platform Differences
+// ----------------------
+
+// platform is how tsk reaches the desktop on one operating system: the
+// command that opens links and the one text is copied through. Keeping
+// each system's commands in one value, instead of a switch in every
+// caller, keeps them side by side where they can be read and checked
+// together.
+type platform struct {
+	// openCommand returns the command that opens link in the default
+	// browser.
+	openCommand func(link string) *exec.Cmd
+	// clipboardCommand returns the command text is piped into to copy it,
+	// or an error if there is no clipboard to reach.
+	clipboardCommand func() (*exec.Cmd, error)
+	// clipboardBytes encodes text the way clipboardCommand reads it.
+	clipboardBytes func(text string) []byte
+}
+
+// unixPlatform is Linux's, and any other Unix's with a freedesktop
+// session, copying with whichever of wl-copy, xclip and xsel fits.
+var unixPlatform = platform{
+	openCommand: func(link string) *exec.Cmd { return exec.Command("xdg-open", link) },
+	clipboardCommand: func() (*exec.Cmd, error) {
+		switch {
+		case os.Getenv("WAYLAND_DISPLAY") != "":
+			return exec.Command("wl-copy"), nil
+		case os.Getenv("DISPLAY") != "":
+			if _, err := exec.LookPath("xclip"); err == nil {
+				return exec.Command("xclip", "-selection", "clipboard"), nil
+			}
+			return exec.Command("xsel", "--clipboard", "--input"), nil
+		}
+		return nil, fmt.Errorf("no graphical session to copy to")
+	},
+	clipboardBytes: func(text string) []byte { return []byte(text) },
+}
+
+// platforms maps runtime.GOOS to its platform.
+var platforms = map[string]platform{
+	"linux": unixPlatform,
+	"darwin": {
+		openCommand:      func(link string) *exec.Cmd { return exec.Command("open", link) },
+		clipboardCommand: func() (*exec.Cmd, error) { return exec.Command("pbcopy"), nil },
+		clipboardBytes:   func(text string) []byte { return []byte(text) },
+	},
+	"windows": {
+		// rundll32 hands the link straight to the shell's URL handler, where
+		// `cmd /c start` would split it at every & of a query string.
+		openCommand:      func(link string) *exec.Cmd { return exec.Command("rundll32", "url.dll,FileProtocolHandler", link) },
+		clipboardCommand: func() (*exec.Cmd, error) { return exec.Command("clip"), nil },
+		clipboardBytes:   windowsClipboardText,
+	},
+}
+
+// currentPlatform returns the platform tsk is running on.
+func currentPlatform() platform {
+	return platformFor(runtime.GOOS)
+}
+
+// platformFor returns the platform of the operating system goos, as
+// runtime.GOOS names it. Other Unixes are taken to be like Linux.
+func platformFor(goos string) platform {
+	if p, ok := platforms[goos]; ok {
+		return p
+	}
+	return unixPlatform
+}
+
+// windowsClipboardText encodes text for clip, which reads UTF-8 as the
+// console's code page, turning ä into Ã¤, unless it starts with a UTF-16
+// byte order mark. Lines end in CRLF, as Windows programs expect when the
+// text is pasted.
+func windowsClipboardText(text string) []byte {
+	text = strings.ReplaceAll(strings.ReplaceAll(text, "\r\n", "\n"), "\n", "\r\n")
+	units := utf16.Encode([]rune(text))
+	b := make([]byte, 2, 2+2*len(units))
+	binary.LittleEndian.PutUint16(b, 0xFEFF)
+	for _, u := range units {
+		b = binary.LittleEndian.AppendUint16(b, u)
+	}
+	return b
+}
+
+// openBrowser opens link in the default browser, without waiting for it.
+func openBrowser(link string) error {
+	return currentPlatform().openCommand(link).Start()
+}
+
+// tskConfigDir returns the directory tsk keeps its files in, under the
+// user's config directory: ~/.config/tsk on Linux, ~/Library/Application
+// Support/tsk on macOS and %AppData%\tsk on Windows.
+func tskConfigDir() (string, error) {
+	configDir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(configDir, "tsk"), nil
+}
+
+// sqliteFileURI is a file: URI for the SQLite database at path, with the
+// query parameters in query. Windows paths get the slash SQLite wants
+// before their drive letter, file:///C:/..., and characters that mean
+// something in a URI are escaped so a path containing them opens as is.
+func sqliteFileURI(path, query string) string {
+	return slashFileURI(filepath.ToSlash(path), filepath.VolumeName(path), query)
+}
+
+// slashFileURI is sqliteFileURI for a path already in forward slashes and
+// its volume name, if it has one, so that it works the same on any system.
+func slashFileURI(path, volume, query string) string {
+	uri := strings.NewReplacer("%", "%25", "?", "%3f", "#", "%23").Replace(path)
+	if volume != "" && !strings.HasPrefix(uri, "/") {
+		uri = "/" + uri
+	}
+	if strings.HasPrefix(uri, "/") {
+		uri = "//" + uri
+	}
+	return "file:" + uri + "?" + query
+}
+
+// windowsReservedNames are the device names Windows won't create a file
+// under, whatever the extension.
+var windowsReservedNames = []string{"CON", "PRN", "AUX", "NUL",
+	"COM1", "COM2", "COM3", "COM4", "COM5", "COM6", "COM7", "COM8", "COM9",
+	"LPT1", "LPT2", "LPT3", "LPT4", "LPT5", "LPT6", "LPT7", "LPT8", "LPT9"}
+
+// safeFileName makes name, e.g. an export file named after an export
+// profile from config.json, one every platform can create: the
+// characters Windows forbids become '_', and so do trailing dots and
+// spaces, which it drops, and a name it reserves for a device.
+func safeFileName(name string) string {
+	name = strings.Map(func(r rune) rune {
+		if r < ' ' || strings.ContainsRune(`<>:"/\|?*`, r) {
+			return '_'
+		}
+		return r
+	}, name)
+	trimmed := strings.TrimRight(name, ". ")
+	name = trimmed + strings.Repeat("_", len(name)-len(trimmed))
+	base, _, _ := strings.Cut(name, ".")
+	if slices.Contains(windowsReservedNames, strings.ToUpper(base)) {
+		name = "_" + name
+	}
+	return name
+}
+
+// ----------------------
+// Wiktionary Links (`--wiktionary`)
+// ----------------------
+
+// DEFAULT_WIKTIONARY is the Wiktionary edition words are opened in.
+const DEFAULT_WIKTIONARY = "en"
+
+// wiktionaryEdition is the language code of the Wiktionary edition, the
+// subdomain of wiktionary.org, the Wiktionary key opens words in, e.g.
+// "fi" for the entries written in Finnish.
+var wiktionaryEdition = DEFAULT_WIKTIONARY
+
+// useWiktionary makes edition the one words are opened in.
+func useWiktionary(edition string) error {
+	if len(edition) < 2 || strings.IndexFunc(edition, func(r rune) bool { return r < 'a' || r > 'z' }) >= 0 {
+		return fmt.Errorf("'%s' is not a Wiktionary edition (try e.g. en or fi)", edition)
+	}
+	wiktionaryEdition = edition
+	return nil
+}
+
+// wiktionaryURL is word's page on Wiktionary's edition. The English
+// edition puts every language on one page, so it jumps to the section for
+// language, e.g. https://en.wiktionary.org/wiki/talo#Finnish.
+func wiktionaryURL(edition, word, language string) string {
+	link := fmt.Sprintf("https://%s.wiktionary.org/wiki/%s", edition, url.PathEscape(strings.ReplaceAll(word, " ", "_")))
+	if edition == "en" && language != "" {
+		link += "#" + strings.ReplaceAll(language, " ", "_")
+	}
+	return link
+}
+
+// ----------------------
+// Utility: Copy to the system clipboard
+// ----------------------
+
+// copyToClipboard pipes text into the platform's clipboard tool. Terminals
+// that support OSC 52 get the text through the tcell screen as well, which
+// also reaches the clipboard of an ssh guest; this covers the rest.
+func copyToClipboard(text string) error {
+	p := currentPlatform()
+	cmd, err := p.clipboardCommand()
+	if err != nil {
+		return err
+	}
+	cmd.Stdin = bytes.NewReader(p.clipboardBytes(text))
+	return cmd.Run()
+}
+
+// ----------------------
+// Utility: Speak a word aloud
+// ----------------------
+
+// speechLanguages maps a dictionary pack's language to the ISO 639-1 code
+// the speech synthesizers pick their voices by.
+var speechLanguages = map[string]string{
+	"Danish":    "da",
+	"English":   "en",
+	"Estonian":  "et",
+	"Finnish":   "fi",
+	"French":    "fr",
+	"German":    "de",
+	"Hungarian": "hu",
+	"Norwegian": "nb",
+	"Russian":   "ru",
+	"Spanish":   "es",
+	"Swedish":   "sv",
+}
+
+// sapiSpeakScript speaks $env:TSK_SPEAK_TEXT through Windows' SAPI, with a
+// voice for $env:TSK_SPEAK_LANG if one is installed, at SAPI's
+// $env:TSK_SPEAK_RATE. The text goes through the environment so it never
+// has to be quoted for PowerShell.
+const sapiSpeakScript = `Add-Type -AssemblyName System.Speech
+$s = New-Object System.Speech.Synthesis.SpeechSynthesizer
+$v = $s.GetInstalledVoices() | Where-Object { $_.VoiceInfo.Culture.TwoLetterISOLanguageName -eq $env:TSK_SPEAK_LANG } | Select-Object -First 1
+if ($v) { $s.SelectVoice($v.VoiceInfo.Name) }
+$s.Rate = [int]$env:TSK_SPEAK_RATE
+$s.Speak($env:TSK_SPEAK_TEXT)`
+
+// Speech rates are percents of the synthesizer's normal speed, which
+// espeak and say put at NORMAL_WORDS_PER_MINUTE.
+const (
+	DEFAULT_SPEECH_RATE     = 100
+	MIN_SPEECH_RATE         = 50
+	MAX_SPEECH_RATE         = 200
+	SPEECH_RATE_STEP        = 10
+	NORMAL_WORDS_PER_MINUTE = 175
+)
+
+// speechRate is how fast words and sentences are said at first, as a
+// percent of normal speed. Slower helps pick out the sounds of a sentence.
+var speechRate = DEFAULT_SPEECH_RATE
+
+// speechCommand is the command that says text aloud in the active pack's
+// language at rate percent of normal speed: espeak-ng (or espeak, or
+// spd-say) on Linux, say on macOS and SAPI on Windows. It is killed if ctx
+// is done first.
+func speechCommand(ctx context.Context, text string, rate int) (*exec.Cmd, error) {
+	// Suffix entries like "-kin" are said without the hyphen, which also
+	// keeps them from being read as command-line options.
+	text = strings.TrimLeft(text, "-")
+	if text == "" {
+		return nil, fmt.Errorf("nothing to say")
+	}
+	lang := speechLanguages[activePack.Meta.Language]
+	rate = min(max(rate, MIN_SPEECH_RATE), MAX_SPEECH_RATE)
+	wordsPerMinute := strconv.Itoa(NORMAL_WORDS_PER_MINUTE * rate / 100)
+
+	var cmd *exec.Cmd
+	switch runtime.GOOS {
+	case "windows":
+		// SAPI's rates run from -10 to 10, about three times slower to
+		// three times faster.
+		sapiRate := int(math.Round(10 * math.Log(float64(rate)/100) / math.Log(3)))
+		cmd = exec.CommandContext(ctx, "powershell", "-NoProfile", "-NonInteractive", "-Command", sapiSpeakScript)
+		cmd.Env = append(os.Environ(), "TSK_SPEAK_TEXT="+text, "TSK_SPEAK_LANG="+lang, "TSK_SPEAK_RATE="+strconv.Itoa(sapiRate))
+	case "darwin":
+		args := []string{"-r", wordsPerMinute, text}
+		if voice := sayVoice(lang); voice != "" {
+			args = append([]string{"-v", voice}, args...)
+		}
+		cmd = exec.CommandContext(ctx, "say", args...)
+	default:
+		for _, tool := range []string{"espeak-ng", "espeak", "spd-say"} {
+			if _, err := exec.LookPath(tool); err != nil {
+				continue
+			}
+			var args []string
+			if tool == "spd-say" {
+				// spd-say's rates run from -100 to 100, and it returns
+				// before it has finished speaking unless told to wait.
+				args = append(args, "-w", "-r", strconv.Itoa(rate-100))
+				if lang != "" {
+					args = append(args, "-l", lang)
+				}
+			} else {
+				args = append(args, "-s", wordsPerMinute)
+				if lang != "" {
+					args = append(args, "-v", lang)
+				}
+			}
+			cmd = exec.CommandContext(ctx, tool, append(args, text)...)
+			break
+		}
+		if cmd == nil {
+			return nil, fmt.Errorf("no speech synthesizer found; install espeak-ng")
+		}
+	}
+	return cmd, nil
+}
+
+// speak says text aloud at rate percent of normal speed. It returns once
+// the synthesizer has started and lets it finish on its own.
+func speak(text string, rate int) error {
+	cmd, err := speechCommand(context.Background(), text, rate)
+	if err != nil {
+		return err
+	}
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+	go cmd.Wait()
+	return nil
+}
+
+// speakAndWait says text aloud at rate percent of normal speed and returns
+// once it has been said, or as soon as ctx is done.
+func speakAndWait(ctx context.Context, text string, rate int) error {
+	cmd, err := speechCommand(ctx, text, rate)
+	if err != nil {
+		return err
+	}
+	if err := cmd.Run(); err != nil && ctx.Err() == nil {
+		return err
+	}
+	return nil
+}
+
+// mediaPlayerScript plays the audio file $env:TSK_AUDIO_FILE with Windows'
+// own MediaPlayer, waiting for it to finish since it stops with PowerShell.
+const mediaPlayerScript = `Add-Type -AssemblyName PresentationCore
+$p = New-Object System.Windows.Media.MediaPlayer
+$p.Open([Uri]$env:TSK_AUDIO_FILE)
+$p.Play()
+while (-not $p.NaturalDuration.HasTimeSpan) { Start-Sleep -Milliseconds 50 }
+Start-Sleep -Milliseconds $p.NaturalDuration.TimeSpan.TotalMilliseconds`
+
+// playAudio plays an audio file, such as a recording from the audio pack,
+// with afplay on macOS, MediaPlayer on Windows and mpv, ffplay or mpg123 on
+// Linux. Like speak, it returns once the player has started.
+func playAudio(path string) error {
+	var cmd *exec.Cmd
+	switch runtime.GOOS {
+	case "windows":
+		cmd = exec.Command("powershell", "-NoProfile", "-NonInteractive", "-Command", mediaPlayerScript)
+		cmd.Env = append(os.Environ(), "TSK_AUDIO_FILE="+path)
+	case "darwin":
+		cmd = exec.Command("afplay", path)
+	default:
+		for _, player := range [][]string{
+			{"mpv", "--no-video", "--really-quiet"},
+			{"ffplay", "-nodisp", "-autoexit", "-loglevel", "quiet"},
+			{"mpg123", "-q"},
+		} {
+			if _, err := exec.LookPath(player[0]); err != nil {
+				continue
+			}
+			cmd = exec.Command(player[0], append(player[1:], path)...)
+			break
+		}
+		if cmd == nil {
+			return fmt.Errorf("no audio player found; install mpv, ffmpeg or mpg123")
+		}
+	}
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+	go cmd.Wait()
+	return nil
+}
+
+// sayVoice finds a macOS voice for lang in the list `say -v ?` prints,
+// lines like "Satu    fi_FI    # Hei! Nimeni on Satu.".
+func sayVoice(lang string) string {
+	if lang == "" {
+		return ""
+	}
+	out, err := exec.Command("say", "-v", "?").Output()
+	if err != nil {
+		return ""
+	}
+	for _, line := range strings.Split(string(out), "\n") {
+		fields := strings.Fields(strings.SplitN(line, "#", 2)[0])
+		if len(fields) < 2 {
+			continue
+		}
+		// Voice names can have spaces; the locale is the last field.
+		if locale := fields[len(fields)-1]; strings.HasPrefix(locale, lang+"_") {
+			return strings.Join(fields[:len(fields)-1], " ")
+		}
+	}
+	return ""
+}
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"bytes"
+	"slices"
+	"testing"
+)
+
+func TestPlatformFor(t *testing.T) {
+	const link = "https://en.wiktionary.org/wiki/talo?a=1&b=2"
+	tests := []struct {
+		goos      string
+		open      []string
+		clipboard []string
+	}{
+		{"darwin", []string{"open", link}, []string{"pbcopy"}},
+		{"windows", []string{"rundll32", "url.dll,FileProtocolHandler", link}, []string{"clip"}},
+		{"linux", []string{"xdg-open", link}, []string{"wl-copy"}},
+		{"freebsd", []string{"xdg-open", link}, []string{"wl-copy"}},
+	}
+	t.Setenv("WAYLAND_DISPLAY", "wayland-0")
+	for _, tt := range tests {
+		p := platformFor(tt.goos)
+		if got := p.openCommand(link).Args; !slices.Equal(got, tt.open) {
+			t.Errorf("%s: openCommand = %q, want %q", tt.goos, got, tt.open)
+		}
+		cmd, err := p.clipboardCommand()
+		if err != nil {
+			t.Errorf("%s: clipboardCommand: %v", tt.goos, err)
+		} else if !slices.Equal(cmd.Args, tt.clipboard) {
+			t.Errorf("%s: clipboardCommand = %q, want %q", tt.goos, cmd.Args, tt.clipboard)
+		}
+	}
+}
+
+func TestUnixClipboardCommand(t *testing.T) {
+	// With no xclip on the PATH, an X session copies with xsel.
+	t.Setenv("PATH", t.TempDir())
+	tests := []struct {
+		wayland, display string
+		want             []string
+	}{
+		{"wayland-0", ":0", []string{"wl-copy"}},
+		{"", ":0", []string{"xsel", "--clipboard", "--input"}},
+		{"", "", nil},
+	}
+	for _, tt := range tests {
+		t.Setenv("WAYLAND_DISPLAY", tt.wayland)
+		t.Setenv("DISPLAY", tt.display)
+		cmd, err := unixPlatform.clipboardCommand()
+		switch {
+		case tt.want == nil && err == nil:
+			t.Errorf("WAYLAND_DISPLAY=%q DISPLAY=%q: clipboardCommand = %q, want an error", tt.wayland, tt.display, cmd.Args)
+		case tt.want != nil && err != nil:
+			t.Errorf("WAYLAND_DISPLAY=%q DISPLAY=%q: clipboardCommand: %v", tt.wayland, tt.display, err)
+		case tt.want != nil && !slices.Equal(cmd.Args, tt.want):
+			t.Errorf("WAYLAND_DISPLAY=%q DISPLAY=%q: clipboardCommand = %q, want %q", tt.wayland, tt.display, cmd.Args, tt.want)
+		}
+	}
+}
+
+func TestWindowsClipboardText(t *testing.T) {
+	bom := []byte{0xFF, 0xFE}
+	tests := []struct {
+		text string
+		want []byte
+	}{
+		{"", bom},
+		{"äö", append(bom, 0xE4, 0, 0xF6, 0)},
+		{"a\nb", append(bom, 'a', 0, '\r', 0, '\n', 0, 'b', 0)},
+		{"a\r\nb", append(bom, 'a', 0, '\r', 0, '\n', 0, 'b', 0)},
+		{"𝄞", append(bom, 0x34, 0xD8, 0x1E, 0xDD)}, // outside the BMP, a surrogate pair
+	}
+	for _, tt := range tests {
+		if got := windowsClipboardText(tt.text); !bytes.Equal(got, tt.want) {
+			t.Errorf("windowsClipboardText(%q) = % x, want % x", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestSlashFileURI(t *testing.T) {
+	tests := []struct {
+		path, volume, want string
+	}{
+		{"/home/me/.cache/tsk/examples.sqlite", "", "file:///home/me/.cache/tsk/examples.sqlite?immutable=1"},
+		{"C:/Users/me/AppData/Local/tsk/examples.sqlite", "C:", "file:///C:/Users/me/AppData/Local/tsk/examples.sqlite?immutable=1"},
+		{"//server/share/tsk/examples.sqlite", "//server/share", "file:////server/share/tsk/examples.sqlite?immutable=1"},
+		{"/home/me/what?#/examples.sqlite", "", "file:///home/me/what%3f%23/examples.sqlite?immutable=1"},
+		{"/home/me/100%/examples.sqlite", "", "file:///home/me/100%25/examples.sqlite?immutable=1"},
+		{"examples.sqlite", "", "file:examples.sqlite?immutable=1"},
+	}
+	for _, tt := range tests {
+		if got := slashFileURI(tt.path, tt.volume, "immutable=1"); got != tt.want {
+			t.Errorf("slashFileURI(%q, %q) = %q, want %q", tt.path, tt.volume, got, tt.want)
+		}
+	}
+}
+
+func TestSafeFileName(t *testing.T) {
+	tests := []struct {
+		name, want string
+	}{
+		{"anki.csv", "anki.csv"},
+		{"chapter 7: verbs?.txt", "chapter 7_ verbs_.txt"},
+		{`a/b\c*d|e<f>g"h`, "a_b_c_d_e_f_g_h"},
+		{"tab\there", "tab_here"},
+		{"notes.", "notes_"},
+		{"notes. .", "notes___"},
+		{"CON", "_CON"},
+		{"nul.txt", "_nul.txt"},
+		{"Com1.tar.gz", "_Com1.tar.gz"},
+		{"LPT9", "_LPT9"},
+		{"console.txt", "console.txt"},
+		{"COM10", "COM10"},
+		{"sanat äöå.txt", "sanat äöå.txt"},
+	}
+	for _, tt := range tests {
+		if got := safeFileName(tt.name); got != tt.want {
+			t.Errorf("safeFileName(%q) = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
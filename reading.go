@@ -0,0 +1,336 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/hiAndrewQuinn/tsk/pkg/tsk"
+)
+
+// ----------------------
+// Reading Assistant (`tsk read`)
+// ----------------------
+
+// readingReport splits a text into the words we have glosses for and the
+// ones we don't, each sorted and without duplicates.
+type readingReport struct {
+	known   []string
+	unknown []string
+}
+
+// tokenizeFinnish splits running text into word tokens. Hyphens are kept
+// inside words so that compounds like "EU-maa" stay whole.
+func tokenizeFinnish(text string) []string {
+	fields := strings.FieldsFunc(text, func(r rune) bool {
+		return !unicode.IsLetter(r) && r != '-'
+	})
+	var tokens []string
+	for _, field := range fields {
+		if token := strings.Trim(field, "-"); token != "" {
+			tokens = append(tokens, token)
+		}
+	}
+	return tokens
+}
+
+// lookupHeadword returns the headword under which word has glosses, trying
+// the word as written, then in lowercase, as sentence-initial words are
+// capitalised, and finally as an inflected form of some base form.
+func lookupHeadword(word string, glosses map[string][]tsk.Gloss) (string, bool) {
+	if _, ok := glosses[word]; ok {
+		return word, true
+	}
+	if lower := strings.ToLower(word); lower != word {
+		if _, ok := glosses[lower]; ok {
+			return lower, true
+		}
+	}
+	if standards, _ := colloquialStandards(strings.ToLower(word), glosses); len(standards) > 0 {
+		return standards[0], true
+	}
+	if analyses := tsk.AnalyzeWord(word, glosses); len(analyses) > 0 {
+		return analyses[0].Lemma, true
+	}
+	return "", false
+}
+
+func buildReadingReport(text string, glosses map[string][]tsk.Gloss) readingReport {
+	known := make(map[string]struct{})
+	unknown := make(map[string]struct{})
+	for _, token := range tokenizeFinnish(text) {
+		if headword, ok := lookupHeadword(token, glosses); ok {
+			known[headword] = struct{}{}
+		} else {
+			unknown[strings.ToLower(token)] = struct{}{}
+		}
+	}
+
+	var report readingReport
+	for word := range known {
+		report.known = append(report.known, word)
+	}
+	for word := range unknown {
+		report.unknown = append(report.unknown, word)
+	}
+	sort.Strings(report.known)
+	sort.Strings(report.unknown)
+	return report
+}
+
+func printReadingReport(report readingReport, glosses map[string][]tsk.Gloss) {
+	fmt.Println("===")
+	fmt.Printf("Glossary (%d words):\n", len(report.known))
+	for _, word := range report.known {
+		for _, gloss := range glosses[word] {
+			meaning := ""
+			if len(gloss.Meanings) > 0 {
+				meaning = gloss.Meanings[0]
+			}
+			fmt.Printf("%s (%s) - %s\n", gloss.Word, gloss.Pos, meaning)
+		}
+	}
+	fmt.Println("---")
+	fmt.Printf("Unknown words (%d):\n", len(report.unknown))
+	for _, word := range report.unknown {
+		fmt.Println(word)
+	}
+	fmt.Println("===")
+}
+
+// diffWords returns the words in after that are not in before.
+func diffWords(before, after []string) []string {
+	seen := make(map[string]struct{}, len(before))
+	for _, word := range before {
+		seen[word] = struct{}{}
+	}
+	var added []string
+	for _, word := range after {
+		if _, ok := seen[word]; !ok {
+			added = append(added, word)
+		}
+	}
+	return added
+}
+
+// readTextSource reads a whole file, or standard input if path is "-".
+func readTextSource(path string) (string, error) {
+	if path == "-" {
+		data, err := ioutil.ReadAll(os.Stdin)
+		return string(data), err
+	}
+	data, err := os.ReadFile(path)
+	return string(data), err
+}
+
+// runReadCommand prints a glossary of the known words in a text and a list of
+// the unknown ones. With --watch, it keeps polling the file and reprints the
+// report whenever it changes, along with which unknown words came and went.
+func runReadCommand(args []string) error {
+	fs := newCommandFlags("read")
+	watch := fs.Bool("watch", false, "re-run the report whenever the file changes")
+	interval := fs.Duration("interval", 500*time.Millisecond, "how often to check the file for changes in --watch mode")
+	fs.Parse(args)
+
+	if fs.NArg() != 1 {
+		return fmt.Errorf("usage: tsk read [--watch] FILE (use - for standard input)")
+	}
+	path := fs.Arg(0)
+	if *watch && path == "-" {
+		return fmt.Errorf("--watch needs a file, not standard input")
+	}
+
+	glosses, err := loadGlosses()
+	if err != nil {
+		return fmt.Errorf("loading glosses: %w", err)
+	}
+
+	text, err := readTextSource(path)
+	if err != nil {
+		return err
+	}
+	report := buildReadingReport(text, glosses)
+	printReadingReport(report, glosses)
+	if !*watch {
+		return nil
+	}
+
+	fmt.Printf("Watching %s for changes. Press Ctrl-C to stop.\n", path)
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	lastMod, lastSize := info.ModTime(), info.Size()
+	for {
+		time.Sleep(*interval)
+		info, err := os.Stat(path)
+		if err != nil {
+			// Editors often replace the file on save; try again next tick.
+			continue
+		}
+		if info.ModTime().Equal(lastMod) && info.Size() == lastSize {
+			continue
+		}
+		lastMod, lastSize = info.ModTime(), info.Size()
+
+		text, err := readTextSource(path)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error reading %s: %v\n", path, err)
+			continue
+		}
+		previous := report
+		report = buildReadingReport(text, glosses)
+
+		fmt.Printf("\n%s changed at %s\n", path, time.Now().Format("15:04:05"))
+		printReadingReport(report, glosses)
+
+		added := diffWords(previous.unknown, report.unknown)
+		removed := diffWords(report.unknown, previous.unknown)
+		if len(added)+len(removed) > 0 {
+			fmt.Println("Unknown words since the last change:")
+		}
+		for _, word := range added {
+			fmt.Printf("+ %s\n", word)
+		}
+		for _, word := range removed {
+			fmt.Printf("- %s\n", word)
+		}
+	}
+}
+
+// ----------------------
+// Annotated Reading (`tsk --annotate`)
+// ----------------------
+
+// `cat article.txt | tsk --annotate` copies the text through as it arrives,
+// with a short gloss after every word tsk recognises: inline by default, or
+// as numbered footnotes after each paragraph. Where `tsk read` boils a text
+// down to a glossary, this keeps the text whole for reading straight through.
+
+// annotationWordPattern matches the words of running text, keeping hyphens
+// inside compounds like "EU-maa" as tokenizeFinnish does.
+var annotationWordPattern = regexp.MustCompile(`\p{L}+(?:-\p{L}+)*`)
+
+// ANNOTATION_MAX_MEANING caps how long an inline gloss can get.
+const ANNOTATION_MAX_MEANING = 40
+
+// annotation is the gloss --annotate puts after a word: the headword it was
+// found under and a short meaning.
+type annotation struct {
+	headword string
+	meaning  string
+}
+
+func (a annotation) String() string {
+	return a.headword + ": " + a.meaning
+}
+
+// annotateWord finds the headword and meaning for a word of running text,
+// following form-of glosses like "inessive singular of talo" to the base
+// form so the meaning is worth reading.
+func annotateWord(word string, glosses map[string][]tsk.Gloss) (annotation, bool) {
+	headword, ok := lookupHeadword(word, glosses)
+	if !ok {
+		return annotation{}, false
+	}
+	if meaning := firstMeaning(headword, glosses); meaning != "" {
+		return annotation{headword, shortMeaning(meaning)}, true
+	}
+	for _, g := range glosses[headword] {
+		for _, meaning := range g.Meanings {
+			lemma := strings.TrimSuffix(strings.TrimPrefix(meaning, "inflection of "), ":")
+			if _, base, ok := parseFormOf(meaning); ok {
+				lemma = base
+			}
+			if m := firstMeaning(lemma, glosses); m != "" {
+				return annotation{lemma, shortMeaning(m)}, true
+			}
+		}
+	}
+	return annotation{}, false
+}
+
+// parenthesesPattern matches asides like "(Felis catus)" in a meaning.
+var parenthesesPattern = regexp.MustCompile(`\s*\([^()]*\)`)
+
+// shortMeaning cuts a meaning down to its first sense, e.g. "cat" from
+// "cat (Felis catus)", and to at most ANNOTATION_MAX_MEANING letters.
+func shortMeaning(meaning string) string {
+	if stripped := strings.TrimSpace(parenthesesPattern.ReplaceAllString(meaning, "")); stripped != "" {
+		meaning = stripped
+	}
+	if i := strings.IndexAny(meaning, ",;"); i > 0 {
+		meaning = meaning[:i]
+	}
+	meaning = strings.TrimSpace(meaning)
+	if runes := []rune(meaning); len(runes) > ANNOTATION_MAX_MEANING {
+		meaning = strings.TrimSpace(string(runes[:ANNOTATION_MAX_MEANING])) + "…"
+	}
+	return meaning
+}
+
+// annotateText copies r to w a line at a time, annotating each recognised
+// word. With footnotes, words get a number instead, the same one each time
+// a headword comes back, and the notes follow the paragraph they first
+// appear in.
+func annotateText(r io.Reader, w io.Writer, glosses map[string][]tsk.Gloss, footnotes bool) error {
+	numbers := make(map[annotation]int)
+	var pending []annotation // footnotes not yet written out
+
+	flushNotes := func() {
+		if len(pending) == 0 {
+			return
+		}
+		fmt.Fprintln(w)
+		for _, a := range pending {
+			fmt.Fprintf(w, "[%d] %s\n", numbers[a], a)
+		}
+		pending = pending[:0]
+	}
+
+	reader := bufio.NewReader(r)
+	for {
+		line, err := reader.ReadString('\n')
+		if line != "" {
+			if strings.TrimSpace(line) == "" && footnotes {
+				flushNotes()
+			}
+			annotated := annotationWordPattern.ReplaceAllStringFunc(line, func(word string) string {
+				a, ok := annotateWord(word, glosses)
+				if !ok {
+					return word
+				}
+				if !footnotes {
+					return fmt.Sprintf("%s [%s]", word, a)
+				}
+				n, seen := numbers[a]
+				if !seen {
+					n = len(numbers) + 1
+					numbers[a] = n
+					pending = append(pending, a)
+				}
+				return fmt.Sprintf("%s[%d]", word, n)
+			})
+			if _, werr := io.WriteString(w, annotated); werr != nil {
+				return werr
+			}
+		}
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+	}
+	if footnotes {
+		flushNotes()
+	}
+	return nil
+}
@@ -0,0 +1,421 @@
+package main
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/gdamore/tcell/v2"
+	"golang.org/x/crypto/ssh"
+)
+
+// ----------------------
+// SSH Server (`tsk ssh-serve`)
+// ----------------------
+
+// sshTty lets tcell drive a terminal on the far end of an SSH session
+// channel. Input is pumped from the channel in the background so that Drain
+// can wake up a pending Read when tcell shuts the screen down.
+type sshTty struct {
+	ch     ssh.Channel
+	input  chan []byte
+	closed chan struct{} // closed once the guest disconnects
+	done   chan struct{} // closed once the session is over, to stop the input pump
+
+	mu       sync.Mutex
+	pending  []byte
+	drained  chan struct{}
+	width    int
+	height   int
+	onResize func()
+}
+
+func newSSHTty(ch ssh.Channel) *sshTty {
+	t := &sshTty{
+		ch:      ch,
+		input:   make(chan []byte),
+		closed:  make(chan struct{}),
+		done:    make(chan struct{}),
+		drained: make(chan struct{}),
+	}
+	go func() {
+		defer close(t.closed)
+		for {
+			buf := make([]byte, 256)
+			n, err := ch.Read(buf)
+			if n > 0 {
+				// Once the app has stopped, nothing reads input any more.
+				select {
+				case t.input <- buf[:n]:
+				case <-t.done:
+					return
+				}
+			}
+			if err != nil {
+				return
+			}
+		}
+	}()
+	return t
+}
+
+func (t *sshTty) Start() error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.drained = make(chan struct{})
+	return nil
+}
+
+func (t *sshTty) Stop() error { return nil }
+
+func (t *sshTty) Drain() error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	select {
+	case <-t.drained:
+	default:
+		close(t.drained)
+	}
+	return nil
+}
+
+func (t *sshTty) NotifyResize(cb func()) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.onResize = cb
+}
+
+func (t *sshTty) WindowSize() (tcell.WindowSize, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return tcell.WindowSize{Width: t.width, Height: t.height}, nil
+}
+
+func (t *sshTty) resize(width, height int) {
+	t.mu.Lock()
+	t.width, t.height = width, height
+	cb := t.onResize
+	t.mu.Unlock()
+	if cb != nil {
+		cb()
+	}
+}
+
+func (t *sshTty) Read(p []byte) (int, error) {
+	t.mu.Lock()
+	if len(t.pending) > 0 {
+		n := copy(p, t.pending)
+		t.pending = t.pending[n:]
+		t.mu.Unlock()
+		return n, nil
+	}
+	drained := t.drained
+	t.mu.Unlock()
+
+	select {
+	case data := <-t.input:
+		n := copy(p, data)
+		t.mu.Lock()
+		t.pending = data[n:]
+		t.mu.Unlock()
+		return n, nil
+	case <-t.closed:
+		return 0, io.EOF
+	case <-drained:
+		return 0, io.EOF
+	}
+}
+
+func (t *sshTty) Write(p []byte) (int, error) { return t.ch.Write(p) }
+
+// Close is a no-op: tcell closes its tty when the app stops, but the session
+// still has to report an exit status on the channel before closing it.
+func (t *sshTty) Close() error { return nil }
+
+// Payloads of the SSH channel requests we care about (RFC 4254, section 6).
+type sshPtyRequest struct {
+	Term          string
+	Columns, Rows uint32
+	Width, Height uint32
+	Modes         string
+}
+
+type sshWindowChange struct {
+	Columns, Rows uint32
+	Width, Height uint32
+}
+
+// loadOrCreateHostKey reads the server's private host key, generating and
+// saving a new ed25519 key on first use so the fingerprint stays stable.
+func loadOrCreateHostKey(path string) (ssh.Signer, error) {
+	if path == "" {
+		dir, err := userDataDir()
+		if err != nil {
+			return nil, err
+		}
+		path = filepath.Join(dir, SSH_HOST_KEY_FILE)
+	}
+
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		_, key, err := ed25519.GenerateKey(rand.Reader)
+		if err != nil {
+			return nil, err
+		}
+		block, err := ssh.MarshalPrivateKey(key, "tsk ssh-serve host key")
+		if err != nil {
+			return nil, err
+		}
+		data = pem.EncodeToMemory(block)
+		if err := os.WriteFile(path, data, 0600); err != nil {
+			return nil, err
+		}
+		fmt.Printf("Generated a new SSH host key at %s\n", path)
+	} else if err != nil {
+		return nil, err
+	}
+	return ssh.ParsePrivateKey(data)
+}
+
+// runSSHApp runs an isolated TUI on the guest's terminal until they quit or
+// disconnect.
+func runSSHApp(tty *sshTty, termName string, dict *Dictionary) error {
+	ti, err := tcell.LookupTerminfo(termName)
+	if err != nil {
+		ti, err = tcell.LookupTerminfo("xterm-256color")
+		if err != nil {
+			return err
+		}
+	}
+	screen, err := tcell.NewTerminfoScreenFromTtyTerminfo(tty, ti)
+	if err != nil {
+		return err
+	}
+
+	app := newTUI(dict, true)
+	if noColor {
+		screen = monochromeScreen{screen}
+	}
+	app.SetScreen(screen)
+	go func() {
+		<-tty.closed
+		app.Stop()
+	}()
+	return app.Run()
+}
+
+func serveSSHSession(ch ssh.Channel, requests <-chan *ssh.Request, dict *Dictionary) {
+	defer ch.Close()
+	tty := newSSHTty(ch)
+	defer close(tty.done)
+	termName := ""
+
+	for req := range requests {
+		switch req.Type {
+		case "pty-req":
+			var pty sshPtyRequest
+			if err := ssh.Unmarshal(req.Payload, &pty); err != nil {
+				req.Reply(false, nil)
+				continue
+			}
+			termName = pty.Term
+			tty.resize(int(pty.Columns), int(pty.Rows))
+			req.Reply(true, nil)
+		case "window-change":
+			var change sshWindowChange
+			if err := ssh.Unmarshal(req.Payload, &change); err == nil {
+				tty.resize(int(change.Columns), int(change.Rows))
+			}
+		case "shell":
+			if termName == "" {
+				req.Reply(false, nil)
+				fmt.Fprint(ch, "tsk needs a terminal. Please connect with ssh -t.\r\n")
+				return
+			}
+			req.Reply(true, nil)
+			go func() {
+				status := uint32(0)
+				if err := runSSHApp(tty, termName, dict); err != nil {
+					log.Printf("ssh-serve: session ended with error: %v", err)
+					status = 1
+				}
+				ch.SendRequest("exit-status", false, ssh.Marshal(struct{ Status uint32 }{status}))
+				ch.Close()
+			}()
+		default:
+			if req.WantReply {
+				req.Reply(false, nil)
+			}
+		}
+	}
+}
+
+func serveSSHConn(conn net.Conn, config *ssh.ServerConfig, dict *Dictionary) {
+	sconn, channels, requests, err := ssh.NewServerConn(conn, config)
+	if err != nil {
+		log.Printf("ssh-serve: handshake with %s failed: %v", conn.RemoteAddr(), err)
+		return
+	}
+	defer sconn.Close()
+	fmt.Printf("Guest connected from %s\n", sconn.RemoteAddr())
+	go ssh.DiscardRequests(requests)
+
+	for newChannel := range channels {
+		if newChannel.ChannelType() != "session" {
+			newChannel.Reject(ssh.UnknownChannelType, "only session channels are supported")
+			continue
+		}
+		ch, chRequests, err := newChannel.Accept()
+		if err != nil {
+			log.Printf("ssh-serve: could not accept channel: %v", err)
+			continue
+		}
+		go serveSSHSession(ch, chRequests, dict)
+	}
+	fmt.Printf("Guest from %s disconnected\n", sconn.RemoteAddr())
+}
+
+// SSH_PASSWORD_ENV names the environment variable `tsk serve` takes the
+// guests' password from, unless --password-file gives one. Either way it
+// stays out of the command line, which anyone on the server can see.
+const SSH_PASSWORD_ENV = "TSK_SSH_PASSWORD"
+
+// readSSHPassword returns the guests' password: the first line of file if
+// one is given, else $SSH_PASSWORD_ENV, else "" for none.
+func readSSHPassword(file string) (string, error) {
+	if file == "" {
+		return os.Getenv(SSH_PASSWORD_ENV), nil
+	}
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return "", err
+	}
+	password, _, _ := strings.Cut(string(data), "\n")
+	password = strings.TrimSuffix(password, "\r")
+	if password == "" {
+		return "", fmt.Errorf("%s has no password on its first line", file)
+	}
+	return password, nil
+}
+
+// loadAuthorizedKeys reads the public keys in an OpenSSH authorized_keys
+// file, keyed by their wire format.
+func loadAuthorizedKeys(path string) (map[string]bool, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	keys := make(map[string]bool)
+	for len(bytes.TrimSpace(data)) > 0 {
+		key, _, _, rest, err := ssh.ParseAuthorizedKey(data)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		keys[string(key.Marshal())] = true
+		data = rest
+	}
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("%s has no keys", path)
+	}
+	return keys, nil
+}
+
+// isLoopbackAddr reports whether the listen address addr, like
+// "localhost:2222", only takes connections from this machine. An address
+// without a host, like ":2222", listens on every interface.
+func isLoopbackAddr(addr string) bool {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return false
+	}
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+// runSSHServeCommand serves the full TUI to anyone who connects over SSH,
+// e.g. a study group sharing one server. Every connection gets its own
+// isolated session: marks live only as long as the connection, and nothing
+// is written to the host's files. Without a password or authorized keys,
+// it only listens on a loopback address.
+func runSSHServeCommand(args []string) error {
+	fs := newCommandFlags("serve")
+	addr := fs.String("addr", "localhost:2222", "`address` to listen on; one other machines can reach needs a password or authorized keys")
+	hostKey := fs.String("host-key", "", "private host key `file` (default: generated in tsk's config directory)")
+	passwordFile := fs.String("password-file", "", "require guests to log in with the shared password on the first line of this `file` (default $"+SSH_PASSWORD_ENV+")")
+	authorizedKeys := fs.String("authorized-keys", "", "let guests log in with the public keys in this authorized_keys `file`")
+	fs.Parse(args)
+
+	password, err := readSSHPassword(*passwordFile)
+	if err != nil {
+		return err
+	}
+	var keys map[string]bool
+	if *authorizedKeys != "" {
+		if keys, err = loadAuthorizedKeys(*authorizedKeys); err != nil {
+			return err
+		}
+	}
+	if password == "" && keys == nil && !isLoopbackAddr(*addr) {
+		return fmt.Errorf("anyone who can reach %s could use tsk; set a password with $%s or --password-file, or give --authorized-keys", *addr, SSH_PASSWORD_ENV)
+	}
+
+	signer, err := loadOrCreateHostKey(*hostKey)
+	if err != nil {
+		return fmt.Errorf("loading host key: %w", err)
+	}
+	config := &ssh.ServerConfig{}
+	if password == "" && keys == nil {
+		config.NoClientAuth = true
+	}
+	if password != "" {
+		config.PasswordCallback = func(_ ssh.ConnMetadata, given []byte) (*ssh.Permissions, error) {
+			if subtle.ConstantTimeCompare(given, []byte(password)) == 1 {
+				return nil, nil
+			}
+			return nil, fmt.Errorf("wrong password")
+		}
+	}
+	if keys != nil {
+		config.PublicKeyCallback = func(_ ssh.ConnMetadata, key ssh.PublicKey) (*ssh.Permissions, error) {
+			if keys[string(key.Marshal())] {
+				return nil, nil
+			}
+			return nil, fmt.Errorf("unknown public key")
+		}
+	}
+	config.AddHostKey(signer)
+
+	dict, err := loadDictionary()
+	if err != nil {
+		return err
+	}
+
+	listener, err := net.Listen("tcp", *addr)
+	if err != nil {
+		return err
+	}
+	defer listener.Close()
+	fmt.Printf("Serving tsk over SSH on %s. Press Ctrl-C to stop.\n", listener.Addr())
+	fmt.Printf("Host key fingerprint: %s\n", ssh.FingerprintSHA256(signer.PublicKey()))
+
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			return err
+		}
+		go serveSSHConn(conn, config, dict)
+	}
+}
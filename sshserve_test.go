@@ -0,0 +1,74 @@
+package main
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+)
+
+func TestIsLoopbackAddr(t *testing.T) {
+	tests := []struct {
+		addr string
+		want bool
+	}{
+		{"localhost:2222", true},
+		{"127.0.0.1:2222", true},
+		{"[::1]:2222", true},
+		{":2222", false},
+		{"0.0.0.0:2222", false},
+		{"[::]:2222", false},
+		{"192.168.1.10:2222", false},
+		{"example.com:2222", false},
+		{"2222", false},
+	}
+	for _, tt := range tests {
+		if got := isLoopbackAddr(tt.addr); got != tt.want {
+			t.Errorf("isLoopbackAddr(%q) = %v, want %v", tt.addr, got, tt.want)
+		}
+	}
+}
+
+func TestReadSSHPassword(t *testing.T) {
+	t.Setenv(SSH_PASSWORD_ENV, "from-env")
+	if got, err := readSSHPassword(""); err != nil || got != "from-env" {
+		t.Errorf(`readSSHPassword("") = %q, %v; want "from-env"`, got, err)
+	}
+	file := filepath.Join(t.TempDir(), "password")
+	if err := os.WriteFile(file, []byte("kissa\r\nthe rest\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := readSSHPassword(file); err != nil || got != "kissa" {
+		t.Errorf(`readSSHPassword(file) = %q, %v; want "kissa"`, got, err)
+	}
+	if err := os.WriteFile(file, []byte("\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := readSSHPassword(file); err == nil {
+		t.Error("readSSHPassword of an empty file: no error")
+	}
+}
+
+// typingChannel is an ssh.Channel whose guest never stops typing.
+type typingChannel struct{ ssh.Channel }
+
+func (typingChannel) Read(p []byte) (int, error) { return copy(p, "a"), nil }
+
+// sshTty's input pump must stop once the session is over, even with input
+// that no app will read.
+func TestSSHTtyStopsReading(t *testing.T) {
+	tty := newSSHTty(typingChannel{})
+	buf := make([]byte, 1)
+	if _, err := tty.Read(buf); err != nil && err != io.EOF {
+		t.Fatal(err)
+	}
+	close(tty.done)
+	select {
+	case <-tty.closed:
+	case <-time.After(5 * time.Second):
+		t.Fatal("the input pump is still running after the session ended")
+	}
+}
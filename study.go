@@ -0,0 +1,872 @@
+package main
+
+import (
+	"bufio"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hiAndrewQuinn/tsk/pkg/tsk"
+	"github.com/rivo/tview"
+)
+
+// ----------------------
+// Spaced-Repetition Quiz (`tsk quiz`)
+// ----------------------
+
+// The quiz deck lives in a per-profile SQLite database: one row per word with
+// its SM-2 scheduling state, and a log of every review for the statistics.
+// Marked words are added to it when the TUI exits, and `tsk quiz LIST...`
+// adds word lists or marked-word exports. Like the sentence database, it is
+// not covered by --encrypt.
+const quizSchema = `
+CREATE TABLE IF NOT EXISTS cards (
+  word        TEXT PRIMARY KEY,
+  easiness    REAL NOT NULL DEFAULT 2.5,
+  interval    INTEGER NOT NULL DEFAULT 0,
+  repetitions INTEGER NOT NULL DEFAULT 0,
+  due         TEXT NOT NULL,
+  added       TEXT NOT NULL
+);
+CREATE TABLE IF NOT EXISTS reviews (
+  word     TEXT NOT NULL,
+  reviewed TEXT NOT NULL,
+  grade    INTEGER NOT NULL
+)`
+
+const quizDateFormat = "2006-01-02"
+
+// quizCard is one word's SM-2 state. Interval is in days; a card with no
+// repetitions and a zero interval has never been reviewed.
+type quizCard struct {
+	Word        string
+	Easiness    float64
+	Interval    int
+	Repetitions int
+}
+
+// review applies one SM-2 grade, from 0 (blackout) to 5 (perfect recall),
+// and returns the number of days until the card is due again.
+func (c *quizCard) review(grade int) int {
+	if grade >= 3 {
+		switch c.Repetitions {
+		case 0:
+			c.Interval = 1
+		case 1:
+			c.Interval = 6
+		default:
+			c.Interval = int(math.Round(float64(c.Interval) * c.Easiness))
+		}
+		c.Repetitions++
+	} else {
+		c.Repetitions = 0
+		c.Interval = 1
+	}
+	q := float64(5 - grade)
+	c.Easiness += 0.1 - q*(0.08+q*0.02)
+	if c.Easiness < 1.3 {
+		c.Easiness = 1.3
+	}
+	return c.Interval
+}
+
+func openQuizDB() (*sql.DB, error) {
+	dir, err := userDataDir()
+	if err != nil {
+		return nil, err
+	}
+	db, err := sql.Open("sqlite", filepath.Join(dir, QUIZ_FILE))
+	if err != nil {
+		return nil, err
+	}
+	if _, err := db.Exec(quizSchema); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return db, nil
+}
+
+// addQuizCards adds words to the deck, due today, leaving words already in
+// it alone. It returns how many were new.
+func addQuizCards(db *sql.DB, words []string) (int, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+	stmt, err := tx.Prepare("INSERT OR IGNORE INTO cards (word, due, added) VALUES (?, ?, ?)")
+	if err != nil {
+		return 0, err
+	}
+	defer stmt.Close()
+
+	today := time.Now().Format(quizDateFormat)
+	added := 0
+	for _, word := range words {
+		result, err := stmt.Exec(word, today, today)
+		if err != nil {
+			return 0, err
+		}
+		if n, _ := result.RowsAffected(); n > 0 {
+			added++
+		}
+	}
+	return added, tx.Commit()
+}
+
+// dueQuizCards returns the cards due by today, reviewed ones first (most
+// overdue first), then at most newLimit never-reviewed ones. If only is
+// non-nil, it returns just the cards of the words in it.
+func dueQuizCards(db *sql.DB, newLimit int, only map[string]bool) ([]quizCard, error) {
+	rows, err := db.Query(`
+        SELECT word, easiness, interval, repetitions FROM cards
+        WHERE due <= ? ORDER BY interval = 0, due, word`, time.Now().Format(quizDateFormat))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var cards []quizCard
+	newCards := 0
+	for rows.Next() {
+		var c quizCard
+		if err := rows.Scan(&c.Word, &c.Easiness, &c.Interval, &c.Repetitions); err != nil {
+			return nil, err
+		}
+		if only != nil && !only[c.Word] {
+			continue
+		}
+		if c.Interval == 0 {
+			if newCards >= newLimit {
+				continue
+			}
+			newCards++
+		}
+		cards = append(cards, c)
+	}
+	return cards, rows.Err()
+}
+
+// saveQuizReview stores a card's new state and logs the grade.
+func saveQuizReview(db *sql.DB, c quizCard, grade int) error {
+	now := time.Now()
+	due := now.AddDate(0, 0, c.Interval).Format(quizDateFormat)
+	if _, err := db.Exec("UPDATE cards SET easiness = ?, interval = ?, repetitions = ?, due = ? WHERE word = ?",
+		c.Easiness, c.Interval, c.Repetitions, due, c.Word); err != nil {
+		return err
+	}
+	_, err := db.Exec("INSERT INTO reviews (word, reviewed, grade) VALUES (?, ?, ?)",
+		c.Word, now.Format(time.RFC3339), grade)
+	return err
+}
+
+// quizAnswerText is the plain-text gloss shown as a card's answer.
+func quizAnswerText(word string, glosses map[string][]tsk.Gloss) string {
+	headword, ok := lookupHeadword(word, glosses)
+	if !ok {
+		return "No gloss available."
+	}
+	text := stripColorTags(generateGlossText(headword, glosses))
+	if headword != word {
+		text = fmt.Sprintf("%s ~> %s\n\n%s", word, headword, text)
+	}
+	return strings.TrimSpace(text)
+}
+
+// printQuizStats summarises the deck and how well its words are recalled.
+func printQuizStats(db *sql.DB) error {
+	today := time.Now().Format(quizDateFormat)
+	weekAgo := time.Now().AddDate(0, 0, -7).Format(time.RFC3339)
+
+	var total, due, unseen int
+	var meanEasiness sql.NullFloat64
+	if err := db.QueryRow(`
+        SELECT COUNT(*), COALESCE(SUM(due <= ?), 0), COALESCE(SUM(interval = 0), 0), AVG(easiness)
+        FROM cards`, today).Scan(&total, &due, &unseen, &meanEasiness); err != nil {
+		return err
+	}
+	var reviews, recalled, weekReviews, weekRecalled int
+	if err := db.QueryRow(`
+        SELECT COUNT(*), COALESCE(SUM(grade >= 3), 0),
+               COALESCE(SUM(reviewed >= ?), 0), COALESCE(SUM(reviewed >= ? AND grade >= 3), 0)
+        FROM reviews`, weekAgo, weekAgo).Scan(&reviews, &recalled, &weekReviews, &weekRecalled); err != nil {
+		return err
+	}
+
+	percent := func(part, whole int) string {
+		if whole == 0 {
+			return "-"
+		}
+		return fmt.Sprintf("%.0f%%", 100*float64(part)/float64(whole))
+	}
+
+	fmt.Println("===")
+	fmt.Printf("Words in deck:       %d\n", total)
+	fmt.Printf("Due today:           %d\n", due)
+	fmt.Printf("Never reviewed:      %d\n", unseen)
+	fmt.Printf("Reviews:             %d (%d in the last 7 days)\n", reviews, weekReviews)
+	fmt.Printf("Recalled:            %s (%s in the last 7 days)\n", percent(recalled, reviews), percent(weekRecalled, weekReviews))
+	if meanEasiness.Valid {
+		fmt.Printf("Average easiness:    %.2f\n", meanEasiness.Float64)
+	}
+
+	// The hardest words are the reviewed ones SM-2 rates least easy.
+	rows, err := db.Query(`
+        SELECT word, easiness FROM cards WHERE interval > 0
+        ORDER BY easiness, word LIMIT 5`)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	first := true
+	for rows.Next() {
+		var word string
+		var easiness float64
+		if err := rows.Scan(&word, &easiness); err != nil {
+			return err
+		}
+		if first {
+			fmt.Println("---")
+			fmt.Println("Hardest words:")
+			first = false
+		}
+		fmt.Printf("  %s (%.2f)\n", word, easiness)
+	}
+	fmt.Println("===")
+	return rows.Err()
+}
+
+// runQuizCommand drills the words that are due, e.g. `tsk quiz`, after
+// adding any word lists given, e.g. `tsk quiz syllabus.txt`. With --tag it
+// adds the words of an imported list and drills only those.
+func runQuizCommand(args []string) error {
+	fs := newCommandFlags("quiz")
+	newLimit := fs.Int("new", 20, "introduce at most this many never-reviewed words per session")
+	stats := fs.Bool("stats", false, "show recall statistics instead of quizzing")
+	tag := fs.String("tag", "", "quiz only the words of the word list with this `tag` (see tsk import-words)")
+	fs.Parse(args)
+
+	db, err := openQuizDB()
+	if err != nil {
+		return fmt.Errorf("opening your quiz deck: %w", err)
+	}
+	defer db.Close()
+
+	for _, path := range fs.Args() {
+		words, err := readWordList(path)
+		if err != nil {
+			return err
+		}
+		added, err := addQuizCards(db, words)
+		if err != nil {
+			return fmt.Errorf("adding %s to your quiz deck: %w", path, err)
+		}
+		fmt.Printf("Added %d new words from %s to your quiz deck.\n", added, path)
+	}
+
+	var only map[string]bool
+	if *tag != "" {
+		*tag = strings.TrimPrefix(*tag, "#")
+		tagged, err := loadTaggedWords()
+		if err != nil {
+			return err
+		}
+		words := taggedWith(tagged, *tag)
+		if len(words) == 0 {
+			return fmt.Errorf("no words are tagged '%s' (see tsk tags)", *tag)
+		}
+		added, err := addQuizCards(db, words)
+		if err != nil {
+			return fmt.Errorf("adding #%s to your quiz deck: %w", *tag, err)
+		}
+		if added > 0 {
+			fmt.Printf("Added %d new words tagged #%s to your quiz deck.\n", added, *tag)
+		}
+		only = make(map[string]bool, len(words))
+		for _, w := range words {
+			only[w] = true
+		}
+	}
+
+	if *stats {
+		return printQuizStats(db)
+	}
+
+	cards, err := dueQuizCards(db, *newLimit, only)
+	if err != nil {
+		return err
+	}
+	if len(cards) == 0 && *tag != "" {
+		fmt.Printf("No words tagged #%s are due for review.\n", *tag)
+		return nil
+	} else if len(cards) == 0 {
+		fmt.Println("No words are due for review. Mark words in the TUI, or run `tsk quiz LIST` to add a word list.")
+		return nil
+	}
+
+	glosses, err := loadGlosses()
+	if err != nil {
+		return fmt.Errorf("loading glosses: %w", err)
+	}
+
+	fmt.Printf("=== %d words due. Press Enter to see the answer, q to stop. ===\n", len(cards))
+	input := bufio.NewScanner(os.Stdin)
+	ask := func(prompt string) (string, bool) {
+		fmt.Print(prompt)
+		if !input.Scan() {
+			return "", false
+		}
+		answer := strings.TrimSpace(input.Text())
+		return answer, answer != "q"
+	}
+
+	// As in SM-2, words graded below 4 come back at the end of the session
+	// until they are remembered well; only the first grade is scheduled.
+	queue := cards
+	graded := make(map[string]bool)
+	reviewed, recalled := 0, 0
+	for len(queue) > 0 {
+		card := queue[0]
+		queue = queue[1:]
+
+		fmt.Printf("\n[%d left] %s\n", len(queue)+1, card.Word)
+		if _, ok := ask("(Enter to show the answer) "); !ok {
+			break
+		}
+		fmt.Println("---")
+		fmt.Println(quizAnswerText(card.Word, glosses))
+		fmt.Println("---")
+
+		grade := -1
+		for grade < 0 {
+			answer, ok := ask("How well did you remember it? 0 = not at all ... 5 = perfectly: ")
+			if !ok {
+				queue = nil
+				break
+			}
+			if g, err := strconv.Atoi(answer); err == nil && g >= 0 && g <= 5 {
+				grade = g
+			}
+		}
+		if grade < 0 {
+			break
+		}
+
+		if !graded[card.Word] {
+			graded[card.Word] = true
+			days := card.review(grade)
+			if err := saveQuizReview(db, card, grade); err != nil {
+				return fmt.Errorf("saving review of %s: %w", card.Word, err)
+			}
+			reviewed++
+			if grade >= 3 {
+				recalled++
+			}
+			fmt.Printf("Next review in %d day(s).\n", days)
+		}
+		if grade < 4 {
+			queue = append(queue, card)
+		}
+	}
+
+	fmt.Println("\n===")
+	if reviewed > 0 {
+		fmt.Printf("Reviewed %d words and recalled %d (%.0f%%).\n", reviewed, recalled, 100*float64(recalled)/float64(reviewed))
+	}
+	fmt.Println("Run `tsk quiz --stats` to see how you are doing overall.")
+	fmt.Println("===")
+	return nil
+}
+
+// ----------------------
+// Statistics (`tsk stats`, Ctrl-B)
+// ----------------------
+
+// STATS_TOP_WORDS is how many of the most looked-up words the statistics
+// list.
+const STATS_TOP_WORDS = 10
+
+// wordCount is a word and how many times it came up.
+type wordCount struct {
+	Word  string `json:"word"`
+	Count int    `json:"count"`
+}
+
+// dictionaryStats is what `tsk stats` and Ctrl-B show: how much is in the
+// dictionary, and how the user has been using it.
+type dictionaryStats struct {
+	Headwords    int            `json:"headwords"`
+	Glossed      int            `json:"glossed"`
+	GlossesByPos map[string]int `json:"glosses_by_pos"`
+	Sentences    int            `json:"sentences"`
+	// WithExamples counts the glossed headwords used in at least one
+	// example sentence.
+	WithExamples int `json:"with_examples"`
+	TrieNodes    int `json:"trie_nodes"`
+	TrieBytes    int `json:"trie_bytes"`
+
+	Lookups         int         `json:"lookups"`
+	WordsLookedUp   int         `json:"words_looked_up"`
+	Marked          int         `json:"marked"`
+	MarkedSentences int         `json:"marked_sentences"`
+	MostLookedUp    []wordCount `json:"most_looked_up"`
+}
+
+// MostLookedUp returns up to n of the words looked up most often, with
+// how often, most first.
+func (h *SearchHistory) MostLookedUp(n int) []wordCount {
+	counts := make(map[string]int)
+	for _, e := range h.entries {
+		counts[e.Word]++
+	}
+	top := make([]wordCount, 0, len(counts))
+	for word, count := range counts {
+		top = append(top, wordCount{word, count})
+	}
+	sort.Slice(top, func(i, j int) bool {
+		if top[i].Count != top[j].Count {
+			return top[i].Count > top[j].Count
+		}
+		return top[i].Word < top[j].Word
+	})
+	return top[:min(n, len(top))]
+}
+
+// collectStats counts up dict and the user's history, marks and marked
+// sentences.
+func collectStats(dict *Dictionary, history *SearchHistory, marked map[string]senseSet, markedSentences int) (dictionaryStats, error) {
+	glosses := dict.Glosses()
+	stats := dictionaryStats{
+		Headwords:       len(dict.Words()),
+		Glossed:         len(glosses),
+		GlossesByPos:    make(map[string]int),
+		TrieNodes:       dict.Trie().CountNodes(),
+		TrieBytes:       dict.Trie().SizeBytes(),
+		Lookups:         len(history.entries),
+		WordsLookedUp:   len(history.Recent()),
+		Marked:          len(marked),
+		MarkedSentences: markedSentences,
+		MostLookedUp:    history.MostLookedUp(STATS_TOP_WORDS),
+	}
+	for word, glossSlice := range glosses {
+		for _, g := range glossSlice {
+			stats.GlossesByPos[g.Pos]++
+		}
+		if dict.frequencies[word].Count > 0 {
+			stats.WithExamples++
+		}
+	}
+	if db := dict.ExamplesDB(); db != nil {
+		if err := db.QueryRow("SELECT COUNT(*) FROM sentences").Scan(&stats.Sentences); err != nil {
+			return stats, fmt.Errorf("counting example sentences: %w", err)
+		}
+	}
+	return stats, nil
+}
+
+// statsText lays the statistics out for Word Details, or, without its
+// color tags, for the terminal.
+func statsText(stats dictionaryStats) string {
+	var b strings.Builder
+	percent := func(part, whole int) string {
+		if whole == 0 {
+			return "-"
+		}
+		return fmt.Sprintf("%.0f%%", 100*float64(part)/float64(whole))
+	}
+
+	fmt.Fprintf(&b, "[yellow]Dictionary[white]\n")
+	fmt.Fprintf(&b, "  Headwords:           %d (%d with glosses)\n", stats.Headwords, stats.Glossed)
+	fmt.Fprintf(&b, "  Example sentences:   %d\n", stats.Sentences)
+	fmt.Fprintf(&b, "  Words in a sentence: %d (%s of glossed words)\n", stats.WithExamples, percent(stats.WithExamples, stats.Glossed))
+	fmt.Fprintf(&b, "  Search trie:         %d nodes, ~%.2f MB\n", stats.TrieNodes, float64(stats.TrieBytes)/(1024*1024))
+
+	// Parts of speech, most glosses first.
+	pos := make([]string, 0, len(stats.GlossesByPos))
+	for p := range stats.GlossesByPos {
+		pos = append(pos, p)
+	}
+	sort.Slice(pos, func(i, j int) bool {
+		if stats.GlossesByPos[pos[i]] != stats.GlossesByPos[pos[j]] {
+			return stats.GlossesByPos[pos[i]] > stats.GlossesByPos[pos[j]]
+		}
+		return pos[i] < pos[j]
+	})
+	fmt.Fprintf(&b, "\n[yellow]Glosses by part of speech[white]\n")
+	for _, p := range pos {
+		fmt.Fprintf(&b, "  %-20s %d\n", p+":", stats.GlossesByPos[p])
+	}
+
+	fmt.Fprintf(&b, "\n[yellow]You[white]\n")
+	fmt.Fprintf(&b, "  Lookups:             %d (%d different words)\n", stats.Lookups, stats.WordsLookedUp)
+	fmt.Fprintf(&b, "  Marked words:        %d\n", stats.Marked)
+	fmt.Fprintf(&b, "  Marked sentences:    %d\n", stats.MarkedSentences)
+	if len(stats.MostLookedUp) > 0 {
+		fmt.Fprintf(&b, "\n[yellow]Most looked-up words[white]\n")
+		for _, wc := range stats.MostLookedUp {
+			fmt.Fprintf(&b, "  %-20s %d\n", tview.Escape(wc.Word), wc.Count)
+		}
+	}
+	return b.String()
+}
+
+// runStatsCommand prints the statistics Ctrl-B shows, e.g. `tsk stats`,
+// or with --json as one JSON object for scripts.
+func runStatsCommand(args []string) error {
+	fs := newCommandFlags("stats")
+	asJSON := fs.Bool("json", false, "print the statistics as JSON")
+	fs.Parse(args)
+
+	if err := unlockUserData(); err != nil {
+		return fmt.Errorf("unlocking your data: %w", err)
+	}
+
+	words, err := loadWords()
+	if err != nil {
+		return fmt.Errorf("loading words: %w", err)
+	}
+	glosses, err := loadGlosses()
+	if err != nil {
+		return fmt.Errorf("loading glosses: %w", err)
+	}
+	frequencies, err := loadFrequencies()
+	if err != nil {
+		return fmt.Errorf("loading frequencies: %w", err)
+	}
+	exampleDB, err := openExamplesDB()
+	if err != nil {
+		return err
+	}
+	defer exampleDB.Close()
+	dict := &Dictionary{Dictionary: tsk.New(words, glosses), frequencies: frequencies}
+	dict.SetExamples(exampleDB)
+
+	history, err := loadSearchHistory()
+	if err != nil {
+		return fmt.Errorf("loading search history: %w", err)
+	}
+	marked, err := loadMarked()
+	if err != nil {
+		return fmt.Errorf("loading marked words: %w", err)
+	}
+	sentences, err := loadMarkedSentences()
+	if err != nil {
+		return fmt.Errorf("loading marked sentences: %w", err)
+	}
+
+	stats, err := collectStats(dict, history, marked, len(sentences))
+	if err != nil {
+		return err
+	}
+	if *asJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(stats)
+	}
+	fmt.Println("===")
+	fmt.Print(stripColorTags(statsText(stats)))
+	fmt.Println("===")
+	return nil
+}
+
+// ----------------------
+// Study Log (`--study-log`, `tsk report`)
+// ----------------------
+
+// With --study-log, every lookup, mark and unmark in the TUI is logged to a
+// per-profile SQLite database, for `tsk report`. It is off unless asked
+// for, and like the other databases it is not covered by --encrypt. day is
+// the local date of at, so reports split days where the user's days split.
+const studyLogSchema = `
+CREATE TABLE IF NOT EXISTS events (
+  at   TEXT NOT NULL,
+  day  TEXT NOT NULL,
+  kind TEXT NOT NULL CHECK (kind IN ('lookup', 'mark', 'unmark')),
+  word TEXT NOT NULL
+);
+CREATE INDEX IF NOT EXISTS events_day ON events (day)`
+
+// studyLog turns the study log on, and studyLogDB is it once opened.
+var (
+	studyLog   bool
+	studyLogDB *sql.DB
+)
+
+// openStudyLog opens the study log, creating it first if create is set. It
+// returns a nil DB if it doesn't exist and create is unset.
+func openStudyLog(create bool) (*sql.DB, error) {
+	dir, err := userDataDir()
+	if err != nil {
+		return nil, err
+	}
+	path := filepath.Join(dir, STUDY_LOG_FILE)
+	if _, err := os.Stat(path); os.IsNotExist(err) && !create {
+		return nil, nil
+	}
+
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		return nil, err
+	}
+	// A row goes in with every lookup, so they are written ahead rather
+	// than waited on.
+	if _, err := db.Exec("PRAGMA journal_mode = WAL; PRAGMA synchronous = NORMAL;" + studyLogSchema); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return db, nil
+}
+
+// logStudyEvent adds a lookup, mark or unmark of word to the study log, if
+// it is kept.
+func logStudyEvent(kind, word string) {
+	if studyLogDB == nil {
+		return
+	}
+	now := time.Now()
+	if _, err := studyLogDB.Exec("INSERT INTO events (at, day, kind, word) VALUES (?, ?, ?, ?)",
+		now.Format(time.RFC3339), now.Format(quizDateFormat), kind, word); err != nil {
+		log.Printf("Could not log %s of %s: %v", kind, word, err)
+	}
+}
+
+// studyPeriod is what was done on one day, or in one week, of a report.
+type studyPeriod struct {
+	Start   string `json:"start"`   // the day, or the Monday of the week
+	Lookups int    `json:"lookups"` // lookups, counting repeats
+	Words   int    `json:"words"`   // different words looked up
+	Marked  int    `json:"marked"`  // words marked
+}
+
+// relookedWord is a word looked up on more than one day, which suggests it
+// hasn't stuck yet.
+type relookedWord struct {
+	Word    string `json:"word"`
+	Lookups int    `json:"lookups"`
+	Days    int    `json:"days"`
+}
+
+// studyReport is what `tsk report` prints.
+type studyReport struct {
+	From          string         `json:"from"`
+	To            string         `json:"to"`
+	Periods       []studyPeriod  `json:"periods"`
+	Streak        int            `json:"streak"`         // days in a row up to today, or yesterday
+	LongestStreak int            `json:"longest_streak"` // most days in a row ever
+	Relooked      []relookedWord `json:"relooked"`
+}
+
+// weekStart is the Monday of day's week.
+func weekStart(day time.Time) time.Time {
+	return day.AddDate(0, 0, -(int(day.Weekday())+6)%7)
+}
+
+// buildStudyReport sums up the study log from from to today, by week if
+// weekly is set, listing up to top words looked up on the most days.
+func buildStudyReport(db *sql.DB, from time.Time, weekly bool, top int) (studyReport, error) {
+	today := time.Now()
+	report := studyReport{From: from.Format(quizDateFormat), To: today.Format(quizDateFormat)}
+	if weekly {
+		from = weekStart(from)
+	}
+
+	// Every period gets a row, idle ones too, so gaps show.
+	index := make(map[string]int)
+	for d := from; d.Format(quizDateFormat) <= report.To; d = d.AddDate(0, 0, 1) {
+		start := d
+		if weekly {
+			start = weekStart(d)
+		}
+		key := start.Format(quizDateFormat)
+		if _, ok := index[key]; !ok {
+			index[key] = len(report.Periods)
+			report.Periods = append(report.Periods, studyPeriod{Start: key})
+		}
+	}
+
+	rows, err := db.Query("SELECT day, kind, word FROM events WHERE day >= ? ORDER BY day", from.Format(quizDateFormat))
+	if err != nil {
+		return report, err
+	}
+	defer rows.Close()
+	periodWords := make(map[string]map[string]bool)
+	lookups := make(map[string]int)
+	lookupDays := make(map[string]map[string]bool)
+	for rows.Next() {
+		var day, kind, word string
+		if err := rows.Scan(&day, &kind, &word); err != nil {
+			return report, err
+		}
+		key := day
+		if weekly {
+			if t, err := time.ParseInLocation(quizDateFormat, day, time.Local); err == nil {
+				key = weekStart(t).Format(quizDateFormat)
+			}
+		}
+		i, ok := index[key]
+		if !ok {
+			continue
+		}
+		p := &report.Periods[i]
+		switch kind {
+		case "lookup":
+			p.Lookups++
+			if periodWords[key] == nil {
+				periodWords[key] = make(map[string]bool)
+			}
+			periodWords[key][word] = true
+			p.Words = len(periodWords[key])
+			lookups[word]++
+			if lookupDays[word] == nil {
+				lookupDays[word] = make(map[string]bool)
+			}
+			lookupDays[word][day] = true
+		case "mark":
+			p.Marked++
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return report, err
+	}
+
+	for word, days := range lookupDays {
+		if len(days) > 1 {
+			report.Relooked = append(report.Relooked, relookedWord{word, lookups[word], len(days)})
+		}
+	}
+	sort.Slice(report.Relooked, func(i, j int) bool {
+		a, b := report.Relooked[i], report.Relooked[j]
+		if a.Days != b.Days {
+			return a.Days > b.Days
+		}
+		if a.Lookups != b.Lookups {
+			return a.Lookups > b.Lookups
+		}
+		return a.Word < b.Word
+	})
+	if len(report.Relooked) > top {
+		report.Relooked = report.Relooked[:top]
+	}
+
+	report.Streak, report.LongestStreak, err = studyStreaks(db, today)
+	return report, err
+}
+
+// studyStreaks counts the days in a row the log has something on: the
+// streak running up to today, which a day not yet studied doesn't break
+// until it is over, and the longest one.
+func studyStreaks(db *sql.DB, today time.Time) (current, longest int, err error) {
+	rows, err := db.Query("SELECT DISTINCT day FROM events ORDER BY day")
+	if err != nil {
+		return 0, 0, err
+	}
+	defer rows.Close()
+	var run int
+	var last time.Time
+	for rows.Next() {
+		var day string
+		if err := rows.Scan(&day); err != nil {
+			return 0, 0, err
+		}
+		t, err := time.ParseInLocation(quizDateFormat, day, time.Local)
+		if err != nil {
+			continue
+		}
+		if run > 0 && last.AddDate(0, 0, 1).Equal(t) {
+			run++
+		} else {
+			run = 1
+		}
+		last = t
+		longest = max(longest, run)
+	}
+	if err := rows.Err(); err != nil {
+		return 0, 0, err
+	}
+	switch last.Format(quizDateFormat) {
+	case today.Format(quizDateFormat), today.AddDate(0, 0, -1).Format(quizDateFormat):
+		current = run
+	}
+	return current, longest, nil
+}
+
+// studyReportText lays out a report for the terminal.
+func studyReportText(report studyReport, weekly bool) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Study report, %s to %s\n\n", report.From, report.To)
+	period := "Day"
+	if weekly {
+		period = "Week of"
+	}
+	fmt.Fprintf(&b, "%-12s %8s %6s %7s\n", period, "Lookups", "Words", "Marked")
+	var total studyPeriod
+	for _, p := range report.Periods {
+		fmt.Fprintf(&b, "%-12s %8d %6d %7d\n", p.Start, p.Lookups, p.Words, p.Marked)
+		total.Lookups += p.Lookups
+		total.Marked += p.Marked
+	}
+	fmt.Fprintf(&b, "%-12s %8d %6s %7d\n", "Total", total.Lookups, "", total.Marked)
+
+	days := func(n int) string {
+		if n == 1 {
+			return "1 day"
+		}
+		return fmt.Sprintf("%d days", n)
+	}
+	fmt.Fprintf(&b, "\nStreak: %s (longest %s)\n", days(report.Streak), days(report.LongestStreak))
+
+	if len(report.Relooked) > 0 {
+		fmt.Fprintf(&b, "\nLooked up on the most days, so not learned yet:\n")
+		for _, w := range report.Relooked {
+			fmt.Fprintf(&b, "  %-20s %d lookups on %s\n", w.Word, w.Lookups, days(w.Days))
+		}
+	}
+	return b.String()
+}
+
+// runReportCommand sums up the study log, e.g. `tsk report` for the last
+// week day by day, or `tsk report --weeks 8` for two months week by week.
+func runReportCommand(args []string) error {
+	fs := newCommandFlags("report")
+	numDays := fs.Int("days", 7, "report on the last `N` days, day by day")
+	numWeeks := fs.Int("weeks", 0, "report on the last `N` weeks, week by week, instead")
+	top := fs.Int("top", 10, "list up to `N` words looked up on the most days")
+	asJSON := fs.Bool("json", false, "print the report as JSON")
+	fs.Parse(args)
+
+	db, err := openStudyLog(false)
+	if err != nil {
+		return fmt.Errorf("opening your study log: %w", err)
+	}
+	if db == nil {
+		return fmt.Errorf("there is no study log yet; start tsk with --study-log, or put \"study_log\": true in %s, to keep one", CONFIG_FILE)
+	}
+	defer db.Close()
+
+	weekly := *numWeeks > 0
+	from := time.Now().AddDate(0, 0, -max(*numDays, 1)+1)
+	if weekly {
+		from = weekStart(time.Now()).AddDate(0, 0, -7*(*numWeeks-1))
+	}
+	report, err := buildStudyReport(db, from, weekly, *top)
+	if err != nil {
+		return err
+	}
+	if *asJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(report)
+	}
+	fmt.Println("===")
+	fmt.Print(studyReportText(report, weekly))
+	fmt.Println("===")
+	return nil
+}
@@ -0,0 +1,321 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+)
+
+// ----------------------
+// Themes (`--theme`)
+// ----------------------
+
+// modalTheme colors the full-screen modals (Ctrl-E and Ctrl-F), which
+// paint their own backgrounds.
+type modalTheme struct {
+	Background, HeaderFooter, Details tcell.Color
+	Primary, Accent, FieldBackground  tcell.Color
+	SelectBackground, SelectText      tcell.Color
+}
+
+// Theme is a named color scheme for the TUI. Styles replaces tview's own
+// defaults, the other colors are the ones tsk picks for itself, and Tags
+// swaps the inline color tags in tsk's text (e.g. "[white]") for ones that
+// read well on the theme's background.
+type Theme struct {
+	Styles tview.Theme
+
+	Header, HeaderText, HeaderLink tcell.Color
+	Selected                       tcell.Color // selected word in the results
+	Details                        tcell.Color // Word Details border and title
+	Marked                         tcell.Color // marked words, Ctrl-S and Ctrl-G
+	MarkedList                     tcell.Color // Ctrl-L, Ctrl-W
+	Examples                       tcell.Color // Ctrl-T
+	Inflections                    tcell.Color // Ctrl-D
+	History                        tcell.Color // Ctrl-O
+	Error                          tcell.Color
+	Tour                           tcell.Color // the first-run tour's highlights
+
+	Lemmatizer, ReverseFind modalTheme
+
+	Tags map[string]string
+}
+
+// DEFAULT_THEME is tsk's original look.
+const DEFAULT_THEME = "dark"
+
+var themes = map[string]*Theme{
+	"dark": {
+		Styles: tview.Styles,
+		Header: tcell.ColorLightGray, HeaderText: tcell.ColorBlack, HeaderLink: tcell.ColorWhite,
+		Selected: tcell.ColorWhite, Details: tcell.ColorWhite, Marked: tcell.ColorYellow,
+		MarkedList: tcell.ColorGreen, Examples: tcell.ColorTeal, Inflections: tcell.ColorPurple,
+		History: tcell.ColorAqua, Error: tcell.ColorRed, Tour: tcell.ColorOrange,
+		Lemmatizer: modalTheme{
+			Background: tcell.ColorSteelBlue, HeaderFooter: tcell.ColorDarkSlateGray, Details: tcell.ColorMidnightBlue,
+			Primary: tcell.ColorLightCyan, Accent: tcell.ColorAqua, FieldBackground: tcell.ColorDarkBlue,
+			SelectBackground: tcell.ColorDarkSlateGray, SelectText: tcell.ColorAqua,
+		},
+		ReverseFind: modalTheme{
+			Background: tcell.ColorDarkViolet, HeaderFooter: tcell.ColorIndigo, Details: tcell.ColorMidnightBlue,
+			Primary: tcell.ColorGold, Accent: tcell.ColorPlum, FieldBackground: tcell.ColorRebeccaPurple,
+			SelectBackground: tcell.ColorIndigo, SelectText: tcell.ColorGold,
+		},
+	},
+	"light": {
+		Styles: tview.Theme{
+			PrimitiveBackgroundColor:    tcell.ColorWhite,
+			ContrastBackgroundColor:     tcell.ColorLightSteelBlue,
+			MoreContrastBackgroundColor: tcell.ColorLightGreen,
+			BorderColor:                 tcell.ColorBlack,
+			TitleColor:                  tcell.ColorBlack,
+			GraphicsColor:               tcell.ColorBlack,
+			PrimaryTextColor:            tcell.ColorBlack,
+			SecondaryTextColor:          tcell.ColorDarkGoldenrod,
+			TertiaryTextColor:           tcell.ColorDarkGreen,
+			InverseTextColor:            tcell.ColorWhite,
+			ContrastSecondaryTextColor:  tcell.ColorDimGray,
+		},
+		Header: tcell.ColorNavy, HeaderText: tcell.ColorWhite, HeaderLink: tcell.ColorLightYellow,
+		Selected: tcell.ColorBlack, Details: tcell.ColorBlack, Marked: tcell.ColorDarkGoldenrod,
+		MarkedList: tcell.ColorDarkGreen, Examples: tcell.ColorTeal, Inflections: tcell.ColorPurple,
+		History: tcell.ColorNavy, Error: tcell.ColorRed, Tour: tcell.ColorOrangeRed,
+		Lemmatizer: modalTheme{
+			Background: tcell.ColorLightSteelBlue, HeaderFooter: tcell.ColorSteelBlue, Details: tcell.ColorAliceBlue,
+			Primary: tcell.ColorNavy, Accent: tcell.ColorDarkBlue, FieldBackground: tcell.ColorWhite,
+			SelectBackground: tcell.ColorSteelBlue, SelectText: tcell.ColorWhite,
+		},
+		ReverseFind: modalTheme{
+			Background: tcell.ColorThistle, HeaderFooter: tcell.ColorMediumPurple, Details: tcell.ColorLavender,
+			Primary: tcell.ColorIndigo, Accent: tcell.ColorDarkViolet, FieldBackground: tcell.ColorWhite,
+			SelectBackground: tcell.ColorMediumPurple, SelectText: tcell.ColorWhite,
+		},
+		Tags: map[string]string{
+			"white":     "black",
+			"lightgray": "dimgray",
+			"yellow":    "darkgoldenrod",
+			"green":     "darkgreen",
+			"aqua":      "teal",
+			"cyan":      "darkcyan",
+			"pink":      "mediumvioletred",
+		},
+	},
+	"solarized": {
+		Styles: tview.Theme{
+			PrimitiveBackgroundColor:    tcell.NewHexColor(0x002b36), // base03
+			ContrastBackgroundColor:     tcell.NewHexColor(0x073642), // base02
+			MoreContrastBackgroundColor: tcell.NewHexColor(0x586e75), // base01
+			BorderColor:                 tcell.NewHexColor(0x839496), // base0
+			TitleColor:                  tcell.NewHexColor(0x93a1a1), // base1
+			GraphicsColor:               tcell.NewHexColor(0x839496),
+			PrimaryTextColor:            tcell.NewHexColor(0x93a1a1),
+			SecondaryTextColor:          tcell.NewHexColor(0xb58900), // yellow
+			TertiaryTextColor:           tcell.NewHexColor(0x859900), // green
+			InverseTextColor:            tcell.NewHexColor(0x268bd2), // blue
+			ContrastSecondaryTextColor:  tcell.NewHexColor(0x657b83), // base00
+		},
+		Header: tcell.NewHexColor(0x073642), HeaderText: tcell.NewHexColor(0x93a1a1), HeaderLink: tcell.NewHexColor(0x268bd2),
+		Selected: tcell.NewHexColor(0x93a1a1), Details: tcell.NewHexColor(0x839496), Marked: tcell.NewHexColor(0xb58900),
+		MarkedList: tcell.NewHexColor(0x859900), Examples: tcell.NewHexColor(0x2aa198), Inflections: tcell.NewHexColor(0x6c71c4),
+		History: tcell.NewHexColor(0x268bd2), Error: tcell.NewHexColor(0xdc322f), Tour: tcell.NewHexColor(0xcb4b16),
+		Lemmatizer: modalTheme{
+			Background: tcell.NewHexColor(0x073642), HeaderFooter: tcell.NewHexColor(0x002b36), Details: tcell.NewHexColor(0x002b36),
+			Primary: tcell.NewHexColor(0x93a1a1), Accent: tcell.NewHexColor(0x2aa198), FieldBackground: tcell.NewHexColor(0x002b36),
+			SelectBackground: tcell.NewHexColor(0x586e75), SelectText: tcell.NewHexColor(0xfdf6e3),
+		},
+		ReverseFind: modalTheme{
+			Background: tcell.NewHexColor(0x073642), HeaderFooter: tcell.NewHexColor(0x002b36), Details: tcell.NewHexColor(0x002b36),
+			Primary: tcell.NewHexColor(0x93a1a1), Accent: tcell.NewHexColor(0x6c71c4), FieldBackground: tcell.NewHexColor(0x002b36),
+			SelectBackground: tcell.NewHexColor(0x586e75), SelectText: tcell.NewHexColor(0xfdf6e3),
+		},
+		Tags: map[string]string{
+			"white":  "#93a1a1",
+			"gray":   "#657b83",
+			"yellow": "#b58900",
+			"red":    "#dc322f",
+			"green":  "#859900",
+			"blue":   "#268bd2",
+			"teal":   "#2aa198",
+			"aqua":   "#2aa198",
+			"cyan":   "#2aa198",
+			"purple": "#6c71c4",
+			"pink":   "#d33682",
+		},
+	},
+	"high-contrast": {
+		Styles: tview.Theme{
+			PrimitiveBackgroundColor:    tcell.ColorBlack,
+			ContrastBackgroundColor:     tcell.ColorNavy,
+			MoreContrastBackgroundColor: tcell.ColorWhite,
+			BorderColor:                 tcell.ColorWhite,
+			TitleColor:                  tcell.ColorWhite,
+			GraphicsColor:               tcell.ColorWhite,
+			PrimaryTextColor:            tcell.ColorWhite,
+			SecondaryTextColor:          tcell.ColorYellow,
+			TertiaryTextColor:           tcell.ColorLime,
+			InverseTextColor:            tcell.ColorBlack,
+			ContrastSecondaryTextColor:  tcell.ColorWhite,
+		},
+		Header: tcell.ColorWhite, HeaderText: tcell.ColorBlack, HeaderLink: tcell.ColorBlack,
+		Selected: tcell.ColorWhite, Details: tcell.ColorWhite, Marked: tcell.ColorYellow,
+		MarkedList: tcell.ColorLime, Examples: tcell.ColorAqua, Inflections: tcell.ColorFuchsia,
+		History: tcell.ColorAqua, Error: tcell.ColorRed, Tour: tcell.ColorOrange,
+		Lemmatizer: modalTheme{
+			Background: tcell.ColorBlack, HeaderFooter: tcell.ColorWhite, Details: tcell.ColorBlack,
+			Primary: tcell.ColorWhite, Accent: tcell.ColorAqua, FieldBackground: tcell.ColorNavy,
+			SelectBackground: tcell.ColorAqua, SelectText: tcell.ColorBlack,
+		},
+		ReverseFind: modalTheme{
+			Background: tcell.ColorBlack, HeaderFooter: tcell.ColorWhite, Details: tcell.ColorBlack,
+			Primary: tcell.ColorWhite, Accent: tcell.ColorFuchsia, FieldBackground: tcell.ColorNavy,
+			SelectBackground: tcell.ColorFuchsia, SelectText: tcell.ColorBlack,
+		},
+		Tags: map[string]string{
+			"gray":      "white",
+			"blue":      "aqua",
+			"teal":      "aqua",
+			"green":     "lime",
+			"purple":    "fuchsia",
+			"darkred":   "red",
+			"lightgray": "white",
+		},
+	},
+}
+
+// theme is the color scheme in use, picked with --theme or "theme" in the
+// config file.
+var theme = themes[DEFAULT_THEME]
+
+// themeNames lists the built-in themes, for usage and error messages.
+func themeNames() []string {
+	names := make([]string, 0, len(themes))
+	for name := range themes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// useTheme makes the named theme current. tview reads its defaults when
+// each widget is created, so this has to happen before the TUI is built.
+func useTheme(name string) error {
+	t, ok := themes[name]
+	if !ok {
+		return fmt.Errorf("unknown theme '%s' (choose from %s)", name, strings.Join(themeNames(), ", "))
+	}
+	theme = t
+	tview.Styles = t.Styles
+	return nil
+}
+
+// monoTheme is the look of --no-color, NO_COLOR and --plain. Its only
+// colors are the backgrounds of what stands out, like the selected word and
+// the header bar, which monochromeScreen shows in reverse video; everything
+// else is left to the terminal.
+var monoTheme = &Theme{
+	Styles: tview.Theme{
+		PrimitiveBackgroundColor:    tcell.ColorDefault,
+		ContrastBackgroundColor:     tcell.ColorWhite,
+		MoreContrastBackgroundColor: tcell.ColorWhite,
+		BorderColor:                 tcell.ColorDefault,
+		TitleColor:                  tcell.ColorDefault,
+		GraphicsColor:               tcell.ColorDefault,
+		PrimaryTextColor:            tcell.ColorDefault,
+		SecondaryTextColor:          tcell.ColorDefault,
+		TertiaryTextColor:           tcell.ColorDefault,
+		InverseTextColor:            tcell.ColorDefault,
+		ContrastSecondaryTextColor:  tcell.ColorDefault,
+	},
+	Header: tcell.ColorWhite, HeaderText: tcell.ColorDefault, HeaderLink: tcell.ColorDefault,
+	Selected: tcell.ColorWhite, Details: tcell.ColorDefault, Marked: tcell.ColorWhite,
+	MarkedList: tcell.ColorDefault, Examples: tcell.ColorDefault, Inflections: tcell.ColorDefault,
+	History: tcell.ColorDefault, Error: tcell.ColorDefault, Tour: tcell.ColorDefault,
+	Lemmatizer: modalTheme{
+		Background: tcell.ColorDefault, HeaderFooter: tcell.ColorWhite, Details: tcell.ColorDefault,
+		Primary: tcell.ColorDefault, Accent: tcell.ColorDefault, FieldBackground: tcell.ColorWhite,
+		SelectBackground: tcell.ColorWhite, SelectText: tcell.ColorDefault,
+	},
+	ReverseFind: modalTheme{
+		Background: tcell.ColorDefault, HeaderFooter: tcell.ColorWhite, Details: tcell.ColorDefault,
+		Primary: tcell.ColorDefault, Accent: tcell.ColorDefault, FieldBackground: tcell.ColorWhite,
+		SelectBackground: tcell.ColorWhite, SelectText: tcell.ColorDefault,
+	},
+}
+
+// noColor turns color off, in the TUI and anything else tsk prints: set by
+// --no-color or --plain, "no_color" in the config file, or the NO_COLOR
+// environment variable (https://no-color.org).
+var noColor bool
+
+// useMonochrome makes monoTheme current in place of the --theme.
+func useMonochrome() {
+	theme = monoTheme
+	tview.Styles = monoTheme.Styles
+}
+
+// monochromeScreen draws with no colors, only bold, underline and the like,
+// turning any background color into reverse video.
+type monochromeScreen struct {
+	tcell.Screen
+}
+
+func (s monochromeScreen) SetContent(x, y int, primary rune, combining []rune, style tcell.Style) {
+	_, bg, attrs := style.Decompose()
+	plain := tcell.StyleDefault.Attributes(attrs)
+	if bg != tcell.ColorDefault {
+		plain = plain.Reverse(attrs&tcell.AttrReverse == 0)
+	}
+	s.Screen.SetContent(x, y, primary, combining, plain)
+}
+
+func (s monochromeScreen) SetCell(x, y int, style tcell.Style, ch ...rune) {
+	if len(ch) == 0 {
+		ch = []rune{' '}
+	}
+	s.SetContent(x, y, ch[0], ch[1:], style)
+}
+
+// colorScreen gives app a monochromeScreen if color is off. tview makes its
+// own screen otherwise.
+func colorScreen(app *tview.Application) error {
+	if !noColor {
+		return nil
+	}
+	screen, err := tcell.NewScreen()
+	if err != nil {
+		return err
+	}
+	app.SetScreen(monochromeScreen{screen})
+	return nil
+}
+
+// colorTagPattern matches the foreground part of a color tag like
+// "[yellow]" or "[white:black]".
+var colorTagPattern = regexp.MustCompile(`\[([a-z]+)([:\]])`)
+
+// Recolor swaps the color tags in text for the theme's own.
+func (t *Theme) Recolor(text string) string {
+	if len(t.Tags) == 0 {
+		return text
+	}
+	return colorTagPattern.ReplaceAllStringFunc(text, func(tag string) string {
+		m := colorTagPattern.FindStringSubmatch(tag)
+		if color, ok := t.Tags[m[1]]; ok {
+			return "[" + color + m[2]
+		}
+		return tag
+	})
+}
+
+// themedTextView is a TextView whose text is recolored for the theme.
+type themedTextView struct {
+	*tview.TextView
+}
+
+func (v themedTextView) SetText(text string) *tview.TextView {
+	return v.TextView.SetText(theme.Recolor(text))
+}
@@ -1,32 +1,23 @@
 package main
 
 import (
-	"bufio"
-	"bytes"
 	"database/sql"
-	"encoding/csv"
-	"encoding/gob"
-	"encoding/json"
 	"flag"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"log"
 	_ "modernc.org/sqlite" // pure-Go SQLite driver with FTS5 support
 	"os"
-	"os/exec"
 	"path/filepath"
-	"regexp"
-	"runtime"
-	"sort"
+	"slices"
 	"strings"
+	"text/template"
 	"time"
-	"unicode"
-	"unsafe"
 
 	_ "embed"
 
-	"github.com/gdamore/tcell/v2"
-	"github.com/rivo/tview"
+	"github.com/hiAndrewQuinn/tsk/pkg/tsk"
 )
 
 // ----------------------
@@ -35,35 +26,94 @@ import (
 const version = "v0.0.6"
 
 // ----------------------
-// Help Text Constant
+// Help Text
 // ----------------------
-const helpText = `[gray]
+// helpHeader is the top of the help text, the keys that can't be rebound.
+const helpHeader = `[gray]
 	Keybindings:
 	Esc        = Exit
 	Enter      = Clear search
 	Up/Down    = Scroll word list
+	PgDn/PgUp  = Jump to the next/previous group of a long word list
 
 	Tab        = Scroll Word Details forward
 	Shift-Tab  = Scroll Word Details backward
+	Ctrl-Left/Ctrl-Right = Narrow/widen the word list
+	Ctrl-A/Ctrl-U = Start of line/clear the search. With --editing readline or vim,
+	             Ctrl-E/K/W edit the search too, and commands on them move to Alt-E/K/W.
+
+`
+
+// helpEntries are the help text's lines for the keys in the keymap, each
+// shown in its color.
+var helpEntries = []struct {
+	action keyAction
+	color  string
+	text   string
+}{
+	{actionLemmatizer, "blue", "[blue]Etsi perusmuotin, aka lemmatizer[gray]. Find a word's base form from its inflected form."},
+	{actionExamples, "teal", "List [teal]example sentences[gray] of the selected word, to mark, copy or open on Tatoeba one by one.\n\t             Press it again, or PgDn/PgUp, for the next or previous page of sentences.\n\t             There, the speak key reads them aloud one after another, and +/- set the speed."},
+	{actionInflections, "purple", "Show the [purple]declension[gray] or conjugation table of the selected word."},
+	{actionCopy, "white", "Cop[white]y[gray] the selected word's gloss to the clipboard, as plain text."},
+	{actionSpeak, "white", "[white]Kuuntele[gray]: hear the selected word said aloud, by a native speaker\n\t             if tsk update-audio got a recording of it, else by a speech synthesizer."},
+	{actionMark, "yellow", "[yellow]Mark[gray]/unmark words. Marks are remembered between sessions and saved upon Esc to a text file."},
+	{actionSave, "yellow", "[yellow]Write[gray] the marked words to their files now, or with an export profile, without quitting."},
+	{actionSenses, "yellow", "Pick which senses of a word to mark, if you only care about some of its meanings."},
+	{actionCollect, "yellow", "Put the selected word in a [yellow]collection[gray] of marked words, like \"Chapter 7\", or take it out."},
+	{actionHistoryBack, "aqua", "Step back through your search history."},
+	{actionHistoryForward, "aqua", "Step forward again through your search history."},
+	{actionHistory, "aqua", "Open your search history."},
+	{actionListMarked, "green", "[green]List[gray] marked words. Press it again to unmark some or clear them all."},
+	{actionReverseFind, "cyan", "[cyan]Reverse-find[gray] words by searching their English definitions."},
+	{actionEnglishSearch, "cyan", "Switch the search bar between Finnish words and [cyan]English meanings[gray]; the label says which."},
+	{actionFoldDiacritics, "orange", "Search ignoring diacritics, so oljy finds [orange]öljy[gray], or press it again to search as typed."},
+	{actionRhymes, "orange", "List words [orange]rhyming[gray] with the selected one, by its last syllable; again for two, and so on."},
+	{actionCollapseList, "white", "Hide the word [white]list[gray] to give Word Details the whole width, or press it again to show it."},
+	{actionPosFilter, "orange", "List only nouns, then only [orange]verbs[gray], adjectives or adverbs, then all words again."},
+	{actionSentences, "teal", "Search all the [teal]example sentences[gray] for any Finnish or English phrase, and mark some to save."},
+	{actionSurprise, "orange", "[orange]Surprise[gray] me with a random word and an example sentence, of the part of speech listed."},
+	{actionEditGloss, "white", "[white]Edit[gray] the selected word's glosses for yourself. tsk overrides lists your edits to share."},
+	{actionNote, "fuchsia", "Write a [fuchsia]note[gray] of your own on the selected word, in $EDITOR if set. Search them with @."},
+	{actionNeighbors, "orange", "Show the selected word's [orange]neighbors[gray]: synonyms and words sharing a meaning, and theirs.\n\t             Up/Down and Enter walk from word to word; press it again to go back to the gloss."},
+	{actionRelated, "orange", "Show or hide the [orange]etymology[gray], synonyms, antonyms and derived terms, where the data has them."},
+	{actionWiktionary, "white", "Open the selected word's [white]Wiktionary[gray] page in your web browser, for the full entry."},
+	{actionStats, "white", "Show [white]statistics[gray] about the dictionary and the words you look up and mark."},
+	{actionReleaseNotes, "green", "Open the [green]release notes[gray] of the latest tsk in your browser. With --check-updates,\n\t             the header says when it's newer than yours."},
+	{actionHelp, "pink", "Show this [pink]help[gray] text again."},
+}
 
-	[blue]Control-E[gray]  = [blue]Etsi perusmuotin, aka lemmatizer[gray]. Find a word's base form from its inflected form.
-	[teal]Control-T[gray]  = Show [teal]example sentences[gray], from Tatoeba for the selected word.
-	[yellow]Control-S[gray]  = [yellow]Mark[gray]/unmark words. All marked words will be saved upon Esc to a text file.
-	[green]Control-L[gray]  = [green]List[gray] marked words. 
-	[cyan]Control-F[gray]  = [cyan]Reverse-find[gray] words by searching their English definitions.
-	[pink]Control-H[gray]  = Show this [pink]help[gray] text again.
-
-	[red]Control-R[gray]  = [red]Report a bug[gray] on GitHub.com. [red]Opens your web browser[gray] to
+// helpFooter is the rest of the help text, after the bug report key.
+const helpFooter = ` = [red]Report a bug[gray] on GitHub.com. [red]Opens your web browser[gray] to
 
 	                   [red]https://github.com/hiAndrewQuinn/tsk/issues/new[gray]
 
 	             Provide as many details as you can. Response is on a best-effort basis.
 
+	[green]Search taloissa[gray] and tsk works out its [green]base form[gray], talo, and its case and number.
+	[green]Search $sto[gray] to find words [green]ending[gray] in -sto, e.g. for rhymes.
+	[green]Search *kirja*[gray] to find words [green]containing[gray] kirja, like compounds. *kauppa finds words ending in -kauppa.
+	[green]Search k___a[gray] or [green]s.n.[gray] to find words matching a crossword [green]pattern[gray].
+	[green]Search k?ssa[gray] or [green]ta*o[gray] for words matching a [green]glob[gray]: ? is any one letter, * anything at all.
+	[green]Click a word[gray] in Word Details to look it up, and [green]◂ back to[gray] to return. Ctrl-click a word in the list to search for it.
+	[green]Alt-Left[gray] and [green]Alt-Right[gray] go back and forward through the words you've viewed, as in a web browser.
+
 	[green]Search zzz[gray] to see what is [green]coming soon[gray] in new versions of tsk!
 
 	[white]
 	`
 
+// helpText is the help text for the keys in keymap.
+func helpText() string {
+	var b strings.Builder
+	b.WriteString(helpHeader)
+	for _, entry := range helpEntries {
+		fmt.Fprintf(&b, "\t[%s]%-9s[gray]  = %s\n", entry.color, keymap.Label(entry.action), entry.text)
+	}
+	fmt.Fprintf(&b, "\n\t[red]%-9s[gray] ", keymap.Label(actionBugReport))
+	b.WriteString(helpFooter)
+	return b.String()
+}
+
 const finnishFlag = `[gray]
                         _,-(.;)
                     _,-',###""
@@ -99,6 +149,73 @@ _,-',###;-'"~. #####9   :' |
 // ----------------------
 var debug bool
 
+// ----------------------
+// Global Profile Flag
+// ----------------------
+
+// profile selects a separate set of per-user state (marks, history, settings),
+// e.g. for people sharing one machine. The empty string is the default profile.
+var profile string
+
+// ----------------------
+// Global History Flag
+// ----------------------
+
+// noHistory keeps the search history in memory only, for this session.
+var noHistory bool
+
+// examplesPerPage is how many example sentences Ctrl-T shows at a time.
+var examplesPerPage = EXAMPLES_PER_PAGE
+
+// noPrefetch stops the TUI looking up the selected word's example
+// sentences in the background, for low-powered machines.
+var noPrefetch bool
+
+// noMouse leaves the mouse to the terminal, for selecting text, instead of
+// clicking words in the TUI.
+var noMouse bool
+
+// exportDeeper makes the marked words' export also carry the glosses of
+// the words they are forms of, like omena for omenan.
+var exportDeeper bool
+
+// exportExamples is how many example sentences to export with each marked
+// word, for flashcards with some context. Zero leaves them out.
+var exportExamples int
+
+// noResume starts the TUI at the start screen, rather than where the last
+// session stopped reading.
+var noResume bool
+
+// reverseFindLive makes reverse-find search as you type, once typing
+// pauses for REVERSE_FIND_DEBOUNCE, instead of waiting for Enter.
+var reverseFindLive bool
+
+// foldDiacritics starts the TUI's search ignoring diacritics, so "oljy"
+// finds öljy. The fold-diacritics key turns it on and off.
+var foldDiacritics bool
+
+// maxResults is how many words a search lists at most. The list's title
+// says how many more there were.
+var maxResults = tsk.MaxResults
+
+// listWidth is the percent of the main view's width the word list takes,
+// and listCollapsed hides the list altogether. Both come from the config
+// file, and are saved back to it when changed in the TUI.
+var (
+	listWidth     = DEFAULT_LIST_WIDTH
+	listCollapsed bool
+)
+
+// posOnly is the part of speech given with --pos, such as "verb". Lookups
+// on the command line leave out every other part of speech, and the TUI
+// starts out listing only words of it.
+var posOnly string
+
+// lookupTemplate is the --template lookups on the command line are written
+// with, if any.
+var lookupTemplate *template.Template
+
 // ----------------------
 // Embedded Data Files
 // ----------------------
@@ -112,13 +229,27 @@ var glossesGob []byte
 //go:embed go-deeper.txt
 var goDeeperTxt string
 
+//go:embed frequencies.txt
+var frequenciesTxt string
+
+//go:embed rection.tsv
+var rectionTsv string
+
+//go:embed colloquial.tsv
+var colloquialTsv string
+
 //go:embed example-sentences.sqlite
 var embeddedDB []byte
-var exampleDB *sql.DB
 
 // --- NEW --- Global DB handle for the external inflections database.
 var inflectionsDB *sql.DB
 
+// Global DB handle for the user's own imported sentence pairs, if any.
+var userSentencesDB *sql.DB
+
+// recordings is the audio pack from `tsk update-audio`, if any.
+var recordings *audioPack
+
 // Schema for the embeddedDB, at least as of 2025-05-07 :
 //
 // CREATE VIRTUAL TABLE sentences USING fts5(
@@ -139,14 +270,52 @@ var inflectionsDB *sql.DB
 // ----------------------
 
 const (
-	TRIE_MAX_SEARCH_DEPTH = 50 // Maximum number of words to return
-
 	// Informational only.
 	WORD_LIST_FILE   = "words.txt"
 	GLOSSES_FILE     = "glosses.gob"
+	GLOSSES_SOURCE   = "glosses.jsonl"
+	FREQUENCIES_FILE = "frequencies.txt"
+	GO_DEEPER_FILE   = "go-deeper.txt"
+	RECTION_FILE     = "rection.tsv"
+	COLLOQUIAL_FILE  = "colloquial.tsv"
+	EXAMPLES_FILE    = "example-sentences.sqlite"
+	EXAMPLES_SOURCE  = "example-sentences.tsv"
+	PACK_META_FILE   = "pack.json"
+	CHECKSUMS_FILE   = "checksums.txt"
+	SIGNATURE_FILE   = "checksums.txt.sig"
 	INFLECTIONS_FILE = "inflections.db"
 
-	scrollDebounce = 5000 * time.Millisecond // Only allow one scroll event in this timeframe
+	// Per-user state, kept in the same directory as the inflections database.
+	LAST_SESSION_FILE     = "last-session.txt"
+	LAST_VIEW_FILE        = "last-view.json"
+	HISTORY_FILE          = "history.tsv"
+	MARKED_FILE           = "marked.json"
+	MARKED_SENTENCES_FILE = "marked-sentences.tsv"
+	COLLECTIONS_FILE      = "collections.json"
+	NOTES_FILE            = "notes.json"
+	USER_SENTENCES_FILE   = "user-sentences.sqlite"
+	SSH_HOST_KEY_FILE     = "ssh_host_ed25519_key"
+	CONFIG_FILE           = "config.json"
+	QUIZ_FILE             = "quiz.sqlite"
+	TOUR_FILE             = "tour-seen"
+	OVERRIDES_FILE        = "overrides.jsonl"
+	STUDY_LOG_FILE        = "study-log.sqlite"
+	TAGS_FILE             = "tags.tsv"
+	TRUSTED_KEYS_FILE     = "trusted-keys.txt"
+
+	HISTORY_MAX_ENTRIES = 5000  // Oldest history entries beyond this are dropped on save
+	EXAMPLES_PER_PAGE   = 20    // Default number of example sentences per Ctrl-T page
+	SENTENCE_CACHE_SIZE = 64    // Recent Ctrl-T searches whose first page is kept
+	WOTD_MIN_RANK       = 500   // The word of the day skips words more common than this...
+	WOTD_MAX_RANK       = 10000 // ...and rarer than this
+	GLOB_LIMIT          = 500   // Default most words `tsk pattern` lists for a glob like k*ssa
+	DEFAULT_LIST_WIDTH  = 33    // Percent of the width the word list takes, Word Details the rest
+	LIST_WIDTH_STEP     = 5     // Percent Ctrl-Left/Ctrl-Right move the split by
+	MIN_LIST_WIDTH      = 10    // The list can't be made narrower than this...
+	MAX_LIST_WIDTH      = 80    // ...or wider than this, but can be collapsed
+
+	scrollDebounce        = 5000 * time.Millisecond // Only allow one scroll event in this timeframe
+	REVERSE_FIND_DEBOUNCE = 250 * time.Millisecond  // Typing pause before --reverse-find-live searches
 )
 
 // ----------------------
@@ -161,6 +330,7 @@ func printCustomUsage() {
 	fmt.Fprintf(os.Stderr, "USAGE:\n")
 	fmt.Fprintf(os.Stderr, "  tsk [flags]\n")
 	fmt.Fprintf(os.Stderr, "  tsk [flags] [word...]\n")
+	fmt.Fprintf(os.Stderr, "  tsk <subcommand> [flags] [args...]\n")
 	fmt.Fprintf(os.Stderr, "  <command> | tsk [flags]\n\n")
 
 	fmt.Fprintf(os.Stderr, "MODES OF OPERATION:\n")
@@ -175,849 +345,350 @@ func printCustomUsage() {
 	fmt.Fprintf(os.Stderr, "  Direct CLI (by piped input):\n")
 	fmt.Fprintf(os.Stderr, "    Pipe text into the program to look up all words from the input stream.\n")
 	fmt.Fprintf(os.Stderr, "    $ echo \"terve taas\" | tsk\n\n")
+	fmt.Fprintf(os.Stderr, "  Batch lookup (from a word list):\n")
+	fmt.Fprintf(os.Stderr, "    Look up every word in a file and write the results as text, jsonl or csv.\n")
+	fmt.Fprintf(os.Stderr, "    $ tsk --file chapter3.txt --out chapter3.csv\n\n")
+
+	fmt.Fprintf(os.Stderr, "SUBCOMMANDS:\n")
+	for _, doc := range commandDocs {
+		fmt.Fprintf(os.Stderr, "  %-28s %s\n", strings.TrimSpace(doc.name+" "+doc.args), doc.summary)
+	}
+	fmt.Fprintf(os.Stderr, "  Run `tsk help COMMAND` or `tsk COMMAND -h` for a subcommand's flags and an example.\n\n")
 
 	fmt.Fprintf(os.Stderr, "FLAGS:\n")
 	// This helper function prints the default flag information.
 	flag.PrintDefaults()
 }
 
-// ----------------------
-// Trie Data Structure
-// ----------------------
-
-type TrieNode struct {
-	children map[rune]*TrieNode
-	isEnd    bool
-}
-
-func newTrieNode() *TrieNode {
-	return &TrieNode{children: make(map[rune]*TrieNode)}
-}
-
-type Trie struct {
-	root *TrieNode
-}
-
-func NewTrie() *Trie {
-	return &Trie{root: newTrieNode()}
-}
-
-func (t *Trie) Insert(word string) {
-	node := t.root
-	for _, ch := range word {
-		if _, ok := node.children[ch]; !ok {
-			node.children[ch] = newTrieNode()
-		}
-		node = node.children[ch]
-	}
-	node.isEnd = true
-}
-
-func (node *TrieNode) collectWords(prefix string, words *[]string) {
-	if len(*words) >= TRIE_MAX_SEARCH_DEPTH {
-		return
-	}
-	if node.isEnd {
-		*words = append(*words, prefix)
-		if len(*words) >= TRIE_MAX_SEARCH_DEPTH {
-			return
-		}
-	}
-	for ch, child := range node.children {
-		child.collectWords(prefix+string(ch), words)
-		if len(*words) >= TRIE_MAX_SEARCH_DEPTH {
-			return
-		}
+// inflectionGlossText shows the readings of an inflected word followed by
+// the glosses of each base form, for the Word Details pane.
+func inflectionGlossText(word string, glosses map[string][]tsk.Gloss) (string, bool) {
+	lemmas, forms := tsk.GroupAnalyses(tsk.AnalyzeWord(word, glosses))
+	if len(lemmas) == 0 {
+		return "", false
 	}
-}
-
-func (t *Trie) FindWords(prefix string) []string {
-	node := t.root
-	for _, ch := range prefix {
-		next, exists := node.children[ch]
-		if !exists {
-			return []string{}
+	var builder strings.Builder
+	for i, lemma := range lemmas {
+		if i > 0 {
+			builder.WriteString("\n")
 		}
-		node = next
+		fmt.Fprintf(&builder, "[aqua]%s[white] ~> [yellow]%s[white] (%s)\n\n", word, lemma, strings.Join(forms[lemma], ", "))
+		builder.WriteString(generateGlossText(lemma, glosses))
 	}
-	var words []string
-	node.collectWords(prefix, &words)
-	return words
+	return builder.String(), true
 }
 
-func (t *Trie) CountNodes() int {
-	count := 0
-	var traverse func(node *TrieNode)
-	traverse = func(node *TrieNode) {
-		count++
-		for _, child := range node.children {
-			traverse(child)
+// compoundGlossText shows a compound split into headwords, followed by the
+// glosses of each part, for the Word Details pane. A part that is itself
+// an inflected form shows its base forms instead.
+func compoundGlossText(word string, parts []string, glosses map[string][]tsk.Gloss) string {
+	var builder strings.Builder
+	fmt.Fprintf(&builder, "[aqua]%s[white] ~> [yellow]%s[white] (compound analysis)\n", word, strings.Join(parts, "[white] + [yellow]"))
+	builder.WriteString("[gray]Not in the dictionary; these are the glosses of its parts.[white]\n")
+	for _, part := range parts {
+		builder.WriteString("\n")
+		if _, ok := glosses[part]; !ok {
+			if text, ok := inflectionGlossText(part, glosses); ok {
+				builder.WriteString(text)
+				continue
+			}
 		}
+		builder.WriteString(generateGlossText(part, glosses))
 	}
-	traverse(t.root)
-	return count
+	return builder.String()
 }
 
 // ----------------------
-// Utility to load words from embedded data
+// Entry Point
 // ----------------------
 
-func loadWords() ([]string, error) {
-	scanner := bufio.NewScanner(strings.NewReader(wordsTxt))
-	var words []string
-	for scanner.Scan() {
-		line := strings.TrimSpace(scanner.Text())
-		line = strings.Trim(line, "\"")
-		if line != "" {
-			words = append(words, line)
+func main() {
+	if len(os.Args) > 1 && (os.Args[1] == "completion" || os.Args[1] == "__complete") {
+		if cmd := subcommands[os.Args[1]]; cmd != nil {
+			if err := cmd(os.Args[2:]); err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				os.Exit(1)
+			}
+			os.Exit(0)
 		}
 	}
-	return words, scanner.Err()
-}
-
-// ----------------------
-// Utility: Strip tview color tags
-// ----------------------
-
-func stripColorTags(s string) string {
-	// This regex matches any sequence like `[<color>]` or `[<color>:<bgcolor>]`
-	re := regexp.MustCompile(`\[[^\]]*\]`)
-	return re.ReplaceAllString(s, "")
-}
-
-// ----------------------
-// Gloss Data Structures & Loader
-// ----------------------
-
-type Gloss struct {
-	Word     string   `json:"word"`
-	Pos      string   `json:"pos"`
-	Meanings []string `json:"meanings"`
-}
-
-func loadGlosses() (map[string][]Gloss, error) {
-	// Create a reader from the embedded byte slice.
-	reader := bytes.NewReader(glossesGob)
 
-	// Create a new decoder.
-	decoder := gob.NewDecoder(reader)
-
-	// Declare the map to decode into.
-	var glosses map[string][]Gloss
-
-	// Decode the gob data into the map.
-	if err := decoder.Decode(&glosses); err != nil {
-		return nil, err
+	// `tsk lookup WORD...` is plain `tsk WORD...` that never starts the TUI,
+	// so it takes all the same flags, and words that are subcommand names.
+	lookupOnly := len(os.Args) > 1 && os.Args[1] == "lookup"
+	if lookupOnly {
+		os.Args = append(os.Args[:1], os.Args[2:]...)
 	}
 
-	return glosses, nil
-}
+	// Initialize global debug flag.
+	flag.BoolVar(&debug, "debug", false, "print debug info")
+	flag.StringVar(&profile, "profile", "", "keep marks, history and settings separate under this `name`")
+	flag.BoolVar(&noHistory, "no-history", false, "don't save the words you look up to your search history")
+	encrypt := flag.Bool("encrypt", false, "encrypt your marks, notes and history with a passphrase")
+	decrypt := flag.Bool("decrypt", false, "turn off encryption of your data files")
+	flag.IntVar(&examplesPerPage, "examples-per-page", EXAMPLES_PER_PAGE, "show this many example sentences per page in Ctrl-T")
+	flag.BoolVar(&noPrefetch, "no-prefetch", false, "don't look up the selected word's example sentences in the background, on low-powered machines")
+	dataDir := flag.String("data-dir", os.Getenv(DATA_DIR_ENV), "use the data files in this `directory` instead of the built-in ones, file by file (default $"+DATA_DIR_ENV+")")
+	dictPack := flag.String("dict", "", "use the dictionary pack in this `directory or zip file` instead of the built-in Finnish one")
+	batchFile := flag.String("file", "", "look up every word in this `list` (one per line, or a marked-words export) and exit")
+	batchFormatName := flag.String("format", "", "output `format` for lookups: text, jsonl, csv, tsv or quizlet (default from --out's extension, else text)")
+	columnList := flag.String("columns", "", "comma-separated `columns` for csv and tsv, one row per word and part of speech: "+strings.Join(lookupColumns, ", ")+", meaning1...")
+	templateFile := flag.String("template", "", "write lookups with this text/template `file`, executed once per word and part of speech found")
+	batchOut := flag.String("out", "", "write --file's results to this `file` instead of stdout")
+	themeName := flag.String("theme", DEFAULT_THEME, "color `scheme` for the TUI: "+strings.Join(themeNames(), ", "))
+	flag.BoolVar(&noColor, "no-color", os.Getenv("NO_COLOR") != "", "don't use color, for monochrome terminals (default true if $NO_COLOR is set)")
+	plain := flag.Bool("plain", false, "no color, banner or progress notes, for scripts")
+	editingName := flag.String("editing", DEFAULT_EDITING, "key `mode` for the search fields: "+strings.Join(editingModes, ", "))
+	wiktionaryName := flag.String("wiktionary", DEFAULT_WIKTIONARY, "language `code` of the Wiktionary edition to open words in, e.g. en or fi")
+	flag.BoolVar(&forceTour, "tour", false, "show the first-run tour of the TUI again")
+	flag.BoolVar(&interactive, "i", false, "look words up at a plain prompt instead of in the TUI, for dumb terminals (see :help there)")
+	flag.BoolVar(&interactive, "interactive", false, "the same as -i")
+	flag.BoolVar(&noMouse, "no-mouse", false, "leave the mouse to the terminal, for selecting text, instead of clicking words to look them up")
+	flag.IntVar(&exportExamples, "export-examples", 0, "export up to `N` example sentences with each marked word")
+	exportProfileFlag := flag.String("export-profile", "", "save marked words on quit with this export `profile`, e.g. anki, quizlet, notes or raw, instead of as JSONL and a word list")
+	flag.BoolVar(&exportDeeper, "export-deeper", false, "also export the glosses of the words marked words are forms of, like omena for omenan")
+	flag.StringVar(&markCollection, "collection", "", "put the words you mark in this `collection` too, e.g. \"Chapter 7\"")
+	flag.BoolVar(&foldDiacritics, "fold-diacritics", false, "search ignoring diacritics in the TUI, so oljy finds öljy")
+	flag.IntVar(&maxResults, "max-results", tsk.MaxResults, "list at most `N` words per search")
+	flag.IntVar(&speechRate, "speech-rate", DEFAULT_SPEECH_RATE, fmt.Sprintf("say words and sentences at this `percent` of normal speed, %d to %d", MIN_SPEECH_RATE, MAX_SPEECH_RATE))
+	flag.BoolVar(&studyLog, "study-log", false, "log your lookups and marks, for tsk report")
+	flag.BoolVar(&checkUpdates, "check-updates", false, "check GitHub once a day for a newer tsk, and say so in the TUI's header")
+	flag.BoolVar(&reverseFindLive, "reverse-find-live", false, "search as you type in reverse-find, instead of on Enter")
+	flag.StringVar(&surpriseBand, "surprise-band", "", "only pick random words (Alt-S) this common: "+strings.Join(frequencyBandNames(), ", "))
+	flag.IntVar(&senseOnly, "sense", 0, "print only sense `N` of each word looked up, as Word Details numbers them")
+	flag.StringVar(&posOnly, "pos", "", "only show words of this `part of speech`: "+strings.Join(posFilters, ", ")+", ...")
+	oneshot := flag.String("oneshot", "", "show only the Word Details of this `word`, e.g. in a tmux popup, and exit on any key")
+	annotate := flag.Bool("annotate", false, "copy standard input to standard output with a gloss after each Finnish word")
+	annotateStyle := flag.String("annotate-style", "inline", "where --annotate puts the glosses: `inline` or footnotes")
+	flag.StringVar(&pprofAddr, "pprof", "", "serve Go's profiler on this loopback `address`, e.g. localhost:6060, while tsk runs")
+	flag.Usage = printCustomUsage
+	flag.Parse()
 
-// getDeeperGlosses is a recursive helper that looks for linkable phrases in a meaning string,
-// fetches their definitions, and formats them with the appropriate indentation and color
-// based on the recursion depth. It recurses one level deep to handle nested definitions.
-func getDeeperGlosses(text string, glosses map[string][]Gloss, level int) string {
-	// Base case: We only go two levels deep (level 1 and level 2).
-	if level > 2 {
-		return ""
+	// Annotated text, lookups in jsonl, csv or tsv on standard output, and
+	// the language server's messages are read by other programs, so they get
+	// no banner or progress notes, and neither do the quiet subcommands or
+	// anything with --plain.
+	chatter := io.Writer(os.Stdout)
+	_, quiet := quietSubcommands[flag.Arg(0)]
+	if *plain || *annotate || *oneshot != "" || (*batchOut == "" && ((*batchFormatName != "" && *batchFormatName != "text") || *templateFile != "")) || flag.Arg(0) == "lsp" || (quiet && !lookupOnly) ||
+		((flag.Arg(0) == "stats" || flag.Arg(0) == "report") && (slices.Contains(flag.Args(), "--json") || slices.Contains(flag.Args(), "-json"))) {
+		chatter = io.Discard
+	}
+	fmt.Fprintln(chatter, fmt.Sprintf("tsk (%s) - Andrew's Pocket Finnish Dictionary\n", version))
+	fmt.Fprintln(chatter, "Project @ https://github.com/hiAndrewQuinn/tsk")
+	fmt.Fprint(chatter, "Author  @ https://andrew-quinn.me/\n\n")
+
+	if profile != "" && !validProfileName(profile) {
+		fmt.Fprintf(os.Stderr, "Invalid profile name '%s'. Use letters, digits, '-' and '_' only.\n", profile)
+		os.Exit(1)
 	}
 
-	var builder strings.Builder
-
-	// Local helper for cleaning the target word found after a prefix.
-	extractTarget := func(meaning, prefix string) string {
-		target := strings.TrimRight(strings.TrimSpace(strings.TrimPrefix(meaning, prefix)), ".,:;!?")
-		if idx := strings.Index(target, "("); idx != -1 {
-			target = strings.TrimSpace(target[:idx])
-		}
-		if idx := strings.Index(target, ";"); idx != -1 {
-			target = strings.TrimSpace(target[:idx])
+	if *encrypt {
+		if err := enableEncryption(); err != nil {
+			fmt.Fprintln(os.Stderr, "Error enabling encryption:", err)
+			os.Exit(1)
 		}
-		return target
-	}
-
-	// Define formatting based on recursion level to match the original output.
-	var glossFormat, meaningFormat string
-	if level == 1 {
-		glossFormat = "[lightgray]  ~> %s (%s)[white]\n"
-		meaningFormat = "[lightgray]      - %s[white]\n"
-	} else { // level == 2
-		glossFormat = "[gray]         ~> %s (%s)[white]\n"
-		meaningFormat = "[gray]            - %s[white]\n"
-	}
-
-	// Main logic: find prefix, extract target, look up glosses, and format.
-	if prefix, found := findLongestPrefix(text); found {
-		target := extractTarget(text, prefix)
-		if targetGlosses, ok := glosses[target]; ok {
-			for _, tg := range targetGlosses {
-				builder.WriteString(fmt.Sprintf(glossFormat, tg.Word, tg.Pos))
-				for _, tm := range tg.Meanings {
-					builder.WriteString(fmt.Sprintf(meaningFormat, tm))
-					// Recursive call for the next level deep.
-					builder.WriteString(getDeeperGlosses(tm, glosses, level+1))
-				}
-			}
+		fmt.Println("Encryption enabled. You will be asked for your passphrase when tsk starts.")
+	} else if *decrypt {
+		if err := disableEncryption(); err != nil {
+			fmt.Fprintln(os.Stderr, "Error disabling encryption:", err)
+			os.Exit(1)
 		}
+		fmt.Println("Encryption disabled. Your data files are stored as plain text again.")
 	}
 
-	return builder.String()
-}
-
-// generateGlossText creates the formatted string for a word's details.
-// This is used by both the main view and the reverse-find modal.
-func generateGlossText(word string, glosses map[string][]Gloss) string {
-	if glossSlice, ok := glosses[word]; ok {
-		var formatted string
-
-		for i, gloss := range glossSlice {
-			if debug {
-				log.Printf("generateGlossText: processing gloss[%d]: %s (%s)", i, gloss.Word, gloss.Pos)
-			}
-			if i > 0 {
-				formatted += "\n"
-			}
-			formatted += fmt.Sprintf("[white]%s [yellow](%s)[white]\n\n", gloss.Word, gloss.Pos)
-			for _, meaning := range gloss.Meanings {
-				if debug {
-					log.Printf("generateGlossText: processing meaning: %s", meaning)
-				}
-				formatted += fmt.Sprintf("- %s\n", meaning)
-
-				// Call the recursive helper function to get all deeper glosses.
-				formatted += getDeeperGlosses(meaning, glosses, 1)
-			}
-		}
-		return formatted
+	// Flags given on the command line win over the config file.
+	config, err := loadUserConfig()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "[WARNING] Could not read %s: %v\n", CONFIG_FILE, err)
 	}
-
-	if debug {
-		log.Printf("generateGlossText: no gloss available for word: %s", word)
+	setFlags := make(map[string]bool)
+	flag.Visit(func(f *flag.Flag) { setFlags[f.Name] = true })
+	if !setFlags["examples-per-page"] && config.ExamplesPerPage > 0 {
+		examplesPerPage = config.ExamplesPerPage
 	}
-	return fmt.Sprintf("%s\n\nNo gloss available.", word)
-}
-
-// ----------------------
-// Go Deeper Loader and Prefix Lookup
-// ----------------------
-
-func loadDeeperPhrases() ([]string, error) {
-	scanner := bufio.NewScanner(strings.NewReader(goDeeperTxt))
-	var phrases []string
-	for scanner.Scan() {
-		line := strings.TrimSpace(scanner.Text())
-		if line != "" {
-			phrases = append(phrases, line)
-		}
+	if examplesPerPage < 1 {
+		examplesPerPage = 1
 	}
-	return phrases, scanner.Err()
-}
-
-var (
-	deeperPrefixMap     map[string]struct{}
-	deeperPrefixLengths []int
-)
-
-// initDeeperPrefixes builds a hashmap for lookups where the keys are each phrase
-// from go-deeper.txt with an appended space. It also builds a slice of key lengths,
-// sorted in descending order so that the longest (most precise) prefix is matched first.
-func initDeeperPrefixes() error {
-	phrases, err := loadDeeperPhrases()
-	if err != nil {
-		return err
+	if !setFlags["no-prefetch"] {
+		noPrefetch = config.NoPrefetch
 	}
-	deeperPrefixMap = make(map[string]struct{}, len(phrases))
-	lengthSet := make(map[int]struct{})
-	for _, phrase := range phrases {
-		key := phrase + " "
-		deeperPrefixMap[key] = struct{}{}
-		lengthSet[len(key)] = struct{}{}
+	if !setFlags["theme"] && config.Theme != "" {
+		*themeName = config.Theme
 	}
-	for l := range lengthSet {
-		deeperPrefixLengths = append(deeperPrefixLengths, l)
+	if err := useTheme(*themeName); err != nil {
+		fmt.Fprintln(os.Stderr, "Error:", err)
+		os.Exit(1)
 	}
-	// Sort lengths in descending order.
-	sort.Sort(sort.Reverse(sort.IntSlice(deeperPrefixLengths)))
-	return nil
-}
-
-func findLongestPrefix(s string) (string, bool) {
-	if debug {
-		log.Printf("findLongestPrefix: Checking for prefixes which match '%s'", s)
+	if !setFlags["no-color"] && config.NoColor {
+		noColor = true
 	}
-
-	// Split the input string into words.
-	words := strings.Fields(s)
-
-	// Start with the full set of words and remove one word at a time.
-	for i := len(words); i > 0; i-- {
-		// Join the first i words with a space and add a trailing space.
-		candidate := strings.Join(words[:i], " ") + " "
-		if debug {
-			log.Printf("findLongestPrefix: Is '%s' in deeperPrefixMap?", candidate)
-		}
-
-		if _, ok := deeperPrefixMap[candidate]; ok {
-			if debug {
-				log.Printf("findLongestPrefix: Yes! Returning '%s' from deeperPrefixMap.", candidate)
-			}
-			return candidate, true
-		}
+	if *plain {
+		noColor = true
 	}
-
-	return "", false
-}
-
-// ----------------------
-// Utility: Open URL in default browser
-// ----------------------
-
-func openBrowser(url string) error {
-	var cmd *exec.Cmd
-	switch runtime.GOOS {
-	case "linux":
-		cmd = exec.Command("xdg-open", url)
-	case "windows":
-		cmd = exec.Command("rundll32", "url.dll,FileProtocolHandler", url)
-	case "darwin":
-		cmd = exec.Command("open", url)
-	default:
-		return fmt.Errorf("unsupported platform")
-	}
-	return cmd.Start()
-}
-
-// ----------------------
-// Utility: Clean up SQL terms properly
-//
-
-func cleanTerm(s string) string {
-	// Trim off any leading/trailing non-letters
-	start, end := 0, len(s)
-	for start < end && !unicode.IsLetter(rune(s[start])) {
-		start++
+	if noColor {
+		useMonochrome()
 	}
-	for end > start && !unicode.IsLetter(rune(s[end-1])) {
-		end--
+	if !setFlags["wiktionary"] && config.Wiktionary != "" {
+		*wiktionaryName = config.Wiktionary
 	}
-	return s[start:end]
-}
-
-// ----------------------------------------------------
-// --- NEW --- Inflection Search Modal (Ctrl-I)
-// ----------------------------------------------------
-func showInflectionSearchModal(pages *tview.Pages, glosses map[string][]Gloss, app *tview.Application, mainInputField *tview.InputField, db *sql.DB) {
-	const modalPageName = "inflectionSearch"
-	if debug {
-		log.Println("showInflectionSearchModal: Function called.")
+	if err := useWiktionary(*wiktionaryName); err != nil {
+		fmt.Fprintln(os.Stderr, "Error:", err)
+		os.Exit(1)
 	}
-
-	const inflectionHelpText = `[gray]
-	Keybindings:
-
-	Up/Down     = Scroll result list.
-
-	[green]Enter on a result[gray] in the list to select its base form and return to the main view.
-	[red]Esc[gray] or [red]Enter on an empty search bar[gray] to close this window.
-	
-	This feature searches for a word's base form in real-time.
-	A minimum of 3 characters is required to begin a search.
-
-	[white]
-	`
-
-	const (
-		modalBgColor        = tcell.ColorSteelBlue
-		modalHeaderFooterBg = tcell.ColorDarkSlateGray
-		modalDetailsBg      = tcell.ColorMidnightBlue
-		modalPrimaryColor   = tcell.ColorLightCyan
-		modalAccentColor    = tcell.ColorAqua
-		modalFieldBgColor   = tcell.ColorDarkBlue
-		modalListSelectBg   = tcell.ColorDarkSlateGray
-		modalListSelectText = tcell.ColorAqua
-	)
-
-	// --- Components ---
-	searchInput := tview.NewInputField().
-		SetLabel("Inflected form: ").
-		SetLabelColor(modalAccentColor).
-		SetFieldBackgroundColor(modalFieldBgColor).
-		SetFieldTextColor(modalPrimaryColor).
-		SetFieldWidth(30)
-
-	resultsList := tview.NewList().
-		ShowSecondaryText(false).
-		SetSelectedBackgroundColor(modalListSelectBg).
-		SetSelectedTextColor(modalListSelectText)
-
-	detailsView := tview.NewTextView().
-		SetDynamicColors(true).
-		SetScrollable(true).
-		SetWrap(true).
-		SetWordWrap(true).
-		SetTextColor(modalPrimaryColor).
-		SetText("[blue]Type 3 characters or more to start searching.[white]") // Initial message
-
-	detailsView.SetBorder(true).
-		SetTitle("Base Form Details (Tab/Shift-Tab to scroll)").
-		SetBorderColor(modalAccentColor).
-		SetTitleColor(modalAccentColor)
-	detailsView.SetBackgroundColor(modalDetailsBg)
-
-	// --- Main Layout ---
-	contentFlex := tview.NewFlex().
-		SetDirection(tview.FlexColumn).
-		AddItem(
-			tview.NewFlex().SetDirection(tview.FlexRow).
-				AddItem(searchInput, 3, 1, true).
-				AddItem(resultsList, 0, 4, false),
-			0, 1, true,
-		).
-		AddItem(detailsView, 0, 2, false)
-	contentFlex.SetBackgroundColor(modalBgColor)
-
-	// --- Header & Footer ---
-	header := tview.NewTextView().
-		SetText(fmt.Sprintf("tsk (%s) - Inflection Search", version)).
-		SetTextAlign(tview.AlignCenter).
-		SetTextColor(modalPrimaryColor).
-		SetBackgroundColor(modalHeaderFooterBg)
-
-	footer := tview.NewTextView().
-		SetText("Esc to close. Enter on result to select.").
-		SetTextAlign(tview.AlignCenter).
-		SetTextColor(modalPrimaryColor).
-		SetBackgroundColor(modalHeaderFooterBg)
-
-	// --- Final Modal Layout ---
-	modalLayout := tview.NewFlex().
-		SetDirection(tview.FlexRow).
-		AddItem(header, 1, 0, false).
-		AddItem(nil, 1, 0, false).
-		AddItem(contentFlex, 0, 1, true).
-		AddItem(nil, 1, 0, false).
-		AddItem(footer, 1, 0, false)
-	modalLayout.SetBackgroundColor(modalBgColor)
-
-	// --- Event Handlers ---
-
-	// When selection in list changes, update the details view
-	resultsList.SetChangedFunc(func(index int, mainText, secondaryText string, shortcut rune) {
-		parts := strings.Split(mainText, " ~> ")
-		if len(parts) != 2 {
-			detailsView.SetText(fmt.Sprintf("[red]Error parsing result: %s[white]", mainText))
-			return
-		}
-		inflection, baseWord := parts[0], parts[1]
-
-		var builder strings.Builder
-		builder.WriteString(fmt.Sprintf("[aqua]%s[white] ~> [yellow]%s[white]\n\n", inflection, baseWord))
-		builder.WriteString(generateGlossText(baseWord, glosses))
-
-		detailsView.SetText(builder.String()).ScrollToBeginning()
-	})
-
-	// When a list item is selected with Enter, go back to main view
-	resultsList.SetSelectedFunc(func(index int, mainText, secondaryText string, shortcut rune) {
-		parts := strings.Split(mainText, " ~> ")
-		if len(parts) == 2 {
-			baseWord := parts[1]
-			mainInputField.SetText(baseWord)
-		}
-		pages.RemovePage(modalPageName)
-		app.SetFocus(mainInputField)
-	})
-
-	// When input text changes, run a search
-	searchInput.SetChangedFunc(func(text string) {
-		query := strings.TrimSpace(text)
-		resultsList.Clear()
-		detailsView.Clear().ScrollToBeginning()
-
-		if len(query) < 3 {
-			detailsView.SetText("[blue]Type 3 characters or more to start searching.[white]")
-			return
-		}
-
-		// Prepare and run the FTS5 prefix query
-		ftsQuery := query + "*"
-		q := "SELECT inflection, word FROM inflections_fts WHERE inflection MATCH ? ORDER BY RANDOM() LIMIT 50"
-		rows, err := db.Query(q, ftsQuery)
-		if err != nil {
-			detailsView.SetText(fmt.Sprintf("[red]Database query failed: %v[white]", err))
-			return
-		}
-		defer rows.Close()
-
-		found := false
-		for rows.Next() {
-			found = true
-			var inflection, word string
-			if err := rows.Scan(&inflection, &word); err != nil {
-				continue // Skip malformed rows
-			}
-			displayString := fmt.Sprintf("%s ~> %s", inflection, word)
-			resultsList.AddItem(displayString, "", 0, nil)
-		}
-		resultsList.SetCurrentItem(0)
-
-		if !found {
-			detailsView.SetText(fmt.Sprintf("[red]No base form found for '[darkred:%s]'.[white]", query))
-		}
-	})
-
-	// Handle special keys in the input field
-	searchInput.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
-		switch event.Key() {
-		case tcell.KeyEsc:
-			pages.RemovePage(modalPageName)
-			return nil
-		case tcell.KeyEnter:
-			if searchInput.GetText() == "" {
-				pages.RemovePage(modalPageName)
-			} else {
-				// Transfer focus to list to allow selection
-				app.SetFocus(resultsList)
-			}
-			return nil
-		case tcell.KeyDown:
-			app.SetFocus(resultsList)
-			cur := resultsList.GetCurrentItem()
-			if cur < resultsList.GetItemCount()-1 {
-				resultsList.SetCurrentItem(cur + 1)
-			}
-			return nil
-		case tcell.KeyUp:
-			app.SetFocus(resultsList)
-			cur := resultsList.GetCurrentItem()
-			if cur > 0 {
-				resultsList.SetCurrentItem(cur - 1)
-			}
-			return nil
-		case tcell.KeyTab:
-			app.SetFocus(detailsView)
-			row, col := detailsView.GetScrollOffset()
-			detailsView.ScrollTo(row+1, col)
-			return nil
-		case tcell.KeyBacktab:
-			app.SetFocus(detailsView)
-			row, col := detailsView.GetScrollOffset()
-			newRow := row - 1
-			if newRow < 0 {
-				newRow = 0
-			}
-			detailsView.ScrollTo(newRow, col)
-			return nil
-		}
-		return event
-	})
-
-	pages.AddPage(modalPageName, modalLayout, true, true)
-	app.SetFocus(searchInput)
-}
-
-// showMeaningSearchModal creates and displays a modal window for searching word meanings.
-// This modal is designed to look and feel like the main application window, with a
-// two-pane layout for search/results and details.
-// MODIFIED: Added mainInputField to the function signature to allow interaction with the main view.
-func showMeaningSearchModal(pages *tview.Pages, glosses map[string][]Gloss, app *tview.Application, mainInputField *tview.InputField) {
-	if debug {
-		log.Println("showMeaningSearchModal: Function called.")
+	if !setFlags["editing"] && config.Editing != "" {
+		*editingName = config.Editing
+	}
+	if !setFlags["no-mouse"] {
+		noMouse = config.NoMouse
+	}
+	if !setFlags["export-deeper"] {
+		exportDeeper = config.ExportDeeper
+	}
+	if !setFlags["export-examples"] {
+		exportExamples = config.ExportExamples
+	}
+	if !setFlags["collection"] {
+		markCollection = config.Collection
+	}
+	noResume = config.NoResume
+	if config.ListWidth > 0 {
+		listWidth = min(max(config.ListWidth, MIN_LIST_WIDTH), MAX_LIST_WIDTH)
+	}
+	listCollapsed = config.ListCollapsed
+	if !setFlags["fold-diacritics"] {
+		foldDiacritics = config.FoldDiacritics
+	}
+	if !setFlags["max-results"] && config.MaxResults > 0 {
+		maxResults = config.MaxResults
+	}
+	if !setFlags["speech-rate"] && config.SpeechRate > 0 {
+		speechRate = config.SpeechRate
+	}
+	speechRate = min(max(speechRate, MIN_SPEECH_RATE), MAX_SPEECH_RATE)
+	if !setFlags["study-log"] {
+		studyLog = config.StudyLog
+	}
+	if !setFlags["check-updates"] {
+		checkUpdates = config.CheckUpdates
+	}
+	if !setFlags["reverse-find-live"] {
+		reverseFindLive = config.ReverseFindLive
+	}
+	if !setFlags["surprise-band"] {
+		surpriseBand = config.SurpriseBand
+	}
+	if err := checkFrequencyBand(surpriseBand); err != nil {
+		fmt.Fprintln(os.Stderr, "Error:", err)
+		os.Exit(1)
+	}
+	if err := useEditingMode(*editingName); err != nil {
+		fmt.Fprintln(os.Stderr, "Error:", err)
+		os.Exit(1)
+	}
+	if err := useKeymap(config.Keys); err != nil {
+		fmt.Fprintln(os.Stderr, "Error:", err)
+		os.Exit(1)
+	}
+	if !setFlags["export-profile"] {
+		*exportProfileFlag = config.ExportProfile
+	}
+	if err := useExportProfiles(config.ExportProfiles, *exportProfileFlag); err != nil {
+		fmt.Fprintln(os.Stderr, "Error:", err)
+		os.Exit(1)
 	}
 
-	// --- NEW: Help text specific to this modal ---
-	const reverseFindHelpText = `[gray]
-	Keybindings:
-
-	Enter       = Search for the English term.
-	Up/Down     = Scroll result list.
-
-	[green]Enter on a result[gray] in the list to select it and return to the main view.
-	[red]Enter on an empty search bar[gray] to close this window and return to the main view.
-	
-	Unlike the normal Finnish lookup, this mode does *not* search as you type.
-	You aren't supposed to stay here for long...
-
-	[white]
-	`
-
-	// --- NEW: Color Theme for Modal ---
-	const (
-		// Main background set to a dark violet.
-		modalBgColor = tcell.ColorDarkViolet
-
-		// Header/footer set to an even darker purple for contrast.
-		modalHeaderFooterBg = tcell.ColorIndigo
-		modalDetailsBg      = tcell.ColorMidnightBlue
-
-		// Text remains white for readability.
-		modalPrimaryColor = tcell.ColorGold
-
-		// Accents (borders, titles) are now a lighter purple.
-		modalAccentColor = tcell.ColorPlum
-
-		// Input field background is a muted purple.
-		modalFieldBgColor = tcell.ColorRebeccaPurple
-
-		// Selection colors for the list remain high-contrast.
-		modalListSelectBg   = tcell.ColorIndigo
-		modalListSelectText = tcell.ColorGold
-	)
-
-	// --- Components ---
-
-	// Left Pane: Search Input & Results List
-	searchInput := tview.NewInputField().
-		SetLabel("English term: ").
-		SetLabelColor(modalAccentColor).            // NEW: Color
-		SetFieldBackgroundColor(modalFieldBgColor). // NEW: Color
-		SetFieldTextColor(modalPrimaryColor).       // NEW: Color
-		SetFieldWidth(30)
-
-	resultsList := tview.NewList().
-		ShowSecondaryText(false).
-		SetSelectedBackgroundColor(modalListSelectBg). // NEW: Color
-		SetSelectedTextColor(modalListSelectText)      // NEW: Color
-
-	// Right Pane: Details Display
-	detailsView := tview.NewTextView().
-		SetDynamicColors(true).
-		SetScrollable(true).
-		SetWrap(true).
-		SetWordWrap(true).
-		SetTextColor(modalPrimaryColor)
-
-	detailsView.SetBorder(true).
-		SetTitle("Word Details (Tab/Shift-Tab to scroll)").
-		SetBorderColor(modalAccentColor). // NEW: Color
-		SetTitleColor(modalAccentColor)   // NEW: Color
-
-	// Set the initial help text for this modal.
-	detailsView.SetText(reverseFindHelpText)
-
-	detailsView.SetBackgroundColor(modalDetailsBg)
-
-	// --- Main Content Layout (The two panes) ---
-	contentFlex := tview.NewFlex().
-		SetDirection(tview.FlexColumn).
-		AddItem(
-			tview.NewFlex().SetDirection(tview.FlexRow).
-				AddItem(searchInput, 3, 1, true).
-				AddItem(resultsList, 0, 4, false),
-			0, 1, true,
-		).
-		AddItem(detailsView, 0, 2, false)
-	contentFlex.SetBackgroundColor(modalBgColor) // NEW: Set overall background
-
-	// --- Header ---
-	headerLeft := tview.NewTextView().
-		SetText(fmt.Sprintf("tsk (%s) - Reverse-Find by English Meaning", version)).
-		SetTextAlign(tview.AlignLeft).
-		SetTextColor(modalPrimaryColor) // NEW: Color
-	headerLeft.SetBackgroundColor(modalHeaderFooterBg)
-
-	headerRight := tview.NewButton("[::u]https://github.com/hiAndrewQuinn/tsk[::-]")
-	headerRight.SetLabelColor(modalPrimaryColor) // NEW: Color
-	headerRight.SetSelectedFunc(func() {
-		if err := openBrowser("https://github.com/hiAndrewQuinn/tsk"); err != nil {
-			fmt.Fprintf(os.Stderr, "Error opening browser: %v\n", err)
-		}
-	})
-
-	headerFlex := tview.NewFlex().SetDirection(tview.FlexColumn)
-	headerFlex.SetBackgroundColor(modalHeaderFooterBg) // NEW: Color
-	headerFlex.
-		AddItem(headerLeft, 0, 1, false).
-		AddItem(headerRight, 40, 0, false)
-
-	// --- Footer ---
-	footerLeft := tview.NewTextView().
-		SetText("Esc to close. Enter to search. Up/Down to scroll results.").
-		SetTextAlign(tview.AlignLeft).
-		SetTextColor(modalPrimaryColor) // NEW: Color
-	footerLeft.SetBackgroundColor(modalHeaderFooterBg)
-
-	footerRight := tview.NewButton("[::u]https://andrew-quinn.me/[::-]")
-	footerRight.SetLabelColor(modalPrimaryColor) // NEW: Color
-	footerRight.SetSelectedFunc(func() {
-		if err := openBrowser("https://andrew-quinn.me/"); err != nil {
-			fmt.Fprintf(os.Stderr, "Error opening browser: %v\n", err)
+	// Pick the dictionary pack.
+	if *dictPack == "" {
+		*dictPack = config.Dict
+	}
+	if *dictPack != "" {
+		pack, err := openDictionaryPack(*dictPack)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error loading dictionary pack '%s': %v\n", *dictPack, err)
+			os.Exit(1)
 		}
-	})
-
-	footerFlex := tview.NewFlex().SetDirection(tview.FlexColumn)
-	footerFlex.SetBackgroundColor(modalHeaderFooterBg) // NEW: Color
-	footerFlex.
-		AddItem(footerLeft, 0, 1, false).
-		AddItem(footerRight, 40, 0, false)
-
-	// --- Final Modal Layout (mimicking mainFlex) ---
-	modalLayout := tview.NewFlex().
-		SetDirection(tview.FlexRow).
-		AddItem(headerFlex, 1, 0, false).
-		AddItem(nil, 1, 0, false). // Spacer
-		AddItem(contentFlex, 0, 1, true).
-		AddItem(nil, 1, 0, false). // Spacer
-		AddItem(footerFlex, 1, 0, false)
-	modalLayout.SetBackgroundColor(modalBgColor) // NEW: Set overall background
-
-	// --- Logic & Event Handlers ---
-
-	searchAction := func() {
+		activePack = pack
+		fmt.Fprintf(chatter, "Using dictionary pack %s from %s\n", pack.Describe(), *dictPack)
+	} else if pack, err := openUpdatedData(); err != nil {
+		fmt.Fprintf(os.Stderr, "[WARNING] Could not load the data from `tsk update-data`, using the embedded copies: %v\n", err)
+	} else if pack != nil {
+		activePack = pack
 		if debug {
-			log.Println("showMeaningSearchModal: searchAction triggered.")
+			log.Printf("Using %s from %s", pack.Describe(), pack.Path)
 		}
-		query := strings.ToLower(strings.TrimSpace(searchInput.GetText()))
-		if debug {
-			log.Printf("showMeaningSearchModal: Cleaned query: '%s'", query)
+	}
+	if *dataDir != "" {
+		if info, err := os.Stat(*dataDir); err != nil || !info.IsDir() {
+			fmt.Fprintf(os.Stderr, "Error: --data-dir '%s' is not a directory\n", *dataDir)
+			os.Exit(1)
 		}
-
-		resultsList.Clear()
-		detailsView.Clear().ScrollToBeginning()
-
-		// This check is now redundant because SetDoneFunc handles the empty case,
-		// but it's harmless to leave as a safeguard.
-		if query == "" {
-			detailsView.SetText(reverseFindHelpText)
-			return
+		pack, err := overlayPack(activePack, *dataDir)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error loading data files from '%s': %v\n", *dataDir, err)
+			os.Exit(1)
 		}
+		activePack = pack
+	}
 
-		foundMap := make(map[string]struct{})
-		for word, glossSlice := range glosses {
-			for _, gloss := range glossSlice {
-				for _, meaning := range gloss.Meanings {
-					if strings.Contains(strings.ToLower(meaning), query) {
-						foundMap[word] = struct{}{}
-						break
-					}
-				}
-			}
+	if !lookupOnly && flag.Arg(0) == "help" {
+		if err := runHelpCommand(flag.Args()[1:]); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
 		}
+		os.Exit(0)
+	}
 
-		if len(foundMap) == 0 {
-			detailsView.SetText(fmt.Sprintf("[red]No words found with meaning containing '[darkred:%s]'.[white]", query))
-		} else {
-			matches := make([]string, 0, len(foundMap))
-			for word := range foundMap {
-				matches = append(matches, word)
-			}
-			sort.Strings(matches)
-
-			for _, match := range matches {
-				resultsList.AddItem(match, "", 0, nil)
-			}
-			resultsList.SetCurrentItem(0)
+	// -------------------------------
+	// Popup lookup (`tsk --oneshot WORD`)
+	// -------------------------------
+	if *oneshot != "" {
+		if err := runOneshot(*oneshot); err != nil {
+			fmt.Fprintln(os.Stderr, "Error:", err)
+			os.Exit(1)
 		}
+		os.Exit(0)
 	}
 
-	resultsList.SetChangedFunc(func(index int, mainText, secondaryText string, shortcut rune) {
-		glossText := generateGlossText(mainText, glosses)
-		detailsView.SetText(glossText).ScrollToBeginning()
-	})
-
-	// NEW: Add a selection handler to the list.
-	// When the user presses Enter on a list item, this function is called.
-	resultsList.SetSelectedFunc(func(index int, mainText, secondaryText string, shortcut rune) {
-		// Set the main application's search bar text to the selected word.
-		mainInputField.SetText(mainText)
-		// Close the modal.
-		pages.RemovePage("meaningSearch")
-		// Set focus back to the main input field for a seamless transition.
-		app.SetFocus(mainInputField)
-	})
-
-	// MODIFIED: Updated the DoneFunc to handle exiting on an empty search.
-	searchInput.SetDoneFunc(func(key tcell.Key) {
-		if key == tcell.KeyEnter {
-			query := strings.TrimSpace(searchInput.GetText())
-			if query == "" {
-				// If the search bar is empty, just close the modal.
-				pages.RemovePage("meaningSearch")
-			} else {
-				// Otherwise, perform the search.
-				searchAction()
-			}
+	// -------------------------------
+	// Annotated reading (`cat article.txt | tsk --annotate`)
+	// -------------------------------
+	if *annotate {
+		if *annotateStyle != "inline" && *annotateStyle != "footnotes" {
+			fmt.Fprintf(os.Stderr, "Error: unknown --annotate-style '%s' (choose from inline, footnotes)\n", *annotateStyle)
+			os.Exit(1)
 		}
-	})
-
-	searchInput.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
-		// MODIFIED: Give focus to the list on Down/Up arrow keys to enable selection.
-		switch event.Key() {
-		case tcell.KeyEsc:
-			pages.RemovePage("meaningSearch")
-			return nil
-		case tcell.KeyDown:
-			app.SetFocus(resultsList)
-			cur := resultsList.GetCurrentItem()
-			if cur < resultsList.GetItemCount()-1 {
-				resultsList.SetCurrentItem(cur + 1)
-			}
-			return nil
-		case tcell.KeyUp:
-			app.SetFocus(resultsList)
-			cur := resultsList.GetCurrentItem()
-			if cur > 0 {
-				resultsList.SetCurrentItem(cur - 1)
-			}
-			return nil
-		case tcell.KeyTab:
-			row, col := detailsView.GetScrollOffset()
-			detailsView.ScrollTo(row+1, col)
-			return nil
-		case tcell.KeyBacktab:
-			row, col := detailsView.GetScrollOffset()
-			newRow := row - 1
-			if newRow < 0 {
-				newRow = 0
-			}
-			detailsView.ScrollTo(newRow, col)
-			return nil
+		glosses, err := loadGlosses()
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Error loading glosses:", err)
+			os.Exit(1)
 		}
-		return event
-	})
-
-	// --- FIX #1: Add the modal to the pages view to make it visible. ---
-	if debug {
-		log.Println("showMeaningSearchModal: Adding 'meaningSearch' page to pages container.")
+		if err := annotateText(os.Stdin, os.Stdout, glosses, *annotateStyle == "footnotes"); err != nil {
+			fmt.Fprintln(os.Stderr, "Error:", err)
+			os.Exit(1)
+		}
+		os.Exit(0)
 	}
-	pages.AddPage("meaningSearch", modalLayout, true, true)
-}
-
-// ----------------------
-// Main TUI Application
-// ----------------------
-
-func main() {
-
-	fmt.Println(fmt.Sprintf("tsk (%s) - Andrew's Pocket Finnish Dictionary\n", version))
-	fmt.Println("Project @ https://github.com/hiAndrewQuinn/tsk")
-	fmt.Println("Author  @ https://andrew-quinn.me/\n")
-
-	// Initialize global debug flag.
-	flag.BoolVar(&debug, "debug", false, "print debug info")
-	flag.Parse()
-
-	flag.Usage = printCustomUsage
 
 	// Attempt to load the optional inflections database.
-	configDir, err := os.UserConfigDir()
+	tskDir, err := tskConfigDir()
 	if err != nil {
 		// This is a rare error, but good to handle.
 		fmt.Fprintf(os.Stderr, "[WARNING] Could not determine user config directory: %v. Ctrl-I search is disabled.\n", err)
 	} else {
 		// Construct the full, platform-agnostic path to the database.
 		// It's good practice to put your app's data in a dedicated subdirectory.
-		inflectionsDBPath := filepath.Join(configDir, "tsk", INFLECTIONS_FILE)
+		inflectionsDBPath := filepath.Join(tskDir, INFLECTIONS_FILE)
 
 		// Check if the database file exists at the expected location.
 		if _, err := os.Stat(inflectionsDBPath); os.IsNotExist(err) {
-			fmt.Printf("Note: Inflections database not found at '%s'.\n", inflectionsDBPath)
-			fmt.Println("To enable inflected word search (Ctrl-I), place your 'inflections.db' file there.")
+			fmt.Fprintf(chatter, "Note: Inflections database not found at '%s'.\n", inflectionsDBPath)
+			fmt.Fprintln(chatter, "To enable inflected word search (Ctrl-I), place your 'inflections.db' file there.")
 		} else {
-			fmt.Printf("Attempting to load inflections database from %s...\n", inflectionsDBPath)
+			fmt.Fprintf(chatter, "Attempting to load inflections database from %s...\n", inflectionsDBPath)
 
 			// Using a file DSN URI is safer for paths that might contain special characters.
-			dsn := fmt.Sprintf("file:%s?_journal_mode=WAL&immutable=1", filepath.ToSlash(inflectionsDBPath))
+			dsn := sqliteFileURI(inflectionsDBPath, "_journal_mode=WAL&immutable=1")
 
 			inflectionsDB, err = sql.Open("sqlite", dsn)
 			if err != nil {
@@ -1025,7 +696,7 @@ func main() {
 			} else if err = inflectionsDB.Ping(); err != nil {
 				fmt.Fprintf(os.Stderr, "[WARNING] Could not connect to inflections database: %v. Ctrl-I search is disabled.\n", err)
 			} else {
-				fmt.Println("Inflections database loaded successfully. Ctrl-I is enabled.")
+				fmt.Fprintln(chatter, "Inflections database loaded successfully. Ctrl-I is enabled.")
 				defer inflectionsDB.Close()
 			}
 		}
@@ -1043,6 +714,54 @@ func main() {
 		log.Println("Debug mode enabled")
 	}
 
+	if pprofAddr != "" {
+		if err := startPprof(pprofAddr); err != nil {
+			fmt.Fprintln(os.Stderr, "Error:", err)
+			os.Exit(1)
+		}
+		fmt.Fprintf(chatter, "Profiling at http://%s/debug/pprof/\n", pprofAddr)
+	}
+
+	// -------------------------------
+	// Subcommands (e.g. `tsk suffix sto`)
+	// -------------------------------
+	if len(flag.Args()) > 0 && !lookupOnly {
+		if cmd, ok := subcommands[flag.Arg(0)]; ok {
+			if debug {
+				log.Printf("Running subcommand %q with args %v", flag.Arg(0), flag.Args()[1:])
+			}
+			if err := cmd(flag.Args()[1:]); err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				os.Exit(1)
+			}
+			os.Exit(0)
+		}
+	}
+
+	// -------------------------------
+	// Batch lookup (`tsk --file list.txt`)
+	// -------------------------------
+	if *templateFile != "" {
+		if lookupTemplate, err = parseLookupTemplate(*templateFile); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: --template: %v\n", err)
+			os.Exit(1)
+		}
+	}
+	var columns []string
+	if *columnList != "" {
+		if columns, err = parseColumns(*columnList); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: --columns: %v\n", err)
+			os.Exit(1)
+		}
+	}
+	if *batchFile != "" {
+		if err := runBatchLookup(*batchFile, *batchFormatName, *batchOut, columns); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		os.Exit(0)
+	}
+
 	// -------------------------------
 	// NEW: CLI Mode Logic
 	// -------------------------------
@@ -1054,8 +773,9 @@ func main() {
 		if debug {
 			log.Printf("CLI mode activated via arguments: %v", searchTerms)
 		}
-	} else {
-		// If no arguments, check if data is being piped via stdin.
+	} else if !interactive {
+		// If no arguments, check if data is being piped via stdin. The
+		// prompt reads its own stdin, piped or not.
 		stat, _ := os.Stdin.Stat()
 		if (stat.Mode() & os.ModeCharDevice) == 0 {
 			if debug {
@@ -1066,17 +786,26 @@ func main() {
 				fmt.Fprintf(os.Stderr, "Error reading from stdin: %v\n", err)
 				os.Exit(1)
 			}
-			// Assume space-separated words from the piped input.
-			searchTerms = strings.Fields(string(bytes))
+			// Space-separated words, or "quoted phrases", from the piped input.
+			searchTerms = splitSearchTerms(string(bytes))
 		}
 	}
+	if lookupOnly && len(searchTerms) == 0 {
+		fmt.Fprintln(os.Stderr, "Error: usage: tsk lookup WORD..., or pipe the words in")
+		os.Exit(1)
+	}
 
 	// If we have terms from either args or stdin, run in CLI mode.
 	if len(searchTerms) > 0 {
 		// Suppress the loading messages for piped input to keep the output clean.
 		if len(flag.Args()) > 0 {
-			fmt.Println("Loading word definitions...")
-			fmt.Println("Initializing deeper lookup prefixes...")
+			fmt.Fprintln(chatter, "Loading word definitions...")
+			fmt.Fprintln(chatter, "Initializing deeper lookup prefixes...")
+		}
+		format, err := batchFormat(*batchFormatName, "")
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Error:", err)
+			os.Exit(1)
 		}
 
 		glosses, err := loadGlosses()
@@ -1084,33 +813,54 @@ func main() {
 			fmt.Fprintln(os.Stderr, "Error loading glosses:", err)
 			os.Exit(1)
 		}
+		if posOnly != "" {
+			if err := checkPos(glosses, posOnly); err != nil {
+				fmt.Fprintln(os.Stderr, "Error:", err)
+				os.Exit(1)
+			}
+			glosses = filterGlossesByPos(glosses, posOnly)
+		}
 
 		if err := initDeeperPrefixes(); err != nil {
 			fmt.Fprintln(os.Stderr, "Error initializing deeper prefixes:", err)
 			os.Exit(1)
 		}
 
-		fmt.Println("===")
+		lw, err := newLookupWriter(os.Stdout, format, columns, glosses, lookupTemplate)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Error:", err)
+			os.Exit(1)
+		}
 
-		// Loop over all provided search terms.
-		for i, term := range searchTerms {
-			// Check if the word exists.
-			if _, ok := glosses[term]; ok {
-				// Generate the gloss text, strip color tags, and print.
-				glossText := generateGlossText(term, glosses)
-				cleanText := stripColorTags(glossText)
-				fmt.Println(cleanText)
-			} else {
-				fmt.Printf("'%s' not found.\n", term)
+		// Built on first use, to suggest words for terms that aren't found.
+		var fuzzyIndex *tsk.PatternIndex
+		fuzzy := func() *tsk.PatternIndex {
+			if fuzzyIndex == nil {
+				if words, err := loadWords(); err == nil {
+					fuzzyIndex = tsk.NewPatternIndex(words)
+				}
 			}
+			return fuzzyIndex
+		}
 
-			// Print a separator between results, but not after the last one.
-			if i < len(searchTerms)-1 {
-				fmt.Println("---")
+		// Loop over all provided search terms.
+		for _, term := range searchTerms {
+			r := lookupWord(term, glosses, fuzzy)
+			if senseOnly > 0 {
+				if r, err = keepSense(r, glosses, senseOnly); err != nil {
+					fmt.Fprintln(os.Stderr, "Error:", err)
+					continue
+				}
+			}
+			if err := lw.Write(r); err != nil {
+				fmt.Fprintln(os.Stderr, "Error:", err)
+				os.Exit(1)
 			}
 		}
-
-		fmt.Println("===")
+		if err := lw.Close(); err != nil {
+			fmt.Fprintln(os.Stderr, "Error:", err)
+			os.Exit(1)
+		}
 
 		// Exit successfully, skipping the TUI.
 		os.Exit(0)
@@ -1119,554 +869,60 @@ func main() {
 	// End of CLI Mode Logic
 	// -------------------------------
 
-	// Load words from embedded data.
-	fmt.Println("Loading words from", WORD_LIST_FILE)
-	start := time.Now()
-	words, err := loadWords()
-	if err != nil {
-		fmt.Fprintln(os.Stderr, "Error loading words:", err)
+	// Ask for the passphrase up front if this profile's data is encrypted.
+	if err := unlockUserData(); err != nil {
+		fmt.Fprintln(os.Stderr, "Error unlocking your data:", err)
 		os.Exit(1)
 	}
-	fmt.Printf("Loaded %d words in %v\n", len(words), time.Since(start))
-
-	// Build trie.
-	trie := NewTrie()
-	start = time.Now()
-	for _, word := range words {
-		trie.Insert(word)
-	}
-	buildDuration := time.Since(start)
-	fmt.Printf("Built trie in %v\n", buildDuration)
-
-	// Track words the user explicitly marks.
-	marked := make(map[string]struct{})
-
-	// Debug info.
-	if debug {
-		totalNodes := trie.CountNodes()
-		nodeStructSize := unsafe.Sizeof(TrieNode{})
-		const estimatedMapOverhead = 48
-		estimatedPerNode := int(nodeStructSize) + estimatedMapOverhead
-		estimatedMemory := totalNodes * estimatedPerNode
-
-		log.Printf("Debug: Trie has %d nodes\n", totalNodes)
-		log.Printf("Debug: Estimated per-node memory usage: %d bytes\n", estimatedPerNode)
-		log.Printf("Debug: Estimated total memory usage: %d bytes (~%.2f MB)\n",
-			estimatedMemory, float64(estimatedMemory)/(1024*1024))
-	}
 
-	// Load glosses.
-	start = time.Now() // Re-use the 'start' variable
-	glosses, err := loadGlosses()
+	dict, err := loadDictionary()
 	if err != nil {
-		fmt.Fprintln(os.Stderr, "Error loading glosses:", err)
+		fmt.Fprintln(os.Stderr, "Error:", err)
 		os.Exit(1)
 	}
-	fmt.Printf("Loaded word glosses from %s in %v\n", GLOSSES_FILE, time.Since(start))
-
-	// Initialize deeper lookup prefixes.
-	start = time.Now() // Re-use the 'start' variable again
-	if err := initDeeperPrefixes(); err != nil {
-		fmt.Fprintln(os.Stderr, "Error initializing deeper prefixes:", err)
-		os.Exit(1)
-	}
-	fmt.Printf("Initialized deeper lookup prefixes from go-deeper.txt in %v\n", time.Since(start))
-
-	// dump embeddedDB bytes into a temporary file for SQL lookups
-	tmp, err := ioutil.TempFile("", "tsksentences-*.sqlite")
-	if err != nil {
-		log.Fatalf("could not create temp file: %v", err)
+	if posOnly != "" {
+		if err := checkPos(dict.Glosses(), posOnly); err != nil {
+			fmt.Fprintln(os.Stderr, "Error:", err)
+			os.Exit(1)
+		}
 	}
-	defer tmp.Close()
-
-	if _, err := tmp.Write(embeddedDB); err != nil {
-		log.Fatalf("could not write embedded DB: %v", err)
+	if interactive {
+		if err := runREPL(dict, os.Stdin, os.Stdout); err != nil {
+			fmt.Fprintln(os.Stderr, "Error:", err)
+			os.Exit(1)
+		}
+		os.Exit(0)
 	}
 
-	// open it via sqlite
-	exampleDB, err := sql.Open("sqlite", tmp.Name()+"?_foreign_keys=on")
+	// Open the user's own imported sentences, if they have any.
+	userSentencesDB, err = openUserSentencesDB(false)
 	if err != nil {
-		log.Fatalf("could not open example sentences DB: %v", err)
+		fmt.Fprintf(os.Stderr, "[WARNING] Could not open your imported sentences: %v\n", err)
+	} else if userSentencesDB != nil {
+		defer userSentencesDB.Close()
 	}
-
-	fmt.Println("Starting the TUI. Thank you for your patience!")
-	app := tview.NewApplication()
-	pages := tview.NewPages()
-
-	// -------------------------------
-	// Header (Top Line)
-	// -------------------------------
-	headerLeft := tview.NewTextView().
-		SetText(fmt.Sprintf("tsk (%s) - Andrew's Pocket Finnish Dictionary", version)).
-		SetTextAlign(tview.AlignLeft).
-		SetTextColor(tcell.ColorBlack)
-	headerLeft.SetBackgroundColor(tcell.ColorLightGray)
-
-	headerRight := tview.NewButton("[::u]https://github.com/hiAndrewQuinn/tsk[::-]")
-	headerRight.SetLabelColor(tcell.ColorWhite)
-	// Set the selected style to ensure light gray background with black text.
-	headerRight.SetSelectedFunc(func() {
-		if err := openBrowser("https://github.com/hiAndrewQuinn/tsk"); err != nil {
-			fmt.Fprintf(os.Stderr, "Error opening browser: %v\n", err)
-		}
-	})
-
-	headerFlex := tview.NewFlex().SetDirection(tview.FlexColumn)
-	headerFlex.SetBackgroundColor(tcell.ColorLightGray)
-	headerFlex.
-		AddItem(headerLeft, 0, 1, false).
-		AddItem(headerRight, 40, 0, false)
-
-	// -------------------------------
-	// Left Pane: Search Input & List
-	// -------------------------------
-	inputField := tview.NewInputField().SetLabel("Search: ").SetFieldWidth(30)
-	list := tview.NewList().ShowSecondaryText(false)
-
-	updateList := func(text string) {
-		list.Clear()
-		if text == "" {
-			return
-		}
-		matches := trie.FindWords(text)
-		for _, w := range matches {
-			list.AddItem(w, "", 0, nil)
-		}
-		list.SetCurrentItem(0)
-	}
-
-	leftFlex := tview.NewFlex().SetDirection(tview.FlexRow).
-		AddItem(inputField, 3, 1, true).
-		AddItem(list, 0, 4, false)
-
-	// -------------------------------
-	// Right Pane: Gloss Display
-	// -------------------------------
-	textView := tview.NewTextView()
-	textView.SetDynamicColors(true)
-	textView.SetWrap(true)
-	textView.SetWordWrap(true)
-	textView.SetBorder(true)
-	textView.SetTitle("Word Details (Tab/Shift-Tab to scroll, Ctrl-S to mark)")
-	// Set initial help text in gray.
-	textView.SetText(helpText)
-
-	displayGloss := func(word string) {
-		if debug {
-			log.Printf("displayGloss: called for word: %s", word)
-		}
-
-		// Handle marking visuals (title and border color)
-		_, isMarked := marked[word]
-		if isMarked {
-			if debug {
-				log.Printf("displayGloss: %s is marked.", word)
-			}
-			textView.SetTitle("Word Details (Tab/Shift-Tab to scroll, Ctrl-S to unmark)")
-			textView.SetBorderColor(tcell.ColorYellow)
-			textView.SetTitleColor(tcell.ColorYellow)
+	if studyLog {
+		if studyLogDB, err = openStudyLog(true); err != nil {
+			fmt.Fprintf(os.Stderr, "[WARNING] Could not open your study log: %v\n", err)
 		} else {
-			if debug {
-				log.Printf("displayGloss: %s is NOT marked.", word)
-			}
-			textView.SetTitle("Word Details (Tab/Shift-Tab to scroll, Ctrl-S to mark)")
-			textView.SetBorderColor(tcell.ColorWhite)
-			textView.SetTitleColor(tcell.ColorWhite)
+			defer studyLogDB.Close()
 		}
-
-		// Generate the content using the new helper and set it
-		glossText := generateGlossText(word, glosses)
-		textView.SetText(glossText)
 	}
 
-	list.SetChangedFunc(func(idx int, mainText string, _ string, _ rune) {
-		// first show the gloss as before:
-		displayGloss(mainText)
-
-		// then pick selection style:
-		if _, marked := marked[mainText]; marked {
-			// “reverse-video” in yellow:
-			list.SetSelectedBackgroundColor(tcell.ColorYellow)
-		} else {
-			// back to the List’s defaults
-			list.SetSelectedBackgroundColor(tcell.ColorWhite)
+	// Ctrl-K falls back to speech synthesis without an audio pack.
+	if dir, err := audioPackDir(); err == nil {
+		if recordings, err = openAudioPack(dir); err != nil {
+			fmt.Fprintf(os.Stderr, "[WARNING] Could not open the audio pack: %v\n", err)
 		}
-	})
-
-	inputField.SetChangedFunc(func(text string) {
-		updateList(text)
-	})
-
-	inputField.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
-		switch event.Key() {
-		case tcell.KeyDown:
-			cur := list.GetCurrentItem()
-			if cur < list.GetItemCount()-1 {
-				list.SetCurrentItem(cur + 1)
-			}
-			return nil
-		case tcell.KeyUp:
-			cur := list.GetCurrentItem()
-			if cur > 0 {
-				list.SetCurrentItem(cur - 1)
-			}
-			return nil
-		case tcell.KeyEnter:
-			inputField.SetText("")
-			updateList("")
-			return nil
-		}
-		return event
-	})
-
-	const debounceDuration = 100 * time.Millisecond
-	var lastScrollTime time.Time
-
-	app.SetMouseCapture(func(event *tcell.EventMouse, action tview.MouseAction) (*tcell.EventMouse, tview.MouseAction) {
-		now := time.Now()
-		if now.Sub(lastScrollTime) < debounceDuration {
-			return nil, 0
-		}
-		lastScrollTime = now
-		if app.GetFocus() == list {
-			switch event.Buttons() {
-			case tcell.WheelUp, tcell.WheelDown:
-				cur := list.GetCurrentItem()
-				if event.Buttons() == tcell.WheelUp && cur > 0 {
-					list.SetCurrentItem(cur - 1)
-				} else if event.Buttons() == tcell.WheelDown && cur < list.GetItemCount()-1 {
-					list.SetCurrentItem(cur + 1)
-				}
-				return nil, 0
-			}
-		}
-		return event, action
-	})
-
-	topFlex := tview.NewFlex().SetDirection(tview.FlexColumn).
-		AddItem(leftFlex, 0, 1, true).
-		AddItem(textView, 0, 2, false)
-
-	// -------------------------------
-	// Footer (Bottom Line)
-	// -------------------------------
-	footerLeft := tview.NewTextView().
-		SetText("Esc to exit. Enter to clear the search. Up/Down to scroll. Wiktionary entries under CC BY-SA.").
-		SetTextAlign(tview.AlignLeft).
-		SetTextColor(tcell.ColorBlack)
-	footerLeft.SetBackgroundColor(tcell.ColorLightGray)
-
-	footerRight := tview.NewButton("[::u]https://andrew-quinn.me/[::-]")
-	footerRight.SetLabelColor(tcell.ColorWhite)
-	// Set the selected style for the footer button as well.
-	footerRight.SetSelectedFunc(func() {
-		if err := openBrowser("https://andrew-quinn.me/"); err != nil {
-			fmt.Fprintf(os.Stderr, "Error opening browser: %v\n", err)
-		}
-	})
-
-	footerFlex := tview.NewFlex().SetDirection(tview.FlexColumn)
-	footerFlex.SetBackgroundColor(tcell.ColorLightGray)
-	footerFlex.
-		AddItem(footerLeft, 0, 1, false).
-		AddItem(footerRight, 40, 0, false)
-
-	// -------------------------------
-	// Global Key Capture: Tab/Shift+Tab scrolling without focus change.
-	// -------------------------------
-	app.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
-		switch event.Key() {
-		case tcell.KeyCtrlR:
-			if debug {
-				log.Println("Ctrl-R detected, opening bug report URL.")
-			}
-			url := "https://github.com/hiAndrewQuinn/tsk/issues/new"
-			if err := openBrowser(url); err != nil {
-				log.Printf("Error opening browser for bug report: %v", err)
-			}
-			return nil // Consume the event so it's not processed further.
-
-		case tcell.KeyCtrlF:
-			showMeaningSearchModal(pages, glosses, app, inputField)
-			return nil
-		case tcell.KeyCtrlE:
-			if inflectionsDB != nil {
-				showInflectionSearchModal(pages, glosses, app, inputField, inflectionsDB)
-			} else {
-				textView.SetTitle("Inflection Search Unavailable")
-				textView.SetBorderColor(tcell.ColorRed)
-				textView.SetTitleColor(tcell.ColorRed)
-				textView.SetText("\n[red]Inflection search is disabled. Do you have the inflections database installed?[white]")
-			}
-			return nil
-
-		case tcell.KeyCtrlT:
-			if list.GetItemCount() == 0 {
-				textView.SetBorderColor(tcell.ColorTeal)
-				textView.SetTitleColor(tcell.ColorTeal)
-				textView.SetTitle("No word selected. Kotimaa itkee...")
-				textView.SetText(finnishFlag)
-				return nil
-			}
-
-			idx := list.GetCurrentItem()
-			word, _ := list.GetItemText(idx)
-
-			// 1a) if the search bar is empty, show teal “please enter something” message
-			if strings.TrimSpace(word) == "" {
-				textView.SetBorderColor(tcell.ColorTeal)
-				textView.SetTitleColor(tcell.ColorTeal)
-				textView.SetTitle("No word entered. Kotimaa itkee...")
-				textView.SetText(finnishFlag)
-				textView.SetText("[teal]No word entered. Please type something in the search bar.[white]")
-				return nil
-			}
-
-			phrase := `"` + cleanTerm(word) + `"`
-
-			const q = `
-        SELECT finnish, english
-        FROM sentences
-        WHERE sentences MATCH ? 
-    `
-			rows, err := exampleDB.Query(q, phrase)
-			if err != nil {
-				textView.SetText(fmt.Sprintf("Error querying examples: %v", err))
-				textView.SetBorderColor(tcell.ColorRed)
-				return nil
-			}
-			defer rows.Close()
-
-			// 3) build output
-			var buf strings.Builder
-			found := false
-
-			buf.WriteString("[white]Example sentences are from https://tatoeba.org and under CC BY 2.0 FR.\n\n")
-
-			for rows.Next() {
-				found = true
-
-				var fin, eng string
-				if err := rows.Scan(&fin, &eng); err != nil {
-					continue
-				}
-
-				// Finnish in teal (no per-word highlight)
-				buf.WriteString("[teal]" + fin + "\n")
-
-				// English in pink
-				buf.WriteString("[pink]" + eng + "\n\n")
-			}
-
-			if err := rows.Err(); err != nil {
-				buf.WriteString(fmt.Sprintf("\nError reading rows: %v", err))
-			}
-
-			// 3a) if nothing was found, show a special message
-			if !found {
-				textView.SetBorderColor(tcell.ColorTeal)
-				textView.SetTitleColor(tcell.ColorTeal)
-				textView.SetTitle("No examples found")
-				textView.SetText("[red]No Tatoeba example sentences found.[white]")
-				return nil
-			}
-
-			// 4) display results
-			textView.SetTitle(fmt.Sprintf("Examples for '%s' (Tab/Shift-Tab to scroll)", word))
-			textView.SetBorderColor(tcell.ColorTeal)
-			textView.SetTitleColor(tcell.ColorTeal)
-			textView.SetText(buf.String())
-
-			return nil
-		case tcell.KeyCtrlH:
-			textView.SetTitle("Word Details (Tab/Shift-Tab to scroll, Ctrl-S to mark)")
-			textView.SetBorderColor(tcell.ColorWhite)
-			textView.SetTitleColor(tcell.ColorWhite)
-			textView.SetText(helpText)
-			return nil
-		case tcell.KeyCtrlL:
-			textView.SetBorderColor(tcell.ColorGreen)
-			textView.SetTitleColor(tcell.ColorGreen)
-
-			count := len(marked)
-			if count == 0 {
-				textView.SetTitle("Marked words list empty. Kotimaa itkee...")
-				textView.SetText(finnishFlag)
-			} else {
-				textView.SetTitle(fmt.Sprintf("Listing marked words. (count: %d)", count))
-				textView.SetBorderColor(tcell.ColorGreen)
-				textView.SetTitleColor(tcell.ColorGreen)
-
-				// build a sorted slice of the set
-				var words []string
-				for w := range marked {
-					words = append(words, w)
-				}
-				sort.Strings(words)
-
-				// render them in green
-				builder := strings.Builder{}
-				builder.WriteString("[green]")
-				for _, w := range words {
-					builder.WriteString(w)
-					builder.WriteByte('\n')
-				}
-				builder.WriteString("[white]")
-
-				builder.WriteByte('\n')
-				builder.WriteByte('\n')
-				builder.WriteString("[gray]Caution: The exported files [red]do NOT[gray] include any \"go-deeper\" words or phrases.")
-				builder.WriteByte('\n')
-				builder.WriteByte('\n')
-				builder.WriteString("[gray]For example, marking '[yellow]omenan[gray]' [red]will NOT[gray] include any info about '[yellow]omena[gray]'.")
-				builder.WriteByte('\n')
-				builder.WriteByte('\n')
-				builder.WriteString("If you want those go-deeper phrases in the export, please add them separately.[white]")
-
-				textView.SetText(builder.String())
-			}
-			return nil
-		case tcell.KeyCtrlS:
-			if list.GetItemCount() == 0 {
-				textView.SetText("\n  [red]You need to search for something before you can mark or unmark it.[white]")
-				textView.SetTitle("Word Details (Tab/Shift-Tab to scroll, Ctrl-S to mark)")
-				textView.SetBorderColor(tcell.ColorRed)
-				textView.SetTitleColor(tcell.ColorRed)
-				return nil
-			}
-			idx := list.GetCurrentItem()
-			word, _ := list.GetItemText(idx)
-
-			inputField.SetText(word)
-
-			if _, present := marked[word]; present {
-				delete(marked, word)
-				if debug {
-					log.Printf("Unmarking %s.", word)
-				}
-			} else {
-				marked[word] = struct{}{}
-				if debug {
-					log.Printf("Marking %s.", word)
-				}
-			}
-			updateList(inputField.GetText())
-			return nil
-		case tcell.KeyTab:
-			// Scroll down one line in the textView.
-			currentRow, currentCol := textView.GetScrollOffset()
-			textView.ScrollTo(currentRow+1, currentCol)
-			return nil // swallow event
-		case tcell.KeyBacktab:
-			// Scroll up one line in the textView.
-			currentRow, currentCol := textView.GetScrollOffset()
-			newRow := currentRow - 1
-			if newRow < 0 {
-				newRow = 0
-			}
-			textView.ScrollTo(newRow, currentCol)
-			return nil // swallow event
-		case tcell.KeyEsc:
-			app.Stop()
-			fmt.Println("Stopping the TUI. Thank you for exiting gracefully!")
-
-			// 1) If nothing’s marked, just exit.
-			if len(marked) == 0 {
-				return nil
-			}
-
-			// 2) Build base filename with timestamp
-			ts := time.Now().Format("2006-01-02-15-04-05")
-			base := fmt.Sprintf("tsk-marked_%s", ts)
-			jsonFile := base + ".jsonl"
-			txtFile := base + ".txt"
-
-			// --- JSONL dump ---
-			fj, err := os.Create(jsonFile)
-			if err != nil {
-				fmt.Fprintf(os.Stderr, "Error creating %s: %v\n", jsonFile, err)
-				os.Exit(1)
-			}
-			defer fj.Close()
-
-			for wform := range marked {
-				if glossSlice, ok := glosses[wform]; ok {
-					for _, gloss := range glossSlice {
-						line, err := json.Marshal(gloss)
-						if err != nil {
-							fmt.Fprintf(os.Stderr,
-								"Error marshaling gloss for %s: %v\n",
-								wform, err,
-							)
-							continue
-						}
-						if _, err := fj.Write(append(line, '\n')); err != nil {
-							fmt.Fprintf(os.Stderr,
-								"Error writing to %s: %v\n",
-								jsonFile, err,
-							)
-							os.Exit(1)
-						}
-					}
-				}
-			}
-			fmt.Printf("Saved %d words’ gloss entries to %s\n", len(marked), jsonFile)
-
-			// --- TXT (one-column CSV) dump ---
-			// We’ll use encoding/csv to get proper quoting, but it's just one column.
-			ft, err := os.Create(txtFile)
-			if err != nil {
-				fmt.Fprintf(os.Stderr, "Error creating %s: %v\n", txtFile, err)
-				os.Exit(1)
-			}
-			defer ft.Close()
-
-			cw := csv.NewWriter(ft)
-			defer cw.Flush()
-
-			// Header
-			cw.Write([]string{"Base Form"})
-
-			// Collect & sort keys
-			var words []string
-			for w := range marked {
-				words = append(words, w)
-			}
-			sort.Strings(words)
-
-			// One row per word
-			for _, w := range words {
-				cw.Write([]string{w})
-			}
-
-			fmt.Printf("Saved %d marked words to %s\n", len(words), txtFile)
-
-			return nil
-		default:
-			return event
-		}
-	})
+	}
 
-	// -------------------------------
-	// Main Layout
-	// -------------------------------
-	mainFlex := tview.NewFlex().
-		SetDirection(tview.FlexRow).
-		// Top row (header)
-		AddItem(headerFlex, 1, 0, false).
-		// Spacer for a black bar
-		AddItem(nil, 1, 0, false).
-		// Main content (search + list + details)
-		AddItem(topFlex, 0, 1, true).
-		// Spacer for a black bar
-		AddItem(nil, 1, 0, false).
-		// Bottom row (footer)
-		AddItem(footerFlex, 1, 0, false)
-
-	// --- FIX #2 & #3: Add the mainFlex as the "main" page, and remove the invalid modalLayout call.
-	pages.AddPage("main", mainFlex, true, true)
-
-	if err := app.SetRoot(pages, true).Run(); err != nil {
+	fmt.Println("Starting the TUI. Thank you for your patience!")
+	app := newTUI(dict, false)
+	if err := colorScreen(app); err != nil {
+		fmt.Fprintln(os.Stderr, "Error:", err)
+		os.Exit(1)
+	}
+	if err := app.Run(); err != nil {
 		fmt.Fprintln(os.Stderr, err)
 		os.Exit(1)
 	}
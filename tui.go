@@ -0,0 +1,2516 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"maps"
+	mathrand "math/rand/v2"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/hiAndrewQuinn/tsk/pkg/tsk"
+	"github.com/rivo/tview"
+)
+
+// ----------------------
+// Start Screen Dashboard
+// ----------------------
+
+// dashboardItem is one line of the start screen. Items without an action are
+// section headings, which the selection skips over.
+type dashboardItem struct {
+	text   string
+	detail string // shown dimmed under the item, e.g. a meaning
+	action func()
+}
+
+func renderDashboard(items []dashboardItem, selected int) string {
+	var builder strings.Builder
+	builder.WriteString("[white]Start typing to search, or use Up/Down and Enter to pick something below.\n")
+	for i, item := range items {
+		switch {
+		case item.action == nil:
+			builder.WriteString(fmt.Sprintf("\n[yellow]%s[white]\n", item.text))
+		case i == selected:
+			builder.WriteString(fmt.Sprintf("  [black:aqua] %s [-:-]\n", item.text))
+		default:
+			builder.WriteString(fmt.Sprintf("   %s\n", item.text))
+		}
+		for _, line := range strings.Split(item.detail, "\n") {
+			if line != "" {
+				builder.WriteString(fmt.Sprintf("     [gray]%s[white]\n", line))
+			}
+		}
+	}
+	fmt.Fprintf(&builder, "\n[gray]Press %s at any time to see all keybindings.[white]\n", keymap.Label(actionHelp))
+	return builder.String()
+}
+
+// nextDashboardItem returns the index of the next selectable item after
+// selected in the direction of step (+1 or -1), or selected if there is none.
+func nextDashboardItem(items []dashboardItem, selected, step int) int {
+	for i := selected + step; i >= 0 && i < len(items); i += step {
+		if items[i].action != nil {
+			return i
+		}
+	}
+	return selected
+}
+
+// ----------------------
+// Grouped Results
+// ----------------------
+
+// A prefix like "ka" matches words of every part of speech. Past
+// GROUP_RESULTS_MIN words, the result list is split into groups under
+// headings like "Verbs" and "Nouns", which PgUp and PgDn jump between.
+const GROUP_RESULTS_MIN = 12
+
+// posHeadings names the groups of the common parts of speech. Any other
+// part of speech is headed by its tag as it is.
+var posHeadings = map[string]string{
+	"noun":        "Nouns",
+	"verb":        "Verbs",
+	"adj":         "Adjectives",
+	"adv":         "Adverbs",
+	"name":        "Names",
+	"pron":        "Pronouns",
+	"num":         "Numerals",
+	"phrase":      "Phrases",
+	"proverb":     "Proverbs",
+	"intj":        "Interjections",
+	"conj":        "Conjunctions",
+	"postp":       "Postpositions",
+	"prep":        "Prepositions",
+	"prefix":      "Prefixes",
+	"suffix":      "Suffixes",
+	"particle":    "Particles",
+	"contraction": "Contractions",
+}
+
+// resultsTitle is the word list's title for a search that matched total
+// words and lists listed of them, hidden more being left out by the part
+// of speech filter, e.g. "Showing 50 of 873 matches".
+func resultsTitle(listed, total, hidden int) string {
+	var title string
+	switch {
+	case total == 0:
+		return ""
+	case listed < total:
+		title = fmt.Sprintf("Showing %d of %d matches", listed, total)
+	case total == 1:
+		title = "1 match"
+	default:
+		title = fmt.Sprintf("%d matches", total)
+	}
+	if hidden > 0 {
+		title += fmt.Sprintf(", %d hidden", hidden)
+	}
+	return title
+}
+
+// resultGroup is one heading of a grouped result list and its words.
+type resultGroup struct {
+	heading string
+	words   []string
+}
+
+// groupByPos splits words into groups by their first part of speech,
+// keeping the words' order within each group. The groups come in the
+// order their first words did, so the group of the most common match
+// leads. Words without glosses, like some inflected forms, go last.
+func groupByPos(words []string, glosses map[string][]tsk.Gloss) []resultGroup {
+	var groups []resultGroup
+	index := make(map[string]int) // heading -> its place in groups
+	var others []string
+	for _, w := range words {
+		parts := tsk.PartsOfSpeech(glosses[w])
+		if len(parts) == 0 {
+			others = append(others, w)
+			continue
+		}
+		heading, ok := posHeadings[parts[0]]
+		if !ok {
+			heading = parts[0]
+		}
+		i, ok := index[heading]
+		if !ok {
+			i = len(groups)
+			index[heading] = i
+			groups = append(groups, resultGroup{heading: heading})
+		}
+		groups[i].words = append(groups[i].words, w)
+	}
+	if len(others) > 0 {
+		groups = append(groups, resultGroup{heading: "Other forms", words: others})
+	}
+	return groups
+}
+
+// ----------------------
+// Clickable Words
+// ----------------------
+
+// linkWords wraps each word of text that linkable accepts in a region tag,
+// so that clicking it in a TextView with regions enabled highlights it. The
+// region IDs are indexes into the returned words. Color tags and escaped
+// brackets are copied as they are.
+func linkWords(text string, linkable func(word string) bool) (string, []string) {
+	var b strings.Builder
+	var words []string
+	runes := []rune(text)
+	for i := 0; i < len(runes); {
+		switch r := runes[i]; {
+		case r == '[':
+			end := i + 1
+			for end < len(runes) && runes[end] != ']' && runes[end] != '\n' {
+				end++
+			}
+			if end < len(runes) && runes[end] == ']' {
+				end++
+			}
+			b.WriteString(string(runes[i:end]))
+			i = end
+		case unicode.IsLetter(r):
+			end := i + 1
+			// Hyphenated compounds like linja-auto are one word.
+			for end < len(runes) && (unicode.IsLetter(runes[end]) ||
+				runes[end] == '-' && end+1 < len(runes) && unicode.IsLetter(runes[end+1])) {
+				end++
+			}
+			word := string(runes[i:end])
+			if linkable(word) {
+				fmt.Fprintf(&b, `["%d"]%s[""]`, len(words), word)
+				words = append(words, word)
+			} else {
+				b.WriteString(word)
+			}
+			i = end
+		default:
+			b.WriteRune(r)
+			i++
+		}
+	}
+	return b.String(), words
+}
+
+// ----------------------
+// First-Run Tour (`--tour`)
+// ----------------------
+
+// The first time tsk starts for a profile, the footer walks the user
+// through the basics: searching, scrolling the details, marking, example
+// sentences and reverse-find. Each step waits until the user has done it,
+// with the pane it's about outlined in the theme's tour color. The tour
+// is only shown once, however far the user gets; `tsk --tour` shows it
+// again.
+
+// forceTour shows the tour even if it has been shown before.
+var forceTour bool
+
+// The steps of the tour, which the TUI reports as the user does them.
+const (
+	tourSearch = iota
+	tourScroll
+	tourMark
+	tourExamples
+	tourReverseFind
+)
+
+// tourPane is the part of the screen a tour step is about.
+type tourPane int
+
+const (
+	tourSearchBar tourPane = iota
+	tourWordDetails
+)
+
+type tourStep struct {
+	pane tourPane
+	key  string // the key the step is about, if any
+	text string // what to do, with %s where the key goes
+}
+
+// tour is how far the user has got through the tour.
+type tour struct {
+	steps   []tourStep
+	current int // the step being waited for; len(steps) once they're done
+}
+
+func newTour() *tour {
+	return &tour{steps: []tourStep{
+		tourSearch:      {tourSearchBar, "", "Type a Finnish word, like kissa, to look it up."},
+		tourScroll:      {tourWordDetails, "Tab", "Press %s and Shift-Tab to scroll the Word Details."},
+		tourMark:        {tourWordDetails, keymap.Label(actionMark), "Press %s to mark the word. Marked words are saved when you leave."},
+		tourExamples:    {tourWordDetails, keymap.Label(actionExamples), "Press %s to see the word in example sentences."},
+		tourReverseFind: {tourSearchBar, keymap.Label(actionReverseFind), "Press %s to find Finnish words by their English meaning."},
+	}}
+}
+
+// Did records that the user did step, returning true if that was the step
+// the tour was waiting for. Steps done ahead of time don't count.
+func (t *tour) Did(step int) bool {
+	if t.current != step {
+		return false
+	}
+	t.current++
+	return true
+}
+
+func (t *tour) Finished() bool {
+	return t.current >= len(t.steps)
+}
+
+// Pane is the pane the current step is about.
+func (t *tour) Pane() (tourPane, bool) {
+	if t.Finished() {
+		return 0, false
+	}
+	return t.steps[t.current].pane, true
+}
+
+// Footer is the footer line for the current step, with its key picked out
+// in color.
+func (t *tour) Footer(color tcell.Color) string {
+	if t.Finished() {
+		return fmt.Sprintf("That's the tour! Press [%s::b]%s[-::-] for every key, or run tsk --tour to see it again.",
+			colorTag(color), keymap.Label(actionHelp))
+	}
+	step := t.steps[t.current]
+	text := step.text
+	if step.key != "" {
+		text = fmt.Sprintf(text, fmt.Sprintf("[%s::b]%s[-::-]", colorTag(color), step.key))
+	}
+	return fmt.Sprintf("Tour %d/%d: %s", t.current+1, len(t.steps), text)
+}
+
+// colorTag is color as tview's color tags write it.
+func colorTag(color tcell.Color) string {
+	return fmt.Sprintf("#%06x", color.Hex())
+}
+
+// outlineTour recolors the border of the box at x, y, w, h on screen,
+// keeping what is drawn there, so the tour can point a pane out without
+// changing the colors tsk gives it. Boxes without a border, like the
+// search bar, get their first row recolored instead.
+func outlineTour(screen tcell.Screen, x, y, w, h int, bordered bool, color tcell.Color) {
+	recolor := func(cx, cy int) {
+		r, comb, style, _ := screen.GetContent(cx, cy)
+		screen.SetContent(cx, cy, r, comb, style.Foreground(color).Bold(true))
+	}
+	if !bordered {
+		for cx := x; cx < x+w; cx++ {
+			recolor(cx, y)
+		}
+		return
+	}
+	for cx := x; cx < x+w; cx++ {
+		recolor(cx, y)
+		recolor(cx, y+h-1)
+	}
+	for cy := y + 1; cy < y+h-1; cy++ {
+		recolor(x, cy)
+		recolor(x+w-1, cy)
+	}
+}
+
+// tourSeen reports whether this profile has been shown the tour.
+func tourSeen() bool {
+	dir, err := userDataDir()
+	if err != nil {
+		return true // nowhere to remember it, so don't show it every time
+	}
+	_, err = os.Stat(filepath.Join(dir, TOUR_FILE))
+	return err == nil
+}
+
+// markTourSeen remembers that the tour has been shown, and when.
+func markTourSeen() error {
+	dir, err := userDataDir()
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(dir, TOUR_FILE), []byte(time.Now().Format(time.RFC3339)+"\n"), 0644)
+}
+
+// ----------------------
+// Details Messages
+// ----------------------
+
+// statusKind is what a detailsStatus is about, which decides its title and
+// color.
+type statusKind int
+
+const (
+	statusNoResults   statusKind = iota // the search found nothing at all
+	statusNotFound                      // not a word, but close to some
+	statusNeedsWord                     // a key that needs a word to work on
+	statusUnavailable                   // a feature that's off or missing here
+	statusError                         // something went wrong, e.g. a query
+)
+
+// detailsStatus is a message for the Word Details pane in place of a
+// gloss: what happened, words to look up instead, and what else to try.
+// The key handlers' messages all go through it, so they look alike.
+type detailsStatus struct {
+	kind        statusKind
+	title       string // the pane's title, if not the kind's own
+	message     string
+	suggestions []string // words to look up instead
+	hints       []string // what to try next, e.g. another key
+}
+
+// Title is the pane's title while the status shows.
+func (s detailsStatus) Title() string {
+	if s.title != "" {
+		return s.title
+	}
+	switch s.kind {
+	case statusNoResults:
+		return "No Results"
+	case statusNotFound:
+		return "Not in the Dictionary"
+	case statusNeedsWord:
+		return "No Word Selected"
+	case statusUnavailable:
+		return "Unavailable"
+	}
+	return "Error"
+}
+
+// Color is the pane's border and title color while the status shows.
+func (s detailsStatus) Color() tcell.Color {
+	if s.kind == statusNoResults || s.kind == statusNotFound {
+		return theme.Details
+	}
+	return theme.Error
+}
+
+// Text is the status as Word Details text. With links, the suggestions are
+// regions "0", "1", ..., to be clicked like the words of a gloss.
+func (s detailsStatus) Text(links bool) string {
+	color := "red"
+	if s.kind == statusNoResults || s.kind == statusNotFound {
+		color = "yellow"
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "\n  [%s]%s[white]\n", color, tview.Escape(s.message))
+	if len(s.suggestions) > 0 {
+		words := make([]string, len(s.suggestions))
+		for i, w := range s.suggestions {
+			words[i] = tview.Escape(w)
+			if links {
+				words[i] = fmt.Sprintf(`["%d"]%s[""]`, i, words[i])
+			}
+		}
+		fmt.Fprintf(&b, "\n  Did you mean: %s?\n", strings.Join(words, ", "))
+	}
+	for _, hint := range s.hints {
+		fmt.Fprintf(&b, "\n  [gray]%s[white]", tview.Escape(hint))
+	}
+	return b.String()
+}
+
+// noResultsStatus says a search for query found nothing, with what else
+// to try. posHidden is set if the part-of-speech filter pos hid results.
+func noResultsStatus(query, pos string, posHidden, english bool) detailsStatus {
+	s := detailsStatus{kind: statusNoResults, message: fmt.Sprintf("Nothing matches '%s'.", query)}
+	if posHidden {
+		s.message = fmt.Sprintf("No %s matches '%s'.", pos, query)
+		s.hints = append(s.hints, fmt.Sprintf("Only %s words are listed. Press %s until every word is listed again.", pos, keymap.Label(actionPosFilter)))
+	}
+	if english {
+		s.message = fmt.Sprintf("No meaning matches '%s'.", query)
+		s.hints = append(s.hints, fmt.Sprintf("The search bar looks up English meanings. Press %s to search Finnish words again.", keymap.Label(actionEnglishSearch)))
+		return s
+	}
+	s.hints = append(s.hints, fmt.Sprintf("Press %s to search English meanings instead.", keymap.Label(actionEnglishSearch)))
+	if !strings.ContainsAny(query, "$*?_.") {
+		s.hints = append(s.hints, fmt.Sprintf("Search $%s for words ending in it, or *%s* for words containing it.", query, query))
+	}
+	return s
+}
+
+// notFoundStatus says word isn't in the dictionary, suggesting the closest
+// words to it.
+func notFoundStatus(word string, suggestions []string) detailsStatus {
+	return detailsStatus{kind: statusNotFound, message: fmt.Sprintf("'%s' isn't in the dictionary.", word), suggestions: suggestions}
+}
+
+// needsWordStatus says a key needs a word selected before it can do what
+// it does, e.g. "copy its gloss".
+func needsWordStatus(doing string) detailsStatus {
+	return detailsStatus{
+		kind:    statusNeedsWord,
+		message: fmt.Sprintf("You need to search for something before you can %s.", doing),
+		hints:   []string{fmt.Sprintf("Type a Finnish word in the search bar, or press %s to find one by its English meaning.", keymap.Label(actionReverseFind))},
+	}
+}
+
+// errorStatus says what went wrong while doing something, e.g. "querying
+// the example sentences".
+func errorStatus(doing string, err error) detailsStatus {
+	return detailsStatus{
+		kind:    statusError,
+		message: fmt.Sprintf("Error %s: %v", doing, err),
+		hints:   []string{fmt.Sprintf("If it keeps happening, press %s to report it, with the message above.", keymap.Label(actionBugReport))},
+	}
+}
+
+// ----------------------
+// Update Check (`--check-updates`)
+// ----------------------
+
+// With --check-updates, the TUI asks GitHub for the latest release in the
+// background as it starts, and the header says if there's a newer tsk. It
+// asks at most once every UPDATE_CHECK_INTERVAL, remembering the answer in
+// UPDATE_CHECK_FILE, which is also what it goes by when offline.
+const (
+	RELEASES_API_URL      = "https://api.github.com/repos/hiAndrewQuinn/tsk/releases/latest"
+	RELEASES_URL          = "https://github.com/hiAndrewQuinn/tsk/releases/latest"
+	UPDATE_CHECK_FILE     = "update-check.json"
+	UPDATE_CHECK_INTERVAL = 24 * time.Hour
+	UPDATE_CHECK_TIMEOUT  = 5 * time.Second
+)
+
+// checkUpdates turns the update check on.
+var checkUpdates bool
+
+// releaseInfo is the part of GitHub's release JSON tsk uses, and what
+// UPDATE_CHECK_FILE keeps with the time it was fetched.
+type releaseInfo struct {
+	TagName string    `json:"tag_name"`
+	HTMLURL string    `json:"html_url"`
+	Checked time.Time `json:"checked"`
+}
+
+// fetchLatestRelease asks GitHub for the latest release, giving up after
+// UPDATE_CHECK_TIMEOUT.
+func fetchLatestRelease() (releaseInfo, error) {
+	client := &http.Client{Timeout: UPDATE_CHECK_TIMEOUT}
+	req, err := http.NewRequest("GET", RELEASES_API_URL, nil)
+	if err != nil {
+		return releaseInfo{}, err
+	}
+	req.Header.Set("Accept", "application/vnd.github+json")
+	req.Header.Set("User-Agent", fmt.Sprintf("tsk/%s (https://github.com/hiAndrewQuinn/tsk)", version))
+	resp, err := client.Do(req)
+	if err != nil {
+		return releaseInfo{}, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return releaseInfo{}, fmt.Errorf("%s", resp.Status)
+	}
+	var release releaseInfo
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(&release); err != nil {
+		return releaseInfo{}, err
+	}
+	if release.TagName == "" {
+		return releaseInfo{}, fmt.Errorf("the latest release has no tag")
+	}
+	return release, nil
+}
+
+// latestRelease returns the latest release, from UPDATE_CHECK_FILE if it
+// was checked recently, else from GitHub. If GitHub can't be reached it
+// falls back on the last answer, however old.
+func latestRelease() (releaseInfo, error) {
+	dir, err := tskConfigDir()
+	if err != nil {
+		return releaseInfo{}, err
+	}
+	path := filepath.Join(dir, UPDATE_CHECK_FILE)
+	var cached releaseInfo
+	if data, err := os.ReadFile(path); err == nil {
+		if err := json.Unmarshal(data, &cached); err != nil && debug {
+			log.Printf("Ignoring %s: %v", path, err)
+		}
+	}
+	if cached.TagName != "" && time.Since(cached.Checked) < UPDATE_CHECK_INTERVAL {
+		return cached, nil
+	}
+
+	release, err := fetchLatestRelease()
+	if err != nil {
+		if cached.TagName != "" {
+			return cached, nil
+		}
+		return releaseInfo{}, err
+	}
+	release.Checked = time.Now()
+	if data, err := json.Marshal(release); err == nil {
+		if err := os.MkdirAll(dir, 0o755); err == nil {
+			err = os.WriteFile(path, data, 0o644)
+		}
+		if err != nil && debug {
+			log.Printf("Could not save %s: %v", path, err)
+		}
+	}
+	return release, nil
+}
+
+// parseVersion reads a version like "v0.0.6" into its numbers, ignoring
+// anything after a '-' or '+', as in "v0.1.0-rc1".
+func parseVersion(v string) ([]int, bool) {
+	v = strings.TrimPrefix(strings.TrimSpace(v), "v")
+	if i := strings.IndexAny(v, "-+"); i >= 0 {
+		v = v[:i]
+	}
+	var parts []int
+	for _, s := range strings.Split(v, ".") {
+		n, err := strconv.Atoi(s)
+		if err != nil || n < 0 {
+			return nil, false
+		}
+		parts = append(parts, n)
+	}
+	return parts, true
+}
+
+// newerVersion reports whether latest is a later version than current.
+// Versions it can't read are never newer.
+func newerVersion(latest, current string) bool {
+	l, ok := parseVersion(latest)
+	if !ok {
+		return false
+	}
+	c, ok := parseVersion(current)
+	if !ok {
+		return false
+	}
+	return slices.Compare(l, c) > 0
+}
+
+// releaseNotesURL is the page of release's notes, or of the latest
+// release's if it isn't known.
+func releaseNotesURL(release releaseInfo) string {
+	if strings.HasPrefix(release.HTMLURL, "https://github.com/") {
+		return release.HTMLURL
+	}
+	return RELEASES_URL
+}
+
+// ----------------------
+// Main TUI Application
+// ----------------------
+
+// newTUI builds the interactive dictionary on top of dict. An isolated TUI,
+// as served to SSH guests, keeps its marks to itself and never reads or
+// writes the local user's session or export files.
+func newTUI(dict *Dictionary, isolated bool) *tview.Application {
+	words, glosses := dict.Words(), dict.Glosses()
+	meaningIndex := dict.MeaningIndex()
+
+	// Track words the user explicitly marks, and which of their senses.
+	// Marks carry over between sessions, except for guests.
+	marked := make(map[string]senseSet)
+	var markedSentences []sentencePair
+	if !isolated {
+		var err error
+		if marked, err = loadMarked(); err != nil {
+			log.Printf("Could not load marked words: %v", err)
+		}
+		if markedSentences, err = loadMarkedSentences(); err != nil {
+			log.Printf("Could not load marked sentences: %v", err)
+		}
+	}
+	// loggedMarks is the marked words as last saved, to tell the study log
+	// which were marked and unmarked since, however that was done.
+	// The tags of the user's imported word lists, shown with the words and
+	// searched as "#tag".
+	var tagged []taggedWord
+	if !isolated {
+		var err error
+		if tagged, err = loadTaggedWords(); err != nil {
+			log.Printf("Could not load tagged words: %v", err)
+		}
+	}
+	tagsOf := wordTags(tagged)
+	// The user's notes on words, shown at the top of Word Details and
+	// searched as "@text".
+	notes := make(map[string]string)
+	if !isolated {
+		var err error
+		if notes, err = loadNotes(); err != nil {
+			log.Printf("Could not load notes: %v", err)
+			notes = make(map[string]string)
+		}
+	}
+	// The collections marked words are sorted into, and the one the
+	// marked-words manager last showed, which Ctrl-W saves; "" for all.
+	collections := make(map[string][]string)
+	if !isolated {
+		var err error
+		if collections, err = loadCollections(); err != nil {
+			log.Printf("Could not load collections: %v", err)
+			collections = make(map[string][]string)
+		}
+	}
+	var shownCollection string
+	saveCollectionsNow := func() {
+		if isolated {
+			return
+		}
+		if err := saveCollections(collections); err != nil {
+			log.Printf("Could not save collections: %v", err)
+		}
+	}
+	loggedMarks := make(map[string]bool, len(marked))
+	for word := range marked {
+		loggedMarks[word] = true
+	}
+	saveMarks := func() {
+		// However a word was unmarked, it leaves its collections.
+		if pruneCollections(collections, marked) {
+			saveCollectionsNow()
+		}
+		if isolated {
+			return
+		}
+		if err := saveMarked(marked); err != nil {
+			log.Printf("Could not save marked words: %v", err)
+		}
+		for word := range marked {
+			if !loggedMarks[word] {
+				logStudyEvent("mark", word)
+				loggedMarks[word] = true
+			}
+		}
+		for word := range loggedMarks {
+			if _, ok := marked[word]; !ok {
+				logStudyEvent("unmark", word)
+				delete(loggedMarks, word)
+			}
+		}
+	}
+
+	// New users get a tour of the basics, once.
+	var onboarding *tour
+	if forceTour || (!isolated && !tourSeen()) {
+		onboarding = newTour()
+		if !isolated {
+			if err := markTourSeen(); err != nil {
+				log.Printf("Could not remember that the tour was shown: %v", err)
+			}
+		}
+	}
+	// advanceTour is set up with the footer the tour is shown in.
+	advanceTour := func(step int) {}
+
+	app := tview.NewApplication().EnableMouse(!noMouse)
+	pages := tview.NewPages()
+
+	// tview only hands out its screen while drawing; Ctrl-Y needs it to
+	// copy through the terminal (OSC 52).
+	var screen tcell.Screen
+	app.SetBeforeDrawFunc(func(s tcell.Screen) bool {
+		screen = s
+		return false
+	})
+	// copyText puts text on the clipboard, through the terminal and the
+	// platform's clipboard tool both.
+	copyText := func(text string) {
+		if screen != nil {
+			screen.SetClipboard([]byte(text))
+		}
+		// A guest's clipboard is only reachable through their terminal.
+		if !isolated {
+			if err := copyToClipboard(text); err != nil && debug {
+				log.Printf("Clipboard tool failed, relying on OSC 52: %v", err)
+			}
+		}
+	}
+
+	// -------------------------------
+	// Header (Top Line)
+	// -------------------------------
+	headerText := fmt.Sprintf("tsk (%s) - Andrew's Pocket Finnish Dictionary", version)
+	if profile != "" {
+		headerText += fmt.Sprintf(" [profile: %s]", profile)
+	}
+	headerLeft := tview.NewTextView().
+		SetText(headerText).
+		SetTextAlign(tview.AlignLeft).
+		SetTextColor(theme.HeaderText)
+	headerLeft.SetBackgroundColor(theme.Header)
+
+	headerRight := tview.NewButton("[::u]https://github.com/hiAndrewQuinn/tsk[::-]")
+	headerRight.SetLabelColor(theme.HeaderLink)
+
+	// The update check runs in the background, so a slow or missing
+	// network never holds up the start; a newer release, if any, is added
+	// to the header when the answer comes.
+	var newRelease releaseInfo
+	if checkUpdates && !isolated {
+		go func() {
+			release, err := latestRelease()
+			if err != nil {
+				if debug {
+					log.Printf("Update check failed: %v", err)
+				}
+				return
+			}
+			if !newerVersion(release.TagName, version) {
+				return
+			}
+			app.QueueUpdateDraw(func() {
+				newRelease = release
+				headerLeft.SetText(fmt.Sprintf("%s  ·  %s is out: %s for what's new", headerText, release.TagName, keymap.Label(actionReleaseNotes)))
+			})
+		}()
+	}
+
+	headerFlex := tview.NewFlex().SetDirection(tview.FlexColumn)
+	headerFlex.SetBackgroundColor(theme.Header)
+	headerFlex.
+		AddItem(headerLeft, 0, 1, false).
+		AddItem(headerRight, 40, 0, false)
+
+	// -------------------------------
+	// Left Pane: Search Input & List
+	// -------------------------------
+	const (
+		searchLabel        = "Search: "
+		englishSearchLabel = "Search (English meanings): "
+		fuzzySearchLabel   = "Did you mean: "
+		englishModeLabel   = "English → Finnish: "
+	)
+	inputField := tview.NewInputField().SetLabel(searchLabel).SetFieldWidth(30)
+	list := tview.NewList().ShowSecondaryText(false)
+	list.SetBorder(true).
+		SetBorderColor(theme.Details).
+		SetTitleColor(theme.Details)
+	editor := newLineEditor()
+
+	// When the search term turned out to be an inflected form, inflectedFrom
+	// holds it and inflectedForms maps each base form found to its readings.
+	var inflectedFrom string
+	var inflectedForms map[string][]string
+	// Likewise compoundFrom holds a search term that was split into the
+	// headwords in compoundParts.
+	var compoundFrom string
+	var compoundParts []string
+	// reverseFrom is the English query of the reverse-find results the
+	// list shows in full, reverseMatches, until the search bar changes.
+	var reverseFrom string
+	var reverseMatches []string
+
+	// posFilter is the part of speech the list is limited to, if any; the
+	// pos-filter key steps it through posFilters.
+	posFilter := posOnly
+	// foldSearch makes prefix searches ignore diacritics; the
+	// fold-diacritics key turns it on and off.
+	foldSearch := foldDiacritics
+	// englishMode makes the search bar look up English meanings only,
+	// rather than Finnish words first; the english-search key switches.
+	var englishMode bool
+	// rhymeWord is the word the rhymes key last listed rhymes of, by the
+	// ending of its last rhymeSyllables syllables.
+	var rhymeWord string
+	var rhymeSyllables int
+	// searchKind is how the last search was read, and posHidden how many
+	// of its results posFilter left out, for the message if none are left.
+	var searchKind tsk.SearchKind
+	var posHidden int
+
+	// A long list of results is grouped under headings, which are items
+	// without a word in their secondary text. selectWord selects the item
+	// at i, or the nearest word past it in the direction of step, so that
+	// a heading is never selected. It does nothing if there's no word that
+	// way.
+	selectWord := func(i, step int) {
+		for ; i >= 0 && i < list.GetItemCount(); i += step {
+			if _, w := list.GetItemText(i); w != "" {
+				list.SetCurrentItem(i)
+				return
+			}
+		}
+	}
+
+	// jumpGroup selects the first word of the next group of results, or
+	// of the previous one for a negative step. It reports whether the list
+	// is grouped at all.
+	jumpGroup := func(step int) bool {
+		var headings []int
+		for i := 0; i < list.GetItemCount(); i++ {
+			if _, w := list.GetItemText(i); w == "" {
+				headings = append(headings, i)
+			}
+		}
+		if len(headings) == 0 {
+			return false
+		}
+		// The group the selection is in; -1 for an exact match pinned
+		// above the first heading.
+		group, cur := -1, list.GetCurrentItem()
+		for g, h := range headings {
+			if h < cur {
+				group = g
+			}
+		}
+		switch group += step; {
+		case group >= len(headings):
+			// Already in the last group.
+		case group < 0:
+			selectWord(0, 1)
+		default:
+			selectWord(headings[group]+1, 1)
+		}
+		return true
+	}
+
+	// searcher reads how to search, from the modes set now, and returns
+	// the search of text. It is called on the UI goroutine, but the search
+	// it returns can be run on any.
+	searcher := func(text string) func() tsk.SearchResult {
+		switch english, fold := englishMode, foldSearch; {
+		case text == "":
+			return func() tsk.SearchResult { return tsk.SearchResult{} }
+		case reverseFrom != "" && text == reverseFrom:
+			matches := reverseMatches
+			return func() tsk.SearchResult { return tsk.SearchResult{Words: matches, Kind: tsk.SearchEnglish} }
+		case strings.HasPrefix(strings.TrimSpace(text), "@"):
+			// "@text": the words whose notes mention it.
+			matches := notesMatching(notes, strings.TrimPrefix(strings.TrimSpace(text), "@"))
+			return func() tsk.SearchResult { return tsk.SearchResult{Words: matches, Kind: tsk.SearchPrefix} }
+		case tagged != nil && strings.HasPrefix(strings.TrimSpace(text), "#"):
+			// "#tag", or "#tag prefix": the words of an imported list.
+			return func() tsk.SearchResult {
+				tag, prefix, _ := tagQuery(text)
+				return tsk.SearchResult{Words: tagMatches(tagged, tag, prefix, dict.frequencies), Kind: tsk.SearchPrefix}
+			}
+		case english:
+			return func() tsk.SearchResult { return dict.SearchMeanings(text) }
+		case fold:
+			return func() tsk.SearchResult { return dict.SearchFolded(text) }
+		default:
+			return func() tsk.SearchResult { return dict.Search(text) }
+		}
+	}
+
+	// showResults lists the result of searching for text.
+	showResults := func(text string, result tsk.SearchResult) {
+		list.Clear()
+		list.SetTitle("")
+		if englishMode {
+			inputField.SetLabel(englishModeLabel)
+		} else {
+			inputField.SetLabel(searchLabel)
+		}
+		inflectedFrom, inflectedForms = "", nil
+		compoundFrom, compoundParts = "", nil
+		posHidden = 0
+		if text == "" {
+			return
+		}
+		if tagged != nil && strings.HasPrefix(strings.TrimSpace(text), "#") && text != reverseFrom {
+			tag, _, _ := tagQuery(text)
+			inputField.SetLabel(fmt.Sprintf("Search (#%s): ", tag))
+		}
+		if strings.HasPrefix(strings.TrimSpace(text), "@") && text != reverseFrom {
+			inputField.SetLabel("Search (notes): ")
+		}
+		if text != reverseFrom {
+			reverseFrom, reverseMatches = "", nil
+		}
+		matches := result.Words
+		searchKind = result.Kind
+		if len(result.Forms) > 0 {
+			// An inflected form, like "taloissa" for "talo", or a spoken
+			// one, like "mä" or "meiän" for "minä" or "meidän".
+			inflectedFrom, inflectedForms = text, result.Forms
+		}
+		switch result.Kind {
+		case tsk.SearchCompound:
+			// A compound that isn't a headword, like "sanakirjakauppias":
+			// the term itself leads the list, followed by its parts.
+			compoundFrom, compoundParts = result.Words[0], result.Parts
+		case tsk.SearchEnglish:
+			// Nothing Finnish matched, but it reads like English; say so
+			// in the search bar's label.
+			if !englishMode {
+				inputField.SetLabel(englishSearchLabel)
+			}
+		case tsk.SearchFuzzy:
+			// Still nothing: these are the closest words to a typo.
+			if len(matches) > 0 {
+				inputField.SetLabel(fuzzySearchLabel)
+			}
+		}
+		if foldSearch && result.Kind == tsk.SearchPrefix {
+			inputField.SetLabel(strings.TrimSuffix(inputField.GetLabel(), ": ") + " (ä=a): ")
+		}
+		if posFilter != "" {
+			inputField.SetLabel(strings.TrimSuffix(inputField.GetLabel(), ": ") + " (" + posFilter + "): ")
+			var kept []string
+			for i, w := range matches {
+				// A split compound's own term has no glosses to go by.
+				if tsk.HasPos(glosses[w], posFilter) || (i == 0 && compoundFrom != "") {
+					kept = append(kept, w)
+				}
+			}
+			posHidden = len(matches) - len(kept)
+			matches = kept
+		}
+		list.SetTitle(resultsTitle(len(result.Words), max(result.Total, len(result.Words)), posHidden))
+		// The word itself goes in the (hidden) secondary text, so the
+		// frequency rank and parts of speech can be shown next to it.
+		addWord := func(w string) {
+			display := tview.Escape(w)
+			if freq, ok := dict.frequencies[w]; ok {
+				display += fmt.Sprintf(" [gray]#%d[-]", freq.Rank)
+			}
+			if badge := frequencyBadge(w, dict.frequencies); badge != "" {
+				display += " " + badge
+			}
+			if parts := tsk.PartsOfSpeech(glosses[w]); len(parts) > 0 {
+				display += " [orange]" + tview.Escape(strings.Join(parts, "/")) + "[-]"
+			}
+			for _, tag := range tagsOf[w] {
+				display += " [teal]#" + tview.Escape(tag) + "[-]"
+			}
+			if _, ok := notes[w]; ok {
+				display += " [fuchsia]✎[-]"
+			}
+			if _, ok := marked[w]; ok {
+				display += " [yellow]*[-]"
+			}
+			list.AddItem(theme.Recolor(display), w, 0, nil)
+		}
+		groups := []resultGroup{{words: matches}}
+		if result.Kind <= tsk.SearchGlob && len(matches) >= GROUP_RESULTS_MIN {
+			// The word typed in, if it is one, stays on top by itself, and
+			// so do the standard words of a spoken form, after it.
+			rest := matches
+			if strings.EqualFold(rest[0], strings.Trim(text, "*$")) {
+				addWord(rest[0])
+				rest = rest[1:]
+			}
+			for len(rest) > 0 && inflectedForms[rest[0]] != nil {
+				addWord(rest[0])
+				rest = rest[1:]
+			}
+			if byPos := groupByPos(rest, glosses); len(byPos) > 1 {
+				groups = byPos
+			} else {
+				groups[0].words = rest
+			}
+		}
+		for _, g := range groups {
+			if g.heading != "" {
+				list.AddItem(theme.Recolor(fmt.Sprintf("[::b]%s[::-] [gray](%d)[-]", g.heading, len(g.words))), "", 0, nil)
+			}
+			for _, w := range g.words {
+				addWord(w)
+			}
+		}
+		selectWord(0, 1)
+	}
+
+	// Searching as one types runs on a worker goroutine, so that a slow
+	// search never holds up the keys typed after it. cancelSearch cancels
+	// the one running, whose results are then dropped. The worker reads
+	// glosses, so the gloss editor takes glossesMu to change them.
+	cancelSearch := context.CancelFunc(func() {})
+	var glossesMu sync.RWMutex
+	// prepared is the gloss text of the first word of the results being
+	// listed, which the worker has made ready along with them.
+	var prepared struct{ word, text string }
+
+	updateList := func(text string) {
+		cancelSearch()
+		showResults(text, searcher(text)())
+	}
+
+	// setSearch puts text in the search bar and lists its results at
+	// once, for the callers that go on to use them; only typing searches
+	// in the background.
+	var searchNow bool
+	setSearch := func(text string) {
+		searchNow = true
+		inputField.SetText(text)
+		searchNow = false
+	}
+
+	leftFlex := tview.NewFlex().SetDirection(tview.FlexRow).
+		AddItem(inputField, 3, 1, true).
+		AddItem(list, 0, 4, false)
+
+	// -------------------------------
+	// Right Pane: Gloss Display
+	// -------------------------------
+	textView := themedTextView{tview.NewTextView()}
+	textView.SetDynamicColors(true)
+	textView.SetWrap(true)
+	textView.SetWordWrap(true)
+	textView.SetBorder(true)
+	// The titles name the keys from the keymap, which may be rebound.
+	detailsTitle := fmt.Sprintf("Word Details (Tab/Shift-Tab to scroll, %s to mark)", keymap.Label(actionMark))
+	markedDetailsTitle := fmt.Sprintf("Word Details (Tab/Shift-Tab to scroll, %s to unmark, %s to pick senses)",
+		keymap.Label(actionMark), keymap.Label(actionSenses))
+	textView.SetTitle(detailsTitle)
+	// showRelated expands the etymology and related words in Word Details.
+	var showRelated bool
+
+	// Words in Word Details can be clicked to look them up. links are the
+	// clickable words of the text showing, and trail the words viewed
+	// before and after this one, for a "back" link and Alt-Left/Alt-Right.
+	// navigating is set while the search bar is changed to step along the
+	// trail; searching once a search of one's own has left the word that
+	// was showing.
+	var links []string
+	var trail wordTrail
+	var navigating, searching bool
+	textView.SetRegions(!noMouse)
+	setLinkedText := func(text, word string) {
+		if noMouse {
+			textView.SetText(text)
+			return
+		}
+		var back string
+		if crumbs := trail.Crumbs(1); len(crumbs) > 0 {
+			back = fmt.Sprintf("[gray][\"back\"]◂ back to %s[\"\"][white]\n\n", tview.Escape(crumbs[0]))
+		}
+		text, links = linkWords(text, func(w string) bool {
+			if strings.EqualFold(w, word) {
+				return false
+			}
+			if _, ok := glosses[w]; ok {
+				return true
+			}
+			w = strings.ToLower(w)
+			if _, ok := glosses[w]; ok {
+				return true
+			}
+			return len(tsk.AnalyzeWord(w, glosses)) > 0
+		})
+		textView.SetText(back + text)
+	}
+
+	// showStatus shows a message in Word Details in place of a gloss. Its
+	// suggestions can be clicked like the words of a gloss.
+	showStatus := func(s detailsStatus) {
+		textView.SetTitle(s.Title())
+		textView.SetBorderColor(s.Color())
+		textView.SetTitleColor(s.Color())
+		links = nil
+		if !noMouse {
+			links = slices.Clone(s.suggestions)
+		}
+		textView.SetText(s.Text(!noMouse))
+	}
+
+	// glossTextFor builds the Word Details text for word, led by the base
+	// form it was found from if it is an inflected form.
+	glossTextFor := func(word string) string {
+		if compoundFrom != "" && word == compoundFrom {
+			return compoundGlossText(word, compoundParts, glosses)
+		}
+		glossText := prepared.text
+		if prepared.word != word {
+			glossText = generateGlossText(word, glosses)
+		}
+		glossText += relatedGlossText(glosses[word], showRelated)
+		if badge := frequencyBadge(word, dict.frequencies); badge != "" {
+			glossText = badge + "\n" + glossText
+		}
+		glossText = noteText(notes[word]) + tagsText(tagsOf[word]) + glossText
+		if forms, ok := inflectedForms[word]; ok {
+			glossText = fmt.Sprintf("[aqua]%s[white] ~> [yellow]%s[white] (%s)\n\n", inflectedFrom, word, strings.Join(forms, ", ")) + glossText
+		} else if _, ok := glosses[word]; !ok {
+			// Some inflected forms are in the word list without glosses of
+			// their own; show their base forms instead.
+			if text, ok := inflectionGlossText(word, glosses); ok {
+				glossText = text
+			}
+		}
+		return glossText
+	}
+
+	displayGloss := func(word string) {
+		if debug {
+			log.Printf("displayGloss: called for word: %s", word)
+		}
+
+		// Handle marking visuals (title and border color)
+		_, isMarked := marked[word]
+		if isMarked {
+			if debug {
+				log.Printf("displayGloss: %s is marked.", word)
+			}
+			textView.SetTitle(markedDetailsTitle)
+			textView.SetBorderColor(theme.Marked)
+			textView.SetTitleColor(theme.Marked)
+		} else {
+			if debug {
+				log.Printf("displayGloss: %s is NOT marked.", word)
+			}
+			textView.SetTitle(detailsTitle)
+			textView.SetBorderColor(theme.Details)
+			textView.SetTitleColor(theme.Details)
+		}
+
+		// Generate the content using the new helper and set it
+		setLinkedText(glossTextFor(word), word)
+
+		// The header shows the way here, breadcrumb style.
+		if crumbs := trail.Crumbs(3); len(crumbs) > 0 {
+			headerLeft.SetText(headerText + "  |  " + strings.Join(append(crumbs, word), " › "))
+		} else {
+			headerLeft.SetText(headerText)
+		}
+	}
+
+	// speakingRate is how fast this TUI says words and sentences. It starts
+	// at --speech-rate and + and - in Ctrl-X change it, for this session
+	// alone when tsk serve has several.
+	speakingRate := speechRate
+
+	// recentSentences holds the first page of the sentence searches done
+	// or prefetched lately. Selecting a word prefetches its sentences once
+	// it has stayed selected for prefetchDelay, unless --no-prefetch, and
+	// selecting another cancels that if it hasn't started.
+	recentSentences := newSentenceCache(SENTENCE_CACHE_SIZE)
+	cancelPrefetch := context.CancelFunc(func() {})
+	prefetch := func(word string) {
+		cancelPrefetch()
+		if noPrefetch {
+			return
+		}
+		ctx, cancel := context.WithCancel(context.Background())
+		cancelPrefetch = cancel
+		go func() {
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(prefetchDelay):
+			}
+			// An error shows when the examples key runs the query again.
+			if _, err := recentSentences.find(dict, word); err != nil && debug {
+				log.Printf("prefetching sentences of %s: %v", word, err)
+			}
+		}()
+	}
+
+	list.SetChangedFunc(func(idx int, _ string, word string, _ rune) {
+		if word == "" {
+			// A group heading, passed over by selectWord.
+			return
+		}
+		// first show the gloss as before:
+		displayGloss(word)
+		prefetch(word)
+
+		// then pick selection style:
+		if _, marked := marked[word]; marked {
+			// “reverse-video” in yellow:
+			list.SetSelectedBackgroundColor(theme.Marked)
+		} else {
+			// back to the List’s defaults
+			list.SetSelectedBackgroundColor(theme.Selected)
+		}
+	})
+
+	// searched shows why the list is empty, or isn't the word, once the
+	// results of searching for text are in.
+	searched := func(text string) {
+		switch {
+		case text == "":
+		case list.GetItemCount() == 0:
+			showStatus(noResultsStatus(text, posFilter, posHidden > 0, englishMode))
+		case searchKind == tsk.SearchFuzzy:
+			// The list has the closest words; say why, rather than show
+			// the first of them as if it were the word.
+			var closest []string
+			for i := 0; i < min(list.GetItemCount(), 5); i++ {
+				_, w := list.GetItemText(i)
+				closest = append(closest, w)
+			}
+			status := notFoundStatus(text, closest)
+			status.hints = []string{"The list has the closest words: press Down to read them."}
+			showStatus(status)
+		}
+		if list.GetItemCount() > 0 {
+			advanceTour(tourSearch)
+		}
+	}
+
+	inputField.SetChangedFunc(func(text string) {
+		if !navigating && !searching {
+			// A new search leaves the word showing, as a link would.
+			if list.GetItemCount() > 0 {
+				_, from := list.GetItemText(list.GetCurrentItem())
+				trail.Visit(from)
+			}
+			searching = true
+		}
+		if searchNow || navigating {
+			updateList(text)
+			searched(text)
+			return
+		}
+		cancelSearch()
+		ctx, cancel := context.WithCancel(context.Background())
+		cancelSearch = cancel
+		search := searcher(text)
+		go func() {
+			glossesMu.RLock()
+			result := search()
+			var first struct{ word, text string }
+			if len(result.Words) > 0 && ctx.Err() == nil {
+				first.word = result.Words[0]
+				first.text = generateGlossText(first.word, glosses)
+			}
+			glossesMu.RUnlock()
+			if ctx.Err() != nil {
+				return
+			}
+			app.QueueUpdateDraw(func() {
+				// Another search may have started since this was queued.
+				if ctx.Err() != nil {
+					return
+				}
+				prepared = first
+				showResults(text, result)
+				prepared.word, prepared.text = "", ""
+				searched(text)
+			})
+		}()
+	})
+
+	showHelp := func() {
+		textView.SetTitle(detailsTitle)
+		textView.SetBorderColor(theme.Details)
+		textView.SetTitleColor(theme.Details)
+		textView.SetText(helpText())
+	}
+
+	// showReverseFind opens reverse-find. The word picked there is selected
+	// among all the results, listed in the main view.
+	showReverseFind := func() {
+		showMeaningSearchModal(pages, glosses, meaningIndex, app, inputField, editor, func(query string, matches []string, word string) {
+			reverseFrom, reverseMatches = query, matches
+			setSearch(query)
+			for i := 0; i < list.GetItemCount(); i++ {
+				if _, w := list.GetItemText(i); w == word {
+					list.SetCurrentItem(i)
+					break
+				}
+			}
+		})
+	}
+
+	// -------------------------------
+	// Start Screen Dashboard
+	// -------------------------------
+	// The dashboard lives in the right pane until something else is shown
+	// there. Focus stays in the search bar, so typing still searches right
+	// away; Up/Down and Enter on an empty search bar drive the dashboard.
+	session := NewSessionLog()
+
+	// Guests get a history for their session only.
+	history := &SearchHistory{cursor: -1}
+	if !isolated {
+		var err error
+		if history, err = loadSearchHistory(); err != nil {
+			log.Printf("Could not load search history: %v", err)
+		}
+	}
+	recordLookup := func(word string) {
+		session.Add(word)
+		history.Add(word)
+		if !isolated {
+			logStudyEvent("lookup", word)
+		}
+		// The next search leaves this word for another.
+		searching = false
+	}
+	showStats := func() {
+		stats, err := collectStats(dict, history, marked, len(markedSentences))
+		if err != nil {
+			textView.SetTitle(fmt.Sprintf("Could not count everything: %v", err))
+			textView.SetBorderColor(theme.Error)
+			textView.SetTitleColor(theme.Error)
+		} else {
+			textView.SetTitle(fmt.Sprintf("Statistics (%s again to refresh)", keymap.Label(actionStats)))
+			textView.SetBorderColor(theme.Details)
+			textView.SetTitleColor(theme.Details)
+		}
+		textView.SetText(statsText(stats))
+		textView.ScrollToBeginning()
+	}
+	showHistory := func() {
+		showHistoryModal(pages, app, history.Recent(), inputField, func(word string) {
+			setSearch(word)
+		})
+	}
+	// navigate shows word, one step along the trail.
+	navigate := func(word string) {
+		navigating = true
+		setSearch(word)
+		navigating = false
+		searching = false
+	}
+	currentWord := func() string {
+		if list.GetItemCount() == 0 {
+			return ""
+		}
+		_, word := list.GetItemText(list.GetCurrentItem())
+		return word
+	}
+	goBack := func() {
+		if word, ok := trail.Back(currentWord()); ok {
+			navigate(word)
+		}
+	}
+	goForward := func() {
+		if word, ok := trail.Forward(currentWord()); ok {
+			navigate(word)
+		}
+	}
+	// followLink looks up a word clicked in Word Details, or Ctrl-clicked
+	// in the results list, remembering the word from, if any, that was
+	// showing when it was clicked.
+	followLink := func(word, from string) {
+		if _, ok := glosses[word]; !ok {
+			// Sentences start with a capital letter.
+			word = strings.ToLower(word)
+		}
+		if from != "" {
+			recordLookup(from)
+		}
+		trail.Visit(from)
+		navigate(word)
+	}
+	// The neighbor graph (Alt-N) takes the gloss's place in Word Details
+	// until it is pressed again. Up/Down pick a word in it and Enter goes
+	// there, showing that word's graph, so the graph can be walked.
+	var (
+		neighborsWord     string
+		neighborsTitle    string
+		neighborNodes     []neighborNode
+		neighborsSelected int
+	)
+	neighborsActive := func() bool {
+		return neighborsWord != "" && textView.GetTitle() == neighborsTitle
+	}
+	showNeighbors := func(word string, selected int) {
+		if word != neighborsWord {
+			neighborsWord, neighborNodes = word, neighborTree(dict, word)
+		}
+		if len(neighborNodes) == 0 {
+			neighborsWord = ""
+			showStatus(detailsStatus{kind: statusNoResults, title: "No Neighbors",
+				message: fmt.Sprintf("'%s' has no synonyms, and shares no meaning with another word.", word)})
+			return
+		}
+		neighborsSelected = (selected + len(neighborNodes)) % len(neighborNodes)
+		neighborsTitle = fmt.Sprintf("Neighbors of '%s' (Up/Down, Enter to go, %s to close)", word, keymap.Label(actionNeighbors))
+		textView.SetTitle(neighborsTitle)
+		textView.SetBorderColor(theme.Details)
+		textView.SetTitleColor(theme.Details)
+		links = nil
+		if !noMouse {
+			for _, n := range neighborNodes {
+				links = append(links, n.word)
+			}
+		}
+		textView.SetText(neighborTreeText(word, neighborNodes, neighborsSelected, glosses, !noMouse))
+	}
+	// walkNeighbors goes to the selected word of the graph and shows its
+	// neighbors in turn.
+	walkNeighbors := func(word string) {
+		followLink(word, currentWord())
+		showNeighbors(currentWord(), 0)
+	}
+	textView.SetHighlightedFunc(func(added, _, _ []string) {
+		if len(added) == 0 {
+			return
+		}
+		// Clicks only highlight for as long as it takes to follow them.
+		textView.Highlight()
+		if added[0] == "back" {
+			goBack()
+		} else if n, err := strconv.Atoi(added[0]); err == nil && n < len(links) && neighborsActive() {
+			walkNeighbors(links[n])
+		} else if err == nil && n < len(links) {
+			followLink(links[n], currentWord())
+		}
+	})
+	// showSentences searches the example sentences for query, or opens
+	// the search empty if it's "".
+	showSentences := func(query string) {
+		onMark := func(sentences []sentencePair) {
+			markedSentences = sentences
+			if isolated {
+				return
+			}
+			if err := saveMarkedSentences(markedSentences); err != nil {
+				log.Printf("Could not save marked sentences: %v", err)
+			}
+		}
+		onCopy := func(text string) string {
+			copyText(text)
+			return "Copied the sentence and its translation to the clipboard."
+		}
+		onOpen := func(link string) string {
+			if isolated {
+				// A browser would open on the server, so guests get the link.
+				return link
+			}
+			if err := openBrowser(link); err != nil {
+				return fmt.Sprintf("Could not open %s: %v", link, err)
+			}
+			return "Opened the sentence on tatoeba.org in your browser."
+		}
+		say := func(ctx context.Context, text string, rate int) error {
+			if isolated {
+				// The sound would come out of the server's speakers.
+				return errors.New("pronunciation isn't available over SSH")
+			}
+			return speakAndWait(ctx, text, rate)
+		}
+		showSentenceSearchModal(pages, app, dict, recentSentences, editor, markedSentences, query, inputField, onMark, onCopy, onOpen, &speakingRate, say)
+	}
+
+	// markedTitle is the Ctrl-L listing's title, so a second Ctrl-L can
+	// tell the listing is still showing.
+	var markedTitle string
+
+	// The form index is only needed for Ctrl-D, so it is built on first use.
+	var formIndex FormIndex
+
+	// surprise jumps to a random word, of the part of speech listed and the
+	// --surprise-band, and shows an example sentence below its gloss.
+	surprise := func() {
+		candidates := surpriseCandidates(words, glosses, dict.frequencies, posFilter, surpriseBand)
+		if len(candidates) == 0 {
+			status := detailsStatus{kind: statusNoResults, message: "There are no words to pick from."}
+			if posFilter != "" {
+				status.hints = append(status.hints, fmt.Sprintf("Only %s words are picked. Press %s to pick from others.", posFilter, keymap.Label(actionPosFilter)))
+			}
+			if surpriseBand != "" {
+				status.hints = append(status.hints, fmt.Sprintf("Only %s words are picked, as set by --surprise-band or surprise_band in config.json.", surpriseBand))
+			}
+			showStatus(status)
+			return
+		}
+		word := candidates[mathrand.IntN(len(candidates))]
+		setSearch(word)
+		recordLookup(word)
+		if e, ok := exampleOfTheDay(dict.ExamplesDB(), word); ok {
+			setLinkedText(glossTextFor(word)+fmt.Sprintf("\n[teal]%s\n[gray]%s[white]", tview.Escape(e.Finnish), tview.Escape(e.English)), word)
+		}
+	}
+
+	var dashboardItems []dashboardItem
+	var lastSession []string
+	if !isolated {
+		var err error
+		lastSession, err = loadLastSession()
+		if err != nil {
+			log.Printf("Could not load last session: %v", err)
+		}
+	}
+	if len(lastSession) > 0 {
+		dashboardItems = append(dashboardItems, dashboardItem{text: "Resume last session"})
+		for _, word := range lastSession {
+			dashboardItems = append(dashboardItems, dashboardItem{
+				text:   tview.Escape(word),
+				action: func() { setSearch(word) },
+			})
+		}
+	}
+	if wotd := wordOfTheDay(wordOfTheDayCandidates(words, glosses, dict.frequencies), time.Now()); wotd != "" {
+		detail := firstMeaning(wotd, glosses)
+		if e, ok := exampleOfTheDay(dict.ExamplesDB(), wotd); ok {
+			detail += "\n" + e.Finnish + "\n" + e.English
+		}
+		dashboardItems = append(dashboardItems,
+			dashboardItem{text: "Word of the day"},
+			dashboardItem{text: tview.Escape(wotd), detail: tview.Escape(detail), action: func() { setSearch(wotd) }},
+		)
+	}
+	dashboardItems = append(dashboardItems,
+		dashboardItem{text: "Quick actions"},
+		dashboardItem{text: "Reverse-find words by English meaning (" + keymap.Label(actionReverseFind) + ")", action: func() {
+			showReverseFind()
+		}},
+	)
+	if inflectionsDB != nil {
+		dashboardItems = append(dashboardItems, dashboardItem{
+			text: "Find a base form from an inflected form (" + keymap.Label(actionLemmatizer) + ")",
+			action: func() {
+				showInflectionSearchModal(pages, glosses, app, inputField, editor, inflectionsDB)
+			},
+		})
+	}
+	dashboardItems = append(dashboardItems,
+		dashboardItem{text: "Surprise me with a random word (" + keymap.Label(actionSurprise) + ")", action: surprise},
+		dashboardItem{text: "Search the example sentences (" + keymap.Label(actionSentences) + ")", action: func() { showSentences("") }},
+		dashboardItem{text: "Browse your search history (" + keymap.Label(actionHistory) + ")", action: showHistory},
+		dashboardItem{text: "Show all keybindings (" + keymap.Label(actionHelp) + ")", action: showHelp},
+	)
+
+	dashboardSelected := nextDashboardItem(dashboardItems, -1, 1)
+	dashboardText := renderDashboard(dashboardItems, dashboardSelected)
+	textView.SetTitle("Welcome to tsk!")
+	textView.SetText(dashboardText)
+
+	// Open where the last session stopped reading, unless the tour needs
+	// the empty search bar. An empty search leaves the dashboard showing.
+	if !isolated && !noResume && onboarding == nil {
+		if view, err := loadLastView(); err != nil {
+			log.Printf("Could not load the last view: %v", err)
+		} else if view.Search != "" {
+			setSearch(view.Search)
+			for i := 0; i < list.GetItemCount(); i++ {
+				if _, w := list.GetItemText(i); w == view.Word {
+					list.SetCurrentItem(i)
+					break
+				}
+			}
+			textView.ScrollTo(view.Scroll, 0)
+		}
+	}
+
+	dashboardActive := func() bool {
+		return textView.GetText(false) == theme.Recolor(dashboardText)
+	}
+	moveDashboard := func(step int) {
+		dashboardSelected = nextDashboardItem(dashboardItems, dashboardSelected, step)
+		dashboardText = renderDashboard(dashboardItems, dashboardSelected)
+		textView.SetText(dashboardText)
+	}
+
+	inputField.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		switch event.Key() {
+		case tcell.KeyDown:
+			if list.GetItemCount() == 0 && dashboardActive() {
+				moveDashboard(1)
+				return nil
+			}
+			if neighborsActive() {
+				showNeighbors(neighborsWord, neighborsSelected+1)
+				return nil
+			}
+			selectWord(list.GetCurrentItem()+1, 1)
+			return nil
+		case tcell.KeyUp:
+			if list.GetItemCount() == 0 && dashboardActive() {
+				moveDashboard(-1)
+				return nil
+			}
+			if neighborsActive() {
+				showNeighbors(neighborsWord, neighborsSelected-1)
+				return nil
+			}
+			selectWord(list.GetCurrentItem()-1, -1)
+			return nil
+		case tcell.KeyEnter:
+			if inputField.GetText() == "" && dashboardActive() {
+				if dashboardSelected >= 0 {
+					dashboardItems[dashboardSelected].action()
+				}
+				return nil
+			}
+			if neighborsActive() {
+				walkNeighbors(neighborNodes[neighborsSelected].word)
+				return nil
+			}
+			if list.GetItemCount() > 0 {
+				_, word := list.GetItemText(list.GetCurrentItem())
+				recordLookup(word)
+			}
+			setSearch("")
+			updateList("")
+			return nil
+		}
+		return event
+	})
+	editor.Attach(inputField)
+
+	const debounceDuration = 100 * time.Millisecond
+	var lastScrollTime time.Time
+
+	app.SetMouseCapture(func(event *tcell.EventMouse, action tview.MouseAction) (*tcell.EventMouse, tview.MouseAction) {
+		if front, _ := pages.GetFrontPage(); front != "main" {
+			return event, action
+		}
+		// Clicks on the list and Word Details leave the focus in the
+		// search field, so typing carries on where it was.
+		keepFocus := func(tview.Primitive) {}
+		if action == tview.MouseLeftDoubleClick {
+			// Quickly following one link after another is no double click.
+			action = tview.MouseLeftClick
+		}
+		if x, y := event.Position(); action == tview.MouseLeftClick && list.InRect(x, y) {
+			if list.GetItemCount() == 0 {
+				return nil, 0
+			}
+			_, from := list.GetItemText(list.GetCurrentItem())
+			list.MouseHandler()(action, event, keepFocus)
+			// A click on a group heading selects the group's first word.
+			selectWord(list.GetCurrentItem(), 1)
+			if event.Modifiers()&tcell.ModCtrl != 0 {
+				_, word := list.GetItemText(list.GetCurrentItem())
+				followLink(word, from)
+			}
+			return nil, 0
+		} else if action == tview.MouseLeftClick && textView.InRect(x, y) {
+			textView.MouseHandler()(action, event, keepFocus)
+			return nil, 0
+		} else if action == tview.MouseLeftDown && (list.InRect(x, y) || textView.InRect(x, y)) {
+			return nil, 0
+		}
+		if app.GetFocus() == list {
+			switch event.Buttons() {
+			case tcell.WheelUp, tcell.WheelDown:
+				now := time.Now()
+				if now.Sub(lastScrollTime) < debounceDuration {
+					return nil, 0
+				}
+				lastScrollTime = now
+				if event.Buttons() == tcell.WheelUp {
+					selectWord(list.GetCurrentItem()-1, -1)
+				} else {
+					selectWord(list.GetCurrentItem()+1, 1)
+				}
+				return nil, 0
+			}
+		}
+		return event, action
+	})
+
+	topFlex := tview.NewFlex().SetDirection(tview.FlexColumn).
+		AddItem(leftFlex, 0, 1, true).
+		AddItem(textView, 0, 2, false)
+
+	// The word list takes listWidth percent of the width, or none while
+	// it's collapsed. The search bar goes with it, but keeps the focus, so
+	// typing still looks words up.
+	listWide, listHidden := listWidth, listCollapsed
+	sizePanes := func() {
+		if listHidden {
+			topFlex.ResizeItem(leftFlex, 0, 0)
+			topFlex.ResizeItem(textView, 0, 1)
+		} else {
+			topFlex.ResizeItem(leftFlex, 0, listWide)
+			topFlex.ResizeItem(textView, 0, 100-listWide)
+		}
+	}
+	sizePanes()
+	// saveLayout remembers the panes' sizes in the config file.
+	saveLayout := func() {
+		if isolated {
+			return
+		}
+		if err := saveListLayout(listWide, listHidden); err != nil {
+			log.Printf("Could not save the layout: %v", err)
+		}
+	}
+
+	// -------------------------------
+	// Footer (Bottom Line)
+	// -------------------------------
+	const footerText = "Esc to exit. Enter to clear the search. Up/Down to scroll. Wiktionary entries under CC BY-SA."
+	footerLeft := tview.NewTextView().
+		SetText(footerText).
+		SetTextAlign(tview.AlignLeft).
+		SetTextColor(theme.HeaderText)
+	footerLeft.SetBackgroundColor(theme.Header)
+
+	// The tour takes over the footer, and outlines the pane each step is
+	// about as the screen is drawn.
+	if onboarding != nil {
+		footerLeft.SetDynamicColors(true).SetText(onboarding.Footer(theme.Tour))
+		advanceTour = func(step int) {
+			if onboarding != nil && onboarding.Did(step) {
+				footerLeft.SetText(onboarding.Footer(theme.Tour))
+			}
+		}
+		app.SetAfterDrawFunc(func(screen tcell.Screen) {
+			if front, _ := pages.GetFrontPage(); onboarding == nil || front != "main" {
+				return
+			}
+			pane, ok := onboarding.Pane()
+			if !ok {
+				return
+			}
+			if pane == tourSearchBar {
+				x, y, w, h := inputField.GetRect()
+				outlineTour(screen, x, y, w, h, false, theme.Tour)
+			} else {
+				x, y, w, h := textView.GetRect()
+				outlineTour(screen, x, y, w, h, true, theme.Tour)
+			}
+		})
+	}
+
+	footerRight := tview.NewButton("[::u]https://andrew-quinn.me/[::-]")
+	footerRight.SetLabelColor(theme.HeaderLink)
+
+	// openLink opens the header and footer links in the browser. A guest's
+	// browser isn't on this machine, so guests get the link instead.
+	openLink := func(link string) {
+		if isolated {
+			textView.SetTitle(link)
+			return
+		}
+		if err := openBrowser(link); err != nil {
+			textView.SetTitle(fmt.Sprintf("Could not open %s: %v", link, err))
+			textView.SetBorderColor(theme.Error)
+			textView.SetTitleColor(theme.Error)
+		}
+	}
+	headerRight.SetSelectedFunc(func() { openLink("https://github.com/hiAndrewQuinn/tsk") })
+	footerRight.SetSelectedFunc(func() { openLink("https://andrew-quinn.me/") })
+
+	footerFlex := tview.NewFlex().SetDirection(tview.FlexColumn)
+	footerFlex.SetBackgroundColor(theme.Header)
+	footerFlex.
+		AddItem(footerLeft, 0, 1, false).
+		AddItem(footerRight, 40, 0, false)
+
+	// saveMarked writes the marked words with the export profile name, or
+	// as the usual pair of files if it's "", and the marked sentences. With
+	// a collection shown, it writes only that collection's words.
+	saveMarked := func(name string) {
+		picked, sentences := marked, markedSentences
+		if shownCollection != "" {
+			picked, sentences = collectionMarks(marked, collections, shownCollection), nil
+		}
+		saveFailed := func(err error) {
+			status := errorStatus("saving marked words", err)
+			status.title = "Saving marked words failed"
+			showStatus(status)
+		}
+		// The messages say which collection the words are from.
+		var collectionSuffix string
+		if shownCollection != "" {
+			collectionSuffix = fmt.Sprintf(" in '%s'", tview.Escape(shownCollection))
+		}
+		var saved strings.Builder
+		if len(picked) > 0 {
+			var deeper []string
+			if exportDeeper {
+				deeper = deeperWords(picked, glosses)
+			}
+			if name != "" {
+				files, err := exportMarkedAs(name, picked, shownCollection, deeper, glosses)
+				if err != nil {
+					saveFailed(err)
+					return
+				}
+				fmt.Fprintf(&saved, "\n  [green]Saved %d marked words%s as %s to[white] %s", len(picked), collectionSuffix, tview.Escape(name), tview.Escape(strings.Join(files, ", ")))
+				if len(deeper) > 0 {
+					fmt.Fprintf(&saved, "\n  [green]along with the glosses of %d words they are forms of", len(deeper))
+				}
+			} else {
+				jsonFile, txtFile, err := exportMarked(picked, shownCollection, deeper, glosses, markedExamples(dict, picked, exportExamples))
+				if err != nil {
+					saveFailed(err)
+					return
+				}
+				fmt.Fprintf(&saved, "\n  [green]Saved %d words’ gloss entries to[white] %s", len(picked), tview.Escape(jsonFile))
+				if len(deeper) > 0 {
+					fmt.Fprintf(&saved, "\n  [green]along with the glosses of %d words they are forms of", len(deeper))
+				}
+				fmt.Fprintf(&saved, "\n  [green]Saved %d marked words%s to[white] %s", len(picked), collectionSuffix, tview.Escape(txtFile))
+			}
+		}
+		if len(sentences) > 0 {
+			tsvFile, err := exportMarkedSentences(sentences)
+			if err != nil {
+				saveFailed(err)
+				return
+			}
+			fmt.Fprintf(&saved, "\n  [green]Saved %d marked sentences to[white] %s", len(sentences), tview.Escape(tsvFile))
+		}
+		if len(sentences) > 0 {
+			textView.SetTitle(fmt.Sprintf("Saved %d marked words and %d sentences", len(picked), len(sentences)))
+		} else {
+			textView.SetTitle(fmt.Sprintf("Saved %d marked words", len(picked)))
+		}
+		textView.SetText(saved.String() + "\n\n  [gray]They will be saved again when you quit.[white]")
+	}
+
+	// -------------------------------
+	// Global Key Capture: Tab/Shift+Tab scrolling without focus change.
+	// -------------------------------
+	app.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		front, _ := pages.GetFrontPage()
+		if front == sensePickerPage || front == historyPage || front == markedPage || front == sentencePage || front == exportPage || front == glossEditPage || front == collectionPage || front == notePage {
+			return event
+		}
+		// The tour's last word stays up until the next key in the main view.
+		if onboarding != nil && onboarding.Finished() && front == "main" {
+			footerLeft.SetDynamicColors(false).SetText(footerText)
+			onboarding = nil
+		}
+		if editor.Owns(app.GetFocus(), event) {
+			return event
+		}
+		action, ok := keymap.Action(event)
+		if !ok {
+			action, _ = keymap.Action(editor.Command(event))
+		}
+		switch action {
+		case actionBugReport:
+			if isolated {
+				// A browser would open on the server, not for the guest.
+				return nil
+			}
+			if debug {
+				log.Println("Ctrl-R detected, opening bug report URL.")
+			}
+			url := "https://github.com/hiAndrewQuinn/tsk/issues/new"
+			if err := openBrowser(url); err != nil {
+				log.Printf("Error opening browser for bug report: %v", err)
+			}
+			return nil // Consume the event so it's not processed further.
+
+		case actionReverseFind:
+			advanceTour(tourReverseFind)
+			showReverseFind()
+			return nil
+		case actionRelated:
+			showRelated = !showRelated
+			if list.GetItemCount() > 0 {
+				_, word := list.GetItemText(list.GetCurrentItem())
+				displayGloss(word)
+			}
+			return nil
+		case actionSentences:
+			showSentences("")
+			return nil
+		case actionLemmatizer:
+			if inflectionsDB != nil {
+				showInflectionSearchModal(pages, glosses, app, inputField, editor, inflectionsDB)
+			} else {
+				showStatus(detailsStatus{
+					kind:    statusUnavailable,
+					title:   "Inflection Search Unavailable",
+					message: "Inflection search is disabled. Do you have the inflections database installed?",
+					hints:   []string{fmt.Sprintf("Put %s in tsk's config directory and start tsk again.", INFLECTIONS_FILE)},
+				})
+			}
+			return nil
+
+		case actionExamples:
+			if list.GetItemCount() == 0 {
+				textView.SetBorderColor(theme.Examples)
+				textView.SetTitleColor(theme.Examples)
+				textView.SetTitle("No word selected. Kotimaa itkee...")
+				textView.SetText(finnishFlag)
+				return nil
+			}
+
+			idx := list.GetCurrentItem()
+			_, word := list.GetItemText(idx)
+
+			// 1a) if the search bar is empty, show teal “please enter something” message
+			if strings.TrimSpace(word) == "" {
+				textView.SetBorderColor(theme.Examples)
+				textView.SetTitleColor(theme.Examples)
+				textView.SetTitle("No word entered. Kotimaa itkee...")
+				textView.SetText(finnishFlag)
+				textView.SetText("[teal]No word entered. Please type something in the search bar.[white]")
+				return nil
+			}
+
+			recordLookup(word)
+			showSentences(word)
+			advanceTour(tourExamples)
+			return nil
+		case actionInflections:
+			if list.GetItemCount() == 0 {
+				showStatus(needsWordStatus("see its inflections"))
+				return nil
+			}
+			_, word := list.GetItemText(list.GetCurrentItem())
+			recordLookup(word)
+
+			if formIndex == nil {
+				formIndex = NewFormIndex(glosses)
+			}
+			lemma, ok := formIndex.Lemma(word, glosses)
+			if !ok {
+				showStatus(detailsStatus{
+					kind:    statusUnavailable,
+					title:   "No inflections found",
+					message: fmt.Sprintf("No inflection table is available for '%s'.", word),
+					hints:   []string{"Try its base form, if it's an inflected form; some words don't inflect at all."},
+				})
+				return nil
+			}
+
+			textView.SetTitle(fmt.Sprintf("Inflections of '%s' (Tab/Shift-Tab to scroll)", lemma))
+			textView.SetBorderColor(theme.Inflections)
+			textView.SetTitleColor(theme.Inflections)
+			textView.SetText(inflectionTableText(lemma, formIndex[lemma]))
+			textView.ScrollToBeginning()
+			return nil
+		case actionSave:
+			if isolated {
+				// The files would land on the server, out of the guest's reach.
+				showStatus(detailsStatus{kind: statusUnavailable, message: "Saving marked words isn't available over SSH."})
+				return nil
+			}
+			textView.SetBorderColor(theme.MarkedList)
+			textView.SetTitleColor(theme.MarkedList)
+			if len(marked) == 0 && len(markedSentences) == 0 {
+				showStatus(detailsStatus{
+					kind:    statusUnavailable,
+					title:   "Nothing to save. Kotimaa itkee...",
+					message: fmt.Sprintf("Mark some words with %s first.", keymap.Label(actionMark)),
+				})
+				return nil
+			}
+			showExportModal(pages, app, inputField, saveMarked)
+			return nil
+		case actionCopy:
+			if list.GetItemCount() == 0 {
+				showStatus(needsWordStatus("copy its gloss"))
+				return nil
+			}
+			_, word := list.GetItemText(list.GetCurrentItem())
+			recordLookup(word)
+			displayGloss(word)
+
+			copyText(strings.TrimSpace(stripColorTags(glossTextFor(word))) + "\n")
+			textView.SetTitle(fmt.Sprintf("Copied the gloss of '%s' to the clipboard", word))
+			return nil
+		case actionSpeak:
+			if list.GetItemCount() == 0 {
+				showStatus(needsWordStatus("hear it"))
+				return nil
+			}
+			_, word := list.GetItemText(list.GetCurrentItem())
+			if isolated {
+				// The sound would come out of the server's speakers.
+				showStatus(detailsStatus{kind: statusUnavailable, message: "Pronunciation isn't available over SSH."})
+				return nil
+			}
+			recordLookup(word)
+			displayGloss(word)
+			// A native speaker beats the synthesizer, if the audio pack
+			// has them and there is something to play them with.
+			if path, ok := recordings.Recording(word); ok {
+				err := playAudio(path)
+				if err == nil {
+					textView.SetTitle(fmt.Sprintf("Playing a recording of '%s' (%s to hear it again)", word, keymap.Label(actionSpeak)))
+					return nil
+				}
+				log.Printf("Could not play the recording of %s: %v", word, err)
+			}
+			if err := speak(word, speakingRate); err != nil {
+				textView.SetTitle(fmt.Sprintf("Could not say '%s': %v", word, err))
+				textView.SetBorderColor(theme.Error)
+				textView.SetTitleColor(theme.Error)
+				return nil
+			}
+			textView.SetTitle(fmt.Sprintf("Saying '%s' (%s to hear it again)", word, keymap.Label(actionSpeak)))
+			return nil
+		case actionHelp:
+			showHelp()
+			return nil
+		case actionStats:
+			showStats()
+			return nil
+		case actionWiktionary:
+			word := currentWord()
+			if word == "" {
+				showStatus(needsWordStatus("open it on Wiktionary"))
+				return nil
+			}
+			link := wiktionaryURL(wiktionaryEdition, word, activePack.Meta.Language)
+			if isolated {
+				// A browser would open on the server, so guests get the link.
+				textView.SetTitle(link)
+				return nil
+			}
+			if err := openBrowser(link); err != nil {
+				textView.SetTitle(fmt.Sprintf("Could not open %s: %v", link, err))
+				textView.SetBorderColor(theme.Error)
+				textView.SetTitleColor(theme.Error)
+				return nil
+			}
+			textView.SetTitle(fmt.Sprintf("Opened '%s' on Wiktionary in your browser", word))
+			return nil
+		case actionReleaseNotes:
+			link := releaseNotesURL(newRelease)
+			if isolated {
+				textView.SetTitle(link)
+				return nil
+			}
+			if err := openBrowser(link); err != nil {
+				textView.SetTitle(fmt.Sprintf("Could not open %s: %v", link, err))
+				textView.SetBorderColor(theme.Error)
+				textView.SetTitleColor(theme.Error)
+				return nil
+			}
+			if newRelease.TagName != "" {
+				textView.SetTitle(fmt.Sprintf("Opened the release notes of tsk %s in your browser", newRelease.TagName))
+			} else {
+				textView.SetTitle("Opened the latest release notes in your browser")
+			}
+			return nil
+		case actionListMarked:
+			// A second Ctrl-L on the listing opens it for pruning.
+			if len(marked) > 0 && textView.GetTitle() == markedTitle {
+				wordsIn := func(collection string) []string {
+					return finnishSorted(maps.Keys(collectionMarks(marked, collections, collection)))
+				}
+				showMarkedModal(pages, app, collectionNames(collections), shownCollection, wordsIn, inputField, func(word string) {
+					setSearch(word)
+				}, func(collection, word string) {
+					if collection != "" {
+						collections[collection] = slices.DeleteFunc(collections[collection], func(w string) bool { return w == word })
+						saveCollectionsNow()
+						return
+					}
+					delete(marked, word)
+					saveMarks()
+					updateList(inputField.GetText())
+				}, func(collection string) {
+					if collection != "" {
+						delete(collections, collection)
+						saveCollectionsNow()
+						shownCollection = ""
+						textView.SetTitle("Collection deleted")
+						textView.SetText(fmt.Sprintf("\n  [green]The collection '%s' was deleted. Its words are still marked.[white]", tview.Escape(collection)))
+						return
+					}
+					clear(marked)
+					saveMarks()
+					updateList(inputField.GetText())
+					textView.SetTitle("Marked words cleared")
+					textView.SetText("\n  [green]All marked words were unmarked.[white]")
+				}, func(collection string) {
+					shownCollection = collection
+				})
+				return nil
+			}
+			textView.SetBorderColor(theme.MarkedList)
+			textView.SetTitleColor(theme.MarkedList)
+
+			// Only the collection the manager last showed, if any.
+			shown := collectionMarks(marked, collections, shownCollection)
+			count := len(shown)
+			if len(marked) == 0 {
+				textView.SetTitle("Marked words list empty. Kotimaa itkee...")
+				textView.SetText(finnishFlag)
+			} else {
+				markedTitle = fmt.Sprintf("Listing marked words. (count: %d, %s again to unmark)", count, keymap.Label(actionListMarked))
+				if shownCollection != "" {
+					markedTitle = fmt.Sprintf("Listing marked words in '%s'. (count: %d, %s again to manage)", shownCollection, count, keymap.Label(actionListMarked))
+				}
+				textView.SetTitle(markedTitle)
+				textView.SetBorderColor(theme.MarkedList)
+				textView.SetTitleColor(theme.MarkedList)
+
+				// build a sorted slice of the set
+				var words []string
+				for w := range shown {
+					words = append(words, w)
+				}
+				tsk.SortFinnish(words)
+
+				// render them in green, noting words marked for only some
+				// senses, and the collections of each when all are listed
+				builder := strings.Builder{}
+				builder.WriteString("[green]")
+				for _, w := range words {
+					builder.WriteString(w)
+					if senses := marked[w]; senses != nil {
+						fmt.Fprintf(&builder, " [gray](%d of %d senses)[green]", len(senses), len(wordSenses(w, glosses)))
+					}
+					if names := collectionsOf(collections, w); shownCollection == "" && len(names) > 0 {
+						fmt.Fprintf(&builder, " [teal]%s[green]", tview.Escape(strings.Join(names, ", ")))
+					}
+					builder.WriteByte('\n')
+				}
+				builder.WriteString("[white]")
+				if len(collections) > 0 {
+					fmt.Fprintf(&builder, "\n[gray]Press [yellow]%s[gray] again, then Left/Right, to list one of your %d collections, or all your marked words.[white]\n",
+						keymap.Label(actionListMarked), len(collections))
+				}
+
+				builder.WriteByte('\n')
+				builder.WriteByte('\n')
+				if exportDeeper {
+					builder.WriteString("[gray]The exported glosses also include every \"go-deeper\" word, since tsk was started with --export-deeper.")
+					builder.WriteByte('\n')
+					builder.WriteByte('\n')
+					builder.WriteString("[gray]For example, marking '[yellow]omenan[gray]' includes the glosses of '[yellow]omena[gray]' too.")
+				} else {
+					builder.WriteString("[gray]Caution: The exported files [red]do NOT[gray] include any \"go-deeper\" words or phrases.")
+					builder.WriteByte('\n')
+					builder.WriteByte('\n')
+					builder.WriteString("[gray]For example, marking '[yellow]omenan[gray]' [red]will NOT[gray] include any info about '[yellow]omena[gray]'.")
+					builder.WriteByte('\n')
+					builder.WriteByte('\n')
+					builder.WriteString("To include them, start tsk with --export-deeper, or add them separately.")
+				}
+				builder.WriteByte('\n')
+				builder.WriteByte('\n')
+				fmt.Fprintf(&builder, "Press [yellow]%s[gray] again to unmark words or clear them all.[white]", keymap.Label(actionListMarked))
+
+				textView.SetText(builder.String())
+			}
+			return nil
+		case actionMark:
+			if list.GetItemCount() == 0 {
+				showStatus(needsWordStatus("mark or unmark it"))
+				return nil
+			}
+			idx := list.GetCurrentItem()
+			_, word := list.GetItemText(idx)
+
+			setSearch(word)
+			recordLookup(word)
+
+			if _, present := marked[word]; present {
+				delete(marked, word)
+				if debug {
+					log.Printf("Unmarking %s.", word)
+				}
+			} else {
+				marked[word] = nil
+				if debug {
+					log.Printf("Marking %s.", word)
+				}
+				if markCollection != "" {
+					collections[markCollection] = append(collections[markCollection], word)
+					saveCollectionsNow()
+				}
+			}
+			saveMarks()
+			advanceTour(tourMark)
+			updateList(inputField.GetText())
+			return nil
+		case actionCollect:
+			if list.GetItemCount() == 0 {
+				showStatus(needsWordStatus("put it in a collection"))
+				return nil
+			}
+			_, word := list.GetItemText(list.GetCurrentItem())
+			showCollectionModal(pages, app, word, collections, inputField, func(collection string, in bool) {
+				if !in {
+					collections[collection] = slices.DeleteFunc(collections[collection], func(w string) bool { return w == word })
+					saveCollectionsNow()
+					return
+				}
+				// Only marked words are collected, so it is marked too.
+				if _, present := marked[word]; !present {
+					marked[word] = nil
+					saveMarks()
+					updateList(inputField.GetText())
+				}
+				collections[collection] = append(collections[collection], word)
+				saveCollectionsNow()
+			})
+			return nil
+		case actionHistoryBack:
+			if word, ok := history.Prev(); ok {
+				setSearch(word)
+			}
+			return nil
+		case actionHistoryForward:
+			if word, ok := history.Next(); ok {
+				setSearch(word)
+			}
+			return nil
+		case actionHistory:
+			showHistory()
+			return nil
+		case actionPosFilter:
+			next := 0
+			for i, pos := range posFilters {
+				if pos == posFilter {
+					next = i + 1
+				}
+			}
+			if next < len(posFilters) {
+				posFilter = posFilters[next]
+			} else {
+				posFilter = ""
+			}
+			updateList(inputField.GetText())
+			return nil
+		case actionFoldDiacritics:
+			foldSearch = !foldSearch
+			updateList(inputField.GetText())
+			return nil
+		case actionRhymes:
+			// Pressed again, it takes one more syllable of the same word,
+			// until the ending is the whole word and it starts over.
+			if rhymeWord == "" || inputField.GetText() != "$"+tsk.RhymeEnding(rhymeWord, rhymeSyllables) {
+				rhymeWord, rhymeSyllables = currentWord(), 0
+			}
+			if rhymeWord == "" {
+				showStatus(needsWordStatus("find its rhymes"))
+				return nil
+			}
+			rhymeSyllables++
+			if rhymeSyllables > len(tsk.Syllables(rhymeWord)) {
+				rhymeSyllables = 1
+			}
+			setSearch("$" + tsk.RhymeEnding(rhymeWord, rhymeSyllables))
+			return nil
+		case actionSurprise:
+			surprise()
+			return nil
+		case actionEnglishSearch:
+			englishMode = !englishMode
+			updateList(inputField.GetText())
+			if list.GetItemCount() == 0 && inputField.GetText() != "" {
+				showStatus(noResultsStatus(inputField.GetText(), posFilter, posHidden > 0, englishMode))
+			}
+			return nil
+		case actionNeighbors:
+			if neighborsActive() {
+				displayGloss(neighborsWord)
+				return nil
+			}
+			word := currentWord()
+			if word == "" {
+				showStatus(needsWordStatus("see its neighbors"))
+				return nil
+			}
+			neighborsWord = ""
+			showNeighbors(word, 0)
+			return nil
+		case actionCollapseList:
+			listHidden = !listHidden
+			sizePanes()
+			saveLayout()
+			return nil
+		case actionNote:
+			if list.GetItemCount() == 0 {
+				showStatus(needsWordStatus("write a note on it"))
+				return nil
+			}
+			if isolated {
+				showStatus(detailsStatus{kind: statusUnavailable, message: "Notes can't be written over SSH."})
+				return nil
+			}
+			_, word := list.GetItemText(list.GetCurrentItem())
+			save := func(note string) {
+				if err := setNote(notes, word, note); err != nil {
+					showStatus(errorStatus(fmt.Sprintf("saving your note on '%s'", word), err))
+					return
+				}
+				setSearch(word)
+				updateList(word)
+			}
+			edited, ok, err := editNoteExternally(app, word, notes[word])
+			switch {
+			case err != nil:
+				showStatus(errorStatus(fmt.Sprintf("editing your note on '%s'", word), err))
+			case ok:
+				save(edited)
+			default:
+				showNoteModal(pages, app, word, notes[word], inputField, save)
+			}
+			return nil
+		case actionEditGloss:
+			if list.GetItemCount() == 0 {
+				showStatus(needsWordStatus("edit its glosses"))
+				return nil
+			}
+			if isolated {
+				// Guests share one dictionary, and the host's files.
+				showStatus(detailsStatus{kind: statusUnavailable, message: "Glosses can't be edited over SSH."})
+				return nil
+			}
+			_, word := list.GetItemText(list.GetCurrentItem())
+			showGlossEditModal(pages, app, word, glosses[word], inputField, func(edited []tsk.Gloss) {
+				if err := saveOverride(word, edited); err != nil {
+					showStatus(errorStatus(fmt.Sprintf("saving your glosses of '%s'", word), err))
+					return
+				}
+				glossesMu.Lock()
+				if len(edited) > 0 {
+					glosses[word] = edited
+				} else if packGlosses, err := activePack.loadGlosses(); err == nil {
+					if own, ok := packGlosses[word]; ok {
+						glosses[word] = own
+					} else {
+						delete(glosses, word)
+					}
+				}
+				glossesMu.Unlock()
+				// Picked senses are counted by position, which the edit may
+				// have moved, so the whole word is marked instead.
+				if senses, ok := marked[word]; ok && senses != nil {
+					marked[word] = nil
+					saveMarks()
+				}
+				setSearch(word)
+				updateList(word)
+			})
+			return nil
+
+		case actionSenses:
+			if list.GetItemCount() == 0 {
+				showStatus(needsWordStatus("pick its senses"))
+				return nil
+			}
+			_, word := list.GetItemText(list.GetCurrentItem())
+			if len(wordSenses(word, glosses)) == 0 {
+				showStatus(detailsStatus{kind: statusUnavailable, message: fmt.Sprintf("'%s' has no senses to pick from.", word)})
+				return nil
+			}
+			recordLookup(word)
+
+			// Start from the current marks; an unmarked word starts empty.
+			current, present := marked[word]
+			if !present {
+				current = make(senseSet)
+			}
+			showSensePickerModal(pages, app, word, glosses, current, inputField, func(picked senseSet) {
+				if picked != nil && len(picked) == 0 {
+					delete(marked, word)
+				} else {
+					marked[word] = picked
+				}
+				saveMarks()
+				setSearch(word)
+				updateList(word)
+			})
+			return nil
+		}
+
+		switch event.Key() {
+		case tcell.KeyLeft, tcell.KeyRight:
+			// Ctrl-Left and Ctrl-Right narrow and widen the word list.
+			if event.Modifiers()&tcell.ModCtrl != 0 {
+				step := LIST_WIDTH_STEP
+				if event.Key() == tcell.KeyLeft {
+					step = -step
+				}
+				listWide = min(max(listWide+step, MIN_LIST_WIDTH), MAX_LIST_WIDTH)
+				listHidden = false
+				sizePanes()
+				saveLayout()
+				return nil
+			}
+			// Alt-Left and Alt-Right go back and forward, as in a browser.
+			if event.Modifiers()&tcell.ModAlt == 0 {
+				return event
+			}
+			if event.Key() == tcell.KeyLeft {
+				goBack()
+			} else {
+				goForward()
+			}
+			return nil
+		case tcell.KeyPgDn, tcell.KeyPgUp:
+			// Jump between the groups of a long result list.
+			step := 1
+			if event.Key() == tcell.KeyPgUp {
+				step = -1
+			}
+			if jumpGroup(step) {
+				return nil
+			}
+			return event
+		case tcell.KeyTab:
+			// Scroll down one line in the textView.
+			currentRow, currentCol := textView.GetScrollOffset()
+			textView.ScrollTo(currentRow+1, currentCol)
+			advanceTour(tourScroll)
+			return nil // swallow event
+		case tcell.KeyBacktab:
+			// Scroll up one line in the textView.
+			currentRow, currentCol := textView.GetScrollOffset()
+			newRow := currentRow - 1
+			if newRow < 0 {
+				newRow = 0
+			}
+			textView.ScrollTo(newRow, currentCol)
+			advanceTour(tourScroll)
+			return nil // swallow event
+		case tcell.KeyEsc:
+			// Esc closes the Ctrl-E and Ctrl-F modals; only in the main
+			// view does it quit.
+			if front != "main" {
+				return event
+			}
+			app.Stop()
+			if isolated {
+				// Guests' marks and history go with their session.
+				return nil
+			}
+			fmt.Println("Stopping the TUI. Thank you for exiting gracefully!")
+
+			// Remember what was looked up for the next start screen.
+			if list.GetItemCount() > 0 {
+				_, word := list.GetItemText(list.GetCurrentItem())
+				recordLookup(word)
+			}
+			if err := session.Save(); err != nil {
+				fmt.Fprintf(os.Stderr, "Error saving session: %v\n", err)
+			}
+			// The scroll offset only means something for Word Details.
+			view := lastView{Search: inputField.GetText(), Word: currentWord()}
+			if title := textView.GetTitle(); title == detailsTitle || title == markedDetailsTitle {
+				view.Scroll, _ = textView.GetScrollOffset()
+			}
+			if err := saveLastView(view); err != nil {
+				fmt.Fprintf(os.Stderr, "Error saving the last view: %v\n", err)
+			}
+			if !noHistory {
+				if err := history.Save(); err != nil {
+					fmt.Fprintf(os.Stderr, "Error saving search history: %v\n", err)
+				}
+			}
+
+			if err := saveMarkedExports(marked, markedSentences, glosses, dict, exportProfileName, ""); err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				os.Exit(1)
+			}
+
+			return nil
+		default:
+			return event
+		}
+	})
+
+	// -------------------------------
+	// Main Layout
+	// -------------------------------
+	mainFlex := tview.NewFlex().
+		SetDirection(tview.FlexRow).
+		// Top row (header)
+		AddItem(headerFlex, 1, 0, false).
+		// Spacer for a black bar
+		AddItem(nil, 1, 0, false).
+		// Main content (search + list + details)
+		AddItem(topFlex, 0, 1, true).
+		// Spacer for a black bar
+		AddItem(nil, 1, 0, false).
+		// Bottom row (footer)
+		AddItem(footerFlex, 1, 0, false)
+
+	// --- FIX #2 & #3: Add the mainFlex as the "main" page, and remove the invalid modalLayout call.
+	pages.AddPage("main", mainFlex, true, true)
+
+	app.SetRoot(pages, true)
+	return app
+}
@@ -0,0 +1,921 @@
+package main
+
+import (
+	"bytes"
+	"cmp"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/pbkdf2"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"iter"
+	"maps"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/hiAndrewQuinn/tsk/pkg/tsk"
+	"github.com/rivo/tview"
+	"golang.org/x/term"
+)
+
+// ----------------------
+// User Data Directory & Session Log
+// ----------------------
+
+// userDataDir returns the directory tsk keeps per-user state in, creating it
+// if necessary. The default profile uses the same place the optional
+// inflections.db lives; named profiles get their own subdirectory.
+func userDataDir() (string, error) {
+	dir, err := tskConfigDir()
+	if err != nil {
+		return "", err
+	}
+	if profile != "" {
+		dir = filepath.Join(dir, "profiles", profile)
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", err
+	}
+	return dir, nil
+}
+
+// validProfileName reports whether name is safe to use as a directory name:
+// letters, digits, '-' and '_' only.
+func validProfileName(name string) bool {
+	if name == "" {
+		return false
+	}
+	for _, r := range name {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '-' && r != '_' {
+			return false
+		}
+	}
+	return true
+}
+
+// SessionLog records, in order and without repeats, the words the user
+// settled on during a session, so the next start screen can offer them again.
+type SessionLog struct {
+	words []string
+	seen  map[string]struct{}
+}
+
+func NewSessionLog() *SessionLog {
+	return &SessionLog{seen: make(map[string]struct{})}
+}
+
+func (s *SessionLog) Add(word string) {
+	if word == "" {
+		return
+	}
+	if _, ok := s.seen[word]; ok {
+		return
+	}
+	s.seen[word] = struct{}{}
+	s.words = append(s.words, word)
+}
+
+// Save overwrites the last-session file. If nothing was looked up, the
+// previous session is left alone so it can still be resumed next time.
+func (s *SessionLog) Save() error {
+	if len(s.words) == 0 {
+		return nil
+	}
+	data := strings.Join(s.words, "\n") + "\n"
+	return writeUserFile(LAST_SESSION_FILE, []byte(data))
+}
+
+// loadLastSession returns the words saved by the previous session, or nil if
+// there was none.
+func loadLastSession() ([]string, error) {
+	data, err := readUserFile(LAST_SESSION_FILE)
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	var words []string
+	for _, line := range strings.Split(string(data), "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			words = append(words, line)
+		}
+	}
+	return words, nil
+}
+
+// lastView is where the user stopped reading when they quit: the search,
+// the word selected in its results, and how far down that word's Word
+// Details were scrolled. The TUI opens there again next time.
+type lastView struct {
+	Search string `json:"search"`
+	Word   string `json:"word,omitempty"`
+	Scroll int    `json:"scroll,omitempty"`
+}
+
+func saveLastView(view lastView) error {
+	data, err := json.Marshal(view)
+	if err != nil {
+		return err
+	}
+	return writeUserFile(LAST_VIEW_FILE, append(data, '\n'))
+}
+
+// loadLastView returns the view saved by the previous session, which has
+// no search if there was none.
+func loadLastView() (lastView, error) {
+	var view lastView
+	data, err := readUserFile(LAST_VIEW_FILE)
+	if os.IsNotExist(err) {
+		return view, nil
+	} else if err != nil {
+		return view, err
+	}
+	err = json.Unmarshal(data, &view)
+	return view, err
+}
+
+// ----------------------
+// Marked Words Collection
+// ----------------------
+
+// Marked words are kept between sessions in MARKED_FILE, as a JSON object
+// mapping each word to its picked senses, or to null when the whole word is
+// marked. The TUI saves it whenever the marks change.
+
+// loadMarked reads the saved marks. A missing file means nothing is marked.
+func loadMarked() (map[string]senseSet, error) {
+	marked := make(map[string]senseSet)
+	data, err := readUserFile(MARKED_FILE)
+	if os.IsNotExist(err) {
+		return marked, nil
+	} else if err != nil {
+		return nil, err
+	}
+	var saved map[string][]senseKey
+	if err := json.Unmarshal(data, &saved); err != nil {
+		return nil, fmt.Errorf("%s: %w", MARKED_FILE, err)
+	}
+	for word, senses := range saved {
+		if senses == nil {
+			marked[word] = nil
+			continue
+		}
+		set := make(senseSet, len(senses))
+		for _, sense := range senses {
+			set[sense] = struct{}{}
+		}
+		marked[word] = set
+	}
+	return marked, nil
+}
+
+// saveMarked writes the marks back, senses in display order.
+func saveMarked(marked map[string]senseSet) error {
+	saved := make(map[string][]senseKey, len(marked))
+	for word, set := range marked {
+		if set == nil {
+			saved[word] = nil
+			continue
+		}
+		senses := make([]senseKey, 0, len(set))
+		for sense := range set {
+			senses = append(senses, sense)
+		}
+		sort.Slice(senses, func(i, j int) bool {
+			if senses[i].Gloss != senses[j].Gloss {
+				return senses[i].Gloss < senses[j].Gloss
+			}
+			return senses[i].Meaning < senses[j].Meaning
+		})
+		saved[word] = senses
+	}
+	data, err := json.MarshalIndent(saved, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeUserFile(MARKED_FILE, append(data, '\n'))
+}
+
+// ----------------------
+// Collections of Marked Words
+// ----------------------
+
+// Marked words can be sorted into named collections, like "Chapter 7" or
+// "Hard words", kept in COLLECTIONS_FILE as a JSON object mapping each
+// collection's name to its words. A word can be in any number of them.
+// Only marked words are in collections: unmarking a word takes it out of
+// them all, but a collection stays, even empty, until it is deleted in the
+// marked-words manager.
+
+// markCollection is the collection the mark key puts words in, besides
+// marking them.
+var markCollection string
+
+// loadCollections reads the saved collections. A missing file means there
+// are none.
+func loadCollections() (map[string][]string, error) {
+	collections := make(map[string][]string)
+	data, err := readUserFile(COLLECTIONS_FILE)
+	if os.IsNotExist(err) {
+		return collections, nil
+	} else if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &collections); err != nil {
+		return nil, fmt.Errorf("%s: %w", COLLECTIONS_FILE, err)
+	}
+	return collections, nil
+}
+
+// saveCollections writes the collections back, each one's words sorted.
+func saveCollections(collections map[string][]string) error {
+	for name, words := range collections {
+		if words == nil {
+			// An empty collection is kept as [], not null.
+			words = []string{}
+		}
+		slices.Sort(words)
+		collections[name] = slices.Compact(words)
+	}
+	data, err := json.MarshalIndent(collections, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeUserFile(COLLECTIONS_FILE, append(data, '\n'))
+}
+
+// pruneCollections takes the words that are no longer marked out of the
+// collections, and reports whether there were any.
+func pruneCollections(collections map[string][]string, marked map[string]senseSet) bool {
+	pruned := false
+	for name, words := range collections {
+		kept := slices.DeleteFunc(slices.Clone(words), func(w string) bool {
+			_, ok := marked[w]
+			return !ok
+		})
+		if len(kept) < len(words) {
+			collections[name] = kept
+			pruned = true
+		}
+	}
+	return pruned
+}
+
+// collectionsOf returns the names of the collections word is in, sorted.
+func collectionsOf(collections map[string][]string, word string) []string {
+	var names []string
+	for name, words := range collections {
+		if slices.Contains(words, word) {
+			names = append(names, name)
+		}
+	}
+	slices.Sort(names)
+	return names
+}
+
+// collectionMarks returns the marks of the words in the named collection,
+// or all of them if name is "".
+func collectionMarks(marked map[string]senseSet, collections map[string][]string, name string) map[string]senseSet {
+	if name == "" {
+		return marked
+	}
+	picked := make(map[string]senseSet)
+	for _, w := range collections[name] {
+		if senses, ok := marked[w]; ok {
+			picked[w] = senses
+		}
+	}
+	return picked
+}
+
+// collectionNames returns the names of the collections, sorted, led by ""
+// for all the marked words, the order the marked-words manager steps
+// through them in.
+func collectionNames(collections map[string][]string) []string {
+	return append([]string{""}, slices.Sorted(maps.Keys(collections))...)
+}
+
+// finnishSorted collects the words from seq in Finnish alphabetical order,
+// as slices.Sorted does in code point order, for listing marked words.
+func finnishSorted(seq iter.Seq[string]) []string {
+	words := slices.Collect(seq)
+	tsk.SortFinnish(words)
+	return words
+}
+
+// ----------------------
+// Word Notes
+// ----------------------
+
+// Notes of one's own, like "confusable with tuuli/tuli", can be put on any
+// headword. They are kept in NOTES_FILE as a JSON object mapping each word
+// to its note, shown at the top of its Word Details, searched with "@" in
+// the search bar, and exported with the word.
+
+// loadNotes reads the saved notes. A missing file means there are none.
+func loadNotes() (map[string]string, error) {
+	notes := make(map[string]string)
+	data, err := readUserFile(NOTES_FILE)
+	if os.IsNotExist(err) {
+		return notes, nil
+	} else if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &notes); err != nil {
+		return nil, fmt.Errorf("%s: %w", NOTES_FILE, err)
+	}
+	return notes, nil
+}
+
+// saveNotes writes the notes back.
+func saveNotes(notes map[string]string) error {
+	data, err := json.MarshalIndent(notes, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeUserFile(NOTES_FILE, append(data, '\n'))
+}
+
+// setNote gives word the note, or takes its note away if the note is
+// blank, and saves the notes.
+func setNote(notes map[string]string, word, note string) error {
+	if note = strings.TrimSpace(note); note == "" {
+		delete(notes, word)
+	} else {
+		notes[word] = note
+	}
+	return saveNotes(notes)
+}
+
+// noteText sets a note off at the top of Word Details, each of its lines
+// behind a bar, or returns "" if there is no note.
+func noteText(note string) string {
+	if note == "" {
+		return ""
+	}
+	var b strings.Builder
+	for i, line := range strings.Split(note, "\n") {
+		label := ""
+		if i == 0 {
+			label = "Note: "
+		}
+		fmt.Fprintf(&b, "[fuchsia]▍ %s[white]%s\n", label, tview.Escape(line))
+	}
+	b.WriteString("\n")
+	return b.String()
+}
+
+// notesMatching returns the words whose notes mention query, ignoring
+// case, sorted; every word with a note if query is blank.
+func notesMatching(notes map[string]string, query string) []string {
+	query = strings.ToLower(strings.TrimSpace(query))
+	var words []string
+	for word, note := range notes {
+		if strings.Contains(strings.ToLower(note), query) {
+			words = append(words, word)
+		}
+	}
+	tsk.SortFinnish(words)
+	return words
+}
+
+// editNoteExternally opens note in $VISUAL or $EDITOR, suspending app
+// while it runs, and returns the note as saved there. ok is false if
+// neither is set, for the note to be edited in the TUI instead.
+func editNoteExternally(app *tview.Application, word, note string) (edited string, ok bool, err error) {
+	command := strings.Fields(cmp.Or(os.Getenv("VISUAL"), os.Getenv("EDITOR")))
+	if len(command) == 0 {
+		return "", false, nil
+	}
+	f, err := os.CreateTemp("", "tsk-note-*.txt")
+	if err != nil {
+		return "", true, err
+	}
+	defer os.Remove(f.Name())
+	_, err = f.WriteString(note)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return "", true, err
+	}
+	app.Suspend(func() {
+		cmd := exec.Command(command[0], append(command[1:], f.Name())...)
+		cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
+		if runErr := cmd.Run(); runErr != nil {
+			err = fmt.Errorf("editing the note of '%s' with %s: %w", word, command[0], runErr)
+		}
+	})
+	if err != nil {
+		return "", true, err
+	}
+	data, err := os.ReadFile(f.Name())
+	return string(data), true, err
+}
+
+// ----------------------
+// Marked Sentences
+// ----------------------
+
+// Marked sentences are kept between sessions in MARKED_SENTENCES_FILE, as
+// "finnish<TAB>english<TAB>source" lines in the order they were marked.
+
+// loadMarkedSentences reads the saved sentences. A missing file means none
+// are marked.
+func loadMarkedSentences() ([]sentencePair, error) {
+	data, err := readUserFile(MARKED_SENTENCES_FILE)
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	var sentences []sentencePair
+	for _, line := range strings.Split(string(data), "\n") {
+		fields := strings.Split(line, "\t")
+		if len(fields) != 3 {
+			continue
+		}
+		sentences = append(sentences, sentencePair{fields[0], fields[1], fields[2]})
+	}
+	return sentences, nil
+}
+
+// saveMarkedSentences writes the marked sentences back.
+func saveMarkedSentences(sentences []sentencePair) error {
+	var b strings.Builder
+	for _, s := range sentences {
+		fmt.Fprintf(&b, "%s\t%s\t%s\n", s.Finnish, s.English, s.Source)
+	}
+	return writeUserFile(MARKED_SENTENCES_FILE, []byte(b.String()))
+}
+
+// ----------------------
+// Search History
+// ----------------------
+
+// SearchHistory keeps every word the user settled on, across sessions, so
+// they can step back through them with Ctrl-P/Ctrl-N or list them with
+// Ctrl-O. It is stored as "time<TAB>word" lines, oldest first.
+type SearchHistory struct {
+	entries []historyEntry
+	cursor  int // index into Recent() while stepping; -1 when not stepping
+}
+
+type historyEntry struct {
+	Time time.Time
+	Word string
+}
+
+// loadSearchHistory reads the saved history. A missing file is just an
+// empty history.
+func loadSearchHistory() (*SearchHistory, error) {
+	h := &SearchHistory{cursor: -1}
+	data, err := readUserFile(HISTORY_FILE)
+	if os.IsNotExist(err) {
+		return h, nil
+	} else if err != nil {
+		return h, err
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		stamp, word, ok := strings.Cut(line, "\t")
+		if !ok || word == "" {
+			continue
+		}
+		t, err := time.Parse(time.RFC3339, stamp)
+		if err != nil {
+			continue
+		}
+		h.entries = append(h.entries, historyEntry{t, word})
+	}
+	return h, nil
+}
+
+// Add records a lookup, unless it repeats the previous one, and stops any
+// stepping through the history.
+func (h *SearchHistory) Add(word string) {
+	h.cursor = -1
+	if word == "" {
+		return
+	}
+	if n := len(h.entries); n > 0 && h.entries[n-1].Word == word {
+		return
+	}
+	h.entries = append(h.entries, historyEntry{time.Now(), word})
+}
+
+// Recent returns one entry per word, newest first.
+func (h *SearchHistory) Recent() []historyEntry {
+	seen := make(map[string]bool)
+	var recent []historyEntry
+	for i := len(h.entries) - 1; i >= 0; i-- {
+		if e := h.entries[i]; !seen[e.Word] {
+			seen[e.Word] = true
+			recent = append(recent, e)
+		}
+	}
+	return recent
+}
+
+// Prev steps one word further back in the history.
+func (h *SearchHistory) Prev() (string, bool) {
+	recent := h.Recent()
+	if h.cursor+1 >= len(recent) {
+		return "", false
+	}
+	h.cursor++
+	return recent[h.cursor].Word, true
+}
+
+// Next steps one word forward again. Stepping past the newest word returns
+// "" to get back to an empty search.
+func (h *SearchHistory) Next() (string, bool) {
+	if h.cursor < 0 {
+		return "", false
+	}
+	h.cursor--
+	if h.cursor < 0 {
+		return "", true
+	}
+	return h.Recent()[h.cursor].Word, true
+}
+
+// Save writes the history back, keeping only the newest
+// HISTORY_MAX_ENTRIES lookups.
+func (h *SearchHistory) Save() error {
+	entries := h.entries
+	if len(entries) > HISTORY_MAX_ENTRIES {
+		entries = entries[len(entries)-HISTORY_MAX_ENTRIES:]
+	}
+	var b strings.Builder
+	for _, e := range entries {
+		fmt.Fprintf(&b, "%s\t%s\n", e.Time.Format(time.RFC3339), e.Word)
+	}
+	return writeUserFile(HISTORY_FILE, []byte(b.String()))
+}
+
+// ----------------------
+// Back and Forward
+// ----------------------
+
+// wordTrail holds the words viewed this session before and after the
+// current one, like a web browser's back and forward buttons, so following
+// links from word to word with Alt-Left and Alt-Right doesn't lose one's
+// place. Unlike SearchHistory, it isn't saved.
+type wordTrail struct {
+	back, forward []string // most recent last
+}
+
+// Visit records going from the word from to a new one, which forgets the
+// words ahead.
+func (t *wordTrail) Visit(from string) {
+	t.forward = nil
+	if from != "" && (len(t.back) == 0 || t.back[len(t.back)-1] != from) {
+		t.back = append(t.back, from)
+	}
+}
+
+// Back returns the word before current, if there is one, and puts current
+// ahead of it.
+func (t *wordTrail) Back(current string) (string, bool) {
+	if len(t.back) == 0 {
+		return "", false
+	}
+	word := t.back[len(t.back)-1]
+	t.back = t.back[:len(t.back)-1]
+	if current != "" {
+		t.forward = append(t.forward, current)
+	}
+	return word, true
+}
+
+// Forward undoes Back.
+func (t *wordTrail) Forward(current string) (string, bool) {
+	if len(t.forward) == 0 {
+		return "", false
+	}
+	word := t.forward[len(t.forward)-1]
+	t.forward = t.forward[:len(t.forward)-1]
+	if current != "" {
+		t.back = append(t.back, current)
+	}
+	return word, true
+}
+
+// Crumbs returns up to n of the words before the current one, oldest
+// first.
+func (t *wordTrail) Crumbs(n int) []string {
+	return t.back[max(0, len(t.back)-n):]
+}
+
+// ----------------------
+// Optional Encryption of User Data
+// ----------------------
+
+// When a profile has encryption enabled, ENCRYPTION_FILE holds the key
+// derivation salt and a known plaintext sealed with the derived key, which is
+// how we tell a wrong passphrase from a right one. Every file listed in
+// encryptedUserFiles is then stored as encryptedMagic + nonce + AES-GCM
+// ciphertext. Shared data such as inflections.db is never encrypted.
+
+const (
+	ENCRYPTION_FILE = "encryption.json"
+
+	encryptedMagic     = "TSKENC1\n"
+	encryptionCheck    = "tsk passphrase check"
+	pbkdf2Iterations   = 600000
+	maxPassphraseTries = 3
+)
+
+// userDataKey is the AES-256 key for the current profile's data files, or
+// nil when encryption isn't enabled (or hasn't been unlocked yet).
+var userDataKey []byte
+
+var encryptedUserFiles = []string{LAST_SESSION_FILE, LAST_VIEW_FILE, HISTORY_FILE, MARKED_FILE, MARKED_SENTENCES_FILE, COLLECTIONS_FILE, NOTES_FILE}
+
+type encryptionConfig struct {
+	Salt  []byte `json:"salt"`
+	Check []byte `json:"check"`
+}
+
+func deriveKey(passphrase string, salt []byte) ([]byte, error) {
+	return pbkdf2.Key(sha256.New, passphrase, salt, pbkdf2Iterations, 32)
+}
+
+func sealBytes(key, plaintext []byte) ([]byte, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	sealed := append([]byte(encryptedMagic), nonce...)
+	return gcm.Seal(sealed, nonce, plaintext, nil), nil
+}
+
+func openBytes(key, data []byte) ([]byte, error) {
+	if !bytes.HasPrefix(data, []byte(encryptedMagic)) {
+		return nil, fmt.Errorf("data is not encrypted")
+	}
+	data = data[len(encryptedMagic):]
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) < gcm.NonceSize() {
+		return nil, fmt.Errorf("encrypted data is truncated")
+	}
+	nonce, ciphertext := data[:gcm.NonceSize()], data[gcm.NonceSize():]
+	return gcm.Open(nil, nonce, ciphertext, nil)
+}
+
+// readPassphrase asks for a passphrase on the terminal without echoing
+// it. It's a variable so that tests can answer instead.
+var readPassphrase = func(prompt string) (string, error) {
+	fd := int(os.Stdin.Fd())
+	if !term.IsTerminal(fd) {
+		return "", fmt.Errorf("a passphrase is needed, but standard input is not a terminal")
+	}
+	fmt.Fprint(os.Stderr, prompt)
+	passphrase, err := term.ReadPassword(fd)
+	fmt.Fprintln(os.Stderr)
+	return string(passphrase), err
+}
+
+func loadEncryptionConfig() (*encryptionConfig, error) {
+	dir, err := userDataDir()
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(filepath.Join(dir, ENCRYPTION_FILE))
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	var config encryptionConfig
+	if err := json.Unmarshal(data, &config); err != nil {
+		return nil, fmt.Errorf("parsing %s: %w", ENCRYPTION_FILE, err)
+	}
+	return &config, nil
+}
+
+// unlockUserData prompts for the profile's passphrase if encryption is
+// enabled and leaves the derived key in userDataKey. It does nothing if
+// encryption is off or the data is already unlocked.
+func unlockUserData() error {
+	if userDataKey != nil {
+		return nil
+	}
+	config, err := loadEncryptionConfig()
+	if err != nil || config == nil {
+		return err
+	}
+	for try := 1; try <= maxPassphraseTries; try++ {
+		passphrase, err := readPassphrase("Passphrase for your tsk data: ")
+		if err != nil {
+			return err
+		}
+		key, err := deriveKey(passphrase, config.Salt)
+		if err != nil {
+			return err
+		}
+		if check, err := openBytes(key, config.Check); err == nil && string(check) == encryptionCheck {
+			userDataKey = key
+			return nil
+		}
+		fmt.Fprintln(os.Stderr, "Wrong passphrase.")
+	}
+	return fmt.Errorf("too many wrong passphrases")
+}
+
+// enableEncryption asks for a new passphrase and encrypts any existing user
+// data files with it.
+func enableEncryption() error {
+	config, err := loadEncryptionConfig()
+	if err != nil {
+		return err
+	}
+	if config != nil {
+		return fmt.Errorf("encryption is already enabled")
+	}
+
+	passphrase, err := readPassphrase("New passphrase: ")
+	if err != nil {
+		return err
+	}
+	if passphrase == "" {
+		return fmt.Errorf("the passphrase must not be empty")
+	}
+	again, err := readPassphrase("Repeat passphrase: ")
+	if err != nil {
+		return err
+	}
+	if passphrase != again {
+		return fmt.Errorf("the passphrases did not match")
+	}
+
+	config = &encryptionConfig{Salt: make([]byte, 16)}
+	if _, err := rand.Read(config.Salt); err != nil {
+		return err
+	}
+	key, err := deriveKey(passphrase, config.Salt)
+	if err != nil {
+		return err
+	}
+	if config.Check, err = sealBytes(key, []byte(encryptionCheck)); err != nil {
+		return err
+	}
+
+	// Read everything while it's still plaintext, then switch keys.
+	plaintexts := make(map[string][]byte)
+	for _, name := range encryptedUserFiles {
+		data, err := readUserFile(name)
+		if os.IsNotExist(err) {
+			continue
+		} else if err != nil {
+			return err
+		}
+		plaintexts[name] = data
+	}
+
+	// Save the salt and check value before any file is encrypted with
+	// them. If tsk stops partway, the files not yet encrypted are still
+	// plaintext, which readUserFile reads as it is.
+	dir, err := userDataDir()
+	if err != nil {
+		return err
+	}
+	data, err := json.Marshal(config)
+	if err != nil {
+		return err
+	}
+	if err := writeFileAtomic(filepath.Join(dir, ENCRYPTION_FILE), data, 0600); err != nil {
+		return err
+	}
+	userDataKey = key
+	for name, data := range plaintexts {
+		if err := writeUserFile(name, data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// disableEncryption unlocks the user data files and rewrites them as plaintext.
+func disableEncryption() error {
+	config, err := loadEncryptionConfig()
+	if err != nil {
+		return err
+	}
+	if config == nil {
+		return fmt.Errorf("encryption is not enabled")
+	}
+	if err := unlockUserData(); err != nil {
+		return err
+	}
+
+	plaintexts := make(map[string][]byte)
+	for _, name := range encryptedUserFiles {
+		data, err := readUserFile(name)
+		if os.IsNotExist(err) {
+			continue
+		} else if err != nil {
+			return err
+		}
+		plaintexts[name] = data
+	}
+	userDataKey = nil
+	for name, data := range plaintexts {
+		if err := writeUserFile(name, data); err != nil {
+			return err
+		}
+	}
+
+	dir, err := userDataDir()
+	if err != nil {
+		return err
+	}
+	return os.Remove(filepath.Join(dir, ENCRYPTION_FILE))
+}
+
+// readUserFile reads a file from the user data directory, decrypting it if
+// necessary. Errors for missing files satisfy os.IsNotExist.
+func readUserFile(name string) ([]byte, error) {
+	dir, err := userDataDir()
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(filepath.Join(dir, name))
+	if err != nil {
+		return nil, err
+	}
+	if !bytes.HasPrefix(data, []byte(encryptedMagic)) {
+		return data, nil
+	}
+	if userDataKey == nil {
+		return nil, fmt.Errorf("%s is encrypted, but no passphrase was given", name)
+	}
+	return openBytes(userDataKey, data)
+}
+
+// writeUserFile writes a file to the user data directory, encrypting it if
+// encryption is enabled.
+func writeUserFile(name string, data []byte) error {
+	dir, err := userDataDir()
+	if err != nil {
+		return err
+	}
+	if userDataKey == nil {
+		return writeFileAtomic(filepath.Join(dir, name), data, 0644)
+	}
+	sealed, err := sealBytes(userDataKey, data)
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(filepath.Join(dir, name), sealed, 0600)
+}
+
+// writeFileAtomic writes data to path under another name and renames it
+// into place, so that a write cut short leaves the old file whole.
+func writeFileAtomic(path string, data []byte, perm os.FileMode) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Chmod(perm); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
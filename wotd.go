@@ -0,0 +1,350 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	mathrand "math/rand/v2"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/hiAndrewQuinn/tsk/pkg/tsk"
+	"github.com/rivo/tview"
+)
+
+// ----------------------
+// Word of the Day
+// ----------------------
+
+// The word of the day comes from a band of the frequency list: common
+// enough to be worth learning, but not so common that everyone knows it
+// already. Days step through the band in a fixed shuffled order, so a word
+// only comes round again once every other word has had its day.
+
+// wordOfTheDayCandidates returns the words the word of the day is picked
+// from: plain headwords ranked between WOTD_MIN_RANK and WOTD_MAX_RANK with
+// a gloss of their own, not just "genitive singular of ...". Dictionary
+// packs without frequencies fall back to every plain headword.
+func wordOfTheDayCandidates(words []string, glosses map[string][]tsk.Gloss, frequencies map[string]wordFrequency) []string {
+	var candidates []string
+	for word, freq := range frequencies {
+		if freq.Rank >= WOTD_MIN_RANK && freq.Rank <= WOTD_MAX_RANK && isPlainWord(word) && hasOwnMeaning(word, glosses) {
+			candidates = append(candidates, word)
+		}
+	}
+	if len(candidates) == 0 {
+		for _, word := range words {
+			if isPlainWord(word) && hasOwnMeaning(word, glosses) {
+				candidates = append(candidates, word)
+			}
+		}
+	}
+	sort.Strings(candidates)
+	return candidates
+}
+
+// hasOwnMeaning reports whether word has a gloss that isn't just a
+// pointer to the base form it is an inflection of.
+func hasOwnMeaning(word string, glosses map[string][]tsk.Gloss) bool {
+	return firstMeaning(word, glosses) != ""
+}
+
+// isFormGloss reports whether meaning only describes an inflected form:
+// "genitive singular of talo", or "inflection of maata:" and the bare form
+// names listed under it.
+func isFormGloss(meaning string) bool {
+	if _, _, ok := parseFormOf(meaning); ok || strings.HasPrefix(meaning, "inflection of ") {
+		return true
+	}
+	fields := strings.FieldsFunc(meaning, func(r rune) bool { return r == ' ' || r == '/' })
+	for _, w := range fields {
+		if !formWords[w] {
+			return false
+		}
+	}
+	return len(fields) > 0
+}
+
+// wordOfTheDay deterministically picks one of candidates for the given
+// day, so the same date always shows the same word.
+func wordOfTheDay(candidates []string, day time.Time) string {
+	n := len(candidates)
+	if n == 0 {
+		return ""
+	}
+	y, m, d := day.Date()
+	dayNumber := int(time.Date(y, m, d, 0, 0, 0, 0, time.UTC).Unix() / 86400)
+
+	// Stepping by a stride coprime to n visits every candidate once per
+	// n days.
+	stride := 7919
+	for gcd(stride, n) != 1 {
+		stride++
+	}
+	return candidates[(dayNumber%n)*stride%n]
+}
+
+func gcd(a, b int) int {
+	for b != 0 {
+		a, b = b, a%b
+	}
+	return a
+}
+
+// pointerGlossPrefixes start meanings that only send the reader to
+// another headword.
+var pointerGlossPrefixes = []string{"Synonym of ", "Alternative form of ", "Alternative spelling of ", "Misspelling of "}
+
+// firstMeaning returns the first meaning of word that isn't a form-of or
+// synonym pointer, for showing a word in a single line.
+func firstMeaning(word string, glosses map[string][]tsk.Gloss) string {
+	for _, g := range glosses[word] {
+	meanings:
+		for _, meaning := range g.Meanings {
+			if isFormGloss(meaning) {
+				continue
+			}
+			for _, prefix := range pointerGlossPrefixes {
+				if strings.HasPrefix(meaning, prefix) {
+					continue meanings
+				}
+			}
+			return meaning
+		}
+	}
+	return ""
+}
+
+// neighborBranches and neighborLeaves are how many neighbors the Alt-N
+// graph shows of the word, and of each of those.
+const (
+	neighborBranches = 8
+	neighborLeaves   = 4
+)
+
+// neighborNode is one word in the Alt-N graph, with the tree lines drawn
+// before it.
+type neighborNode struct {
+	word   string
+	prefix string
+}
+
+// neighborTree lays out word's neighbors and, under each, up to
+// neighborLeaves of its own that aren't shown already, as an indented tree.
+func neighborTree(dict *Dictionary, word string) []neighborNode {
+	branches := dict.Neighbors(word, neighborBranches)
+	shown := map[string]bool{word: true}
+	for _, w := range branches {
+		shown[w] = true
+	}
+	var nodes []neighborNode
+	for i, branch := range branches {
+		fork, stem := "├─ ", "│  "
+		if i == len(branches)-1 {
+			fork, stem = "└─ ", "   "
+		}
+		nodes = append(nodes, neighborNode{word: branch, prefix: fork})
+		var leaves []string
+		for _, w := range dict.Neighbors(branch, 0) {
+			if len(leaves) == neighborLeaves {
+				break
+			}
+			if !shown[w] {
+				shown[w] = true
+				leaves = append(leaves, w)
+			}
+		}
+		for j, leaf := range leaves {
+			fork := "├─ "
+			if j == len(leaves)-1 {
+				fork = "└─ "
+			}
+			nodes = append(nodes, neighborNode{word: leaf, prefix: stem + fork})
+		}
+	}
+	return nodes
+}
+
+// neighborTreeText draws the tree for Word Details, with the selected node
+// in reverse video and, if links is set, every node clickable as region
+// "0", "1" and so on.
+func neighborTreeText(word string, nodes []neighborNode, selected int, glosses map[string][]tsk.Gloss, links bool) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "[white]%s [gray]%s[white]\n", tview.Escape(word), tview.Escape(firstMeaning(word, glosses)))
+	for i, n := range nodes {
+		label := tview.Escape(n.word)
+		if links {
+			label = fmt.Sprintf(`["%d"]%s[""]`, i, label)
+		}
+		if i == selected {
+			label = "[::r]" + label + "[::-]"
+		}
+		meaning := []rune(firstMeaning(n.word, glosses))
+		if len(meaning) > 50 {
+			meaning = append(meaning[:49], '…')
+		}
+		fmt.Fprintf(&b, "[gray]%s[white]%s [gray]%s[white]\n", n.prefix, label, tview.Escape(string(meaning)))
+	}
+	return b.String()
+}
+
+// surpriseBand limits random words (Alt-S) to one of frequencyBandNames,
+// picked with --surprise-band or "surprise_band" in the config file.
+var surpriseBand string
+
+// checkFrequencyBand returns an error if band isn't one of
+// frequencyBandNames, or "" for any.
+func checkFrequencyBand(band string) error {
+	if band != "" && !slices.Contains(frequencyBandNames(), band) {
+		return fmt.Errorf("unknown frequency band '%s' (choose from %s)", band, strings.Join(frequencyBandNames(), ", "))
+	}
+	return nil
+}
+
+// surpriseCandidates returns the words a random word is picked from: plain
+// headwords with a gloss of their own, like the word of the day, limited to
+// the part of speech pos and the frequency band if they aren't "". Packs
+// without frequencies ignore the band.
+func surpriseCandidates(words []string, glosses map[string][]tsk.Gloss, frequencies map[string]wordFrequency, pos, band string) []string {
+	var candidates []string
+	for _, word := range words {
+		if !isPlainWord(word) || !hasOwnMeaning(word, glosses) {
+			continue
+		}
+		if pos != "" && !tsk.HasPos(glosses[word], pos) {
+			continue
+		}
+		if band != "" && len(frequencies) > 0 && frequencyBand(word, frequencies) != band {
+			continue
+		}
+		candidates = append(candidates, word)
+	}
+	return candidates
+}
+
+// runRandomCommand prints a random word with its glosses and an example
+// sentence, e.g. `tsk random --pos verb --band top5k`.
+func runRandomCommand(args []string) error {
+	fs := newCommandFlags("random")
+	pos := fs.String("pos", posOnly, "only pick words of this `part of speech`: "+strings.Join(posFilters, ", ")+", ...")
+	band := fs.String("band", surpriseBand, "only pick words this common: "+strings.Join(frequencyBandNames(), ", "))
+	fs.Parse(args)
+	if err := checkFrequencyBand(*band); err != nil {
+		return err
+	}
+
+	words, err := loadWords()
+	if err != nil {
+		return fmt.Errorf("loading words: %w", err)
+	}
+	glosses, err := loadGlosses()
+	if err != nil {
+		return fmt.Errorf("loading glosses: %w", err)
+	}
+	if *pos != "" {
+		if err := checkPos(glosses, *pos); err != nil {
+			return err
+		}
+	}
+	frequencies, err := loadFrequencies()
+	if err != nil {
+		return fmt.Errorf("loading frequencies: %w", err)
+	}
+	candidates := surpriseCandidates(words, glosses, frequencies, *pos, *band)
+	if len(candidates) == 0 {
+		return fmt.Errorf("no words to pick from")
+	}
+	word := candidates[mathrand.IntN(len(candidates))]
+
+	fmt.Println("===")
+	fmt.Println(strings.TrimSpace(stripColorTags(generateGlossText(word, glosses))))
+	db, err := openExamplesDB()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	if e, ok := exampleOfTheDay(db, word); ok {
+		fmt.Println("---")
+		fmt.Println(e.Finnish)
+		fmt.Println(e.English)
+	}
+	fmt.Println("===")
+	return nil
+}
+
+// exampleOfTheDay picks one example sentence for word, preferring one of
+// about 40 characters: long enough to show the word in use, short enough to
+// read at a glance.
+func exampleOfTheDay(db *sql.DB, word string) (tsk.Example, bool) {
+	var e tsk.Example
+	if db == nil {
+		return e, false
+	}
+	err := db.QueryRow(
+		"SELECT finnish, english FROM sentences WHERE sentences MATCH ? ORDER BY abs(length(finnish) - 40), finnish LIMIT 1",
+		tsk.MatchPhrase(word)).Scan(&e.Finnish, &e.English)
+	return e, err == nil
+}
+
+// runWotdCommand prints the word of the day with its glosses and an
+// example sentence, e.g. `tsk wotd` from a shell's startup file.
+func runWotdCommand(args []string) error {
+	fs := newCommandFlags("wotd")
+	date := fs.String("date", "", "show the word for this `day` (YYYY-MM-DD) instead of today")
+	fs.Parse(args)
+
+	day := time.Now()
+	if *date != "" {
+		var err error
+		if day, err = time.ParseInLocation("2006-01-02", *date, time.Local); err != nil {
+			return fmt.Errorf("invalid --date '%s': use YYYY-MM-DD", *date)
+		}
+	}
+
+	words, err := loadWords()
+	if err != nil {
+		return fmt.Errorf("loading words: %w", err)
+	}
+	glosses, err := loadGlosses()
+	if err != nil {
+		return fmt.Errorf("loading glosses: %w", err)
+	}
+	frequencies, err := loadFrequencies()
+	if err != nil {
+		return fmt.Errorf("loading frequencies: %w", err)
+	}
+	word := wordOfTheDay(wordOfTheDayCandidates(words, glosses, frequencies), day)
+	if word == "" {
+		return fmt.Errorf("no word of the day in this dictionary")
+	}
+
+	fmt.Printf("=== Word of the day, %s\n", day.Format("2 January 2006"))
+	fmt.Println(strings.TrimSpace(stripColorTags(generateGlossText(word, glosses))))
+	db, err := openExamplesDB()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	if e, ok := exampleOfTheDay(db, word); ok {
+		fmt.Println("---")
+		fmt.Println(e.Finnish)
+		fmt.Println(e.English)
+	}
+	fmt.Println("===")
+	return nil
+}
+
+// isPlainWord reports whether word consists only of lowercase letters.
+func isPlainWord(word string) bool {
+	if word == "" {
+		return false
+	}
+	for _, r := range word {
+		if !unicode.IsLower(r) {
+			return false
+		}
+	}
+	return true
+}